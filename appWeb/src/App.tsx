@@ -12,6 +12,7 @@ import jsonExamples from './codes/examples_json.json';
 import { EditorSettings as EditorSettingsType, Tab, EditorMode } from './types';
 import { useEditor } from './hooks/useEditor';
 import { useTerminal } from './hooks/useTerminal';
+import { useServerConfig } from './hooks/useServerConfig';
 import { EditorHeader } from './components/Editor/EditorHeader';
 
 function App() {
@@ -47,6 +48,8 @@ function App() {
     handleRunCode,
   } = useTerminal();
 
+  const serverConfig = useServerConfig();
+
   useEffect(() => {
     setOutput('');
   }, [mode]);
@@ -57,6 +60,8 @@ function App() {
   };
 
   const activeTab = tabs.find(tab => tab.id === activeTabId);
+  const isCodeTooLong = !!serverConfig && !!activeTab && activeTab.mode === 'go'
+    && activeTab.code.length > serverConfig.max_code_length;
 
   const handleCodeChange = (value: string | undefined) => {
     const newCode = value || '';
@@ -278,9 +283,12 @@ function App() {
                 <div className="grid grid-cols-1 sm:grid-cols-3 gap-3 w-full">
                   <button
                     onClick={handleRunAndUpdateCode}
-                    disabled={isLoading}
+                    disabled={isLoading || isCodeTooLong}
+                    title={isCodeTooLong
+                      ? `Code exceeds the server limit of ${serverConfig?.max_code_length} characters`
+                      : serverConfig ? `Execution timeout: ${serverConfig.execution_timeout_seconds}s` : undefined}
                     className={`px-4 py-2.5 rounded flex items-center gap-2 justify-center text-sm font-medium transition-colors ${
-                      isLoading 
+                      isLoading || isCodeTooLong
                         ? 'bg-gray-600 cursor-not-allowed' 
                         : 'bg-[#007acc] hover:bg-[#0066aa]'
                     }`}
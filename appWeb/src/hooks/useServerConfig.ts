@@ -0,0 +1,24 @@
+import { useEffect, useState } from 'react';
+import { ServerConfig } from '../types';
+
+// Fetches the sanitized server limits once; null until loaded (or if the request fails)
+export function useServerConfig() {
+  const [config, setConfig] = useState<ServerConfig | null>(null);
+
+  useEffect(() => {
+    let cancelled = false;
+    fetch('/api/config')
+      .then(response => (response.ok ? response.json() : null))
+      .then((data: ServerConfig | null) => {
+        if (!cancelled) setConfig(data);
+      })
+      .catch(() => {
+        // Without the config the UI simply skips the client-side checks
+      });
+    return () => {
+      cancelled = true;
+    };
+  }, []);
+
+  return config;
+}
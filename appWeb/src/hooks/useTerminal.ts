@@ -1,5 +1,28 @@
 import { useState } from 'react';
 import { marked } from 'marked';
+import { ExecutionStatus } from '../types';
+
+// The server appends the execution status as JSON after this separator
+const RESULT_SENTINEL = '\x1e';
+
+function splitExecutionStatus(text: string): { output: string; status?: ExecutionStatus } {
+  const index = text.lastIndexOf(RESULT_SENTINEL);
+  if (index === -1) {
+    return { output: text };
+  }
+  try {
+    const status = JSON.parse(text.slice(index + 1)) as ExecutionStatus;
+    return { output: text.slice(0, index).replace(/\n$/, ''), status };
+  } catch {
+    // Still streaming (or the program printed the separator itself)
+    return { output: text.slice(0, index) };
+  }
+}
+
+function formatExecutionStatus(status: ExecutionStatus): string {
+  const truncated = status.truncated ? ', output truncated' : '';
+  return `\n\n[exit code ${status.exitCode} in ${status.durationMs} ms${truncated}]`;
+}
 
 export function useTerminal() {
   const [output, setOutput] = useState('');
@@ -38,7 +61,7 @@ export function useTerminal() {
         headers: {
           'Content-Type': 'application/json',
         },
-        body: JSON.stringify({ code }),
+        body: JSON.stringify({ code, status_sentinel: true }),
       });
 
       if (!response.ok) {
@@ -51,15 +74,19 @@ export function useTerminal() {
       }
 
       const decoder = new TextDecoder();
+      let text = '';
       let done = false;
       while (!done) {
         const { value, done: doneReading } = await reader.read();
         done = doneReading;
         if (value) {
-          const chunk = decoder.decode(value);
-          setOutput(prev => prev + chunk);
+          text += decoder.decode(value, { stream: true });
+          setOutput(splitExecutionStatus(text).output);
         }
       }
+
+      const { output: programOutput, status } = splitExecutionStatus(text);
+      setOutput(status ? programOutput + formatExecutionStatus(status) : programOutput);
     } catch (error) {
       setOutput(`Failed to execute code: ${error instanceof Error ? error.message : 'Unknown error'}`);
     } finally {
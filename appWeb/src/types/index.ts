@@ -32,4 +32,25 @@ export interface TerminalOutputProps {
 
 export interface UserManualProps {
   isVisible: boolean;
-}
\ No newline at end of file
+}
+
+export interface ExecutionStatus {
+  exitCode: number;
+  durationMs: number;
+  truncated: boolean;
+  dataRaces?: number;
+}
+
+export interface ServerConfig {
+  max_code_length: number;
+  max_ast_nodes: number;
+  max_output_length: number;
+  max_output_lines: number;
+  execution_timeout_seconds: number;
+  max_requests_per_minute: number;
+  third_party_modules: boolean;
+  auto_wrap_code: boolean;
+  race_detector: boolean;
+  share_import: boolean;
+  compile_targets: { goos: string; goarch: string }[];
+}
@@ -0,0 +1,133 @@
+// Package audit registra quién ejecuta qué código en el servidor, para los
+// despliegues que necesitan un registro de auditoría. Del código se registra,
+// según CodeMode, su hash, que coincide con la clave del caché de ejecuciones,
+// nada o el código completo, que nunca va al log general sino a un CodeSink.
+package audit
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// LogType es el valor del campo "log" de las entradas de auditoría, que
+// permite separarlas del resto del log del servidor
+const LogType = "audit"
+
+// CodeMode indica qué se registra del código de cada ejecución
+type CodeMode string
+
+const (
+	// CodeModeFull guarda el código completo en el CodeSink, además del hash en el log
+	CodeModeFull CodeMode = "full"
+	// CodeModeHash registra solo el hash del código
+	CodeModeHash CodeMode = "hash"
+	// CodeModeNone no registra nada del código
+	CodeModeNone CodeMode = "none"
+)
+
+// Execution identifica una ejecución de código y a quien la solicitó
+type Execution struct {
+	RequestID string
+	ClientIP  string
+	// APIKeyID es el identificador de la clave de API de la solicitud, o vacío
+	APIKeyID string
+	Endpoint string
+	Language string
+	// CodeHash es el hash del código de executor.CodeHash, igual a su clave
+	// en el caché de ejecuciones
+	CodeHash string
+	// Code es el código ejecutado. Solo se registra con CodeModeFull.
+	Code string
+}
+
+// Outcome es el resultado de una ejecución
+type Outcome struct {
+	Duration time.Duration
+	// ExitCode es el código de salida del programa, o -1 si no terminó por sí mismo
+	ExitCode int
+	// Cached indica si la salida se sirvió desde el caché sin ejecutar el código
+	Cached bool
+	// Err es el error de la ejecución, o nil
+	Err error
+}
+
+// Recorder define el comportamiento para registrar el inicio y el final de
+// cada ejecución. Se llama de forma síncrona desde los manejadores, así que
+// debe ser rápido y seguro para uso concurrente.
+type Recorder interface {
+	ExecutionStarted(execution Execution)
+	ExecutionFinished(execution Execution, outcome Outcome)
+}
+
+// LogRecorder implementa Recorder escribiendo cada evento como una entrada
+// estructurada de log con el campo log=audit
+type LogRecorder struct {
+	log      logger.Logger
+	codeMode CodeMode
+	codeSink CodeSink
+}
+
+// NewLogRecorder crea un registro de auditoría que escribe en log y registra
+// el código según codeMode. Con CodeModeFull el código se guarda en codeSink,
+// que no puede ser nil, y en log solo su hash.
+func NewLogRecorder(log logger.Logger, codeMode CodeMode, codeSink CodeSink) *LogRecorder {
+	return &LogRecorder{
+		log:      log.With(zap.String("log", LogType)),
+		codeMode: codeMode,
+		codeSink: codeSink,
+	}
+}
+
+// ExecutionStarted implementa Recorder
+func (lr *LogRecorder) ExecutionStarted(execution Execution) {
+	if lr.codeMode == CodeModeFull {
+		if err := lr.codeSink.StoreCode(execution); err != nil {
+			lr.log.Error("Error al guardar el código en el registro de auditoría",
+				zap.String("request_id", execution.RequestID),
+				zap.String("code_hash", execution.CodeHash),
+				zap.Error(err))
+		}
+	}
+	lr.log.Info("Ejecución iniciada", lr.executionFields(execution)...)
+}
+
+// ExecutionFinished implementa Recorder
+func (lr *LogRecorder) ExecutionFinished(execution Execution, outcome Outcome) {
+	fields := append(lr.executionFields(execution),
+		zap.Int64("duration_ms", outcome.Duration.Milliseconds()),
+		zap.Int("exit_code", outcome.ExitCode),
+		zap.Bool("cached", outcome.Cached),
+	)
+	if outcome.Err != nil {
+		fields = append(fields, zap.String("error", outcome.Err.Error()))
+	}
+	lr.log.Info("Ejecución terminada", fields...)
+}
+
+// executionFields convierte execution en los campos comunes a los dos eventos.
+// El código nunca se incluye, y el hash solo si codeMode no es CodeModeNone.
+func (lr *LogRecorder) executionFields(execution Execution) []zap.Field {
+	fields := []zap.Field{
+		zap.String("request_id", execution.RequestID),
+		zap.String("client_ip", execution.ClientIP),
+		zap.String("api_key_id", execution.APIKeyID),
+		zap.String("endpoint", execution.Endpoint),
+		zap.String("language", execution.Language),
+	}
+	if lr.codeMode != CodeModeNone {
+		fields = append(fields, zap.String("code_hash", execution.CodeHash))
+	}
+	return fields
+}
+
+// ParseCodeMode convierte el valor de AUDIT_LOG_CODE en un CodeMode
+func ParseCodeMode(value string) (CodeMode, error) {
+	switch mode := CodeMode(value); mode {
+	case CodeModeFull, CodeModeHash, CodeModeNone:
+		return mode, nil
+	}
+	return "", fmt.Errorf("modo de código de auditoría desconocido: %q", value)
+}
@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// CodeSink define el comportamiento del almacén del código completo de las
+// ejecuciones con CodeModeFull. Debe ser seguro para uso concurrente.
+type CodeSink interface {
+	StoreCode(execution Execution) error
+}
+
+// codeRecord es una línea de FileSink
+type codeRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	RequestID string    `json:"request_id"`
+	ClientIP  string    `json:"client_ip"`
+	APIKeyID  string    `json:"api_key_id,omitempty"`
+	Endpoint  string    `json:"endpoint"`
+	Language  string    `json:"language"`
+	CodeHash  string    `json:"code_hash"`
+	Code      string    `json:"code"`
+}
+
+// FileSink implementa CodeSink añadiendo cada código como una línea JSON a un
+// archivo propio, separado del log general para poder restringir quién lo lee.
+// El archivo se crea con permisos 0600 y, si ya existe con permisos más
+// abiertos, se restringen al abrirlo.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileSink abre (o crea) path para añadir el código de las ejecuciones
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("error al abrir el archivo de auditoría del código: %w", err)
+	}
+	if err := file.Chmod(0600); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error al restringir los permisos del archivo de auditoría del código: %w", err)
+	}
+	return &FileSink{file: file}, nil
+}
+
+// StoreCode implementa CodeSink
+func (fs *FileSink) StoreCode(execution Execution) error {
+	line, err := json.Marshal(codeRecord{
+		Timestamp: time.Now().UTC(),
+		RequestID: execution.RequestID,
+		ClientIP:  execution.ClientIP,
+		APIKeyID:  execution.APIKeyID,
+		Endpoint:  execution.Endpoint,
+		Language:  execution.Language,
+		CodeHash:  execution.CodeHash,
+		Code:      execution.Code,
+	})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	_, err = fs.file.Write(line)
+	return err
+}
+
+// Close cierra el archivo
+func (fs *FileSink) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.file.Close()
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// bootstrapLogger escribe los avisos de la configuración, que se carga antes
+// de que exista el logger de la aplicación
+var bootstrapLogger = log.New(os.Stderr, "config: ", log.Ltime)
+
+// bootstrapMessages acumula los avisos de la llamada a NewConfig en curso.
+// newConfigMu evita que dos llamadas simultáneas mezclen sus avisos.
+var (
+	newConfigMu       sync.Mutex
+	bootstrapMessages = struct {
+		sync.Mutex
+		messages []string
+	}{}
+)
+
+// bootstrapf escribe un aviso de la configuración con bootstrapLogger y lo
+// guarda para repetirlo con LogBootstrapMessages. El mensaje empieza por
+// "WARNING: " o "ERROR: ", que indica el nivel con el que se repite.
+func bootstrapf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	bootstrapLogger.Print(msg)
+
+	bootstrapMessages.Lock()
+	defer bootstrapMessages.Unlock()
+	bootstrapMessages.messages = append(bootstrapMessages.messages, msg)
+}
+
+// takeBootstrapMessages devuelve los avisos acumulados y vacía el búfer
+func takeBootstrapMessages() []string {
+	bootstrapMessages.Lock()
+	defer bootstrapMessages.Unlock()
+	messages := bootstrapMessages.messages
+	bootstrapMessages.messages = nil
+	return messages
+}
+
+// BootstrapMessages devuelve los avisos que se produjeron al cargar la
+// configuración, en orden
+func (c *Config) BootstrapMessages() []string {
+	return c.bootstrapMessages
+}
+
+// LogBootstrapMessages repite con appLogger los avisos que se produjeron al cargar
+// la configuración, para que lleguen también al log estructurado y no solo a
+// stderr. Debe llamarse una vez, en cuanto se crea el logger de la aplicación.
+//
+// Ejemplo:
+//
+//     cfg := config.NewConfig()
+//     appLogger := logger.NewLogger(cfg.DebugMode)
+//     cfg.LogBootstrapMessages(appLogger)
+func (c *Config) LogBootstrapMessages(appLogger logger.Logger) {
+	for _, msg := range c.bootstrapMessages {
+		if text, ok := strings.CutPrefix(msg, "ERROR: "); ok {
+			appLogger.Error("Error en la configuración", zap.String("message", text))
+			continue
+		}
+		appLogger.Warn("Aviso de la configuración", zap.String("message", strings.TrimPrefix(msg, "WARNING: ")))
+	}
+}
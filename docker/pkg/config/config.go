@@ -14,42 +14,158 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"net/url"
 	"os"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/security"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/share"
 )
 
 // Config contiene toda la configuración de la aplicación Go Playground Plus.
 //
 // Esta estructura agrupa todas las opciones de configuración organizadas por categorías:
-// - Configuración del servidor (puerto, host, modo debug, directorio de archivos estáticos)
-// - Límites y seguridad (rate limiting, tamaño máximo de código, timeout de ejecución)
-// - Ejecución de código Go (ruta del ejecutable, directorio temporal, intervalo de limpieza)
+// - Configuración del servidor (puerto, host o socket Unix, modo debug y dirección de pprof, archivos estáticos, fallback SPA y timeouts HTTP)
+// - Límites y seguridad (rate limiting, máximo de goroutines antes de rechazar ejecuciones, tamaño máximo de código en bytes y en nodos AST, de la salida enviada y de la cacheada, timeouts de ejecución y de benchmarks)
+// - Detector de carreras (activación, timeout y límite de stderr propios)
+// - Ejecución de código Go (ruta del ejecutable, directorio temporal, intervalos de limpieza de temporales y caché, límites, usuario y variables de entorno del proceso hijo, envoltura automática del código, buffer de lectura de la salida)
+// - Sesiones (historial máximo por sesión y tiempo de expiración por inactividad)
 // - Logging (nivel y formato)
+// - Trazado (nombre del servicio y endpoint OTLP de OpenTelemetry)
+// - Errores (URL base de la documentación enlazada desde las respuestas de error)
+// - Encabezados de seguridad (CSP, X-Frame-Options, HSTS, Referrer-Policy...)
+// - Importación (URL y timeout de los enlaces compartidos del playground oficial)
+// - Administración (token de los endpoints /admin/*)
+// - Autenticación (hashes de las claves de API que exigen los endpoints /api/*)
+// - Alertas (webhook al superar la tasa de ejecuciones fallidas)
 type Config struct {
 	// Configuración del servidor
 	Port                string
 	Host                string
+	SocketPath          string
 	DebugMode          bool
+	PprofAddr          string
 	StaticFilesDir     string
+	SPAFallbackFile    string
+	SPANoFallbackPrefixes []string
+	ServerReadTimeout  time.Duration
+	ServerWriteTimeout time.Duration
+	ServerIdleTimeout  time.Duration
+	TLSCertFile        string
+	TLSKeyFile         string
+	HTTPRedirectPort   string
+	HTTP2Enabled       bool
+	H2CEnabled         bool
+	HandlerTimeout     time.Duration
+	HandlerTimeouts    map[string]time.Duration
 
 	// Límites y seguridad
 	MaxRequestsPerMinute int
+	MaxBurstSize         int
+	MaxExecutionsPerSecond int
+	EndpointRateLimits   map[string]int
+	MaxGoroutines        int
+	MaxConcurrentExecutions int
+	ExecutionQueueSize   int
 	MaxCodeLength        int
+	MaxDecompressedBodyBytes int64
+	MaxASTNodes          int
+	MaxStringLiteralBytes int
+	CodeDenyPatterns     []string
+	CodeDenyCalls        []string
+	RejectCgoDirectives  bool
 	MaxOutputLength      int
+	MaxCachedOutputLength int
+	MaxStdoutLength      int
+	MaxStderrLength      int
+	MaxOutputLines       int
+	NormalizeOutput      bool
 	ExecutionTimeout     time.Duration
+	BenchmarkTimeout     time.Duration
 	AllowedOrigins       []string
 
+	// Encabezados de seguridad
+	ContentSecurityPolicy   string
+	FrameOptions            string
+	ContentTypeOptions      string
+	StrictTransportSecurity string
+	ReferrerPolicy          string
+
+	// Detector de carreras ('go run -race')
+	RaceDetectorEnabled  bool
+	RaceExecutionTimeout time.Duration
+	RaceMaxStderrLength  int
+
 	// Ejecución de código Go
 	GoExecutablePath     string
 	TempDir              string
+	MaxConcurrentTempFiles int
+	TempDirQuotaBytes    int64
+	KeepTempFiles        bool
 	CleanupInterval      time.Duration
+	MaxCacheSize         int
+	CacheTTL             time.Duration
+	CacheCleanupInterval time.Duration
+	CacheNormalizeCode   bool
+	ChildGOMAXPROCS      int
+	ChildMaxProcesses    int
+	ChildUID             int
+	ChildGID             int
+	ChildEnvPassthrough  []string
+	ChildEnvVars         map[string]string
+	ChildScratchHome     bool
+	AutoWrapCode         bool
+	ReadBufferSize       int
+	FakeTimeEnabled      bool
+
+	// Sesiones
+	MaxSessionHistory    int
+	SessionTTL           time.Duration
 
 	// Logging
 	LogLevel            string
 	LogFormat           string
+	AuditLogEnabled     bool
+	AuditLogCode        string
+	AuditCodeFile       string
+
+	// Trazado (OpenTelemetry)
+	OTELServiceName      string
+	OTELExporterEndpoint string
+
+	// Errores
+	ErrorDocsBaseURL string
+
+	// Importación de enlaces compartidos
+	ShareImportURL     string
+	ShareImportTimeout time.Duration
+
+	// Administración
+	AdminToken string
+
+	// Autenticación con claves de API
+	APIKeyHashes     []string
+	APIKeyDailyQuota int
+	APIKeyQuotas     map[string]int
+
+	// Alertas por webhook
+	WebhookURL                  string
+	WebhookFailureRateThreshold int
+	WebhookWindow               time.Duration
+	WebhookDebounce             time.Duration
+
+	// Seguimiento de errores
+	SentryDSN string
+
+	// bootstrapMessages son los avisos de NewConfig (ver LogBootstrapMessages)
+	bootstrapMessages []string
 }
 
 // NewConfig crea una nueva configuración con valores por defecto
@@ -73,33 +189,144 @@ type Config struct {
 //     // La configuración tendrá SERVER_PORT="9000" y DEBUG_MODE=true,
 //     // mientras que el resto de opciones tendrán sus valores por defecto
 func NewConfig() *Config {
+	// Los avisos de la carga se guardan en cfg.bootstrapMessages
+	newConfigMu.Lock()
+	defer newConfigMu.Unlock()
+	takeBootstrapMessages()
+
 	// Valores por defecto
 	cfg := &Config{
 		// Configuración del servidor
 		Port:            getEnvString("SERVER_PORT", "8080"),
 		Host:            getEnvString("SERVER_HOST", "0.0.0.0"),
+		SocketPath:      getEnvString("SERVER_SOCKET_PATH", ""),
 		DebugMode:       getEnvBool("DEBUG_MODE", false),
+		PprofAddr:       getEnvString("PPROF_ADDR", "127.0.0.1:6060"),
 		StaticFilesDir:  getEnvString("STATIC_FILES_DIR", "/app/build"),
+		SPAFallbackFile: getEnvString("SPA_FALLBACK_FILE", "index.html"),
+		SPANoFallbackPrefixes: getEnvStringSlice("SPA_NO_FALLBACK_PREFIXES", []string{"/api"}),
+		ServerReadTimeout:  getEnvDuration("SERVER_READ_TIMEOUT_SECONDS", 5*time.Second),
+		ServerWriteTimeout: getEnvDuration("SERVER_WRITE_TIMEOUT_SECONDS", 60*time.Second),
+		ServerIdleTimeout:  getEnvDuration("SERVER_IDLE_TIMEOUT_SECONDS", 120*time.Second),
+		TLSCertFile:        getEnvString("TLS_CERT_FILE", ""),
+		TLSKeyFile:         getEnvString("TLS_KEY_FILE", ""),
+		HTTPRedirectPort:   getEnvString("HTTP_REDIRECT_PORT", ""),
+		HTTP2Enabled:       getEnvBool("HTTP2_ENABLED", true),
+		H2CEnabled:         getEnvBool("H2C_ENABLED", false),
+		HandlerTimeout:     getEnvDuration("HANDLER_TIMEOUT_SECONDS", 30*time.Second),
+		HandlerTimeouts:    parseHandlerTimeouts(getEnvStringMap("HANDLER_TIMEOUTS")),
 
 		// Límites y seguridad
 		MaxRequestsPerMinute: getEnvInt("MAX_REQUESTS_PER_MINUTE", 30),
+		EndpointRateLimits:   parseEndpointRateLimits(getEnvStringMap("ENDPOINT_RATE_LIMITS")),
+		MaxExecutionsPerSecond: getEnvInt("MAX_EXECUTIONS_PER_SECOND", 0),
+		MaxGoroutines:        getEnvInt("MAX_GOROUTINES", 0),
+		MaxConcurrentExecutions: getEnvInt("MAX_CONCURRENT_EXECUTIONS", 0),
+		ExecutionQueueSize:   getEnvInt("EXECUTION_QUEUE_SIZE", 50),
 		MaxCodeLength:        getEnvInt("MAX_CODE_LENGTH", 10000),
+		MaxDecompressedBodyBytes: int64(getEnvInt("MAX_DECOMPRESSED_BODY_BYTES", 1024*1024)),
+		MaxASTNodes:          getEnvInt("MAX_AST_NODES", 1000),
+		MaxStringLiteralBytes: getEnvInt("MAX_STRING_LITERAL_BYTES", 10*1024),
+		CodeDenyPatterns:     getEnvSeparatedSlice("CODE_DENY_PATTERNS", ";", nil),
+		CodeDenyCalls:        getEnvStringSlice("CODE_DENY_CALLS", nil),
+		RejectCgoDirectives:  getEnvBool("REJECT_CGO_DIRECTIVES", true),
 		MaxOutputLength:      getEnvInt("MAX_OUTPUT_LENGTH", 10000),
-		ExecutionTimeout:     time.Duration(getEnvInt("EXECUTION_TIMEOUT_SECONDS", 10)) * time.Second,
+		NormalizeOutput:      getEnvBool("NORMALIZE_OUTPUT", false),
+		// CACHE_MAX_ENTRY_BYTES es el nombre anterior de MAX_CACHED_OUTPUT_LENGTH
+		MaxCachedOutputLength: getEnvInt("MAX_CACHED_OUTPUT_LENGTH", getEnvInt("CACHE_MAX_ENTRY_BYTES", 64*1024)),
+		ExecutionTimeout:     getEnvDuration("EXECUTION_TIMEOUT_SECONDS", 10*time.Second),
+		BenchmarkTimeout:     getEnvDuration("BENCHMARK_TIMEOUT_SECONDS", 30*time.Second),
 		AllowedOrigins:       getEnvStringSlice("ALLOWED_ORIGINS", []string{"*"}),
 
+		// Encabezados de seguridad
+		ContentSecurityPolicy:   strings.TrimSpace(getEnvString("CONTENT_SECURITY_POLICY", security.DefaultContentSecurityPolicy)),
+		FrameOptions:            getEnvString("X_FRAME_OPTIONS", "DENY"),
+		ContentTypeOptions:      getEnvString("X_CONTENT_TYPE_OPTIONS", "nosniff"),
+		StrictTransportSecurity: getEnvString("STRICT_TRANSPORT_SECURITY", ""),
+		ReferrerPolicy:          getEnvString("REFERRER_POLICY", ""),
+
+		// Detector de carreras
+		RaceDetectorEnabled:  getEnvBool("RACE_DETECTOR_ENABLED", false),
+		RaceExecutionTimeout: getEnvDuration("RACE_EXECUTION_TIMEOUT_SECONDS", 30*time.Second),
+
 		// Ejecución de código Go
 		GoExecutablePath: getEnvString("GO_EXECUTABLE_PATH", "/usr/local/go/bin/go"),
 		TempDir:          getEnvString("TEMP_DIR", os.TempDir()),
-		CleanupInterval:  time.Duration(getEnvInt("CLEANUP_INTERVAL_MINUTES", 60)) * time.Minute,
+		MaxConcurrentTempFiles: getEnvInt("MAX_CONCURRENT_TEMP_FILES", 50),
+		TempDirQuotaBytes: int64(getEnvInt("TEMP_DIR_QUOTA_BYTES", 256*1024*1024)),
+		KeepTempFiles:    getEnvBool("KEEP_TEMP_FILES", false),
+		CleanupInterval:  getEnvDurationUnit("CLEANUP_INTERVAL_MINUTES", time.Minute, 60*time.Minute),
+		MaxCacheSize:     getEnvInt("MAX_CACHE_SIZE", 100),
+		CacheTTL:         getEnvDurationUnit("CACHE_TTL_MINUTES", time.Minute, 30*time.Minute),
+		CacheNormalizeCode: getEnvBool("CACHE_NORMALIZE_CODE", false),
+		ChildGOMAXPROCS:   getEnvInt("CHILD_GOMAXPROCS", 1),
+		ChildMaxProcesses: getEnvInt("CHILD_MAX_PROCESSES", 256),
+		ChildUID:          getEnvInt("CHILD_UID", -1),
+		ChildGID:          getEnvInt("CHILD_GID", -1),
+		ChildEnvPassthrough: getEnvStringSlice("CHILD_ENV_PASSTHROUGH", nil),
+		ChildEnvVars:        getEnvStringMap("CHILD_ENV_VARS"),
+		ChildScratchHome:  getEnvBool("CHILD_SCRATCH_HOME", false),
+		AutoWrapCode:      getEnvBool("AUTO_WRAP_CODE", false),
+		ReadBufferSize:    getEnvInt("EXECUTOR_READ_BUFFER_BYTES", 32*1024),
+		FakeTimeEnabled:   getEnvBool("FAKE_TIME_ENABLED", true),
+
+		// Sesiones
+		MaxSessionHistory: getEnvInt("MAX_SESSION_HISTORY", 20),
+		SessionTTL:        getEnvDurationUnit("SESSION_TTL_MINUTES", time.Minute, 60*time.Minute),
 
 		// Logging
-		LogLevel:  getEnvString("LOG_LEVEL", "info"),
-		LogFormat: getEnvString("LOG_FORMAT", "json"),
+		LogLevel:        getEnvString("LOG_LEVEL", "info"),
+		LogFormat:       getEnvString("LOG_FORMAT", "json"),
+		AuditLogEnabled: getEnvBool("AUDIT_LOG_ENABLED", false),
+		AuditLogCode:    getEnvString("AUDIT_LOG_CODE", "hash"),
+		AuditCodeFile:   getEnvString("AUDIT_CODE_FILE", ""),
+
+		// Trazado (OpenTelemetry)
+		OTELServiceName:      getEnvString("OTEL_SERVICE_NAME", "go-playground-plus"),
+		OTELExporterEndpoint: getEnvString("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+
+		// Errores
+		ErrorDocsBaseURL: getEnvString("ERROR_DOCS_BASE_URL", ""),
+
+		// Importación de enlaces compartidos
+		ShareImportURL:     getEnvString("SHARE_IMPORT_URL", share.DefaultBaseURL),
+		ShareImportTimeout: getEnvDuration("SHARE_IMPORT_TIMEOUT_SECONDS", 10*time.Second),
+
+		// Administración
+		AdminToken: getEnvString("ADMIN_TOKEN", ""),
+
+		// Autenticación con claves de API
+		APIKeyHashes:     getEnvStringSlice("API_KEY_HASHES", nil),
+		APIKeyDailyQuota: getEnvInt("API_KEY_DAILY_QUOTA", 0),
+		APIKeyQuotas:     parseAPIKeyQuotas(getEnvStringMap("API_KEY_QUOTAS")),
+
+		// Alertas por webhook
+		WebhookURL:                  getEnvString("WEBHOOK_URL", ""),
+		WebhookFailureRateThreshold: getEnvInt("WEBHOOK_FAILURE_RATE_THRESHOLD", 50),
+		WebhookWindow:               getEnvDurationUnit("WEBHOOK_WINDOW_MINUTES", time.Minute, 5*time.Minute),
+		WebhookDebounce:             getEnvDurationUnit("WEBHOOK_DEBOUNCE_MINUTES", time.Minute, 5*time.Minute),
+
+		// Seguimiento de errores
+		SentryDSN: getEnvString("SENTRY_DSN", ""),
 	}
 
+	// Sin ráfaga propia, un cliente puede gastar el límite de un minuto de golpe
+	cfg.MaxBurstSize = getEnvInt("MAX_BURST_SIZE", cfg.MaxRequestsPerMinute)
+
+	// La limpieza del caché usa CLEANUP_INTERVAL_MINUTES como valor por defecto
+	cfg.CacheCleanupInterval = getEnvDurationUnit("CACHE_CLEANUP_INTERVAL_MINUTES", time.Minute, cfg.CleanupInterval)
+
+	// Los límites por stream usan MAX_OUTPUT_LENGTH como valor por defecto
+	cfg.MaxStdoutLength = getEnvInt("MAX_STDOUT_LENGTH", cfg.MaxOutputLength)
+	cfg.MaxStderrLength = getEnvInt("MAX_STDERR_LENGTH", cfg.MaxOutputLength)
+	cfg.MaxOutputLines = getEnvInt("MAX_OUTPUT_LINES", 0)
+
+	// Los informes del detector de carreras ocupan bastante más que un error normal
+	cfg.RaceMaxStderrLength = getEnvInt("RACE_MAX_STDERR_LENGTH", 4*cfg.MaxStderrLength)
+
 	// Validación de la configuración
 	validateConfig(cfg)
+	cfg.bootstrapMessages = takeBootstrapMessages()
 
 	return cfg
 }
@@ -121,8 +348,10 @@ func NewConfig() *Config {
 //     port := getEnvString("SERVER_PORT", "8080")
 func getEnvString(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists && value != "" {
+		recordEnvVar(key, value, true)
 		return value
 	}
+	recordEnvVar(key, defaultValue, false)
 	return defaultValue
 }
 
@@ -140,9 +369,45 @@ func getEnvString(key, defaultValue string) string {
 func getEnvInt(key string, defaultValue int) int {
 	if value, exists := os.LookupEnv(key); exists && value != "" {
 		if intValue, err := strconv.Atoi(value); err == nil {
+			recordEnvVar(key, strconv.Itoa(intValue), true)
 			return intValue
 		}
 	}
+	recordEnvVar(key, strconv.Itoa(defaultValue), false)
+	return defaultValue
+}
+
+// getEnvDuration obtiene una variable de entorno time.Duration o devuelve el valor por defecto.
+//
+// Acepta la sintaxis de time.ParseDuration ("10s", "1m30s", "500ms") y, por
+// compatibilidad con las variables *_SECONDS, un entero de segundos. Si el
+// valor no es válido de ninguna de las dos formas usa el valor por defecto con un aviso.
+//
+// Ejemplo:
+//
+//     // Con EXECUTION_TIMEOUT_SECONDS="1m30s" o "90"
+//     timeout := getEnvDuration("EXECUTION_TIMEOUT_SECONDS", 10*time.Second)
+//     // timeout = 90 * time.Second
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	return getEnvDurationUnit(key, time.Second, defaultValue)
+}
+
+// getEnvDurationUnit es como getEnvDuration, pero interpreta los enteros en
+// unit, para que las variables *_MINUTES sigan leyéndose en minutos
+func getEnvDurationUnit(key string, unit, defaultValue time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists && value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			recordEnvVar(key, duration.String(), true)
+			return duration
+		}
+		if intValue, err := strconv.Atoi(value); err == nil {
+			duration := time.Duration(intValue) * unit
+			recordEnvVar(key, duration.String(), true)
+			return duration
+		}
+		bootstrapf("WARNING: %s: %q no es una duración válida (ej. 10s, 1m30s, 500ms), se usa %s", key, value, defaultValue)
+	}
+	recordEnvVar(key, defaultValue.String(), false)
 	return defaultValue
 }
 
@@ -161,8 +426,11 @@ func getEnvInt(key string, defaultValue int) int {
 func getEnvBool(key string, defaultValue bool) bool {
 	if value, exists := os.LookupEnv(key); exists && value != "" {
 		value = strings.ToLower(value)
-		return value == "true" || value == "1" || value == "yes" || value == "y"
+		enabled := value == "true" || value == "1" || value == "yes" || value == "y"
+		recordEnvVar(key, strconv.FormatBool(enabled), true)
+		return enabled
 	}
+	recordEnvVar(key, strconv.FormatBool(defaultValue), false)
 	return defaultValue
 }
 
@@ -173,20 +441,87 @@ func getEnvBool(key string, defaultValue bool) bool {
 //   - defaultValue: Valor por defecto a utilizar si la variable no existe o está vacía.
 //
 // Retorna el valor de la variable de entorno dividido por comas como slice de strings,
-// o el valor por defecto si la variable no existe.
+// sin los espacios alrededor de cada elemento ni los elementos vacíos, o el valor
+// por defecto si la variable no existe.
 //
 // Ejemplo:
 //
-//     // Con ALLOWED_ORIGINS="http://localhost:3000,https://example.com"
+//     // Con ALLOWED_ORIGINS="http://localhost:3000, https://example.com"
 //     origins := getEnvStringSlice("ALLOWED_ORIGINS", []string{"*"})
 //     // origins = ["http://localhost:3000", "https://example.com"]
 func getEnvStringSlice(key string, defaultValue []string) []string {
+	return getEnvSeparatedSlice(key, ",", defaultValue)
+}
+
+// getEnvSeparatedSlice es como getEnvStringSlice, pero divide el valor por sep
+// en lugar de por comas, para listas cuyos elementos pueden contener comas
+func getEnvSeparatedSlice(key, sep string, defaultValue []string) []string {
 	if value, exists := os.LookupEnv(key); exists && value != "" {
-		return strings.Split(value, ",")
+		var values []string
+		for _, item := range strings.Split(value, sep) {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+		recordEnvVar(key, strings.Join(values, sep), true)
+		return values
 	}
+	recordEnvVar(key, strings.Join(defaultValue, sep), false)
 	return defaultValue
 }
 
+// getEnvStringMap obtiene una variable de entorno con pares CLAVE=valor separados
+// por comas y la devuelve como mapa. Los elementos sin "=" se ignoran con un aviso.
+// Retorna nil si la variable no existe o está vacía.
+//
+// Ejemplo:
+//
+//     // Con CHILD_ENV_VARS="MYAPP_MODE=demo, LANG=es_ES.UTF-8"
+//     vars := getEnvStringMap("CHILD_ENV_VARS")
+//     // vars = {"MYAPP_MODE": "demo", "LANG": "es_ES.UTF-8"}
+func getEnvStringMap(key string) map[string]string {
+	items := getEnvStringSlice(key, nil)
+	if len(items) == 0 {
+		return nil
+	}
+	values := make(map[string]string, len(items))
+	for _, item := range items {
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			bootstrapf("WARNING: %s: %q no tiene el formato CLAVE=valor, se ignora", key, item)
+			continue
+		}
+		values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return values
+}
+
+// minMaxCodeLength es el valor mínimo de MaxCodeLength
+const minMaxCodeLength = 100
+
+// minExecutionTimeout es el valor mínimo de ExecutionTimeout
+const minExecutionTimeout = time.Second
+
+// serverWriteTimeoutMargin es el tiempo mínimo que ServerWriteTimeout debe
+// superar a ExecutionTimeout, para compilar y enviar el resultado final
+const serverWriteTimeoutMargin = 10 * time.Second
+
+// maxBenchmarkTimeout es el tiempo máximo que se permite a una ejecución de benchmarks
+const maxBenchmarkTimeout = 5 * time.Minute
+
+// minCacheCleanupInterval es el intervalo mínimo de limpieza del caché
+const minCacheCleanupInterval = 10 * time.Second
+
+// defaultStrictTransportSecurity es el valor de Strict-Transport-Security con
+// TLS activo si STRICT_TRANSPORT_SECURITY no está definido (un año)
+const defaultStrictTransportSecurity = "max-age=31536000"
+
+// minAdminTokenLength es la longitud recomendada de ADMIN_TOKEN
+const minAdminTokenLength = 16
+
+// minMaxGoroutines es el valor mínimo de MAX_GOROUTINES cuando está activado
+const minMaxGoroutines = 100
+
 // validateConfig valida la configuración y ajusta valores si es necesario.
 //
 // Esta función realiza comprobaciones de seguridad y validez en la configuración,
@@ -201,17 +536,282 @@ func validateConfig(cfg *Config) {
 	// Validar límites mínimos
 	if cfg.MaxRequestsPerMinute < 1 {
 		cfg.MaxRequestsPerMinute = 1
-		fmt.Println("WARNING: MAX_REQUESTS_PER_MINUTE ajustado a valor mínimo de 1")
+		bootstrapf("WARNING: MAX_REQUESTS_PER_MINUTE ajustado a valor mínimo de 1")
+	}
+	if cfg.MaxBurstSize < 1 {
+		cfg.MaxBurstSize = 1
+		bootstrapf("WARNING: MAX_BURST_SIZE ajustado a valor mínimo de 1")
+	}
+
+	if cfg.MaxExecutionsPerSecond < 0 {
+		cfg.MaxExecutionsPerSecond = 0
+		bootstrapf("WARNING: MAX_EXECUTIONS_PER_SECOND negativo, se desactiva el límite global")
+	}
+
+	// Por debajo de las goroutines propias del servidor en reposo se rechazaría todo
+	if cfg.MaxGoroutines < 0 {
+		cfg.MaxGoroutines = 0
+		bootstrapf("WARNING: MAX_GOROUTINES negativo, se desactiva el rechazo por carga")
+	} else if cfg.MaxGoroutines > 0 && cfg.MaxGoroutines < minMaxGoroutines {
+		cfg.MaxGoroutines = minMaxGoroutines
+		bootstrapf("WARNING: MAX_GOROUTINES ajustado a valor mínimo de %d", minMaxGoroutines)
+	}
+
+	if cfg.MaxConcurrentExecutions < 0 {
+		cfg.MaxConcurrentExecutions = 0
+		bootstrapf("WARNING: MAX_CONCURRENT_EXECUTIONS negativo, se desactiva la cola de ejecución")
+	}
+
+	if cfg.ExecutionQueueSize < 0 {
+		cfg.ExecutionQueueSize = 0
+		bootstrapf("WARNING: EXECUTION_QUEUE_SIZE ajustado a valor mínimo de 0")
+	}
+
+	if cfg.MaxCodeLength < minMaxCodeLength {
+		cfg.MaxCodeLength = minMaxCodeLength
+		bootstrapf("WARNING: MAX_CODE_LENGTH ajustado a valor mínimo de %d", minMaxCodeLength)
+	}
+
+	// El cuerpo descomprimido debe poder contener al menos un código del tamaño máximo
+	if cfg.MaxDecompressedBodyBytes < int64(cfg.MaxCodeLength) {
+		cfg.MaxDecompressedBodyBytes = int64(cfg.MaxCodeLength)
+		bootstrapf("WARNING: MAX_DECOMPRESSED_BODY_BYTES ajustado a MAX_CODE_LENGTH")
+	}
+
+	if cfg.MaxASTNodes < 0 {
+		cfg.MaxASTNodes = 0
+		bootstrapf("WARNING: MAX_AST_NODES negativo, se desactiva el límite")
+	}
+
+	if cfg.MaxStringLiteralBytes < 0 {
+		cfg.MaxStringLiteralBytes = 0
+		bootstrapf("WARNING: MAX_STRING_LITERAL_BYTES negativo, se desactiva el límite")
+	}
+
+	// TLS necesita el certificado y la clave; con uno solo se sirve HTTP
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		cfg.TLSCertFile, cfg.TLSKeyFile = "", ""
+		bootstrapf("WARNING: TLS_CERT_FILE y TLS_KEY_FILE deben definirse juntos, se sirve HTTP sin TLS")
+	}
+
+	// Con TLS se envía HSTS aunque STRICT_TRANSPORT_SECURITY no esté definido
+	if cfg.TLSEnabled() && cfg.StrictTransportSecurity == "" {
+		cfg.StrictTransportSecurity = defaultStrictTransportSecurity
+	}
+
+	if cfg.HTTPRedirectPort != "" && !cfg.TLSEnabled() {
+		cfg.HTTPRedirectPort = ""
+		bootstrapf("WARNING: HTTP_REDIRECT_PORT requiere TLS_CERT_FILE y TLS_KEY_FILE, se ignora")
+	}
+
+	// h2c es HTTP/2 sin cifrar: con TLS el cliente ya negocia HTTP/2 por ALPN
+	if cfg.H2CEnabled && cfg.TLSEnabled() {
+		cfg.H2CEnabled = false
+		bootstrapf("WARNING: H2C_ENABLED no se aplica con TLS_CERT_FILE y TLS_KEY_FILE, se ignora")
+	}
+
+	switch cfg.AuditLogCode {
+	case "full", "hash", "none":
+	default:
+		bootstrapf("WARNING: AUDIT_LOG_CODE debe ser full, hash o none, se usa hash")
+		cfg.AuditLogCode = "hash"
+	}
+	// El código completo nunca va al log general: sin archivo propio solo se guarda el hash
+	if cfg.AuditLogCode == "full" && cfg.AuditCodeFile == "" {
+		bootstrapf("WARNING: AUDIT_LOG_CODE=full requiere AUDIT_CODE_FILE, se usa hash")
+		cfg.AuditLogCode = "hash"
+	}
+
+	// Una CSP vacía dejaría el frontend sin protección frente a XSS. La variable
+	// vacía ya usa la política por defecto; aquí se rechaza la que solo tiene espacios
+	if cfg.ContentSecurityPolicy == "" {
+		cfg.ContentSecurityPolicy = security.DefaultContentSecurityPolicy
+		bootstrapf("WARNING: CONTENT_SECURITY_POLICY está vacía, se usa la política por defecto")
+	}
+
+	if cfg.WebhookURL != "" {
+		if u, err := url.Parse(cfg.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			bootstrapf("WARNING: WEBHOOK_URL %q no es una URL http(s) válida, se desactivan las alertas", cfg.WebhookURL)
+			cfg.WebhookURL = ""
+		}
+	}
+
+	if cfg.WebhookFailureRateThreshold < 1 || cfg.WebhookFailureRateThreshold > 100 {
+		cfg.WebhookFailureRateThreshold = min(max(cfg.WebhookFailureRateThreshold, 1), 100)
+		bootstrapf("WARNING: WEBHOOK_FAILURE_RATE_THRESHOLD ajustado al rango 1-100")
+	}
+
+	if cfg.WebhookWindow < time.Minute {
+		cfg.WebhookWindow = time.Minute
+		bootstrapf("WARNING: WEBHOOK_WINDOW_MINUTES ajustado a valor mínimo de 1 minuto")
+	}
+
+	if cfg.WebhookDebounce < 0 {
+		cfg.WebhookDebounce = 0
+		bootstrapf("WARNING: WEBHOOK_DEBOUNCE_MINUTES ajustado a valor mínimo de 0")
+	}
+
+	if cfg.AdminToken != "" && len(cfg.AdminToken) < minAdminTokenLength {
+		bootstrapf("WARNING: ADMIN_TOKEN tiene menos de %d caracteres, use un token aleatorio más largo", minAdminTokenLength)
+	}
+
+	cfg.APIKeyHashes = validateAPIKeyHashes(cfg.APIKeyHashes)
+	if cfg.APIKeyDailyQuota < 0 {
+		cfg.APIKeyDailyQuota = 0
+		bootstrapf("WARNING: API_KEY_DAILY_QUOTA negativo, se desactiva la cuota")
+	}
+	for id := range cfg.APIKeyQuotas {
+		if !hasAPIKeyID(cfg.APIKeyHashes, id) {
+			bootstrapf("WARNING: API_KEY_QUOTAS: %q no identifica ninguna clave de API_KEY_HASHES", id)
+		}
+	}
+	cfg.AllowedOrigins = validateAllowedOrigins(cfg.AllowedOrigins)
+	cfg.CodeDenyPatterns = validateCodeDenyPatterns(cfg.CodeDenyPatterns)
+	cfg.CodeDenyCalls = validateCodeDenyCalls(cfg.CodeDenyCalls)
+
+	if cfg.MaxCachedOutputLength < 0 {
+		cfg.MaxCachedOutputLength = 0
+		bootstrapf("WARNING: MAX_CACHED_OUTPUT_LENGTH negativo, se desactiva el límite")
+	}
+
+	if cfg.MaxStdoutLength < 0 {
+		cfg.MaxStdoutLength = 0
+		bootstrapf("WARNING: MAX_STDOUT_LENGTH ajustado a valor mínimo de 0")
+	}
+
+	if cfg.MaxStderrLength < 0 {
+		cfg.MaxStderrLength = 0
+		bootstrapf("WARNING: MAX_STDERR_LENGTH ajustado a valor mínimo de 0")
+	}
+
+	if cfg.MaxOutputLines < 0 {
+		cfg.MaxOutputLines = 0
+		bootstrapf("WARNING: MAX_OUTPUT_LINES ajustado a valor mínimo de 0")
+	}
+
+	if cfg.ExecutionTimeout < minExecutionTimeout {
+		cfg.ExecutionTimeout = minExecutionTimeout
+		bootstrapf("WARNING: EXECUTION_TIMEOUT_SECONDS ajustado a valor mínimo de 1 segundo")
+	}
+
+	// -race multiplica el tiempo de ejecución, así que nunca se da menos que sin él
+	if cfg.RaceExecutionTimeout < cfg.ExecutionTimeout {
+		cfg.RaceExecutionTimeout = cfg.ExecutionTimeout
+		bootstrapf("WARNING: RACE_EXECUTION_TIMEOUT_SECONDS ajustado a EXECUTION_TIMEOUT_SECONDS")
+	}
+
+	if cfg.RaceMaxStderrLength < cfg.MaxStderrLength {
+		cfg.RaceMaxStderrLength = cfg.MaxStderrLength
+		bootstrapf("WARNING: RACE_MAX_STDERR_LENGTH ajustado a MAX_STDERR_LENGTH")
+	}
+
+	if cfg.BenchmarkTimeout < time.Second {
+		cfg.BenchmarkTimeout = time.Second
+		bootstrapf("WARNING: BENCHMARK_TIMEOUT_SECONDS ajustado a valor mínimo de 1 segundo")
+	}
+
+	if cfg.BenchmarkTimeout > maxBenchmarkTimeout {
+		cfg.BenchmarkTimeout = maxBenchmarkTimeout
+		bootstrapf("WARNING: BENCHMARK_TIMEOUT_SECONDS ajustado a valor máximo de %v", maxBenchmarkTimeout)
+	}
+
+	if cfg.ServerReadTimeout < time.Second {
+		cfg.ServerReadTimeout = time.Second
+		bootstrapf("WARNING: SERVER_READ_TIMEOUT_SECONDS ajustado a valor mínimo de 1 segundo")
+	}
+
+	// La respuesta de /api/execute se transmite mientras el programa se ejecuta,
+	// así que la escritura debe poder durar más que la ejecución (o los benchmarks) completa
+	if minWrite := cfg.MaxRequestTimeout() + serverWriteTimeoutMargin; cfg.ServerWriteTimeout < minWrite {
+		cfg.ServerWriteTimeout = minWrite
+		bootstrapf("WARNING: SERVER_WRITE_TIMEOUT_SECONDS debe superar EXECUTION_TIMEOUT_SECONDS y BENCHMARK_TIMEOUT_SECONDS, ajustado a %v", minWrite)
+	}
+
+	if cfg.HandlerTimeout < 0 {
+		cfg.HandlerTimeout = 0
+		bootstrapf("WARNING: HANDLER_TIMEOUT_SECONDS no puede ser negativo, se desactiva")
+	}
+
+	if cfg.ServerIdleTimeout < time.Second {
+		cfg.ServerIdleTimeout = time.Second
+		bootstrapf("WARNING: SERVER_IDLE_TIMEOUT_SECONDS ajustado a valor mínimo de 1 segundo")
+	}
+
+	if cfg.ReadBufferSize < 512 {
+		cfg.ReadBufferSize = 512
+		bootstrapf("WARNING: EXECUTOR_READ_BUFFER_BYTES ajustado a valor mínimo de 512")
+	}
+
+	if cfg.MaxConcurrentTempFiles < 1 {
+		cfg.MaxConcurrentTempFiles = 1
+		bootstrapf("WARNING: MAX_CONCURRENT_TEMP_FILES ajustado a valor mínimo de 1")
+	}
+
+	if cfg.TempDirQuotaBytes < 0 {
+		cfg.TempDirQuotaBytes = 0
+		bootstrapf("WARNING: TEMP_DIR_QUOTA_BYTES negativo, se desactiva la cuota")
+	}
+
+	// Conservar los temporales llena el disco: solo se permite para depurar
+	if cfg.KeepTempFiles && !cfg.DebugMode {
+		cfg.KeepTempFiles = false
+		bootstrapf("WARNING: KEEP_TEMP_FILES requiere DEBUG_MODE=true, se ignora")
+	}
+
+	if cfg.CleanupInterval < time.Minute {
+		cfg.CleanupInterval = time.Minute
+		bootstrapf("WARNING: CLEANUP_INTERVAL_MINUTES ajustado a valor mínimo de 1 minuto")
 	}
 
-	if cfg.MaxCodeLength < 100 {
-		cfg.MaxCodeLength = 100
-		fmt.Println("WARNING: MAX_CODE_LENGTH ajustado a valor mínimo de 100")
+	if cfg.CacheCleanupInterval < minCacheCleanupInterval {
+		cfg.CacheCleanupInterval = minCacheCleanupInterval
+		bootstrapf("WARNING: CACHE_CLEANUP_INTERVAL_MINUTES ajustado a valor mínimo de %v", minCacheCleanupInterval)
 	}
 
-	if cfg.ExecutionTimeout < time.Second {
-		cfg.ExecutionTimeout = time.Second
-		fmt.Println("WARNING: EXECUTION_TIMEOUT_SECONDS ajustado a valor mínimo de 1 segundo")
+	if cfg.ChildGOMAXPROCS < 0 {
+		cfg.ChildGOMAXPROCS = 0
+		bootstrapf("WARNING: CHILD_GOMAXPROCS negativo, se desactiva el límite")
+	}
+
+	if cfg.ChildMaxProcesses < 0 {
+		cfg.ChildMaxProcesses = 0
+		bootstrapf("WARNING: CHILD_MAX_PROCESSES negativo, se desactiva el límite")
+	}
+
+	// El cambio de usuario requiere UID y GID a la vez, y privilegios para hacer setuid
+	if (cfg.ChildUID >= 0) != (cfg.ChildGID >= 0) {
+		bootstrapf("WARNING: CHILD_UID y CHILD_GID deben definirse juntos, se desactiva el cambio de usuario")
+		cfg.ChildUID = -1
+		cfg.ChildGID = -1
+	}
+
+	if cfg.ChildUID >= 0 && os.Geteuid() != 0 {
+		bootstrapf("WARNING: CHILD_UID requiere que el servidor se ejecute como root o con CAP_SETUID/CAP_SETGID")
+	}
+
+	cfg.ChildEnvPassthrough, cfg.ChildEnvVars = validateChildEnv(cfg.ChildEnvPassthrough, cfg.ChildEnvVars)
+
+	// Solo URLs http(s) absolutas; con cualquier otra se desactiva la importación
+	if cfg.ShareImportURL != "" {
+		if u, err := url.Parse(cfg.ShareImportURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			bootstrapf("WARNING: SHARE_IMPORT_URL %q no es una URL http(s) válida, se desactiva la importación", cfg.ShareImportURL)
+			cfg.ShareImportURL = ""
+		}
+	}
+
+	if cfg.ShareImportTimeout < time.Second {
+		cfg.ShareImportTimeout = time.Second
+		bootstrapf("WARNING: SHARE_IMPORT_TIMEOUT_SECONDS ajustado a valor mínimo de 1 segundo")
+	}
+
+	if cfg.MaxSessionHistory < 1 {
+		cfg.MaxSessionHistory = 1
+		bootstrapf("WARNING: MAX_SESSION_HISTORY ajustado a valor mínimo de 1")
+	}
+
+	if cfg.SessionTTL < time.Minute {
+		cfg.SessionTTL = time.Minute
+		bootstrapf("WARNING: SESSION_TTL_MINUTES ajustado a valor mínimo de 1 minuto")
 	}
 
 	// Validar que el directorio temporal exista o se pueda crear
@@ -219,7 +819,7 @@ func validateConfig(cfg *Config) {
 		if _, err := os.Stat(cfg.TempDir); os.IsNotExist(err) {
 			err := os.MkdirAll(cfg.TempDir, 0755)
 			if err != nil {
-				fmt.Printf("ERROR: No se pudo crear el directorio temporal %s: %v\n", cfg.TempDir, err)
+				bootstrapf("ERROR: No se pudo crear el directorio temporal %s: %v", cfg.TempDir, err)
 				cfg.TempDir = os.TempDir()
 			}
 		}
@@ -227,15 +827,233 @@ func validateConfig(cfg *Config) {
 
 	// Validar que el ejecutable de Go exista
 	if _, err := os.Stat(cfg.GoExecutablePath); os.IsNotExist(err) {
-		fmt.Printf("WARNING: El ejecutable de Go no existe en %s\n", cfg.GoExecutablePath)
+		bootstrapf("WARNING: El ejecutable de Go no existe en %s", cfg.GoExecutablePath)
+	}
+}
+
+// validateAllowedOrigins descarta, con un aviso, los patrones de ALLOWED_ORIGINS
+// duplicados o no válidos (ver security.ValidateOriginPattern)
+func validateAllowedOrigins(origins []string) []string {
+	seen := make(map[string]bool, len(origins))
+	valid := make([]string, 0, len(origins))
+	for _, origin := range origins {
+		if seen[origin] {
+			bootstrapf("WARNING: ALLOWED_ORIGINS contiene el patrón duplicado %q", origin)
+			continue
+		}
+		seen[origin] = true
+
+		if err := security.ValidateOriginPattern(origin); err != nil {
+			bootstrapf("WARNING: ALLOWED_ORIGINS: %v, se ignora", err)
+			continue
+		}
+		valid = append(valid, origin)
 	}
+	return valid
+}
+
+// parseEndpointRateLimits convierte los pares ruta=solicitudes por minuto de
+// ENDPOINT_RATE_LIMITS, descartando con un aviso los que no son válidos
+func parseEndpointRateLimits(values map[string]string) map[string]int {
+	if len(values) == 0 {
+		return nil
+	}
+	limits := make(map[string]int, len(values))
+	for path, value := range values {
+		perMinute, err := strconv.Atoi(value)
+		switch {
+		case !strings.HasPrefix(path, "/"):
+			bootstrapf("WARNING: ENDPOINT_RATE_LIMITS: %q no es una ruta, se ignora", path)
+		case err != nil || perMinute < 1:
+			bootstrapf("WARNING: ENDPOINT_RATE_LIMITS: el límite de %s debe ser un entero mayor que 0, se ignora", path)
+		default:
+			limits[path] = perMinute
+		}
+	}
+	return limits
+}
+
+// parseHandlerTimeouts convierte los pares ruta=timeout de HANDLER_TIMEOUTS,
+// con la sintaxis de getEnvDuration, descartando con un aviso los que no son
+// válidos. Un timeout de 0 deja la ruta sin límite.
+func parseHandlerTimeouts(values map[string]string) map[string]time.Duration {
+	if len(values) == 0 {
+		return nil
+	}
+	timeouts := make(map[string]time.Duration, len(values))
+	for path, value := range values {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			var seconds int
+			seconds, err = strconv.Atoi(value)
+			timeout = time.Duration(seconds) * time.Second
+		}
+		switch {
+		case !strings.HasPrefix(path, "/"):
+			bootstrapf("WARNING: HANDLER_TIMEOUTS: %q no es una ruta, se ignora", path)
+		case err != nil || timeout < 0:
+			bootstrapf("WARNING: HANDLER_TIMEOUTS: el timeout de %s debe ser una duración no negativa (ej. 45s, 0 = sin límite), se ignora", path)
+		default:
+			timeouts[path] = timeout
+		}
+	}
+	return timeouts
+}
+
+// parseAPIKeyQuotas convierte los pares identificador de clave=ejecuciones de
+// API_KEY_QUOTAS, descartando con un aviso los que no son válidos
+func parseAPIKeyQuotas(values map[string]string) map[string]int {
+	if len(values) == 0 {
+		return nil
+	}
+	quotas := make(map[string]int, len(values))
+	for id, value := range values {
+		quota, err := strconv.Atoi(value)
+		if err != nil || quota < 0 {
+			bootstrapf("WARNING: API_KEY_QUOTAS: la cuota de %s debe ser un entero mayor o igual que 0, se ignora", id)
+			continue
+		}
+		quotas[strings.ToLower(id)] = quota
+	}
+	return quotas
+}
+
+// hasAPIKeyID indica si id es el identificador (el comienzo del hash) de
+// alguna de las claves de hashes
+func hasAPIKeyID(hashes []string, id string) bool {
+	for _, hash := range hashes {
+		if id != "" && strings.HasPrefix(hash, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateCodeDenyPatterns descarta, con un aviso, las reglas de
+// CODE_DENY_PATTERNS no válidas o con un nombre repetido (ver security.ParseDenyRule)
+func validateCodeDenyPatterns(specs []string) []string {
+	seen := make(map[string]bool, len(specs))
+	valid := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		rule, err := security.ParseDenyRule(spec)
+		if err != nil {
+			bootstrapf("WARNING: CODE_DENY_PATTERNS: %v, se ignora", err)
+			continue
+		}
+		if seen[rule.Name] {
+			bootstrapf("WARNING: CODE_DENY_PATTERNS contiene la regla duplicada %q, se ignora", rule.Name)
+			continue
+		}
+		seen[rule.Name] = true
+		valid = append(valid, spec)
+	}
+	return valid
+}
+
+// validateCodeDenyCalls descarta, con un aviso, las funciones de
+// CODE_DENY_CALLS no válidas (ver security.ParseDeniedCall)
+func validateCodeDenyCalls(specs []string) []string {
+	valid := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		if _, err := security.ParseDeniedCall(spec); err != nil {
+			bootstrapf("WARNING: CODE_DENY_CALLS: %v, se ignora", err)
+			continue
+		}
+		valid = append(valid, spec)
+	}
+	return valid
+}
+
+// validateAPIKeyHashes normaliza a minúsculas los hashes de API_KEY_HASHES y
+// descarta, con un aviso, los que no son un SHA-256 en hexadecimal
+func validateAPIKeyHashes(hashes []string) []string {
+	valid := make([]string, 0, len(hashes))
+	for _, hash := range hashes {
+		hash = strings.ToLower(hash)
+		if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+			bootstrapf("WARNING: API_KEY_HASHES contiene %q, que no es un SHA-256 en hexadecimal, se ignora", hash)
+			continue
+		}
+		valid = append(valid, hash)
+	}
+	return valid
+}
+
+// reservedChildEnvVars son las variables que el ejecutor fija en cada ejecución
+// y que la configuración no puede sustituir
+var reservedChildEnvVars = map[string]bool{
+	"GOMAXPROCS":            true,
+	"PLAYGROUND_REQUEST_ID": true,
+	"PLAYGROUND_CLIENT_ID":  true,
+	"FAKETIME":              true,
+}
+
+// validChildEnvName reconoce los nombres de variable de entorno admitidos
+var validChildEnvName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateChildEnv descarta, con un aviso, los nombres de CHILD_ENV_PASSTHROUGH
+// y CHILD_ENV_VARS no válidos o reservados por el ejecutor
+func validateChildEnv(passthrough []string, vars map[string]string) ([]string, map[string]string) {
+	valid := make([]string, 0, len(passthrough))
+	for _, name := range passthrough {
+		switch {
+		case !validChildEnvName.MatchString(name):
+			bootstrapf("WARNING: CHILD_ENV_PASSTHROUGH: %q no es un nombre de variable válido, se ignora", name)
+		case reservedChildEnvVars[name]:
+			bootstrapf("WARNING: CHILD_ENV_PASSTHROUGH: %s lo fija el ejecutor, se ignora", name)
+		default:
+			valid = append(valid, name)
+		}
+	}
+	for name := range vars {
+		switch {
+		case !validChildEnvName.MatchString(name):
+			bootstrapf("WARNING: CHILD_ENV_VARS: %q no es un nombre de variable válido, se ignora", name)
+			delete(vars, name)
+		case reservedChildEnvVars[name]:
+			bootstrapf("WARNING: CHILD_ENV_VARS: %s lo fija el ejecutor, se ignora", name)
+			delete(vars, name)
+		}
+	}
+	return valid, vars
+}
+
+// ChildEnv devuelve el entorno ("CLAVE=valor") con el que se ejecuta el código
+// enviado: las variables esenciales de GetEssentialEnvVars, las del servidor
+// listadas en CHILD_ENV_PASSTHROUGH y los valores fijos de CHILD_ENV_VARS, que
+// tienen prioridad sobre las anteriores. Ninguna otra variable del servidor
+// llega al proceso hijo. El resultado está ordenado por nombre.
+func (c *Config) ChildEnv() []string {
+	env := make(map[string]string)
+	for key, value := range GetEssentialEnvVars() {
+		if value != "" {
+			env[key] = value
+		}
+	}
+	for _, key := range c.ChildEnvPassthrough {
+		if value, ok := os.LookupEnv(key); ok {
+			env[key] = value
+		}
+	}
+	for key, value := range c.ChildEnvVars {
+		env[key] = value
+	}
+
+	result := make([]string, 0, len(env))
+	for key, value := range env {
+		result = append(result, key+"="+value)
+	}
+	sort.Strings(result)
+	return result
 }
 
 // GetEssentialEnvVars devuelve un mapa con las variables de entorno esenciales
 // para la ejecución de código Go.
 //
 // Esta función recopila las variables de entorno que deben estar disponibles
-// durante la ejecución de código Go, como PATH, GOPATH, GOROOT, etc.
+// durante la ejecución de código Go, como PATH, GOPATH, GOROOT, etc. Son la
+// base del entorno del proceso hijo (ver ChildEnv), que no hereda ninguna otra
+// variable del servidor.
 //
 // Retorna un mapa de strings con las variables de entorno esenciales.
 //
@@ -243,7 +1061,7 @@ func validateConfig(cfg *Config) {
 //
 //     envVars := config.GetEssentialEnvVars()
 //     for key, value := range envVars {
-//         os.Setenv(key, value)
+//         fmt.Printf("%s=%s\n", key, value)
 //     }
 func GetEssentialEnvVars() map[string]string {
 	return map[string]string{
@@ -257,6 +1075,43 @@ func GetEssentialEnvVars() map[string]string {
 	}
 }
 
+// TLSEnabled indica si el servidor se sirve por HTTPS (TLS_CERT_FILE y TLS_KEY_FILE)
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// Environment devuelve el nombre del entorno para el seguimiento de errores:
+// "development" en modo debug y "production" en otro caso
+func (c *Config) Environment() string {
+	if c.DebugMode {
+		return "development"
+	}
+	return "production"
+}
+
+// SecurityHeaders devuelve los encabezados de seguridad configurados
+func (c *Config) SecurityHeaders() security.SecurityHeaders {
+	return security.SecurityHeaders{
+		ContentSecurityPolicy:   c.ContentSecurityPolicy,
+		FrameOptions:            c.FrameOptions,
+		ContentTypeOptions:      c.ContentTypeOptions,
+		StrictTransportSecurity: c.StrictTransportSecurity,
+		ReferrerPolicy:          c.ReferrerPolicy,
+	}
+}
+
+// MaxRequestTimeout devuelve el mayor de los timeouts de ejecución
+// (EXECUTION_TIMEOUT_SECONDS, BENCHMARK_TIMEOUT_SECONDS y, si el detector de
+// carreras está activo, RACE_EXECUTION_TIMEOUT_SECONDS), es decir, lo máximo
+// que puede durar un proceso hijo
+func (c *Config) MaxRequestTimeout() time.Duration {
+	timeout := max(c.ExecutionTimeout, c.BenchmarkTimeout)
+	if c.RaceDetectorEnabled {
+		timeout = max(timeout, c.RaceExecutionTimeout)
+	}
+	return timeout
+}
+
 // String devuelve una representación en string de la configuración.
 //
 // Este método implementa la interfaz Stringer para facilitar el logging
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetEnvDuration(t *testing.T) {
+	bootstrapLogger.SetOutput(io.Discard)
+	t.Cleanup(func() { bootstrapLogger.SetOutput(os.Stderr) })
+
+	const key = "TEST_TIMEOUT_SECONDS"
+	tests := []struct {
+		name    string
+		value   string
+		set     bool
+		want    time.Duration
+		warning bool
+	}{
+		{"sin definir", "", false, 7 * time.Second, false},
+		{"vacía", "", true, 7 * time.Second, false},
+		{"segundos con unidad", "10s", true, 10 * time.Second, false},
+		{"minutos y segundos", "1m30s", true, 90 * time.Second, false},
+		{"milisegundos", "500ms", true, 500 * time.Millisecond, false},
+		{"fracción de segundo", "1.5s", true, 1500 * time.Millisecond, false},
+		{"entero en segundos", "90", true, 90 * time.Second, false},
+		{"inválida", "pronto", true, 7 * time.Second, true},
+		{"decimal sin unidad", "1.5", true, 7 * time.Second, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.set {
+				t.Setenv(key, tt.value)
+			}
+			takeBootstrapMessages()
+
+			if got := getEnvDuration(key, 7*time.Second); got != tt.want {
+				t.Errorf("getEnvDuration(%q) = %v, se esperaba %v", tt.value, got, tt.want)
+			}
+			messages := takeBootstrapMessages()
+			warned := len(messages) == 1 && strings.HasPrefix(messages[0], "WARNING: "+key)
+			if warned != tt.warning || (!tt.warning && len(messages) > 0) {
+				t.Errorf("avisos = %q, se esperaba aviso: %v", messages, tt.warning)
+			}
+		})
+	}
+}
+
+func TestGetEnvDurationUnit(t *testing.T) {
+	const key = "TEST_TTL_MINUTES"
+
+	t.Setenv(key, "5")
+	if got := getEnvDurationUnit(key, time.Minute, time.Hour); got != 5*time.Minute {
+		t.Errorf("entero con unidad de minutos = %v, se esperaba 5m0s", got)
+	}
+	t.Setenv(key, "90s")
+	if got := getEnvDurationUnit(key, time.Minute, time.Hour); got != 90*time.Second {
+		t.Errorf("duración con unidad explícita = %v, se esperaba 1m30s", got)
+	}
+}
+
+func TestNewConfigReadsDurations(t *testing.T) {
+	bootstrapLogger.SetOutput(io.Discard)
+	t.Cleanup(func() { bootstrapLogger.SetOutput(os.Stderr) })
+
+	t.Setenv("EXECUTION_TIMEOUT_SECONDS", "1m30s")
+	t.Setenv("CLEANUP_INTERVAL_MINUTES", "15")
+	cfg := NewConfig()
+	if cfg.ExecutionTimeout != 90*time.Second {
+		t.Errorf("ExecutionTimeout = %v, se esperaba 1m30s", cfg.ExecutionTimeout)
+	}
+	if cfg.CleanupInterval != 15*time.Minute {
+		t.Errorf("CleanupInterval = %v, se esperaba 15m0s", cfg.CleanupInterval)
+	}
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// RuntimeLimits son los límites de la configuración que se pueden cambiar sin
+// reiniciar el servidor, con PATCH /api/config. El resto de la configuración
+// (dirección, directorio estático, timeouts del servidor HTTP...) solo se lee
+// al arrancar.
+type RuntimeLimits struct {
+	MaxRequestsPerMinute int
+	MaxCodeLength        int
+	ExecutionTimeout     time.Duration
+}
+
+// LimitsPatch es un cambio parcial de RuntimeLimits: los campos nil no
+// cambian. Los nombres JSON son los de Config, igual que en GET /admin/config,
+// y ExecutionTimeout va en nanosegundos, como en cualquier time.Duration.
+type LimitsPatch struct {
+	MaxRequestsPerMinute *int           `json:"MaxRequestsPerMinute"`
+	MaxCodeLength        *int           `json:"MaxCodeLength"`
+	ExecutionTimeout     *time.Duration `json:"ExecutionTimeout"`
+}
+
+// RuntimeLimits devuelve los límites actuales de c
+func (c *Config) RuntimeLimits() RuntimeLimits {
+	return RuntimeLimits{
+		MaxRequestsPerMinute: c.MaxRequestsPerMinute,
+		MaxCodeLength:        c.MaxCodeLength,
+		ExecutionTimeout:     c.ExecutionTimeout,
+	}
+}
+
+// MaxPatchableExecutionTimeout es el mayor ExecutionTimeout que admite
+// ApplyLimits: no puede superar RaceExecutionTimeout y la ejecución completa
+// debe caber en ServerWriteTimeout, como exige validateConfig
+func (c *Config) MaxPatchableExecutionTimeout() time.Duration {
+	return min(c.RaceExecutionTimeout, c.ServerWriteTimeout-serverWriteTimeoutMargin)
+}
+
+// ApplyLimits valida los campos de patch con las mismas reglas que
+// validateConfig y, si todos son válidos, los copia en c y devuelve los
+// límites resultantes. A diferencia de validateConfig no ajusta nada: un valor
+// fuera de rango devuelve un error y deja c sin cambios.
+//
+// Los valores que dependen de otros campos se comprueban contra los que están
+// en vigor, que no cambian en caliente: MaxCodeLength no puede superar
+// MaxDecompressedBodyBytes y ExecutionTimeout no puede superar
+// RaceExecutionTimeout ni dejar de caber en ServerWriteTimeout.
+func (c *Config) ApplyLimits(patch LimitsPatch) (RuntimeLimits, error) {
+	if v := patch.MaxRequestsPerMinute; v != nil && *v < 1 {
+		return RuntimeLimits{}, fmt.Errorf("MaxRequestsPerMinute debe ser al menos 1")
+	}
+	if v := patch.MaxCodeLength; v != nil {
+		if *v < minMaxCodeLength {
+			return RuntimeLimits{}, fmt.Errorf("MaxCodeLength debe ser al menos %d", minMaxCodeLength)
+		}
+		if int64(*v) > c.MaxDecompressedBodyBytes {
+			return RuntimeLimits{}, fmt.Errorf("MaxCodeLength no puede superar MaxDecompressedBodyBytes (%d)", c.MaxDecompressedBodyBytes)
+		}
+	}
+	if v := patch.ExecutionTimeout; v != nil {
+		if *v < minExecutionTimeout {
+			return RuntimeLimits{}, fmt.Errorf("ExecutionTimeout debe ser al menos %v", minExecutionTimeout)
+		}
+		if maxTimeout := c.MaxPatchableExecutionTimeout(); *v > maxTimeout {
+			return RuntimeLimits{}, fmt.Errorf("ExecutionTimeout no puede superar %v (RaceExecutionTimeout y ServerWriteTimeout)", maxTimeout)
+		}
+	}
+
+	if v := patch.MaxRequestsPerMinute; v != nil {
+		c.MaxRequestsPerMinute = *v
+	}
+	if v := patch.MaxCodeLength; v != nil {
+		c.MaxCodeLength = *v
+	}
+	if v := patch.ExecutionTimeout; v != nil {
+		c.ExecutionTimeout = *v
+	}
+	return c.RuntimeLimits(), nil
+}
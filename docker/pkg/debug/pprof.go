@@ -0,0 +1,26 @@
+// Package debug contiene las herramientas de diagnóstico que solo se activan
+// con DEBUG_MODE=true.
+package debug
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// NewPprofHandler devuelve un mux con los manejadores de net/http/pprof bajo
+// /debug/pprof/ (perfiles de CPU, memoria, goroutines, bloqueos, etc.).
+//
+// Debe servirse en un listener propio y nunca en el mux público: los perfiles
+// revelan detalles internos y /debug/pprof/profile consume CPU bajo demanda.
+//
+// Importar net/http/pprof registra también estos manejadores en
+// http.DefaultServeMux, por lo que el servidor público no debe usarlo.
+func NewPprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
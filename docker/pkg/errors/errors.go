@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
+	"sync/atomic"
 
 	"github.com/luis198755/go_playGround_plus/docker/pkg/logger"
 	"github.com/pkg/errors"
@@ -14,12 +16,137 @@ import (
 type AppError struct {
 	Err        error
 	StatusCode int
-	Message    string
-	Context    map[string]interface{}
+	// Code identifica el tipo de error para los clientes (ej. "ERR_RATE_LIMITED").
+	// WithContext lo deduce de StatusCode; WithCode permite uno más específico.
+	Code    string
+	Message string
+	Context map[string]interface{}
 }
 
+// Códigos de error de la API. Cada uno tiene su página de documentación (ver ErrorDocs).
+const (
+	CodeBadRequest           = "ERR_BAD_REQUEST"
+	CodeInvalidCode          = "ERR_INVALID_CODE"
+	CodeUnauthorized         = "ERR_UNAUTHORIZED"
+	CodeForbidden            = "ERR_FORBIDDEN"
+	CodeNotFound             = "ERR_NOT_FOUND"
+	CodeMethodNotAllowed     = "ERR_METHOD_NOT_ALLOWED"
+	CodePayloadTooLarge      = "ERR_PAYLOAD_TOO_LARGE"
+	CodeUnsupportedMediaType = "ERR_UNSUPPORTED_MEDIA_TYPE"
+	CodeRateLimited          = "ERR_RATE_LIMITED"
+	CodeQuotaExceeded        = "ERR_QUOTA_EXCEEDED"
+	CodeIdempotencyKeyReused = "ERR_IDEMPOTENCY_KEY_REUSED"
+	CodeInternal             = "ERR_INTERNAL"
+	CodeServerBusy           = "ERR_SERVER_BUSY"
+	CodeServiceUnavailable   = "ERR_SERVICE_UNAVAILABLE"
+	CodeUpstream             = "ERR_UPSTREAM"
+)
+
+// statusCodes es el código de error por defecto de cada estado HTTP
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            CodeBadRequest,
+	http.StatusUnauthorized:          CodeUnauthorized,
+	http.StatusForbidden:             CodeForbidden,
+	http.StatusNotFound:              CodeNotFound,
+	http.StatusMethodNotAllowed:      CodeMethodNotAllowed,
+	http.StatusRequestEntityTooLarge: CodePayloadTooLarge,
+	http.StatusUnsupportedMediaType:  CodeUnsupportedMediaType,
+	http.StatusTooManyRequests:       CodeRateLimited,
+	http.StatusInternalServerError:   CodeInternal,
+	http.StatusServiceUnavailable:    CodeServiceUnavailable,
+	http.StatusBadGateway:            CodeUpstream,
+	http.StatusGatewayTimeout:        CodeUpstream,
+}
+
+// ErrorDocs asocia cada código de error con su página de documentación,
+// relativa a la URL base configurada con SetDocsBaseURL
+var ErrorDocs = map[string]string{
+	CodeBadRequest:           "bad-request",
+	CodeInvalidCode:          "invalid-code",
+	CodeUnauthorized:         "unauthorized",
+	CodeForbidden:            "forbidden",
+	CodeNotFound:             "not-found",
+	CodeMethodNotAllowed:     "method-not-allowed",
+	CodePayloadTooLarge:      "payload-too-large",
+	CodeUnsupportedMediaType: "unsupported-media-type",
+	CodeRateLimited:          "rate-limited",
+	CodeQuotaExceeded:        "quota-exceeded",
+	CodeIdempotencyKeyReused: "idempotency-key-reused",
+	CodeInternal:             "internal",
+	CodeServerBusy:           "server-busy",
+	CodeServiceUnavailable:   "service-unavailable",
+	CodeUpstream:             "upstream",
+}
+
+// docsBaseURL es la URL base de la documentación de errores (vacía = sin enlaces)
+var docsBaseURL atomic.Value
+
+// SetDocsBaseURL fija la URL base de la documentación de errores, por ejemplo
+// "https://docs.playground.example.com/errors". Con una URL vacía las
+// respuestas de error no incluyen docs_url.
+func SetDocsBaseURL(baseURL string) {
+	docsBaseURL.Store(strings.TrimSuffix(baseURL, "/"))
+}
+
+// DocsURL devuelve la URL de la documentación del código de error, o una
+// cadena vacía si no hay URL base configurada o el código no está en ErrorDocs
+func DocsURL(code string) string {
+	base, _ := docsBaseURL.Load().(string)
+	page, ok := ErrorDocs[code]
+	if base == "" || !ok {
+		return ""
+	}
+	return base + "/" + page
+}
+
+// ErrorObserver recibe los errores internos del servidor, por ejemplo para
+// enviarlos a un servicio de seguimiento de errores (ver reporting.SentryErrorReporter).
+// requestID es el ID de la solicitud (la cabecera X-Request-ID), si lo tiene.
+type ErrorObserver interface {
+	ObserveError(r *http.Request, requestID string, err error)
+}
+
+// errorObserver es el ErrorObserver configurado (vacío = ninguno)
+var errorObserver atomic.Value
+
+// SetErrorObserver fija el observador al que HTTPError y ReportError envían los
+// errores internos. Con nil deja de enviarse.
+func SetErrorObserver(observer ErrorObserver) {
+	errorObserver.Store(&observer)
+}
+
+// ReportError envía err al ErrorObserver configurado, si lo hay. HTTPError ya
+// lo llama para las respuestas 500; los manejadores lo usan para los errores
+// internos que no terminan en una respuesta de error.
+func ReportError(r *http.Request, requestID string, err error) {
+	observer, _ := errorObserver.Load().(*ErrorObserver)
+	if observer == nil || *observer == nil || err == nil {
+		return
+	}
+	(*observer).ObserveError(r, requestID, err)
+}
+
+// Errores centinela para comparar con errors.Is por código de estado, por ejemplo:
+//
+//     if errors.Is(err, errors.ErrNotFound) { ... }
+//
+// Cualquier *AppError con el mismo StatusCode coincide con el centinela, aunque
+// su mensaje o su error original sean distintos.
+var (
+	ErrBadRequest          = &AppError{StatusCode: http.StatusBadRequest, Message: http.StatusText(http.StatusBadRequest)}
+	ErrUnauthorized        = &AppError{StatusCode: http.StatusUnauthorized, Message: http.StatusText(http.StatusUnauthorized)}
+	ErrForbidden           = &AppError{StatusCode: http.StatusForbidden, Message: http.StatusText(http.StatusForbidden)}
+	ErrNotFound            = &AppError{StatusCode: http.StatusNotFound, Message: http.StatusText(http.StatusNotFound)}
+	ErrTooManyRequests     = &AppError{StatusCode: http.StatusTooManyRequests, Message: http.StatusText(http.StatusTooManyRequests)}
+	ErrInternalServerError = &AppError{StatusCode: http.StatusInternalServerError, Message: http.StatusText(http.StatusInternalServerError)}
+	ErrServiceUnavailable  = &AppError{StatusCode: http.StatusServiceUnavailable, Message: http.StatusText(http.StatusServiceUnavailable)}
+)
+
 // Error implementa la interfaz error
 func (e *AppError) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
 	return fmt.Sprintf("%s: %v", e.Message, e.Err)
 }
 
@@ -28,11 +155,20 @@ func (e *AppError) Unwrap() error {
 	return e.Err
 }
 
+// Is permite comparar con errors.Is por código de estado: es true si target es
+// un *AppError con el mismo StatusCode (ver ErrNotFound y el resto de centinelas)
+func (e *AppError) Is(target error) bool {
+	t, ok := target.(*AppError)
+	return ok && t.StatusCode == e.StatusCode
+}
+
 // ErrorResponse es la estructura que se envía como respuesta HTTP en caso de error
 type ErrorResponse struct {
 	Status  int                    `json:"status"`
+	Code    string                 `json:"code,omitempty"`
 	Message string                 `json:"message"`
 	Details map[string]interface{} `json:"details,omitempty"`
+	DocsURL string                 `json:"docs_url,omitempty"`
 }
 
 // New crea un nuevo error con contexto
@@ -50,61 +186,65 @@ func Wrapf(err error, format string, args ...interface{}) error {
 	return errors.Wrapf(err, format, args...)
 }
 
+// Is indica si algún error de la cadena de err coincide con target
+func Is(err, target error) bool {
+	return errors.Is(err, target)
+}
+
+// As busca en la cadena de errores el primero que coincida con target
+func As(err error, target interface{}) bool {
+	return errors.As(err, target)
+}
+
 // WithContext añade contexto a un error
 func WithContext(err error, statusCode int, message string, context map[string]interface{}) *AppError {
 	return &AppError{
 		Err:        err,
 		StatusCode: statusCode,
+		Code:       statusCodes[statusCode],
 		Message:    message,
 		Context:    context,
 	}
 }
 
+// WithCode sustituye el código de error deducido del estado HTTP por uno más
+// específico y devuelve el mismo error para poder encadenarlo
+func (e *AppError) WithCode(code string) *AppError {
+	e.Code = code
+	return e
+}
+
 // IsNotFound verifica si un error es de tipo "no encontrado"
 func IsNotFound(err error) bool {
-	var appErr *AppError
-	if errors.As(err, &appErr) {
-		return appErr.StatusCode == http.StatusNotFound
-	}
-	return false
+	return errors.Is(err, ErrNotFound)
 }
 
 // IsBadRequest verifica si un error es de tipo "solicitud incorrecta"
 func IsBadRequest(err error) bool {
-	var appErr *AppError
-	if errors.As(err, &appErr) {
-		return appErr.StatusCode == http.StatusBadRequest
-	}
-	return false
+	return errors.Is(err, ErrBadRequest)
 }
 
 // IsUnauthorized verifica si un error es de tipo "no autorizado"
 func IsUnauthorized(err error) bool {
-	var appErr *AppError
-	if errors.As(err, &appErr) {
-		return appErr.StatusCode == http.StatusUnauthorized
-	}
-	return false
+	return errors.Is(err, ErrUnauthorized)
 }
 
 // IsForbidden verifica si un error es de tipo "prohibido"
 func IsForbidden(err error) bool {
-	var appErr *AppError
-	if errors.As(err, &appErr) {
-		return appErr.StatusCode == http.StatusForbidden
-	}
-	return false
+	return errors.Is(err, ErrForbidden)
 }
 
 // HTTPError responde con un error HTTP y registra el error
 func HTTPError(w http.ResponseWriter, r *http.Request, log logger.Logger, err error) {
 	var appErr *AppError
 	statusCode := http.StatusInternalServerError
+	code := CodeInternal
 	message := "Error interno del servidor"
 	details := make(map[string]interface{})
 
 	if errors.As(err, &appErr) {
 		statusCode = appErr.StatusCode
+		code = appErr.Code
 		message = appErr.Message
 		details = appErr.Context
 	}
@@ -112,17 +252,24 @@ func HTTPError(w http.ResponseWriter, r *http.Request, log logger.Logger, err er
 	// Registrar el error con contexto
 	log.Error("Error HTTP",
 		zap.Int("status_code", statusCode),
+		zap.String("error_code", code),
 		zap.String("method", r.Method),
 		zap.String("path", r.URL.Path),
 		zap.String("remote_addr", r.RemoteAddr),
 		zap.Error(err),
 	)
 
+	if statusCode == http.StatusInternalServerError {
+		ReportError(r, w.Header().Get("X-Request-ID"), err)
+	}
+
 	// Crear respuesta de error
 	resp := ErrorResponse{
 		Status:  statusCode,
+		Code:    code,
 		Message: message,
 		Details: details,
+		DocsURL: DocsURL(code),
 	}
 
 	// Enviar respuesta JSON
@@ -163,3 +310,8 @@ func InternalServerError(err error, message string, context map[string]interface
 func TooManyRequests(err error, message string, context map[string]interface{}) *AppError {
 	return WithContext(err, http.StatusTooManyRequests, message, context)
 }
+
+// ServiceUnavailable crea un error de tipo "servicio no disponible"
+func ServiceUnavailable(err error, message string, context map[string]interface{}) *AppError {
+	return WithContext(err, http.StatusServiceUnavailable, message, context)
+}
@@ -0,0 +1,93 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	logtest "github.com/luis198755/go_playGround_plus/docker/pkg/logger/test"
+)
+
+func TestAppErrorIsByStatusCode(t *testing.T) {
+	notFound := NotFound(New("snippet inexistente"), "No se encontró el código compartido", nil)
+
+	tests := []struct {
+		name   string
+		err    error
+		target error
+		want   bool
+	}{
+		{"centinela envuelto", Wrap(ErrNotFound, "msg"), ErrNotFound, true},
+		{"mismo estado, otro mensaje", notFound, ErrNotFound, true},
+		{"envuelto con fmt.Errorf", fmt.Errorf("importando: %w", notFound), ErrNotFound, true},
+		{"envuelto con Wrapf", Wrapf(notFound, "intento %d", 2), ErrNotFound, true},
+		{"otro estado", notFound, ErrBadRequest, false},
+		{"error sin AppError", New("fallo"), ErrNotFound, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Is(tt.err, tt.target); got != tt.want {
+				t.Errorf("Is(%v, %v) = %v, se esperaba %v", tt.err, tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsStatusHelpers(t *testing.T) {
+	err := Wrap(BadRequest(nil, "JSON inválido", nil), "decodificando")
+	if !IsBadRequest(err) {
+		t.Error("IsBadRequest() = false para un BadRequest envuelto")
+	}
+	if IsNotFound(err) || IsUnauthorized(err) || IsForbidden(err) {
+		t.Error("un BadRequest coincide con otro estado")
+	}
+	if !IsNotFound(NotFound(nil, "No encontrado", nil)) ||
+		!IsUnauthorized(Unauthorized(nil, "No autorizado", nil)) ||
+		!IsForbidden(Forbidden(nil, "Prohibido", nil)) {
+		t.Error("algún Is* no reconoce su propio error")
+	}
+}
+
+// errorResponseFields ejecuta HTTPError con err y devuelve los campos del JSON
+// de la respuesta
+func errorResponseFields(t *testing.T, err error) map[string]interface{} {
+	t.Helper()
+	log, _ := logtest.NewTestLogger(t)
+	rec := httptest.NewRecorder()
+	HTTPError(rec, httptest.NewRequest(http.MethodPost, "/api/execute", nil), log, err)
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &fields); err != nil {
+		t.Fatalf("respuesta no es JSON: %v (%q)", err, rec.Body.String())
+	}
+	return fields
+}
+
+func TestHTTPErrorDocsURL(t *testing.T) {
+	t.Cleanup(func() { SetDocsBaseURL("") })
+	rateLimited := TooManyRequests(nil, "Demasiadas solicitudes", nil)
+
+	SetDocsBaseURL("https://docs.playground.example.com/errors/")
+	fields := errorResponseFields(t, rateLimited)
+	want := "https://docs.playground.example.com/errors/rate-limited"
+	if got := fields["docs_url"]; got != want {
+		t.Errorf("docs_url = %v, se esperaba %q", got, want)
+	}
+
+	// Un código sin página de documentación no lleva enlace
+	fields = errorResponseFields(t, TooManyRequests(nil, "Demasiadas solicitudes", nil).WithCode("ERR_DESCONOCIDO"))
+	if got, ok := fields["docs_url"]; ok {
+		t.Errorf("docs_url = %v para un código sin documentación", got)
+	}
+
+	SetDocsBaseURL("")
+	fields = errorResponseFields(t, rateLimited)
+	if got, ok := fields["docs_url"]; ok {
+		t.Errorf("docs_url = %v sin ERROR_DOCS_BASE_URL", got)
+	}
+	if fields["code"] != CodeRateLimited {
+		t.Errorf("code = %v, se esperaba %s", fields["code"], CodeRateLimited)
+	}
+}
@@ -0,0 +1,129 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// testFileName es el nombre del archivo de tests y benchmarks dentro del directorio de trabajo
+const testFileName = "main_test.go"
+
+// BenchmarkResult es el resultado de un benchmark, tal como lo informa 'go test -bench'
+type BenchmarkResult struct {
+	Name        string  `json:"name"`
+	Procs       int     `json:"procs,omitempty"`
+	Iterations  int64   `json:"iterations"`
+	NsPerOp     float64 `json:"ns_per_op"`
+	BytesPerOp  int64   `json:"bytes_per_op"`
+	AllocsPerOp int64   `json:"allocs_per_op"`
+}
+
+// BenchmarkReport contiene los resultados de una ejecución de benchmarks
+type BenchmarkReport struct {
+	// Passed indica si 'go test' terminó correctamente. Es false si el código no
+	// compila o si algún benchmark falla; el motivo está en Output.
+	Passed bool
+	// Results son los benchmarks completados, en el orden en que se ejecutaron
+	Results []BenchmarkResult
+	// Output es la salida completa de 'go test' (truncada según los límites)
+	Output string
+}
+
+// Benchmarker define el comportamiento de los ejecutores capaces de ejecutar
+// benchmarks de Go.
+type Benchmarker interface {
+	Benchmark(ctx context.Context, code string) (BenchmarkReport, error)
+}
+
+// Benchmark guarda el código como main_test.go y ejecuta
+// 'go test -run=^$ -bench=. -benchmem' sobre él.
+//
+// El código debe declarar "package main" e importar "testing"; no se le aplica
+// AutoWrapCode. Que el código no compile o un benchmark falle no es un error:
+// se indica con Passed=false. Retorna error si no se pudo lanzar 'go test' o si
+// ctx expiró, en cuyo caso el informe contiene los resultados obtenidos hasta entonces.
+func (ge *GoExecutor) Benchmark(ctx context.Context, code string) (BenchmarkReport, error) {
+	workDir, err := ge.prepareWorkDirFile(ctx, testFileName, code)
+	if err != nil {
+		return BenchmarkReport{}, err
+	}
+	defer ge.removeWorkDir(workDir)
+
+	args := []string{"test", "-run=^$", "-bench=.", "-benchmem"}
+	// 'go test' aplica su propio timeout de 10 minutos; usar el del contexto
+	if deadline, ok := ctx.Deadline(); ok {
+		args = append(args, "-timeout="+max(time.Until(deadline), time.Second).String())
+	}
+	args = append(args, ".")
+
+	cmd := ge.command(ctx, workDir, args...)
+	stdout, stderr, waitErr, err := ge.runCaptured(cmd)
+	if err != nil {
+		return BenchmarkReport{}, err
+	}
+
+	var output bytes.Buffer
+	stdout.writeTo(&output)
+	stderr.writeTo(&output)
+
+	report := BenchmarkReport{
+		Passed:  waitErr == nil,
+		Results: ParseBenchmarkOutput(string(stdout.data)),
+		Output:  output.String(),
+	}
+	if waitErr != nil && ctx.Err() != nil {
+		return report, fmt.Errorf("error en los benchmarks: %w", ctx.Err())
+	}
+	return report, nil
+}
+
+// benchmarkProcs separa el sufijo -N (GOMAXPROCS) del nombre del benchmark
+var benchmarkProcs = regexp.MustCompile(`^(Benchmark.*)-(\d+)$`)
+
+// ParseBenchmarkOutput extrae los resultados de las líneas de benchmark de la
+// salida de 'go test -bench', por ejemplo:
+//
+//     BenchmarkJoin-8   1733455   125.4 ns/op   112 B/op   1 allocs/op
+//
+// Las métricas personalizadas (b.ReportMetric) se ignoran.
+func ParseBenchmarkOutput(output string) []BenchmarkResult {
+	var results []BenchmarkResult
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		iterations, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		result := BenchmarkResult{Name: fields[0], Iterations: iterations}
+		if m := benchmarkProcs.FindStringSubmatch(fields[0]); m != nil {
+			result.Name = m[1]
+			result.Procs, _ = strconv.Atoi(m[2])
+		}
+
+		// El resto son pares "valor unidad"
+		for i := 2; i+1 < len(fields); i += 2 {
+			value, unit := fields[i], fields[i+1]
+			switch unit {
+			case "ns/op":
+				result.NsPerOp, _ = strconv.ParseFloat(value, 64)
+			case "B/op":
+				result.BytesPerOp, _ = strconv.ParseInt(value, 10, 64)
+			case "allocs/op":
+				result.AllocsPerOp, _ = strconv.ParseInt(value, 10, 64)
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
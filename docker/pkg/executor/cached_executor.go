@@ -7,10 +7,16 @@
 // Ejemplo de uso básico:
 //
 //     // Crear un ejecutor básico
-//     baseExecutor := executor.NewGoExecutor("/usr/local/go/bin/go", 10000, "/tmp")
+//     baseExecutor, err := executor.NewGoExecutor(executor.GoExecutorOptions{
+//         GoExecutablePath: "/usr/local/go/bin/go",
+//         MaxStdoutLength:  10000,
+//         MaxStderrLength:  10000,
+//         TempDir:          "/tmp",
+//         Limits:           executor.ProcessLimits{GOMAXPROCS: 1},
+//     }, appLogger)
 //
 //     // Envolver con caché para optimizar ejecuciones repetidas
-//     cachedExecutor := executor.NewCachedExecutor(baseExecutor, 100, 30*time.Minute)
+//     cachedExecutor := executor.NewCachedExecutor(baseExecutor, 100, 64*1024, 30*time.Minute)
 //
 //     // Ejecutar código
 //     var output bytes.Buffer
@@ -22,12 +28,22 @@
 package executor
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
+	"math"
+	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	apperrors "github.com/luis198755/go_playGround_plus/docker/pkg/errors"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/logger"
+	"go.uber.org/zap"
 )
 
 // CacheEntry representa una entrada en el caché de ejecuciones.
@@ -35,43 +51,196 @@ import (
 // y un contador de accesos para estadísticas y políticas de reemplazo.
 type CacheEntry struct {
 	Result      []byte
+	Truncated   bool
 	LastAccess  time.Time
 	AccessCount int
+	// ExitCode, Err y CodeHash solo se usan en las entradas de claves de
+	// idempotencia, que también guardan ejecuciones fallidas
+	ExitCode int
+	Err      string
+	CodeHash string
+}
+
+// CacheInspector define el comportamiento de los ejecutores que pueden indicar
+// si un código ya tiene su resultado almacenado en caché.
+type CacheInspector interface {
+	IsCached(code string) bool
+}
+
+// CacheKeyer define el comportamiento de los ejecutores que pueden indicar con
+// qué clave almacenarían un código en su caché
+type CacheKeyer interface {
+	CacheKey(ctx context.Context, code string) string
+}
+
+// CodeHash devuelve el hash con el que ex identifica code: su clave del caché
+// si implementa CacheKeyer y, si no, la de ExecutionKey. Sirve para que los
+// logs se puedan cruzar con las entradas del caché sin guardar el código.
+func CodeHash(ctx context.Context, ex CodeExecutor, code string) string {
+	if keyer, ok := ex.(CacheKeyer); ok {
+		return keyer.CacheKey(ctx, code)
+	}
+	return ExecutionKey(ctx, code)
 }
 
 // CachedExecutor implementa un ejecutor con caché para código frecuentemente ejecutado.
 // Utiliza un sistema de caché basado en el hash SHA-256 del código fuente para
 // identificar ejecuciones idénticas y evitar la re-ejecución innecesaria.
 // Incluye políticas de expiración (TTL) y reemplazo (LRU) para gestionar el tamaño del caché.
+//
+// Las solicitudes concurrentes de un código que no está en caché comparten una
+// única ejecución, cuyo resultado se almacena una sola vez. La ejecución
+// compartida no depende del contexto de ninguna solicitud: solo se cancela
+// cuando todas dejan de esperarla.
 type CachedExecutor struct {
-	executor     CodeExecutor
-	cache        map[string]*CacheEntry
-	cacheMutex   sync.RWMutex
-	maxCacheSize int
-	ttl          time.Duration
+	executor          CodeExecutor
+	cache             map[string]*CacheEntry
+	cacheMutex        sync.RWMutex
+	flightsMu         sync.Mutex
+	flights           map[string]*flight
+	executionTimeout  time.Duration
+	maxCacheSize      int
+	maxEntrySizeBytes int
+	ttl               time.Duration
+	normalizeCode     bool
+	oversizeSkips     atomic.Int64
+	cleanupInterval   time.Duration
+	cleanupRunning    atomic.Bool
+	stop              chan struct{}
+	stopOnce          sync.Once
+	log               logger.Logger
+	// lookupLatency es la media móvil exponencial de la duración de lookup,
+	// en nanosegundos, guardada como los bits de un float64
+	lookupLatency atomic.Uint64
+	// lastSlowLookupWarning es el instante (UnixNano) del último aviso de
+	// búsquedas lentas
+	lastSlowLookupWarning atomic.Int64
+	// EvictionCallback, si no es nil, recibe cada entrada descartada por LRU o
+	// por expiración. Se fija con WithEvictionCallback.
+	EvictionCallback EvictionCallback
+}
+
+// EvictionCallback recibe una entrada descartada del caché y su clave: el hash
+// del código o, en las entradas de claves de idempotencia, la clave con el
+// prefijo "idempotency:". Se invoca sin el cerrojo del caché, así que puede
+// consultarlo, pero no debe bloquearse: retrasa la solicitud que provocó el
+// descarte o la rutina de limpieza.
+type EvictionCallback func(hash string, entry *CacheEntry)
+
+// CachedExecutorOption configura un aspecto opcional de CachedExecutor
+type CachedExecutorOption func(*CachedExecutor)
+
+// WithCleanupInterval fija cada cuánto se eliminan las entradas expiradas.
+// Por defecto es ttl/2. Los valores menores o iguales a 0 se ignoran.
+func WithCleanupInterval(d time.Duration) CachedExecutorOption {
+	return func(ce *CachedExecutor) {
+		if d > 0 {
+			ce.cleanupInterval = d
+		}
+	}
 }
 
+// WithEvictionCallback fija la función que recibe las entradas descartadas
+// del caché, por ejemplo para publicar métricas. nil no hace nada.
+func WithEvictionCallback(callback EvictionCallback) CachedExecutorOption {
+	return func(ce *CachedExecutor) {
+		ce.EvictionCallback = callback
+	}
+}
+
+// WithLogger fija el logger con el que se avisa de que las búsquedas en el
+// caché son lentas. Sin logger no se avisa.
+func WithLogger(log logger.Logger) CachedExecutorOption {
+	return func(ce *CachedExecutor) {
+		ce.log = log
+	}
+}
+
+// WithExecutionTimeout fija el tiempo máximo de una ejecución compartida
+// cuando la solicitud que la inicia no tiene plazo. Si lo tiene, la ejecución
+// compartida conserva ese plazo. Los valores menores o iguales a 0 se ignoran.
+func WithExecutionTimeout(d time.Duration) CachedExecutorOption {
+	return func(ce *CachedExecutor) {
+		if d > 0 {
+			ce.executionTimeout = d
+		}
+	}
+}
+
+// WithCodeNormalization hace que la clave del caché se calcule sobre el código
+// normalizado con NormalizeCode, para que el mismo programa enviado con otro
+// formato o comentarios aproveche la entrada existente. Cuesta analizar y
+// formatear el código en cada solicitud, y no afecta a los códigos que no se
+// pueden analizar, que siguen usando el hash del código tal cual.
+func WithCodeNormalization() CachedExecutorOption {
+	return func(ce *CachedExecutor) {
+		ce.normalizeCode = true
+	}
+}
+
+// CacheStats contiene estadísticas agregadas del caché de ejecuciones
+type CacheStats struct {
+	// Entries es el número de entradas almacenadas actualmente
+	Entries int
+	// OversizeSkips cuenta las ejecuciones no almacenadas por superar maxEntrySizeBytes
+	OversizeSkips int64
+	// CacheLookupLatency es la media móvil exponencial de lo que tarda una
+	// búsqueda en el caché, incluida la espera por su cerrojo. Si crece con la
+	// concurrencia, conviene repartir el caché en varios por prefijo del hash.
+	CacheLookupLatency time.Duration
+}
+
+// Parámetros de la medición de las búsquedas en el caché
+const (
+	// cacheLookupLatencyWeight es el peso de cada búsqueda en la media móvil
+	cacheLookupLatencyWeight = 0.05
+	// slowCacheLookupThreshold es la media a partir de la cual se avisa en el log
+	slowCacheLookupThreshold = time.Millisecond
+	// slowCacheLookupWarnInterval es el tiempo mínimo entre dos avisos
+	slowCacheLookupWarnInterval = time.Minute
+)
+
 // NewCachedExecutor crea un nuevo ejecutor con caché que envuelve a otro ejecutor.
 //
 // Parámetros:
 //   - executor: El ejecutor base que se utilizará para las ejecuciones que no estén en caché.
 //   - maxCacheSize: El número máximo de entradas que se almacenarán en el caché.
+//   - maxEntrySizeBytes: Tamaño máximo en bytes de la salida de una entrada. Las
+//     salidas mayores se sirven directamente sin almacenarse (0 = sin límite).
 //   - ttl: El tiempo de vida de las entradas en el caché antes de ser consideradas expiradas.
+//   - opts: Opciones adicionales, como WithCleanupInterval, WithEvictionCallback o WithLogger.
+//
+// La rutina de limpieza se ejecuta en segundo plano hasta que se llama a Stop.
 //
 // Ejemplo:
 //
-//     baseExecutor := executor.NewGoExecutor("/usr/local/go/bin/go", 10000, os.TempDir())
-//     cachedExecutor := executor.NewCachedExecutor(baseExecutor, 100, 30*time.Minute)
+//     baseExecutor, err := executor.NewGoExecutor(executor.GoExecutorOptions{
+//         GoExecutablePath: "/usr/local/go/bin/go",
+//         MaxStdoutLength:  10000,
+//         MaxStderrLength:  10000,
+//         TempDir:          os.TempDir(),
+//     }, appLogger)
+//     cachedExecutor := executor.NewCachedExecutor(baseExecutor, 100, 64*1024, 30*time.Minute,
+//         executor.WithCleanupInterval(5*time.Minute))
+//     defer cachedExecutor.Stop()
 //     // Ahora cachedExecutor puede usarse como cualquier otro CodeExecutor
-func NewCachedExecutor(executor CodeExecutor, maxCacheSize int, ttl time.Duration) *CachedExecutor {
+func NewCachedExecutor(executor CodeExecutor, maxCacheSize, maxEntrySizeBytes int, ttl time.Duration, opts ...CachedExecutorOption) *CachedExecutor {
 	ce := &CachedExecutor{
-		executor:     executor,
-		cache:        make(map[string]*CacheEntry),
-		maxCacheSize: maxCacheSize,
-		ttl:          ttl,
+		executor:          executor,
+		cache:             make(map[string]*CacheEntry),
+		flights:           make(map[string]*flight),
+		maxCacheSize:      maxCacheSize,
+		maxEntrySizeBytes: maxEntrySizeBytes,
+		ttl:               ttl,
+		cleanupInterval:   ttl / 2,
+		stop:              make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(ce)
 	}
 	
 	// Iniciar rutina de limpieza periódica
+	ce.cleanupRunning.Store(true)
 	go ce.cleanupRoutine()
 	
 	return ce
@@ -99,72 +268,426 @@ func NewCachedExecutor(executor CodeExecutor, maxCacheSize int, ttl time.Duratio
 //         fmt.Println("Resultado:", output.String())
 //     }
 func (ce *CachedExecutor) Execute(ctx context.Context, code string, output io.Writer) error {
-	// Generar hash del código como clave del caché
-	codeHash := ce.hashCode(code)
+	_, err := ce.ExecuteWithResult(ctx, code, output)
+	return err
+}
+
+// ExecuteWithResult ejecuta el código igual que Execute y devuelve el resultado
+// de la ejecución. Solo se almacenan ejecuciones correctas, por lo que un acierto
+// en caché siempre tiene código de salida 0.
+func (ce *CachedExecutor) ExecuteWithResult(ctx context.Context, code string, output io.Writer) (result ExecutionResult, err error) {
+	cached := false
+	ctx, span := startSpan(ctx, "CachedExecutor.Execute", code)
+	defer func(start time.Time) {
+		result.Cached = cached
+		endSpan(span, cached, time.Since(start), err)
+	}(time.Now())
+
+	// Generar hash del código (y de la hora simulada) como clave del caché
+	codeHash := ce.CacheKey(ctx, code)
 	
-	// Intentar obtener del caché
-	ce.cacheMutex.RLock()
-	entry, found := ce.cache[codeHash]
-	if found {
-		// Verificar si la entrada no ha expirado
-		if time.Since(entry.LastAccess) <= ce.ttl {
-			ce.cacheMutex.RUnlock()
-			
-			// Actualizar estadísticas del caché (en una goroutine separada para no bloquear)
-			go ce.updateCacheStats(codeHash)
-			
-			// Escribir resultado desde el caché
-			_, err := output.Write(entry.Result)
-			return err
-		}
-		// La entrada ha expirado
-		found = false
+	// Consultar el caché antes de unirse a una ejecución, que no hace falta
+	// para los aciertos
+	if entry, found := ce.lookup(codeHash); found {
+		cached = true
+		return ce.replay(codeHash, code, entry, output)
 	}
-	ce.cacheMutex.RUnlock()
 	
-	if !found {
-		// Crear un buffer para capturar la salida
-		buffer := &cachingWriter{
-			buffer: make([]byte, 0, 4096), // Buffer inicial de 4KB
+	// Las solicitudes concurrentes del mismo código esperan a la primera, que
+	// inicia la ejecución, recibe la salida en vivo en su output y almacena el
+	// resultado. Las demás reciben la salida completa al terminar.
+	live := &detachableWriter{w: output}
+	defer live.detach()
+	f, leader := ce.joinFlight(ctx, codeHash, code, live)
+
+	select {
+	case <-ctx.Done():
+		ce.leaveFlight(codeHash, f)
+		return ExecutionResult{FormattedCode: code, ExitCode: -1}, fmt.Errorf("error en la ejecución: %w", ctx.Err())
+	case <-f.done:
+		shared := f.shared
+		cached = shared.cached
+		if !leader {
+			// El tiempo de CPU solo se atribuye a la solicitud que ejecutó el código
+			shared.result.CPUTime = 0
+			if _, err := output.Write(shared.output); err != nil {
+				return shared.result, err
+			}
 		}
-		
-		// Crear un escritor multi-destino
-		multiWriter := io.MultiWriter(output, buffer)
-		
-		// Ejecutar el código
-		err := ce.executor.Execute(ctx, code, multiWriter)
-		if err != nil {
-			return err
+		return shared.result, f.err
+	}
+}
+
+// flightResult es el resultado compartido entre las solicitudes de una misma ejecución
+type flightResult struct {
+	output []byte
+	result ExecutionResult
+	// cached indica que el resultado salió del caché
+	cached bool
+}
+
+// detachableWriter escribe en w hasta que se llama a detach. Evita que la
+// ejecución compartida siga escribiendo en la respuesta de una solicitud que
+// ya dejó de esperar (por ejemplo, porque expiró su contexto).
+type detachableWriter struct {
+	mu       sync.Mutex
+	w        io.Writer
+	detached bool
+}
+
+// Write implementa la interfaz io.Writer. Tras detach descarta los datos sin error.
+func (dw *detachableWriter) Write(p []byte) (int, error) {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+	if dw.detached {
+		return len(p), nil
+	}
+	return dw.w.Write(p)
+}
+
+// detach deja de escribir en w
+func (dw *detachableWriter) detach() {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+	dw.detached = true
+}
+
+// flight es una ejecución compartida por las solicitudes concurrentes de un
+// mismo código. shared y err solo se leen después de cerrarse done.
+//
+// Sigue el patrón de golang.org/x/sync/singleflight, pero con la cuenta de
+// solicitudes en espera: singleflight no sabe cuántas llamadas a DoChan siguen
+// esperando el resultado, de modo que no puede cancelar la ejecución cuando se
+// va la última, y Forget solo la olvida sin detenerla, así que el proceso
+// seguiría ocupando CPU y plaza en la cola hasta su timeout sin que nadie lo
+// espere.
+type flight struct {
+	done   chan struct{}
+	shared flightResult
+	err    error
+	// waiters es el número de solicitudes que esperan la ejecución, protegido
+	// por flightsMu
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// joinFlight une la solicitud a la ejecución en curso de codeHash o, si no la
+// hay, inicia una nueva que escribe la salida en vivo en live. Devuelve si la
+// solicitud inició la ejecución.
+//
+// La ejecución usa un contexto separado del de ctx, que conserva sus valores
+// (traza, hora simulada) y su plazo, o executionTimeout si no tiene, para que
+// las demás solicitudes no reciban el error de la primera si esta se cancela.
+func (ce *CachedExecutor) joinFlight(ctx context.Context, codeHash, code string, live io.Writer) (*flight, bool) {
+	ce.flightsMu.Lock()
+	defer ce.flightsMu.Unlock()
+
+	if f, ok := ce.flights[codeHash]; ok {
+		f.waiters++
+		return f, false
+	}
+
+	flightCtx := context.WithoutCancel(ctx)
+	var cancel context.CancelFunc
+	if deadline, ok := ctx.Deadline(); ok {
+		flightCtx, cancel = context.WithDeadline(flightCtx, deadline)
+	} else if ce.executionTimeout > 0 {
+		flightCtx, cancel = context.WithTimeout(flightCtx, ce.executionTimeout)
+	} else {
+		flightCtx, cancel = context.WithCancel(flightCtx)
+	}
+	f := &flight{done: make(chan struct{}), waiters: 1, cancel: cancel}
+	ce.flights[codeHash] = f
+
+	go func() {
+		defer cancel()
+		shared, err := ce.executeAndStore(flightCtx, codeHash, code, live)
+
+		ce.flightsMu.Lock()
+		if ce.flights[codeHash] == f {
+			delete(ce.flights, codeHash)
 		}
-		
-		// Guardar en caché
-		ce.cacheMutex.Lock()
-		defer ce.cacheMutex.Unlock()
-		
-		// Verificar si necesitamos hacer espacio en el caché
-		if len(ce.cache) >= ce.maxCacheSize {
-			ce.evictLeastRecentlyUsed()
+		ce.flightsMu.Unlock()
+
+		f.shared, f.err = shared, err
+		close(f.done)
+	}()
+	return f, true
+}
+
+// leaveFlight retira de f una solicitud que dejó de esperar. Si era la última,
+// cancela la ejecución y la olvida, para que la siguiente solicitud del mismo
+// código inicie otra en lugar de recibir el error de la cancelada.
+func (ce *CachedExecutor) leaveFlight(codeHash string, f *flight) {
+	ce.flightsMu.Lock()
+	defer ce.flightsMu.Unlock()
+
+	f.waiters--
+	if f.waiters > 0 {
+		return
+	}
+	f.cancel()
+	if ce.flights[codeHash] == f {
+		delete(ce.flights, codeHash)
+	}
+}
+
+// executeAndStore ejecuta el código con el ejecutor base escribiendo la salida
+// en output y, si el resultado puede cachearse, lo almacena bajo codeHash. Es
+// la ejecución compartida que inicia joinFlight.
+func (ce *CachedExecutor) executeAndStore(ctx context.Context, codeHash, code string, output io.Writer) (flightResult, error) {
+	// Otra solicitud pudo almacenar el resultado entre la consulta del caché y
+	// el inicio de la ejecución compartida
+	if entry, found := ce.lookup(codeHash); found {
+		result, err := ce.replay(codeHash, code, entry, output)
+		return flightResult{output: entry.Result, result: result, cached: true}, err
+	}
+
+	// La salida se acumula completa porque las solicitudes que esperan la necesitan
+	var buffer bytes.Buffer
+	result, err := RunWithResult(ctx, ce.executor, code, io.MultiWriter(&buffer, output))
+	shared := flightResult{output: buffer.Bytes(), result: result}
+
+	// Nunca almacenar la salida parcial de una ejecución cortada por timeout,
+	// cancelación o fallo interno, aunque el ejecutor base no devuelva error
+	if !ShouldCache(err) || !ShouldCache(ctx.Err()) {
+		return shared, err
+	}
+	// Los errores del propio programa tampoco se almacenan: el caché solo guarda salida
+	if err != nil {
+		return shared, err
+	}
+	
+	// Una salida enorme ocuparía una parte desproporcionada del caché: ya se
+	// escribió completa en output, así que basta con no almacenarla. No se
+	// guarda solo el principio porque un acierto devolvería una salida distinta.
+	if ce.maxEntrySizeBytes > 0 && buffer.Len() > ce.maxEntrySizeBytes {
+		ce.oversizeSkips.Add(1)
+		return shared, nil
+	}
+	
+	// Guardar en caché
+	ce.store(codeHash, &CacheEntry{
+		Result:      shared.output,
+		Truncated:   result.Truncated,
+		LastAccess:  time.Now(),
+		AccessCount: 1,
+	})
+	
+	return shared, nil
+}
+
+// replay escribe en output la salida almacenada en entry y devuelve su resultado
+func (ce *CachedExecutor) replay(codeHash, code string, entry *CacheEntry, output io.Writer) (ExecutionResult, error) {
+	// Actualizar estadísticas del caché (en una goroutine separada para no bloquear)
+	go ce.updateCacheStats(codeHash)
+
+	result := ce.PrepareCode(code)
+	result.Truncated = entry.Truncated
+	_, err := output.Write(entry.Result)
+	return result, err
+}
+
+// lookup devuelve la entrada vigente (no expirada) de key, si existe, y
+// registra cuánto tardó en CacheLookupLatency
+func (ce *CachedExecutor) lookup(key string) (*CacheEntry, bool) {
+	start := time.Now()
+	ce.cacheMutex.RLock()
+	entry, found := ce.cache[key]
+	found = found && time.Since(entry.LastAccess) <= ce.ttl
+	entries := len(ce.cache)
+	ce.cacheMutex.RUnlock()
+	ce.recordLookupLatency(time.Since(start), entries)
+
+	if !found {
+		return nil, false
+	}
+	return entry, true
+}
+
+// recordLookupLatency incorpora latency a la media móvil de las búsquedas y,
+// si la media supera slowCacheLookupThreshold, avisa en el log como mucho una
+// vez cada slowCacheLookupWarnInterval. entries es el tamaño del caché durante
+// la búsqueda.
+func (ce *CachedExecutor) recordLookupLatency(latency time.Duration, entries int) {
+	var average float64
+	for {
+		old := ce.lookupLatency.Load()
+		average = float64(latency)
+		if old != 0 {
+			previous := math.Float64frombits(old)
+			average = previous + cacheLookupLatencyWeight*(average-previous)
 		}
-		
-		// Almacenar resultado en caché
-		ce.cache[codeHash] = &CacheEntry{
-			Result:      buffer.buffer,
-			LastAccess:  time.Now(),
-			AccessCount: 1,
+		if ce.lookupLatency.CompareAndSwap(old, math.Float64bits(average)) {
+			break
 		}
 	}
+
+	if ce.log == nil || average <= float64(slowCacheLookupThreshold) {
+		return
+	}
+	now := time.Now().UnixNano()
+	last := ce.lastSlowLookupWarning.Load()
+	if now-last < int64(slowCacheLookupWarnInterval) || !ce.lastSlowLookupWarning.CompareAndSwap(last, now) {
+		return
+	}
+	ce.log.Warn("Las búsquedas en el caché de ejecuciones son lentas",
+		zap.Duration("lookup_latency", time.Duration(average)),
+		zap.Duration("threshold", slowCacheLookupThreshold),
+		zap.Int("entries", entries))
+}
+
+// store guarda entry bajo key, haciendo espacio antes si el caché está lleno
+func (ce *CachedExecutor) store(key string, entry *CacheEntry) {
+	ce.cacheMutex.Lock()
 	
-	return nil
+	// Verificar si necesitamos hacer espacio en el caché
+	var evictedKey string
+	var evicted *CacheEntry
+	if _, exists := ce.cache[key]; !exists && len(ce.cache) >= ce.maxCacheSize {
+		evictedKey, evicted = ce.evictLeastRecentlyUsed()
+	}
+	ce.cache[key] = entry
+	ce.cacheMutex.Unlock()
+
+	if evicted != nil {
+		ce.notifyEviction(evictedKey, evicted)
+	}
+}
+
+// notifyEviction pasa una entrada descartada a EvictionCallback, si existe.
+// Debe llamarse sin el cerrojo del caché.
+func (ce *CachedExecutor) notifyEviction(key string, entry *CacheEntry) {
+	if ce.EvictionCallback != nil {
+		ce.EvictionCallback(key, entry)
+	}
+}
+
+// ShouldCache indica si el resultado de una ejecución que terminó con err puede
+// almacenarse en caché. Retorna false si la ejecución expiró o fue cancelada
+// (context.DeadlineExceeded, context.Canceled) o si err es un AppError con
+// estado 500 o superior, ya que en esos casos la salida está incompleta.
+func ShouldCache(err error) bool {
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) && appErr.StatusCode >= http.StatusInternalServerError {
+		return false
+	}
+	return true
+}
+
+// Compile delega la compilación en el ejecutor base, sin usar el caché.
+// Implementa la interfaz Compiler si el ejecutor base también lo hace.
+func (ce *CachedExecutor) Compile(ctx context.Context, code string, target BuildTarget) error {
+	compiler, ok := ce.executor.(Compiler)
+	if !ok {
+		return fmt.Errorf("el ejecutor base no soporta compilación")
+	}
+	return compiler.Compile(ctx, code, target)
+}
+
+// ExecuteRace delega la ejecución con el detector de carreras en el ejecutor
+// base, sin usar el caché: las carreras dependen de la planificación de cada
+// ejecución, así que repetirla puede dar otro resultado.
+// Implementa la interfaz RaceExecutor si el ejecutor base también lo hace.
+func (ce *CachedExecutor) ExecuteRace(ctx context.Context, code string, output io.Writer) (RaceResult, error) {
+	raceExecutor, ok := ce.executor.(RaceExecutor)
+	if !ok {
+		return RaceResult{}, fmt.Errorf("el ejecutor base no soporta el detector de carreras")
+	}
+	return raceExecutor.ExecuteRace(ctx, code, output)
 }
 
-// hashCode genera un hash SHA-256 del código.
-// Este hash se utiliza como clave para identificar entradas únicas en el caché.
-func (ce *CachedExecutor) hashCode(code string) string {
+// Benchmark delega los benchmarks en el ejecutor base, sin usar el caché: los
+// tiempos medidos cambian en cada ejecución.
+// Implementa la interfaz Benchmarker si el ejecutor base también lo hace.
+func (ce *CachedExecutor) Benchmark(ctx context.Context, code string) (BenchmarkReport, error) {
+	benchmarker, ok := ce.executor.(Benchmarker)
+	if !ok {
+		return BenchmarkReport{}, fmt.Errorf("el ejecutor base no soporta benchmarks")
+	}
+	return benchmarker.Benchmark(ctx, code)
+}
+
+// Test delega los tests en el ejecutor base, sin usar el caché: los tiempos
+// cambian en cada ejecución y los tests pueden depender de la hora o del azar.
+// Implementa la interfaz Tester si el ejecutor base también lo hace.
+func (ce *CachedExecutor) Test(ctx context.Context, code string) (TestReport, error) {
+	tester, ok := ce.executor.(Tester)
+	if !ok {
+		return TestReport{}, fmt.Errorf("el ejecutor base no soporta tests")
+	}
+	return tester.Test(ctx, code)
+}
+
+// Assembly delega la generación de ensamblador en el ejecutor base, sin usar el caché.
+// Implementa la interfaz Disassembler si el ejecutor base también lo hace.
+func (ce *CachedExecutor) Assembly(ctx context.Context, code string) (string, error) {
+	disassembler, ok := ce.executor.(Disassembler)
+	if !ok {
+		return "", fmt.Errorf("el ejecutor base no soporta la vista de ensamblador")
+	}
+	return disassembler.Assembly(ctx, code)
+}
+
+// PrepareCode delega en el ejecutor base, o devuelve el código sin cambios si
+// este no lo transforma. Implementa la interfaz CodePreparer.
+func (ce *CachedExecutor) PrepareCode(code string) ExecutionResult {
+	if preparer, ok := ce.executor.(CodePreparer); ok {
+		return preparer.PrepareCode(code)
+	}
+	return ExecutionResult{FormattedCode: code}
+}
+
+// SupportsFakeTime delega en el ejecutor base. Implementa la interfaz FakeTimeSupporter.
+func (ce *CachedExecutor) SupportsFakeTime() bool {
+	return SupportsFakeTime(ce.executor)
+}
+
+// CacheKey devuelve la clave del caché y de la ejecución compartida para
+// code: la de ExecutionKey, sobre el código normalizado si se usa
+// WithCodeNormalization.
+// Implementa la interfaz CacheKeyer.
+func (ce *CachedExecutor) CacheKey(ctx context.Context, code string) string {
+	if ce.normalizeCode {
+		code, _ = NormalizeCode(code)
+	}
+	return ExecutionKey(ctx, code)
+}
+
+// HashCode devuelve el hash SHA-256 en hexadecimal del código fuente.
+// Permite a otros paquetes identificar un código sin almacenarlo completo.
+func HashCode(code string) string {
 	hasher := sha256.New()
 	hasher.Write([]byte(code))
 	return hex.EncodeToString(hasher.Sum(nil))
 }
 
+// IsCached indica si el código tiene una entrada vigente en el caché.
+// Implementa la interfaz CacheInspector.
+func (ce *CachedExecutor) IsCached(code string) bool {
+	_, found := ce.lookup(ce.CacheKey(context.Background(), code))
+	return found
+}
+
+// Stats devuelve las estadísticas actuales del caché
+func (ce *CachedExecutor) Stats() CacheStats {
+	ce.cacheMutex.RLock()
+	entries := len(ce.cache)
+	ce.cacheMutex.RUnlock()
+
+	return CacheStats{
+		Entries:            entries,
+		OversizeSkips:      ce.oversizeSkips.Load(),
+		CacheLookupLatency: time.Duration(math.Float64frombits(ce.lookupLatency.Load())),
+	}
+}
+
 // updateCacheStats actualiza las estadísticas de uso del caché.
 // Incrementa el contador de accesos y actualiza el timestamp de último acceso.
 // Esta información se utiliza para la política de reemplazo LRU.
@@ -181,7 +704,9 @@ func (ce *CachedExecutor) updateCacheStats(codeHash string) {
 // evictLeastRecentlyUsed elimina la entrada menos recientemente usada del caché.
 // Se llama cuando el caché está lleno y es necesario hacer espacio para una nueva entrada.
 // Implementa la política de reemplazo Least Recently Used (LRU).
-func (ce *CachedExecutor) evictLeastRecentlyUsed() {
+// Devuelve la clave y la entrada eliminadas, o una entrada nil si el caché
+// estaba vacío. Debe llamarse con el cerrojo del caché bloqueado.
+func (ce *CachedExecutor) evictLeastRecentlyUsed() (string, *CacheEntry) {
 	var oldestKey string
 	var oldestTime time.Time
 	
@@ -201,19 +726,46 @@ func (ce *CachedExecutor) evictLeastRecentlyUsed() {
 	}
 	
 	// Eliminar la entrada más antigua
-	if oldestKey != "" {
-		delete(ce.cache, oldestKey)
+	entry, found := ce.cache[oldestKey]
+	if !found {
+		return "", nil
 	}
+	delete(ce.cache, oldestKey)
+	return oldestKey, entry
+}
+
+// Ready indica si el caché está inicializado y su rutina de limpieza en marcha.
+// Pensado como comprobación de disponibilidad (/readyz).
+func (ce *CachedExecutor) Ready(ctx context.Context) error {
+	if !ce.cleanupRunning.Load() {
+		return errors.New("la rutina de limpieza del caché no está en marcha")
+	}
+	return nil
+}
+
+// Stop detiene la rutina de limpieza periódica. El caché sigue funcionando,
+// pero las entradas expiradas solo se descartan al consultarlas. Es seguro
+// llamarlo más de una vez.
+func (ce *CachedExecutor) Stop() {
+	ce.stopOnce.Do(func() {
+		close(ce.stop)
+	})
 }
 
 // cleanupRoutine limpia periódicamente las entradas expiradas del caché.
-// Se ejecuta en una goroutine separada y se activa cada ttl/2 tiempo.
+// Se ejecuta en una goroutine separada cada cleanupInterval, hasta que se llama a Stop.
 func (ce *CachedExecutor) cleanupRoutine() {
-	ticker := time.NewTicker(ce.ttl / 2)
+	defer ce.cleanupRunning.Store(false)
+	ticker := time.NewTicker(ce.cleanupInterval)
 	defer ticker.Stop()
 	
-	for range ticker.C {
-		ce.cleanupCache()
+	for {
+		select {
+		case <-ce.stop:
+			return
+		case <-ticker.C:
+			ce.cleanupCache()
+		}
 	}
 }
 
@@ -221,25 +773,18 @@ func (ce *CachedExecutor) cleanupRoutine() {
 // Una entrada se considera expirada si ha pasado más tiempo que el TTL desde su último acceso.
 func (ce *CachedExecutor) cleanupCache() {
 	ce.cacheMutex.Lock()
-	defer ce.cacheMutex.Unlock()
 	
 	now := time.Now()
+	expired := make(map[string]*CacheEntry)
 	for k, v := range ce.cache {
 		if now.Sub(v.LastAccess) > ce.ttl {
 			delete(ce.cache, k)
+			expired[k] = v
 		}
 	}
-}
-
-// cachingWriter es un escritor que almacena los datos en un buffer.
-// Se utiliza para capturar la salida de la ejecución y almacenarla en el caché.
-type cachingWriter struct {
-	buffer []byte
-}
+	ce.cacheMutex.Unlock()
 
-// Write implementa la interfaz io.Writer.
-// Almacena los datos escritos en el buffer interno para su posterior almacenamiento en el caché.
-func (cw *cachingWriter) Write(p []byte) (n int, err error) {
-	cw.buffer = append(cw.buffer, p...)
-	return len(p), nil
+	for k, v := range expired {
+		ce.notifyEviction(k, v)
+	}
 }
@@ -0,0 +1,82 @@
+package executor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+)
+
+// OutputHasher calcula el SHA-256 de la salida normalizada de una ejecución,
+// para que un corrector automático compare la salida con la esperada sin
+// recibirla completa. Se escribe en él como en cualquier io.Writer, a la vez
+// que la salida se envía al cliente.
+//
+// La normalización elimina los espacios, tabuladores y retornos de carro al
+// final de cada línea y las líneas vacías y saltos de línea del final de la
+// salida, de modo que "hola \n\n" y "hola" tienen el mismo checksum. Se puede
+// reproducir con OutputChecksum.
+type OutputHasher struct {
+	hash hash.Hash
+	// pending son los espacios en blanco que aún no se sabe si son finales
+	pending []byte
+}
+
+// NewOutputHasher crea un OutputHasher vacío
+func NewOutputHasher() *OutputHasher {
+	return &OutputHasher{hash: sha256.New()}
+}
+
+// Write implementa la interfaz io.Writer. Nunca devuelve error.
+func (oh *OutputHasher) Write(p []byte) (int, error) {
+	start := 0
+	for i := 0; i < len(p); i++ {
+		if isOutputSpace(p[i]) {
+			continue
+		}
+		// Los espacios pendientes van seguidos de texto: se conservan sus
+		// saltos de línea y la sangría de la última línea
+		oh.flushPending(p[start:i])
+		end := i + 1
+		for end < len(p) && !isOutputSpace(p[end]) {
+			end++
+		}
+		oh.hash.Write(p[i:end])
+		start, i = end, end
+	}
+	oh.pending = append(oh.pending, p[start:]...)
+	return len(p), nil
+}
+
+// flushPending escribe en el hash los espacios pendientes, seguidos de more,
+// sin los espacios que preceden a cada salto de línea
+func (oh *OutputHasher) flushPending(more []byte) {
+	ws := append(oh.pending, more...)
+	lineStart := 0
+	for i, b := range ws {
+		if b == '\n' {
+			oh.hash.Write([]byte{'\n'})
+			lineStart = i + 1
+		}
+	}
+	oh.hash.Write(ws[lineStart:])
+	oh.pending = oh.pending[:0]
+}
+
+// Sum devuelve el SHA-256 en hexadecimal de la salida escrita hasta ahora.
+// Los espacios pendientes son finales y no cuentan.
+func (oh *OutputHasher) Sum() string {
+	return hex.EncodeToString(oh.hash.Sum(nil))
+}
+
+// OutputChecksum devuelve el SHA-256 en hexadecimal de output normalizada
+// como en OutputHasher
+func OutputChecksum(output string) string {
+	hasher := NewOutputHasher()
+	hasher.Write([]byte(output))
+	return hasher.Sum()
+}
+
+// isOutputSpace indica si b es un espacio en blanco para la normalización
+func isOutputSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
+}
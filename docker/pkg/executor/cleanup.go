@@ -0,0 +1,166 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/requestctx"
+	"go.uber.org/zap"
+)
+
+// tempDirPattern es el patrón de nombre de los directorios temporales creados por GoExecutor.
+// La limpieza solo elimina entradas que coinciden con este patrón.
+const tempDirPattern = "code-*"
+
+// unknownNamePart sustituye al ID de solicitud o al hash de IP cuando no están en el contexto
+const unknownNamePart = "none"
+
+// TempDirName devuelve el patrón para os.MkdirTemp del directorio de trabajo de una ejecución.
+//
+// El nombre resultante tiene la forma code-{requestID}-{ipHash}-{random}, donde
+// requestID se obtiene del contexto, ipHash son los primeros 8 caracteres del SHA-256
+// de la IP del cliente y {random} lo genera os.MkdirTemp. Así, si un directorio queda
+// huérfano, su nombre permite correlacionarlo con los logs de la solicitud.
+//
+// Ejemplo:
+//
+//     ctx := requestctx.WithRequestID(context.Background(), "a1b2c3d4e5f60718")
+//     dir, err := os.MkdirTemp(tempDir, executor.TempDirName(ctx))
+//     // dir == "/tmp/code-a1b2c3d4e5f60718-none-123456789"
+func TempDirName(ctx context.Context) string {
+	requestID := sanitizeNamePart(requestctx.RequestID(ctx))
+
+	ipHash := unknownNamePart
+	if clientID := requestctx.ClientID(ctx); clientID != "" {
+		ipHash = clientID
+	}
+
+	return "code-" + requestID + "-" + ipHash + "-*"
+}
+
+// sanitizeNamePart limita una parte del nombre de archivo a caracteres alfanuméricos,
+// eliminando los guiones que usa el formato como separador
+func sanitizeNamePart(part string) string {
+	clean := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, part)
+	if len(clean) > 64 {
+		clean = clean[:64]
+	}
+	if clean == "" {
+		return unknownNamePart
+	}
+	return clean
+}
+
+// requestIDFromTempName extrae el ID de solicitud del nombre de una entrada temporal,
+// o devuelve una cadena vacía si el nombre no sigue el formato de TempDirName
+func requestIDFromTempName(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), ".go")
+	parts := strings.Split(name, "-")
+	if len(parts) != 4 || parts[0] != "code" || parts[1] == unknownNamePart {
+		return ""
+	}
+	return parts[1]
+}
+
+// TempCleanup define la política de limpieza de archivos temporales huérfanos.
+type TempCleanup struct {
+	// Interval es la frecuencia con la que se revisa el directorio temporal
+	Interval time.Duration
+	// MaxAge es la antigüedad a partir de la cual una entrada se considera huérfana.
+	// Debe ser mayor que el timeout de ejecución (por ejemplo, el doble).
+	MaxAge time.Duration
+}
+
+// StartCleanup inicia una goroutine que elimina periódicamente los archivos
+// temporales huérfanos del directorio temporal.
+//
+// Los archivos pueden quedar huérfanos si el proceso termina abruptamente o si un
+// pánico impide la limpieza diferida de Execute. La goroutine se activa cada
+// TempCleanup.Interval y elimina los directorios de trabajo code-* cuya última
+// modificación supera TempCleanup.MaxAge, registrando su nombre y el ID de solicitud contenido
+// en él para poder correlacionarlo con los logs. La goroutine termina cuando ctx
+// se cancela.
+//
+// Ejemplo:
+//
+//     shutdownCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+//     defer stop()
+//     goExecutor.StartCleanup(shutdownCtx)
+//
+// Con KeepTempFiles la limpieza no se inicia, para no borrar los archivos
+// conservados para depuración.
+func (ge *GoExecutor) StartCleanup(ctx context.Context) {
+	if ge.cleanup.Interval <= 0 || ge.keepTempFiles {
+		return
+	}
+	go ge.cleanupRoutine(ctx)
+}
+
+// cleanupRoutine ejecuta sweepTempFiles cada intervalo hasta que ctx se cancela.
+func (ge *GoExecutor) cleanupRoutine(ctx context.Context) {
+	ticker := time.NewTicker(ge.cleanup.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			ge.logger.Debug("Limpieza de archivos temporales detenida")
+			return
+		case <-ticker.C:
+			ge.sweepTempFiles(ge.cleanup.MaxAge)
+		}
+	}
+}
+
+// sweepTempFiles elimina las entradas temporales huérfanas más antiguas que maxAge.
+// Solo considera directorios de trabajo que coinciden con tempDirPattern y, por
+// compatibilidad, archivos code-*.go de versiones anteriores, de modo que no toca
+// otros datos que puedan existir en el directorio temporal.
+//
+// Retorna el número de entradas eliminadas.
+func (ge *GoExecutor) sweepTempFiles(maxAge time.Duration) int {
+	matches, err := filepath.Glob(filepath.Join(ge.tempDir, tempDirPattern))
+	if err != nil {
+		ge.logger.Error("Error buscando archivos temporales", zap.Error(err))
+		return 0
+	}
+
+	removed := 0
+	now := time.Now()
+	for _, path := range matches {
+		info, err := os.Lstat(path)
+		if err != nil {
+			continue
+		}
+		isWorkDir := info.IsDir()
+		isLegacyFile := info.Mode().IsRegular() && strings.HasSuffix(path, ".go")
+		if !isWorkDir && !isLegacyFile {
+			continue
+		}
+		if now.Sub(info.ModTime()) <= maxAge {
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			ge.logger.Warn("No se pudo eliminar entrada temporal huérfana",
+				zap.String("path", path),
+				zap.Error(err),
+			)
+			continue
+		}
+		removed++
+		ge.logger.Info("Entrada temporal huérfana eliminada",
+			zap.String("path", path),
+			zap.String("request_id", requestIDFromTempName(path)),
+			zap.Duration("age", now.Sub(info.ModTime())),
+		)
+	}
+	return removed
+}
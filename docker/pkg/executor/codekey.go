@@ -0,0 +1,55 @@
+package executor
+
+import (
+	"bytes"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// NormalizeCode devuelve una forma canónica del código Go para usarla como
+// clave del caché: sin comentarios y con el formato de gofmt, de modo que el
+// mismo programa con otro formato, sangría o comentarios tenga la misma clave.
+// El resultado solo sirve para calcular el hash; lo que se ejecuta es el código
+// original.
+//
+// Se imprime con un FileSet vacío para que no cuenten las posiciones
+// originales: sin ellas gofmt no conserva las líneas en blanco ni los saltos de
+// línea de quien escribió el código.
+//
+// Devuelve false, y el código sin cambios, si no se puede analizar (por
+// ejemplo un fragmento que el ejecutor envuelve en un main) o si algún
+// comentario cambia el comportamiento del programa: directivas //go: y
+// //line, restricciones // +build y el preámbulo de cgo.
+func NormalizeCode(code string) (string, bool) {
+	if hasSignificantComment(code) {
+		return code, false
+	}
+	// Sin parser.ParseComments el árbol no guarda ningún comentario
+	file, err := parser.ParseFile(token.NewFileSet(), "main.go", code, 0)
+	if err != nil {
+		return code, false
+	}
+	for _, imp := range file.Imports {
+		if imp.Path.Value == `"C"` {
+			return code, false
+		}
+	}
+
+	var normalized bytes.Buffer
+	if err := format.Node(&normalized, token.NewFileSet(), file); err != nil {
+		return code, false
+	}
+	return normalized.String(), true
+}
+
+// hasSignificantComment indica si el código puede tener comentarios que
+// afectan a la compilación. Busca el texto en todo el código, también en las
+// cadenas: un falso positivo solo hace que no se normalice.
+func hasSignificantComment(code string) bool {
+	return strings.Contains(code, "//go:") ||
+		strings.Contains(code, "//line ") ||
+		strings.Contains(code, "/*line ") ||
+		strings.Contains(code, "+build")
+}
@@ -0,0 +1,80 @@
+package executor
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PlaygroundFileName sustituye a la ruta del archivo temporal en la salida
+const PlaygroundFileName = "<playground>"
+
+// mainFileReference reconoce las referencias al archivo temporal en la salida
+// del compilador ("./main.go:5:3", o "../main.go:5:3" con ScratchHome) y en las trazas de un panic
+// ("/tmp/go-playground-.../main.go:5 +0x1d"), con la columna opcional
+var mainFileReference = regexp.MustCompile(`(?:\.\.?/|/[^\s:]*/)?` + regexp.QuoteMeta(mainFileName) + `:(\d+)(?::(\d+))?`)
+
+// MapErrors sustituye en rawOutput las referencias al archivo temporal por
+// "<playground>:línea:columna", con las líneas del código enviado por el usuario.
+//
+// Si AutoWrapCode envolvió submittedCode, las líneas del código ejecutado se
+// traducen a las del código enviado. Las que no existen en él (la cabecera o
+// la func main añadidas) conservan la línea del código ejecutado, que el
+// cliente recibe en formatted_code.
+func (ge *GoExecutor) MapErrors(rawOutput, submittedCode string) string {
+	if !strings.Contains(rawOutput, mainFileName) {
+		return rawOutput
+	}
+
+	var lines map[int]int
+	if prepared := ge.PrepareCode(submittedCode); prepared.Wrapped {
+		lines = wrappedLineMap(prepared.FormattedCode, submittedCode)
+	}
+	submittedLines := strings.Count(submittedCode, "\n") + 1
+
+	return mainFileReference.ReplaceAllStringFunc(rawOutput, func(ref string) string {
+		m := mainFileReference.FindStringSubmatch(ref)
+		line, _ := strconv.Atoi(m[1])
+		if lines != nil {
+			if original, ok := lines[line]; ok {
+				line = original
+			}
+		} else if line > submittedLines {
+			// Fuera del código enviado: se deja la referencia sin cambios
+			return ref
+		}
+		mapped := PlaygroundFileName + ":" + strconv.Itoa(line)
+		if m[2] != "" {
+			mapped += ":" + m[2]
+		}
+		return mapped
+	})
+}
+
+// wrappedLineMap relaciona cada línea del código envuelto con la del código
+// enviado que contiene, comparando las líneas sin espacios en blanco (gofmt solo
+// cambia la sangría y los espacios, y une líneas en blanco consecutivas). Las
+// líneas vacías y las añadidas al envolver no se incluyen.
+func wrappedLineMap(wrapped, submitted string) map[int]int {
+	normalize := func(line string) string {
+		return strings.Join(strings.Fields(line), "")
+	}
+	submittedLines := strings.Split(submitted, "\n")
+
+	lines := make(map[int]int)
+	next := 0
+	for i, line := range strings.Split(wrapped, "\n") {
+		key := normalize(line)
+		if key == "" {
+			continue
+		}
+		for j := next; j < len(submittedLines); j++ {
+			if normalize(submittedLines[j]) == key {
+				lines[i+1] = j + 1
+				next = j + 1
+				break
+			}
+		}
+	}
+	return lines
+}
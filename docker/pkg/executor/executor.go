@@ -6,14 +6,28 @@
 package executor
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"go/version"
 	"io"
+	"io/fs"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/logger"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/requestctx"
+	"go.uber.org/zap"
 )
 
 // CodeExecutor define la interfaz para ejecutar código Go.
@@ -23,9 +37,19 @@ import (
 //
 // Ejemplo de uso:
 //
-//     var executor CodeExecutor = NewGoExecutor("/usr/local/go/bin/go", 10000, os.TempDir())
+//     goExecutor, err := NewGoExecutor(GoExecutorOptions{
+//         GoExecutablePath: "/usr/local/go/bin/go",
+//         MaxStdoutLength:  10000,
+//         MaxStderrLength:  10000,
+//         TempDir:          os.TempDir(),
+//         AutoWrapCode:     true,
+//     }, appLogger)
+//     if err != nil {
+//         log.Fatalf("Error: %v", err)
+//     }
+//     var executor CodeExecutor = goExecutor
 //     var output bytes.Buffer
-//     err := executor.Execute(context.Background(), "fmt.Println(\"Hello\")", &output)
+//     err = executor.Execute(context.Background(), "fmt.Println(\"Hello\")", &output)
 //     if err != nil {
 //         log.Fatalf("Error: %v", err)
 //     }
@@ -34,54 +58,306 @@ type CodeExecutor interface {
 	Execute(ctx context.Context, code string, output io.Writer) error
 }
 
+// ExecutionResult describe el código que el ejecutor ejecutó realmente y cómo terminó.
+type ExecutionResult struct {
+	// FormattedCode es el código ejecutado. Si se envolvió, está formateado con gofmt.
+	FormattedCode string
+	// Wrapped indica si se añadió la declaración de paquete (y, si hacía falta, func main)
+	Wrapped bool
+	// ExitCode es el código de salida del programa, o -1 si no terminó por sí mismo
+	ExitCode int
+	// Truncated indica si la salida se recortó por los límites de tamaño
+	Truncated bool
+	// TruncatedStreams son los streams recortados ("stdout", "stderr"). Solo lo
+	// rellena GoExecutor: está vacío en los resultados servidos desde el caché.
+	TruncatedStreams []string
+	// CPUTime es el tiempo de CPU (usuario y sistema) de 'go run', compilación
+	// incluida. Solo lo rellena GoExecutor: es 0 en los resultados del caché.
+	CPUTime time.Duration
+	// Cached indica si la salida se sirvió desde el caché (o desde la respuesta
+	// guardada de una Idempotency-Key) sin ejecutar el código. Solo lo rellena
+	// CachedExecutor.
+	Cached bool
+}
+
+// ResultExecutor es implementado por los ejecutores que, además de escribir la
+// salida, informan del resultado de la ejecución.
+type ResultExecutor interface {
+	ExecuteWithResult(ctx context.Context, code string, output io.Writer) (ExecutionResult, error)
+}
+
+// RunWithResult ejecuta code con ex y devuelve su resultado. Si ex no implementa
+// ResultExecutor, el resultado solo distingue éxito (0) de error (-1).
+func RunWithResult(ctx context.Context, ex CodeExecutor, code string, output io.Writer) (ExecutionResult, error) {
+	if resultExecutor, ok := ex.(ResultExecutor); ok {
+		return resultExecutor.ExecuteWithResult(ctx, code, output)
+	}
+	result := ExecutionResult{FormattedCode: code}
+	err := ex.Execute(ctx, code, output)
+	if err != nil {
+		result.ExitCode = -1
+	}
+	return result, err
+}
+
 // GoExecutor implementa la ejecución de código Go mediante el comando 'go run'.
 //
-// Esta implementación crea un archivo temporal con el código proporcionado,
-// ejecuta 'go run' sobre ese archivo, y captura la salida estándar y de error.
-// Incluye límites para la cantidad de salida generada y utiliza un pool de buffers
+// Esta implementación crea un subdirectorio temporal con el código proporcionado,
+// ejecuta 'go run' sobre ese código, y captura la salida estándar y de error por separado.
+// Incluye límites independientes para la cantidad de salida de cada stream y utiliza un pool de buffers
 // para optimizar el uso de memoria.
 type GoExecutor struct {
 	goExecutablePath string
-	maxOutputLength  int
+	goVersion        string
+	maxStdoutLength  int
+	maxStderrLength  int
+	maxOutputLines   int
+	raceStderrLength int
 	tempDir          string
+	maxTempFiles     int64
+	maxTempBytes     int64
+	keepTempFiles    bool
+	activeTempFiles  atomic.Int64
+	activeTempBytes  atomic.Int64
+	workDirs         sync.Map // directorios de trabajo activos: ruta → *workDirUsage
+	lastTempMeasure  atomic.Int64
+	quotaRejections  atomic.Int64
+	stdoutTruncated  atomic.Int64
+	stderrTruncated  atomic.Int64
+	limits           ProcessLimits
+	cleanup          TempCleanup
+	autoWrapCode     bool
+	env              []string
+	fakeTimeOverlay  string
+	scratchHome      bool
+	logger           logger.Logger
+	// limitsExecutable es el ejecutable del servidor con el que se lanzan los
+	// hijos para aplicarles RLIMIT_NPROC, o "" sin límite de procesos
+	limitsExecutable string
+	readBufferSize   int
 	bufferPool       sync.Pool
 }
 
+// ProcessLimits define los límites de recursos aplicados al proceso hijo.
+//
+// Estos límites son una mitigación frente a patrones como fork-bombs o
+// inundaciones de goroutines (por ejemplo `for { go func(){ for {} }() }`),
+// no una garantía de aislamiento: el código sigue pudiendo consumir CPU hasta
+// el timeout de ejecución. Un valor 0 desactiva el límite correspondiente.
+//
+// RLIMIT_NPROC cuenta todos los procesos e hilos del usuario real, no solo los
+// del proceso hijo, por lo que debe dimensionarse teniendo en cuenta los hilos
+// del propio servidor si ambos se ejecutan con el mismo usuario.
+type ProcessLimits struct {
+	// GOMAXPROCS limita los hilos que ejecutan código Go simultáneamente en el hijo
+	GOMAXPROCS int
+	// MaxProcesses es el valor de RLIMIT_NPROC (procesos/hilos) para el hijo
+	MaxProcesses int
+	// Credential, si no es nil, ejecuta el hijo con ese UID/GID sin privilegios.
+	// Requiere que el servidor arranque como root o con CAP_SETUID/CAP_SETGID.
+	Credential *syscall.Credential
+}
+
+// GoExecutorOptions agrupa la configuración de un GoExecutor.
+//
+// Los campos con valor cero desactivan la funcionalidad correspondiente salvo
+// GoExecutablePath y TempDir, que son obligatorios.
+type GoExecutorOptions struct {
+	// GoExecutablePath es la ruta al ejecutable de Go (ej. "/usr/local/go/bin/go")
+	GoExecutablePath string
+	// MaxStdoutLength es el tamaño máximo en bytes de la salida estándar
+	MaxStdoutLength int
+	// MaxStderrLength es el tamaño máximo en bytes de la salida de error
+	MaxStderrLength int
+	// MaxOutputLines es el número máximo de líneas de stdout y de stderr del
+	// programa (0 = sin límite). Complementa a los límites en bytes: una salida
+	// de muchas líneas cortas cabe en ellos pero satura la consola del cliente.
+	MaxOutputLines int
+	// RaceMaxStderrLength es el tamaño máximo de la salida de error con el
+	// detector de carreras, cuyos informes son extensos (0 = MaxStderrLength)
+	RaceMaxStderrLength int
+	// TempDir es el directorio donde se crean los subdirectorios de trabajo
+	TempDir string
+	// MaxConcurrentTempFiles es el máximo de directorios temporales simultáneos (0 = sin límite)
+	MaxConcurrentTempFiles int
+	// MaxTempBytes es la cuota de bytes escritos en los directorios temporales activos (0 = sin límite)
+	MaxTempBytes int64
+	// KeepTempFiles conserva los directorios de trabajo tras cada ejecución. Solo para depurar:
+	// desactiva también la limpieza periódica, así que los archivos se acumulan en disco.
+	KeepTempFiles bool
+	// Limits son los límites de recursos del proceso hijo (GOMAXPROCS y RLIMIT_NPROC)
+	Limits ProcessLimits
+	// Cleanup es la política de limpieza de temporales huérfanos (ver StartCleanup)
+	Cleanup TempCleanup
+	// AutoWrapCode envuelve en package main/func main el código sin declaración de paquete
+	AutoWrapCode bool
+	// Env es el entorno ("CLAVE=valor") del proceso hijo, al que el ejecutor
+	// añade GOMAXPROCS y la identidad de la solicitud. El hijo nunca hereda el
+	// entorno del servidor: si es nil, solo recibe HOME, PATH y las variables de
+	// Go (GOROOT, GOPATH, GOCACHE, XDG_CACHE_HOME) del servidor.
+	Env []string
+	// ReadBufferSize es el tamaño en bytes de los buffers de lectura de stdout y
+	// stderr (0 = DefaultReadBufferSize). Los programas con mucha salida la
+	// producen en bloques grandes; un buffer pequeño multiplica las lecturas.
+	ReadBufferSize int
+	// FakeTime permite ejecutar código con la hora simulada (ver WithFakeTime).
+	// Si no se puede preparar el overlay de time.Now, se desactiva con un aviso.
+	FakeTime bool
+	// ScratchHome ejecuta cada programa en un subdirectorio scratch de su
+	// directorio de trabajo, que también es su HOME y su TMPDIR, para que pueda
+	// ser el único directorio con escritura (ver runCommand)
+	ScratchHome bool
+}
+
+// DefaultReadBufferSize es el tamaño por defecto de los buffers de lectura de la salida
+const DefaultReadBufferSize = 32 * 1024
+
 // NewGoExecutor crea un nuevo ejecutor de código Go.
 //
 // Parámetros:
-//   - goExecutablePath: Ruta al ejecutable de Go (ej. "/usr/local/go/bin/go").
-//   - maxOutputLength: Tamaño máximo en bytes de la salida permitida.
-//   - tempDir: Directorio temporal donde se crearán los archivos de código.
+//   - opts: Configuración del ejecutor (ver GoExecutorOptions).
+//   - log: Logger para las operaciones en segundo plano del ejecutor.
+//
+// Antes de devolver el ejecutor comprueba que opts.GoExecutablePath es un ejecutable
+// válido ejecutando 'go version' una vez, y registra la versión detectada. Así los
+// errores de configuración aparecen al arrancar y no en la primera solicitud.
 //
-// Retorna un nuevo GoExecutor configurado con los parámetros especificados.
+// Retorna un nuevo GoExecutor configurado con los parámetros especificados, o un
+// error si el ejecutable de Go no existe, no es ejecutable o 'go version' falla.
 //
 // Ejemplo:
 //
-//     executor := executor.NewGoExecutor("/usr/local/go/bin/go", 10000, os.TempDir())
+//     executor, err := executor.NewGoExecutor(executor.GoExecutorOptions{
+//         GoExecutablePath:       "/usr/local/go/bin/go",
+//         MaxStdoutLength:        10000,
+//         MaxStderrLength:        10000,
+//         TempDir:                os.TempDir(),
+//         MaxConcurrentTempFiles: 50,
+//         Limits:                 executor.ProcessLimits{GOMAXPROCS: 1, MaxProcesses: 256},
+//         Cleanup:                executor.TempCleanup{Interval: time.Hour, MaxAge: 20 * time.Second},
+//     }, appLogger)
+//     if err != nil {
+//         log.Fatalf("Ejecutable de Go inválido: %v", err)
+//     }
 //     var output bytes.Buffer
 //     err := executor.Execute(context.Background(), "package main\n\nfunc main() {\n\tfmt.Println(\"Hello\")\n}", &output)
-func NewGoExecutor(goExecutablePath string, maxOutputLength int, tempDir string) *GoExecutor {
-	return &GoExecutor{
-		goExecutablePath: goExecutablePath,
-		maxOutputLength:  maxOutputLength,
-		tempDir:          tempDir,
+func NewGoExecutor(opts GoExecutorOptions, log logger.Logger) (*GoExecutor, error) {
+	goVersion, err := detectGoVersion(opts.GoExecutablePath)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Ejecutable de Go verificado",
+		zap.String("go_path", opts.GoExecutablePath),
+		zap.String("go_version", goVersion),
+	)
+
+	readBufferSize := opts.ReadBufferSize
+	if readBufferSize <= 0 {
+		readBufferSize = DefaultReadBufferSize
+	}
+
+	raceStderrLength := opts.RaceMaxStderrLength
+	if raceStderrLength <= 0 {
+		raceStderrLength = opts.MaxStderrLength
+	}
+
+	env := opts.Env
+	if env == nil {
+		env = defaultChildEnv()
+	}
+	if opts.ScratchHome {
+		if env, err = pinGoEnv(opts.GoExecutablePath, env); err != nil {
+			return nil, err
+		}
+	}
+
+	limitsExecutable, err := processLimitsExecutable(opts.Limits)
+	if err != nil {
+		return nil, err
+	}
+
+	ge := &GoExecutor{
+		goExecutablePath: opts.GoExecutablePath,
+		goVersion:        goVersion,
+		maxStdoutLength:  opts.MaxStdoutLength,
+		maxStderrLength:  opts.MaxStderrLength,
+		maxOutputLines:   opts.MaxOutputLines,
+		raceStderrLength: raceStderrLength,
+		tempDir:          opts.TempDir,
+		maxTempFiles:     int64(opts.MaxConcurrentTempFiles),
+		maxTempBytes:     opts.MaxTempBytes,
+		keepTempFiles:    opts.KeepTempFiles,
+		limits:           opts.Limits,
+		limitsExecutable: limitsExecutable,
+		cleanup:          opts.Cleanup,
+		autoWrapCode:     opts.AutoWrapCode,
+		env:              env,
+		scratchHome:      opts.ScratchHome,
+		logger:           log,
+		readBufferSize:   readBufferSize,
 		bufferPool: sync.Pool{
 			New: func() interface{} {
-				// Crear un buffer de 1KB por defecto
-				buf := make([]byte, 1024)
+				buf := make([]byte, readBufferSize)
 				return &buf
 			},
 		},
 	}
+
+	// Sin la hora simulada el ejecutor funciona igual: solo se rechaza fakeTime
+	if opts.FakeTime {
+		overlay, err := ge.prepareFakeTimeOverlay()
+		if err != nil {
+			log.Warn("No se pudo preparar la simulación de la hora, se desactiva", zap.Error(err))
+		} else {
+			ge.fakeTimeOverlay = overlay
+		}
+	}
+	return ge, nil
+}
+
+// goVersionTimeout es el tiempo máximo para ejecutar 'go version' al crear el ejecutor
+const goVersionTimeout = 10 * time.Second
+
+// detectGoVersion verifica que goExecutablePath es ejecutable y devuelve la versión
+// de Go que reporta (por ejemplo "go1.24.1"), a partir de la salida de 'go version'.
+func detectGoVersion(goExecutablePath string) (string, error) {
+	path, err := exec.LookPath(goExecutablePath)
+	if err != nil {
+		return "", fmt.Errorf("el ejecutable de Go %q no es válido: %w", goExecutablePath, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), goVersionTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, "version").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("error ejecutando %q version: %w: %s", goExecutablePath, err, strings.TrimSpace(string(out)))
+	}
+
+	// Formato esperado: "go version go1.24.1 linux/amd64"
+	fields := strings.Fields(string(out))
+	if len(fields) < 3 || fields[0] != "go" || fields[1] != "version" {
+		return "", fmt.Errorf("salida inesperada de %q version: %s", goExecutablePath, strings.TrimSpace(string(out)))
+	}
+	return fields[2], nil
+}
+
+// GoVersion devuelve la versión de Go detectada al crear el ejecutor
+func (ge *GoExecutor) GoVersion() string {
+	return ge.goVersion
 }
 
 // Execute ejecuta el código Go y escribe la salida en el writer proporcionado.
 //
-// Este método crea un archivo temporal con el código proporcionado, ejecuta 'go run'
-// sobre ese archivo, y escribe la salida en el writer proporcionado. Utiliza el contexto
-// para controlar timeouts y cancelación. Limita la cantidad de salida generada según
-// maxOutputLength y utiliza un pool de buffers para optimizar el uso de memoria.
+// Este método crea un subdirectorio temporal exclusivo con el código proporcionado,
+// ejecuta 'go run' sobre él, y escribe la salida en el writer proporcionado. Utiliza el contexto
+// para controlar timeouts y cancelación. Limita la salida estándar y la de error según
+// maxStdoutLength y maxStderrLength respectivamente, y ambas a maxOutputLines líneas
+// si está configurado, y las concatena (stdout primero) una vez truncadas. Utiliza un pool de buffers para optimizar el uso de memoria.
+//
+// stdout se escribe en output a medida que el programa lo produce, una escritura
+// por cada lectura de la tubería; si output es un http.ResponseWriter, quien lo
+// envuelva debe hacer flush tras cada escritura. stderr se escribe al terminar.
 //
 // Parámetros:
 //   - ctx: Contexto para control de cancelación y timeout.
@@ -102,82 +378,734 @@ func NewGoExecutor(goExecutablePath string, maxOutputLength int, tempDir string)
 //         fmt.Println("Resultado:", output.String())
 //     }
 func (ge *GoExecutor) Execute(ctx context.Context, code string, output io.Writer) error {
-	// Crear archivo temporal para el código
-	tmpFile, err := os.CreateTemp(ge.tempDir, "code-*.go")
+	_, err := ge.ExecuteWithResult(ctx, code, output)
+	return err
+}
+
+// ExecuteWithResult ejecuta el código igual que Execute y además devuelve el
+// código ejecutado, su código de salida y si la salida se truncó.
+//
+// El código de salida es 0 si el programa terminó correctamente, el valor con
+// el que terminó el programa (o 1 si falló la compilación) y -1 si no llegó a
+// terminar por sí mismo (timeout, cancelación o señal).
+func (ge *GoExecutor) ExecuteWithResult(ctx context.Context, code string, output io.Writer) (result ExecutionResult, err error) {
+	ctx, span := startSpan(ctx, "GoExecutor.Execute", code)
+	defer func(start time.Time) {
+		endSpan(span, false, time.Since(start), err)
+	}(time.Now())
+
+	result = ge.PrepareCode(code)
+	result.ExitCode = -1
+
+	// Crear un subdirectorio de trabajo exclusivo para esta ejecución
+	workDir, err := ge.prepareWorkDir(ctx, result.FormattedCode)
 	if err != nil {
-		return fmt.Errorf("error creando archivo temporal: %w", err)
-	}
-	tmpPath := tmpFile.Name()
-	defer func() {
-		tmpFile.Close()
-		// Intentar eliminar el archivo temporal
-		for i := 0; i < 3; i++ {
-			if err := os.Remove(tmpPath); err == nil || os.IsNotExist(err) {
-				break
-			}
-			time.Sleep(100 * time.Millisecond)
+		return result, err
+	}
+	defer ge.removeWorkDir(workDir)
+
+	// Configurar y ejecutar el comando. stdout se escribe en output a medida que
+	// se lee; stderr se acumula para escribirlo al final, ya procesado
+	stdout := &streamCapture{name: "stdout", limit: ge.maxStdoutLength, maxLines: ge.maxOutputLines, live: output}
+	stderr := &streamCapture{name: "stderr", limit: ge.maxStderrLength, maxLines: ge.maxOutputLines}
+	cmd, err := ge.runCommand(ctx, workDir)
+	if err != nil {
+		return result, err
+	}
+	waitErr, err := ge.runInto(cmd, stdout, stderr)
+	if err != nil {
+		return result, err
+	}
+	if cmd.ProcessState != nil {
+		result.CPUTime = cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime()
+	}
+
+	// Los errores de compilación y los panics citan el archivo temporal
+	stderr.data = []byte(ge.MapErrors(string(stderr.data), code))
+
+	// Completar la salida ya truncada: primero stdout y después stderr
+	stdout.writeTo(output)
+	stderr.writeTo(output)
+	ge.recordTruncation(&result, stdout, stderr)
+
+	if waitErr != nil {
+		// Distinguir el timeout o la cancelación del fallo del propio programa
+		if ctx.Err() != nil {
+			return result, fmt.Errorf("error en la ejecución: %w", ctx.Err())
 		}
-	}()
-	
-	if _, err := tmpFile.WriteString(code); err != nil {
-		return fmt.Errorf("error escribiendo código: %w", err)
+		result.ExitCode = exitCode(waitErr, stderr)
+		return result, fmt.Errorf("error en la ejecución: %w", waitErr)
 	}
-	tmpFile.Close()
 
-	// Configurar y ejecutar el comando
-	cmd := exec.CommandContext(ctx, ge.goExecutablePath, "run", tmpPath)
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		Setpgid: true,
+	result.ExitCode = 0
+	return result, nil
+}
+
+// goRunExitStatus reconoce la última línea que 'go run' escribe en stderr cuando
+// el programa termina con un código distinto de cero
+var goRunExitStatus = regexp.MustCompile(`exit status (\d+)\n?$`)
+
+// exitCode obtiene el código de salida del programa a partir del error de Wait.
+// 'go run' siempre termina con 1 si el programa falla, por lo que el código real
+// se lee de su aviso "exit status N" cuando no se ha truncado stderr.
+func exitCode(waitErr error, stderr *streamCapture) int {
+	var exitErr *exec.ExitError
+	if !errors.As(waitErr, &exitErr) {
+		return -1
+	}
+	if m := goRunExitStatus.FindSubmatch(stderr.data); m != nil && !stderr.truncated {
+		if code, err := strconv.Atoi(string(m[1])); err == nil {
+			return code
+		}
+	}
+	return exitErr.ExitCode()
+}
+
+// CompileError representa un fallo de compilación del código enviado.
+// Diagnostics contiene la salida del compilador (ya truncada según los límites).
+type CompileError struct {
+	Diagnostics string
+	Err         error
+}
+
+// Error implementa la interfaz error
+func (e *CompileError) Error() string {
+	return fmt.Sprintf("error de compilación: %v", e.Err)
+}
+
+// Unwrap devuelve el error original del comando
+func (e *CompileError) Unwrap() error {
+	return e.Err
+}
+
+// Compiler define el comportamiento de los ejecutores capaces de compilar
+// código sin ejecutarlo.
+type Compiler interface {
+	Compile(ctx context.Context, code string, target BuildTarget) error
+}
+
+// Compile comprueba que el código compila ejecutando 'go build -o /dev/null',
+// sin llegar a ejecutarlo.
+//
+// target selecciona GOOS/GOARCH para comprobar la compilación cruzada; su valor
+// cero compila para la plataforma del servidor. Solo se admiten los destinos de
+// SupportedTargets: cualquier otro devuelve un error que envuelve ErrUnsupportedTarget.
+//
+// Retorna nil si el código compila, un *CompileError con los diagnósticos del
+// compilador si no compila, u otro error si hubo un problema de infraestructura
+// (por ejemplo, el contexto expiró).
+//
+// Ejemplo:
+//
+//     err := goExecutor.Compile(ctx, code, executor.BuildTarget{GOOS: "linux", GOARCH: "arm64"})
+//     var compileErr *executor.CompileError
+//     if errors.As(err, &compileErr) {
+//         fmt.Println(compileErr.Diagnostics)
+//     }
+func (ge *GoExecutor) Compile(ctx context.Context, code string, target BuildTarget) error {
+	if err := ValidateTarget(target); err != nil {
+		return err
 	}
+	_, err := ge.build(ctx, code, target)
+	return err
+}
+
+// Disassembler define el comportamiento de los ejecutores capaces de devolver
+// el ensamblador generado para el código.
+type Disassembler interface {
+	Assembly(ctx context.Context, code string) (string, error)
+}
+
+// Assembly compila el código con 'go build -gcflags=-S' y devuelve el ensamblador
+// generado por el compilador, sin ejecutar el programa.
+//
+// Usa -trimpath para que las líneas no incluyan la ruta del directorio temporal.
+// La salida respeta los mismos límites de tamaño que la ejecución, ya que el
+// ensamblador puede ser muy extenso. Si el código no compila retorna un
+// *CompileError con los diagnósticos.
+func (ge *GoExecutor) Assembly(ctx context.Context, code string) (string, error) {
+	return ge.build(ctx, code, BuildTarget{}, "-trimpath", "-gcflags=-S")
+}
+
+// build ejecuta 'go build -o /dev/null' para target con los argumentos adicionales
+// indicados y devuelve la salida del compilador (truncada según los límites).
+func (ge *GoExecutor) build(ctx context.Context, code string, target BuildTarget, buildArgs ...string) (string, error) {
+	prepared := ge.PrepareCode(code).FormattedCode
+	workDir, err := ge.prepareWorkDir(ctx, prepared)
+	if err != nil {
+		return "", err
+	}
+	defer ge.removeWorkDir(workDir)
+
+	args := append([]string{"build", "-o", os.DevNull}, buildArgs...)
+	args = append(args, ".")
+	cmd := ge.command(ctx, workDir, args...)
+	if targetEnv := target.env(); targetEnv != nil {
+		cmd.Env = append(cmd.Env, targetEnv...)
+	}
+	stdout, stderr, waitErr, err := ge.runCaptured(cmd)
+	if err != nil {
+		return "", err
+	}
+
+	var output bytes.Buffer
+	stdout.writeTo(&output)
+	stderr.writeTo(&output)
+
+	if waitErr != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("error en la compilación: %w", ctx.Err())
+		}
+		return "", &CompileError{Diagnostics: ge.MapErrors(output.String(), code), Err: waitErr}
+	}
+
+	return output.String(), nil
+}
+
+// runCaptured inicia cmd, captura stdout y stderr por separado (cada uno con su
+// propio límite) y espera a que termine.
+//
+// Devuelve el error de cmd.Wait (waitErr), que indica que el programa terminó con
+// error, separado de err, que indica un fallo al lanzar el proceso o leer su salida.
+func (ge *GoExecutor) runCaptured(cmd *exec.Cmd) (stdout, stderr *streamCapture, waitErr, err error) {
+	stdout = &streamCapture{name: "stdout", limit: ge.maxStdoutLength}
+	stderr = &streamCapture{name: "stderr", limit: ge.maxStderrLength}
+	waitErr, err = ge.runInto(cmd, stdout, stderr)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return stdout, stderr, waitErr, nil
+}
+
+// runInto inicia cmd, lee stdout y stderr en las capturas indicadas (que pueden
+// escribir en vivo, ver streamCapture.live) y espera a que termine
+func (ge *GoExecutor) runInto(cmd *exec.Cmd, stdout, stderr *streamCapture) (waitErr, err error) {
 	stdoutPipe, err := cmd.StdoutPipe()
 	if err != nil {
-		return fmt.Errorf("error obteniendo salida del comando: %w", err)
+		return nil, fmt.Errorf("error obteniendo salida del comando: %w", err)
+	}
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo salida de error del comando: %w", err)
 	}
-	// Combinar stderr con stdout
-	cmd.Stderr = cmd.Stdout
 
 	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("error iniciando el comando: %w", err)
+		return nil, fmt.Errorf("error iniciando el comando: %w", err)
 	}
+	// Eliminar cualquier proceso del grupo que siga vivo al terminar la ejecución
+	defer killProcessGroup(cmd)
+
+	var wg sync.WaitGroup
+	var stdoutErr, stderrErr error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		stdoutErr = ge.capture(stdoutPipe, stdout)
+	}()
+	go func() {
+		defer wg.Done()
+		stderrErr = ge.capture(stderrPipe, stderr)
+	}()
+	wg.Wait()
 
-	totalBytes := 0
-	
+	if stdoutErr != nil {
+		cmd.Wait()
+		return nil, fmt.Errorf("error leyendo salida: %w", stdoutErr)
+	}
+	if stderrErr != nil {
+		cmd.Wait()
+		return nil, fmt.Errorf("error leyendo salida de error: %w", stderrErr)
+	}
+
+	// Esperar a que el comando finalice
+	return cmd.Wait(), nil
+}
+
+// mainFileName es el nombre del archivo de código dentro del directorio de trabajo
+const mainFileName = "main.go"
+
+// workModulePath es la ruta del módulo del go.mod de cada directorio de trabajo
+const workModulePath = "playground/run"
+
+// defaultWorkGoVersion es la versión del go.mod de los directorios de trabajo
+// si no se puede deducir de la versión del ejecutable de Go
+const defaultWorkGoVersion = "1.21"
+
+// workGoMod devuelve el go.mod de los directorios de trabajo. Declara la
+// versión del lenguaje del ejecutable de Go (1.24 para go1.24.1), la misma que
+// se usaba al ejecutar main.go fuera de un módulo: con una anterior cambiaría
+// la semántica del código (variables de bucle por iteración, range sobre
+// enteros...).
+func workGoMod(goVersion string) string {
+	lang := strings.TrimPrefix(version.Lang(goVersion), "go")
+	if lang == "" {
+		lang = defaultWorkGoVersion
+	}
+	return "module " + workModulePath + "\n\ngo " + lang + "\n"
+}
+
+// ErrTooManyTempFiles indica que se alcanzó el máximo de archivos temporales simultáneos
+var ErrTooManyTempFiles = errors.New("demasiadas ejecuciones simultáneas, inténtelo de nuevo en unos segundos")
+
+// ErrTempQuotaExceeded indica que se agotó la cuota de disco de los archivos temporales
+var ErrTempQuotaExceeded = errors.New("cuota de disco temporal agotada, inténtelo de nuevo en unos segundos")
+
+// TempUsage describe el uso actual de los directorios temporales de trabajo
+type TempUsage struct {
+	// ActiveFiles es el número de directorios de trabajo existentes
+	ActiveFiles int64
+	// ActiveBytes es el tamaño en disco de esos directorios: el código, el
+	// go.mod, lo que compila Go y lo que escribe el programa, medido como mucho
+	// cada tempUsageMeasureInterval
+	ActiveBytes int64
+	// QuotaRejections cuenta las ejecuciones rechazadas por superar la cuota
+	QuotaRejections int64
+}
+
+// OutputStats cuenta las ejecuciones cuya salida se recortó, por stream
+type OutputStats struct {
+	// StdoutTruncations es el número de ejecuciones con stdout truncado
+	StdoutTruncations int64
+	// StderrTruncations es el número de ejecuciones con stderr truncado
+	StderrTruncations int64
+}
+
+// OutputStats devuelve cuántas ejecuciones han superado los límites de salida,
+// por ejemplo para metrics.RegisterOutputTruncation
+func (ge *GoExecutor) OutputStats() OutputStats {
+	return OutputStats{
+		StdoutTruncations: ge.stdoutTruncated.Load(),
+		StderrTruncations: ge.stderrTruncated.Load(),
+	}
+}
+
+// recordTruncation marca en result los streams truncados y los contabiliza en OutputStats
+func (ge *GoExecutor) recordTruncation(result *ExecutionResult, stdout, stderr *streamCapture) {
+	if stdout.truncated {
+		ge.stdoutTruncated.Add(1)
+		result.TruncatedStreams = append(result.TruncatedStreams, stdout.name)
+	}
+	if stderr.truncated {
+		ge.stderrTruncated.Add(1)
+		result.TruncatedStreams = append(result.TruncatedStreams, stderr.name)
+	}
+	result.Truncated = len(result.TruncatedStreams) > 0
+}
+
+// TempUsage devuelve el uso actual de los directorios temporales.
+// Los directorios huérfanos que elimina StartCleanup no se contabilizan.
+func (ge *GoExecutor) TempUsage() TempUsage {
+	ge.measureWorkDirs()
+	return TempUsage{
+		ActiveFiles:     ge.activeTempFiles.Load(),
+		ActiveBytes:     ge.activeTempBytes.Load(),
+		QuotaRejections: ge.quotaRejections.Load(),
+	}
+}
+
+// reserveTempSpace reserva un directorio y size bytes en la contabilidad de
+// temporales, tras actualizar el tamaño medido de los directorios activos. Si
+// se supera maxTempFiles o maxTempBytes deshace la reserva, lo registra y
+// retorna ErrTooManyTempFiles o ErrTempQuotaExceeded.
+func (ge *GoExecutor) reserveTempSpace(size int64) error {
+	ge.measureWorkDirs()
+	files := ge.activeTempFiles.Add(1)
+	bytes := ge.activeTempBytes.Add(size)
+
+	var err error
+	switch {
+	case ge.maxTempFiles > 0 && files > ge.maxTempFiles:
+		err = ErrTooManyTempFiles
+	case ge.maxTempBytes > 0 && bytes > ge.maxTempBytes:
+		err = ErrTempQuotaExceeded
+	default:
+		return nil
+	}
+
+	ge.releaseTempSpace(size)
+	ge.quotaRejections.Add(1)
+	ge.logger.Warn("Cuota de archivos temporales alcanzada",
+		zap.Int64("active_files", files-1),
+		zap.Int64("max_files", ge.maxTempFiles),
+		zap.Int64("active_bytes", bytes-size),
+		zap.Int64("requested_bytes", size),
+		zap.Int64("max_bytes", ge.maxTempBytes),
+	)
+	return err
+}
+
+// releaseTempSpace libera una reserva hecha con reserveTempSpace
+func (ge *GoExecutor) releaseTempSpace(size int64) {
+	ge.activeTempFiles.Add(-1)
+	ge.activeTempBytes.Add(-size)
+}
+
+// buildTmpDirName es el subdirectorio del directorio de trabajo que se usa
+// como GOTMPDIR, para que los archivos intermedios del compilador y el binario
+// de 'go run' o 'go test' queden dentro del directorio y cuenten en la cuota
+const buildTmpDirName = "gotmp"
+
+// tempUsageMeasureInterval es cada cuánto se vuelve a medir, como mucho, el
+// tamaño de los directorios de trabajo activos
+const tempUsageMeasureInterval = 100 * time.Millisecond
+
+// workDirUsage son los bytes que un directorio de trabajo tiene cargados en
+// activeTempBytes
+type workDirUsage struct {
+	mu    sync.Mutex
+	bytes int64
+	// peak es el mayor tamaño medido
+	peak int64
+	// released indica que el directorio ya liberó su reserva
+	released bool
+}
+
+// measureWorkDirs actualiza activeTempBytes con el tamaño en disco de cada
+// directorio de trabajo activo, salvo si la última medición (lastTempMeasure,
+// en UnixNano) tiene menos de tempUsageMeasureInterval. Recorrer los directorios es barato: contienen unos
+// pocos archivos, y su número lo acota maxTempFiles.
+func (ge *GoExecutor) measureWorkDirs() {
+	now := time.Now().UnixNano()
+	last := ge.lastTempMeasure.Load()
+	if now-last < int64(tempUsageMeasureInterval) || !ge.lastTempMeasure.CompareAndSwap(last, now) {
+		return
+	}
+	ge.workDirs.Range(func(key, value interface{}) bool {
+		ge.measureWorkDir(key.(string), value.(*workDirUsage))
+		return true
+	})
+}
+
+// measureWorkDir mide workDir y ajusta su carga en activeTempBytes
+func (ge *GoExecutor) measureWorkDir(workDir string, usage *workDirUsage) {
+	size := dirSize(workDir)
+
+	usage.mu.Lock()
+	defer usage.mu.Unlock()
+	if usage.released {
+		return
+	}
+	ge.activeTempBytes.Add(size - usage.bytes)
+	usage.bytes = size
+	usage.peak = max(usage.peak, size)
+}
+
+// dirSize devuelve la suma del tamaño de los archivos bajo dir. Los archivos
+// que desaparecen durante el recorrido se ignoran.
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// prepareWorkDir crea un subdirectorio exclusivo bajo tempDir con el código en
+// main.go y un go.mod mínimo (ver workGoMod).
+//
+// Usar un directorio por ejecución (os.MkdirTemp) evita colisiones entre ejecuciones
+// concurrentes y permite limpiar todo lo generado de una sola vez con removeWorkDir.
+// Con su propio go.mod el directorio es un módulo y los comandos de Go trabajan
+// sobre el paquete ("go run .") y no sobre un archivo suelto, así que se
+// comportan igual sea cual sea tempDir: no les afecta un go.mod o un GOPATH en
+// un directorio superior, y //go:embed puede incluir archivos del directorio.
+// Retorna la ruta del directorio.
+//
+// El directorio incluye el GOTMPDIR de los comandos de Go (buildTmpDirName) y
+// cuenta en la cuota de bytes con su tamaño real, que se vuelve a medir
+// mientras existe (ver measureWorkDirs). Si se alcanzó el máximo de
+// directorios activos o la cuota de bytes, retorna ErrTooManyTempFiles o
+// ErrTempQuotaExceeded sin crear nada en disco.
+func (ge *GoExecutor) prepareWorkDir(ctx context.Context, code string) (string, error) {
+	return ge.prepareWorkDirFile(ctx, mainFileName, code)
+}
+
+// prepareWorkDirFile es como prepareWorkDir, pero guarda el código en fileName
+// en lugar de main.go
+func (ge *GoExecutor) prepareWorkDirFile(ctx context.Context, fileName, code string) (string, error) {
+	// Reservar el hueco antes de crear el directorio para acotar el uso de disco
+	goMod := workGoMod(ge.goVersion)
+	size := int64(len(code) + len(goMod))
+	if err := ge.reserveTempSpace(size); err != nil {
+		return "", err
+	}
+
+	workDir, err := os.MkdirTemp(ge.tempDir, TempDirName(ctx))
+	if err != nil {
+		ge.releaseTempSpace(size)
+		return "", fmt.Errorf("error creando directorio temporal: %w", err)
+	}
+	// Desde aquí removeWorkDir libera la reserva
+	ge.workDirs.Store(workDir, &workDirUsage{bytes: size, peak: size})
+
+	mainPath := filepath.Join(workDir, fileName)
+	if err := os.WriteFile(mainPath, []byte(code), 0600); err != nil {
+		ge.removeWorkDir(workDir)
+		return "", fmt.Errorf("error escribiendo código: %w", err)
+	}
+	goModPath := filepath.Join(workDir, "go.mod")
+	if err := os.WriteFile(goModPath, []byte(goMod), 0600); err != nil {
+		ge.removeWorkDir(workDir)
+		return "", fmt.Errorf("error escribiendo go.mod: %w", err)
+	}
+	buildTmp := filepath.Join(workDir, buildTmpDirName)
+	if err := os.Mkdir(buildTmp, 0700); err != nil {
+		ge.removeWorkDir(workDir)
+		return "", fmt.Errorf("error creando el directorio de compilación: %w", err)
+	}
+
+	// MkdirTemp y WriteFile crean directorio y archivo solo accesibles por el
+	// servidor; si el hijo se ejecuta con otro usuario necesita poder leer el
+	// código y escribir en el directorio de compilación
+	if cred := ge.limits.Credential; cred != nil {
+		if err := os.Chmod(workDir, 0755); err != nil {
+			ge.removeWorkDir(workDir)
+			return "", fmt.Errorf("error ajustando permisos del directorio temporal: %w", err)
+		}
+		for _, path := range []string{mainPath, goModPath} {
+			if err := os.Chmod(path, 0644); err != nil {
+				ge.removeWorkDir(workDir)
+				return "", fmt.Errorf("error ajustando permisos del archivo temporal: %w", err)
+			}
+		}
+		if err := os.Chown(buildTmp, int(cred.Uid), int(cred.Gid)); err != nil {
+			ge.removeWorkDir(workDir)
+			return "", fmt.Errorf("error ajustando el propietario del directorio de compilación: %w", err)
+		}
+	}
+
+	return workDir, nil
+}
+
+// removeWorkDir elimina el directorio de trabajo completo, reintentando si falla,
+// y libera su carga en la contabilidad de temporales activos. Con
+// KeepTempFiles solo registra la ruta conservada.
+func (ge *GoExecutor) removeWorkDir(workDir string) {
+	defer ge.releaseWorkDir(workDir)
+
+	if ge.keepTempFiles {
+		ge.logger.Info("Directorio temporal conservado para depuración",
+			zap.String("dir", workDir),
+			zap.String("request_id", requestIDFromTempName(workDir)),
+		)
+		return
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := os.RemoveAll(workDir); err == nil {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	ge.logger.Warn("No se pudo eliminar el directorio temporal", zap.String("dir", workDir))
+}
+
+// releaseWorkDir mide por última vez workDir, antes de eliminarlo, y libera
+// lo que tenga cargado en la contabilidad de temporales activos
+func (ge *GoExecutor) releaseWorkDir(workDir string) {
+	value, ok := ge.workDirs.LoadAndDelete(workDir)
+	if !ok {
+		return
+	}
+	usage := value.(*workDirUsage)
+
+	usage.mu.Lock()
+	usage.released = true
+	bytes, peak := usage.bytes, usage.peak
+	usage.mu.Unlock()
+
+	ge.releaseTempSpace(bytes)
+	ge.logger.Debug("Directorio temporal liberado",
+		zap.String("dir", workDir),
+		zap.Int64("peak_bytes", peak))
+}
+
+// killProcessGroup envía SIGKILL a todo el grupo de procesos del comando.
+// Ignora el error ESRCH, que indica que el grupo ya no existe.
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+		return err
+	}
+	return nil
+}
+
+// command construye el comando de Go que se ejecuta en workDir, con el grupo de
+// procesos propio, el GOMAXPROCS configurado y RLIMIT_NPROC, que aplica
+// withProcessLimits desde el arranque del hijo.
+//
+// Ejecutar desde workDir con rutas relativas hace que los diagnósticos del
+// compilador muestren ./main.go en lugar de la ruta del directorio temporal.
+func (ge *GoExecutor) command(ctx context.Context, workDir string, args ...string) *exec.Cmd {
+	// Con hora simulada se compila con la versión parcheada de time.Now
+	fakeTime, hasFakeTime := FakeTime(ctx)
+	if hasFakeTime && ge.fakeTimeOverlay != "" && len(args) > 0 {
+		args = append([]string{args[0], "-overlay=" + ge.fakeTimeOverlay}, args[1:]...)
+	}
+	cmd := exec.CommandContext(ctx, ge.goExecutablePath, args...)
+	cmd.Dir = workDir
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid:    true,
+		Credential: ge.limits.Credential,
+	}
+
+	// Al expirar el contexto, matar todo el grupo de procesos (PID negativo) y no
+	// solo el hijo directo, para que los procesos creados por el código no escapen
+	// del timeout. WaitDelay evita bloquear Wait si alguno mantiene las tuberías.
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+	cmd.WaitDelay = time.Second
+
+	// El hijo nunca hereda el entorno del servidor, que puede contener secretos:
+	// cmd.Env siempre se fija (aunque quede vacío) a partir de ge.env. Un
+	// go.work en un directorio superior excluiría el módulo de workDir.
+	env := append(slices.Clip(ge.env), "GOWORK=off", "GOTMPDIR="+filepath.Join(workDir, buildTmpDirName))
+	if ge.limits.GOMAXPROCS > 0 {
+		env = append(env, fmt.Sprintf("GOMAXPROCS=%d", ge.limits.GOMAXPROCS))
+	}
+	// Propagar la identidad de la solicitud para correlacionar lo que registre el hijo
+	if requestID := requestctx.RequestID(ctx); requestID != "" {
+		env = append(env, "PLAYGROUND_REQUEST_ID="+requestID)
+	}
+	if clientID := requestctx.ClientID(ctx); clientID != "" {
+		env = append(env, "PLAYGROUND_CLIENT_ID="+clientID)
+	}
+	if hasFakeTime {
+		env = append(env, fakeTimeEnvVar+"="+fakeTime.UTC().Format(time.RFC3339Nano))
+	}
+	cmd.Env = env
+
+	withProcessLimits(cmd, ge.limitsExecutable, ge.limits)
+	return cmd
+}
+
+// defaultChildEnvVars son las variables del servidor que recibe el hijo si
+// GoExecutorOptions.Env es nil: las imprescindibles para que 'go' encuentre
+// sus herramientas y su caché de compilación
+var defaultChildEnvVars = []string{"HOME", "PATH", "GOROOT", "GOPATH", "GOCACHE", "XDG_CACHE_HOME"}
+
+// defaultChildEnv construye el entorno del hijo a partir de defaultChildEnvVars
+func defaultChildEnv() []string {
+	env := []string{}
+	for _, key := range defaultChildEnvVars {
+		if value := os.Getenv(key); value != "" {
+			env = append(env, key+"="+value)
+		}
+	}
+	return env
+}
+
+// streamCapture acumula la salida de un stream hasta su límite en bytes y,
+// si maxLines es mayor que 0, hasta maxLines líneas. Si live no es nil, cada
+// fragmento leído se escribe además en live en cuanto llega, para que el
+// cliente vea la salida de los programas lentos sin esperar a que terminen.
+type streamCapture struct {
+	name      string
+	limit     int
+	maxLines  int
+	lines     int
+	data      []byte
+	truncated bool
+	// linesTruncated indica que el corte se debió a maxLines y no a limit
+	linesTruncated bool
+	live           io.Writer
+	// unbuffered indica que la salida no se guarda en data ni se limita: cada
+	// fragmento se pasa tal cual a live, que debe acotar lo que conserva
+	unbuffered bool
+}
+
+// writeTo escribe la salida capturada y, si se truncó, un aviso indicando el
+// stream. Con live solo escribe el aviso, ya que la salida se envió al leerla.
+func (sc *streamCapture) writeTo(output io.Writer) {
+	if sc.live == nil {
+		output.Write(sc.data)
+	}
+	switch {
+	case sc.linesTruncated:
+		fmt.Fprintf(output, "\n... (%s truncated after %d lines)", sc.name, sc.maxLines)
+	case sc.truncated:
+		fmt.Fprintf(output, "\n... (%s truncated after %d bytes)", sc.name, sc.limit)
+	}
+}
+
+// cutLines recorta chunk tras la línea número maxLines y marca la captura
+// como truncada si queda algo después. Las líneas se cuentan entre llamadas.
+func (sc *streamCapture) cutLines(chunk []byte) []byte {
+	if sc.maxLines <= 0 || len(chunk) == 0 {
+		return chunk
+	}
+	if sc.lines >= sc.maxLines {
+		sc.truncated = true
+		sc.linesTruncated = true
+		return nil
+	}
+	for i, b := range chunk {
+		if b != '\n' {
+			continue
+		}
+		sc.lines++
+		if sc.lines == sc.maxLines {
+			if i+1 < len(chunk) {
+				sc.truncated = true
+				sc.linesTruncated = true
+			}
+			return chunk[:i+1]
+		}
+	}
+	return chunk
+}
+
+// capture lee r hasta EOF guardando como máximo sc.limit bytes y sc.maxLines líneas.
+// Una vez alcanzado un límite sigue leyendo y descartando para que el proceso
+// hijo no se bloquee escribiendo en una tubería llena.
+func (ge *GoExecutor) capture(r io.Reader, sc *streamCapture) error {
 	// Obtener un buffer del pool
 	bufPtr := ge.bufferPool.Get().(*[]byte)
 	buf := *bufPtr
-	
+	if len(buf) != ge.readBufferSize {
+		// Nunca debería ocurrir, pero un buffer de otro tamaño no vuelve al pool
+		buf = make([]byte, ge.readBufferSize)
+		bufPtr = &buf
+	}
+
 	// Asegurar que el buffer se devuelva al pool
 	defer ge.bufferPool.Put(bufPtr)
-	
+
 	for {
-		n, err := stdoutPipe.Read(buf)
-		if n > 0 {
-			// Limitar la cantidad total de bytes enviados
-			if totalBytes+n > ge.maxOutputLength {
-				allowed := ge.maxOutputLength - totalBytes
-				if allowed > 0 {
-					output.Write(buf[:allowed])
-					totalBytes += allowed
-				}
-				fmt.Fprint(output, "\n... (output truncated)")
-				break
-			} else {
-				output.Write(buf[:n])
-				totalBytes += n
+		n, err := r.Read(buf)
+		if n > 0 && sc.unbuffered {
+			sc.live.Write(buf[:n])
+		} else if n > 0 {
+			start := len(sc.data)
+			chunk := buf[:n]
+			if sc.truncated {
+				// Tras un corte por líneas se descarta también lo que aún cabría en bytes
+				chunk = nil
+			} else if remaining := sc.limit - len(sc.data); n > remaining {
+				chunk = chunk[:max(remaining, 0)]
+				sc.truncated = true
+			}
+			sc.data = append(sc.data, sc.cutLines(chunk)...)
+			// Un error al escribir (cliente desconectado) no interrumpe la
+			// lectura, para que el proceso hijo no se bloquee
+			if sc.live != nil && len(sc.data) > start {
+				sc.live.Write(sc.data[start:])
 			}
 		}
 		if err != nil {
 			if err != io.EOF {
-				return fmt.Errorf("error leyendo salida: %w", err)
+				return err
 			}
-			break
+			return nil
 		}
 	}
-
-	// Esperar a que el comando finalice
-	if err := cmd.Wait(); err != nil {
-		return fmt.Errorf("error en la ejecución: %w", err)
-	}
-	
-	return nil
 }
@@ -0,0 +1,1047 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apperrors "github.com/luis198755/go_playGround_plus/docker/pkg/errors"
+	logtest "github.com/luis198755/go_playGround_plus/docker/pkg/logger/test"
+)
+
+// fakeExecutor cuenta sus ejecuciones y, si release no es nil, no termina
+// hasta que se cierra release o se cancela su contexto. Cada ejecución envía
+// su error a finished, si no es nil.
+type fakeExecutor struct {
+	calls    atomic.Int32
+	release  chan struct{}
+	finished chan error
+	output   string
+}
+
+func (f *fakeExecutor) Execute(ctx context.Context, code string, output io.Writer) error {
+	f.calls.Add(1)
+	err := f.wait(ctx)
+	if err == nil {
+		_, err = io.WriteString(output, f.output)
+	}
+	if f.finished != nil {
+		f.finished <- err
+	}
+	return err
+}
+
+func (f *fakeExecutor) wait(ctx context.Context) error {
+	if f.release == nil {
+		return nil
+	}
+	select {
+	case <-f.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitFor reintenta cond hasta que se cumple o pasa un segundo
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout esperando %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// flightWaiters devuelve cuántas solicitudes esperan la ejecución compartida de code
+func flightWaiters(ce *CachedExecutor, code string) int {
+	ce.flightsMu.Lock()
+	defer ce.flightsMu.Unlock()
+	if f, ok := ce.flights[ce.CacheKey(context.Background(), code)]; ok {
+		return f.waiters
+	}
+	return 0
+}
+
+func TestCachedExecutorLeaderCancelDoesNotAbortWaiters(t *testing.T) {
+	fake := &fakeExecutor{release: make(chan struct{}), output: "hola\n"}
+	ce := NewCachedExecutor(fake, 10, 0, time.Minute)
+	defer ce.Stop()
+	const code = "package main"
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		leaderErr <- ce.Execute(leaderCtx, code, io.Discard)
+	}()
+	waitFor(t, "la ejecución del líder", func() bool { return fake.calls.Load() == 1 })
+
+	var waiterOutput bytes.Buffer
+	waiterErr := make(chan error, 1)
+	go func() {
+		waiterErr <- ce.Execute(context.Background(), code, &waiterOutput)
+	}()
+	waitFor(t, "la segunda solicitud", func() bool { return flightWaiters(ce, code) == 2 })
+
+	cancelLeader()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("error del líder = %v, se esperaba context.Canceled", err)
+	}
+
+	close(fake.release)
+	if err := <-waiterErr; err != nil {
+		t.Fatalf("la solicitud que esperaba falló al cancelarse el líder: %v", err)
+	}
+	if got := waiterOutput.String(); got != "hola\n" {
+		t.Errorf("salida = %q, se esperaba %q", got, "hola\n")
+	}
+	if calls := fake.calls.Load(); calls != 1 {
+		t.Errorf("el código se ejecutó %d veces, se esperaba 1", calls)
+	}
+	if !ce.IsCached(code) {
+		t.Error("el resultado de la ejecución compartida no se almacenó")
+	}
+}
+
+func TestCachedExecutorCancelsSharedRunWhenAllWaitersLeave(t *testing.T) {
+	fake := &fakeExecutor{release: make(chan struct{}), finished: make(chan error, 1)}
+	ce := NewCachedExecutor(fake, 10, 0, time.Minute)
+	defer ce.Stop()
+	const code = "package main"
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() { errs <- ce.Execute(ctx1, code, io.Discard) }()
+	waitFor(t, "la ejecución compartida", func() bool { return fake.calls.Load() == 1 })
+	go func() { errs <- ce.Execute(ctx2, code, io.Discard) }()
+	waitFor(t, "la segunda solicitud", func() bool { return flightWaiters(ce, code) == 2 })
+
+	cancel1()
+	<-errs
+	select {
+	case err := <-fake.finished:
+		t.Fatalf("la ejecución compartida terminó (%v) con una solicitud esperando", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancel2()
+	<-errs
+	select {
+	case err := <-fake.finished:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("error de la ejecución compartida = %v, se esperaba context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("la ejecución compartida no se canceló al irse todas las solicitudes")
+	}
+	if flightWaiters(ce, code) != 0 {
+		t.Error("la ejecución cancelada sigue registrada")
+	}
+}
+
+func TestCachedExecutorSharedRunUsesExecutionTimeout(t *testing.T) {
+	fake := &fakeExecutor{release: make(chan struct{})}
+	ce := NewCachedExecutor(fake, 10, 0, time.Minute, WithExecutionTimeout(20*time.Millisecond))
+	defer ce.Stop()
+
+	// Sin plazo en la solicitud, la ejecución compartida termina por executionTimeout
+	err := ce.Execute(context.Background(), "package main", io.Discard)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v, se esperaba context.DeadlineExceeded", err)
+	}
+	if ce.IsCached("package main") {
+		t.Error("se almacenó una ejecución cortada por timeout")
+	}
+}
+
+func TestCachedExecutorDeduplicatesConcurrentRequests(t *testing.T) {
+	fake := &fakeExecutor{release: make(chan struct{}), output: "hola\n"}
+	ce := NewCachedExecutor(fake, 10, 0, time.Minute)
+	defer ce.Stop()
+	const code = "package main"
+	const requests = 10
+
+	outputs := make([]bytes.Buffer, requests)
+	errs := make(chan error, requests)
+	for i := range requests {
+		go func() { errs <- ce.Execute(context.Background(), code, &outputs[i]) }()
+	}
+	waitFor(t, "las solicitudes", func() bool { return flightWaiters(ce, code) == requests })
+	close(fake.release)
+
+	for range requests {
+		if err := <-errs; err != nil {
+			t.Fatalf("error en una solicitud: %v", err)
+		}
+	}
+	if calls := fake.calls.Load(); calls != 1 {
+		t.Errorf("el código se ejecutó %d veces con %d solicitudes idénticas, se esperaba 1", calls, requests)
+	}
+	for i := range outputs {
+		if got := outputs[i].String(); got != "hola\n" {
+			t.Errorf("salida de la solicitud %d = %q, se esperaba %q", i, got, "hola\n")
+		}
+	}
+}
+
+// newTestGoExecutor crea un GoExecutor con el toolchain de Go del PATH y un
+// directorio temporal propio, o salta el test si no hay toolchain. Los campos
+// de opts que no se indican toman valores adecuados para los tests.
+func newTestGoExecutor(t testing.TB, opts GoExecutorOptions) *GoExecutor {
+	t.Helper()
+	goPath, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("no se encontró el ejecutable de Go")
+	}
+	opts.GoExecutablePath = goPath
+	if opts.TempDir == "" {
+		opts.TempDir = t.TempDir()
+	}
+	if opts.MaxStdoutLength == 0 {
+		opts.MaxStdoutLength = 10000
+	}
+	if opts.MaxStderrLength == 0 {
+		opts.MaxStderrLength = 10000
+	}
+	log, _ := logtest.NewTestLogger(t)
+	ge, err := NewGoExecutor(opts, log)
+	if err != nil {
+		t.Fatalf("NewGoExecutor: %v", err)
+	}
+	return ge
+}
+
+// lineSignal es un io.Writer que guarda lo escrito y envía la primera línea
+// completa a first
+type lineSignal struct {
+	mu    sync.Mutex
+	data  []byte
+	first chan string
+	sent  bool
+}
+
+func (w *lineSignal) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.data = append(w.data, p...)
+	if i := bytes.IndexByte(w.data, '\n'); i >= 0 && !w.sent {
+		w.sent = true
+		w.first <- string(w.data[:i])
+	}
+	return len(p), nil
+}
+
+// processAlive indica si el proceso pid sigue ejecutándose. Un proceso zombi
+// (terminado pero sin recoger por su padre) no cuenta como vivo.
+func processAlive(pid int) bool {
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return false
+	}
+	// El estado va tras el nombre del comando, que está entre paréntesis
+	fields := strings.Fields(string(stat[bytes.LastIndexByte(stat, ')')+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
+
+func TestGoExecutorKillsProcessGroupOnCancel(t *testing.T) {
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("se necesita /proc para comprobar los procesos")
+	}
+	ge := newTestGoExecutor(t, GoExecutorOptions{})
+	code := `package main
+
+import (
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+func main() {
+	cmd := exec.Command("sleep", "100")
+	if err := cmd.Start(); err != nil {
+		panic(err)
+	}
+	fmt.Println(cmd.Process.Pid)
+	time.Sleep(time.Hour)
+}
+`
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	output := &lineSignal{first: make(chan string, 1)}
+	done := make(chan error, 1)
+	go func() { done <- ge.Execute(ctx, code, output) }()
+
+	var pid int
+	select {
+	case line := <-output.first:
+		var err error
+		if pid, err = strconv.Atoi(strings.TrimSpace(line)); err != nil {
+			t.Fatalf("el programa no imprimió un PID: %q", line)
+		}
+	case err := <-done:
+		t.Fatalf("el programa terminó antes de crear el subproceso: %v: %s", err, output.data)
+	}
+	if !processAlive(pid) {
+		t.Fatalf("el subproceso %d no llegó a ejecutarse", pid)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, se esperaba context.Canceled", err)
+	}
+	waitFor(t, "la muerte del subproceso", func() bool { return !processAlive(pid) })
+}
+
+func TestGoExecutorStartCleanupRemovesStaleTempDirs(t *testing.T) {
+	tempDir := t.TempDir()
+	ge := newTestGoExecutor(t, GoExecutorOptions{
+		TempDir: tempDir,
+		Cleanup: TempCleanup{Interval: 10 * time.Millisecond, MaxAge: time.Hour},
+	})
+
+	old := time.Now().Add(-2 * time.Hour)
+	stale := []string{
+		filepath.Join(tempDir, "code-a1b2c3d4-none-123"),
+		filepath.Join(tempDir, "code-none-none-456.go"),
+	}
+	if err := os.Mkdir(stale[0], 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(stale[0], "main.go"), []byte("package main"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stale[1], []byte("package main"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// Ni los directorios recientes ni las entradas ajenas al ejecutor se tocan
+	kept := []string{
+		filepath.Join(tempDir, "code-recent-none-789"),
+		filepath.Join(tempDir, "otros-datos"),
+	}
+	for _, dir := range kept {
+		if err := os.Mkdir(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, path := range append(stale, kept[1]) {
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ge.StartCleanup(ctx)
+
+	waitFor(t, "la limpieza de los temporales huérfanos", func() bool {
+		for _, path := range stale {
+			if _, err := os.Lstat(path); err == nil {
+				return false
+			}
+		}
+		return true
+	})
+	for _, path := range kept {
+		if _, err := os.Lstat(path); err != nil {
+			t.Errorf("se eliminó %s, que no es un temporal huérfano", path)
+		}
+	}
+}
+
+// storeMax guarda n en v si es mayor que su valor actual
+func storeMax(v *atomic.Int64, n int64) {
+	for {
+		current := v.Load()
+		if n <= current || v.CompareAndSwap(current, n) {
+			return
+		}
+	}
+}
+
+func TestGoExecutorMaxConcurrentTempFiles(t *testing.T) {
+	const limit = 3
+	ge := newTestGoExecutor(t, GoExecutorOptions{MaxConcurrentTempFiles: limit})
+
+	var maxSeen, rejected atomic.Int64
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				workDir, err := ge.prepareWorkDirFile(context.Background(), mainFileName, "package main")
+				if errors.Is(err, ErrTooManyTempFiles) {
+					rejected.Add(1)
+					continue
+				}
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				storeMax(&maxSeen, ge.TempUsage().ActiveFiles)
+				// Mantener el directorio un momento, como una ejecución real
+				time.Sleep(time.Millisecond)
+				ge.removeWorkDir(workDir)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if seen := maxSeen.Load(); seen > limit {
+		t.Errorf("hubo %d directorios temporales simultáneos, el máximo es %d", seen, limit)
+	}
+	if rejected.Load() == 0 {
+		t.Error("ninguna ejecución se rechazó por el límite")
+	}
+	if usage := ge.TempUsage(); usage.ActiveFiles != 0 || usage.ActiveBytes != 0 {
+		t.Errorf("quedaron %d directorios y %d bytes contabilizados", usage.ActiveFiles, usage.ActiveBytes)
+	}
+}
+
+func TestGoExecutorRejectsExecutionOverTempFileLimit(t *testing.T) {
+	tempDir := t.TempDir()
+	ge := newTestGoExecutor(t, GoExecutorOptions{TempDir: tempDir, MaxConcurrentTempFiles: 1})
+
+	workDir, err := ge.prepareWorkDirFile(context.Background(), mainFileName, "package main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ge.removeWorkDir(workDir)
+
+	err = ge.Execute(context.Background(), "package main\n\nfunc main() {}\n", io.Discard)
+	if !errors.Is(err, ErrTooManyTempFiles) {
+		t.Fatalf("error = %v, se esperaba ErrTooManyTempFiles", err)
+	}
+	if entries, _ := os.ReadDir(tempDir); len(entries) != 1 {
+		t.Errorf("hay %d entradas en el directorio temporal, se esperaba solo la reservada", len(entries))
+	}
+}
+
+func TestNewGoExecutorRejectsInvalidGoExecutable(t *testing.T) {
+	dir := t.TempDir()
+	notExecutable := filepath.Join(dir, "go")
+	if err := os.WriteFile(notExecutable, []byte("#!/bin/sh\necho go version go1.24.1 linux/amd64\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	notGo := filepath.Join(dir, "not-go")
+	if err := os.WriteFile(notGo, []byte("#!/bin/sh\necho hola\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{"sin permiso de ejecución", notExecutable, "no es válido"},
+		{"no existe", filepath.Join(dir, "missing"), "no es válido"},
+		{"no es Go", notGo, "salida inesperada"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, _ := logtest.NewTestLogger(t)
+			ge, err := NewGoExecutor(GoExecutorOptions{GoExecutablePath: tt.path, TempDir: dir}, log)
+			if err == nil {
+				t.Fatalf("NewGoExecutor(%q) no devolvió error (versión %q)", tt.path, ge.GoVersion())
+			}
+			if !strings.Contains(err.Error(), tt.path) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, se esperaba la ruta y %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewGoExecutorDetectsGoVersion(t *testing.T) {
+	ge := newTestGoExecutor(t, GoExecutorOptions{})
+	if !strings.HasPrefix(ge.GoVersion(), "go1.") {
+		t.Errorf("GoVersion() = %q, se esperaba go1.x", ge.GoVersion())
+	}
+}
+
+func TestGoExecutorPrepareCode(t *testing.T) {
+	tests := []struct {
+		name        string
+		autoWrap    bool
+		code        string
+		wantWrapped bool
+		want        string
+	}{
+		{
+			name:        "sentencias sueltas",
+			autoWrap:    true,
+			code:        `fmt.Println("hola")`,
+			wantWrapped: true,
+			want:        "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hola\")\n}\n",
+		},
+		{
+			name:        "declaraciones sin paquete",
+			autoWrap:    true,
+			code:        "func main() {\n\tprintln(1)\n}",
+			wantWrapped: true,
+			want:        "package main\n\nfunc main() {\n\tprintln(1)\n}\n",
+		},
+		{
+			name:     "con declaración de paquete",
+			autoWrap: true,
+			code:     "package main\n\nfunc main() {}\n",
+			want:     "package main\n\nfunc main() {}\n",
+		},
+		{
+			name:     "con AutoWrapCode desactivado",
+			autoWrap: false,
+			code:     `fmt.Println("hola")`,
+			want:     `fmt.Println("hola")`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ge := &GoExecutor{autoWrapCode: tt.autoWrap}
+			result := ge.PrepareCode(tt.code)
+			if result.Wrapped != tt.wantWrapped {
+				t.Errorf("Wrapped = %v, se esperaba %v", result.Wrapped, tt.wantWrapped)
+			}
+			if result.FormattedCode != tt.want {
+				t.Errorf("FormattedCode = %q, se esperaba %q", result.FormattedCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoExecutorRunsWrappedCode(t *testing.T) {
+	ge := newTestGoExecutor(t, GoExecutorOptions{AutoWrapCode: true})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var output bytes.Buffer
+	result, err := ge.ExecuteWithResult(ctx, `fmt.Println("hola")`, &output)
+	if err != nil {
+		t.Fatalf("ExecuteWithResult: %v: %s", err, output.String())
+	}
+	if !result.Wrapped || !strings.Contains(result.FormattedCode, "func main() {") {
+		t.Errorf("resultado = %+v, se esperaba el código envuelto", result)
+	}
+	if output.String() != "hola\n" {
+		t.Errorf("salida = %q, se esperaba %q", output.String(), "hola\n")
+	}
+}
+
+// executorFunc adapta una función a CodeExecutor
+type executorFunc func(ctx context.Context, code string, output io.Writer) error
+
+func (f executorFunc) Execute(ctx context.Context, code string, output io.Writer) error {
+	return f(ctx, code, output)
+}
+
+func TestShouldCache(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"sin error", nil, true},
+		{"timeout", context.DeadlineExceeded, false},
+		{"cancelación", context.Canceled, false},
+		{"timeout envuelto", fmt.Errorf("error en la ejecución: %w", context.DeadlineExceeded), false},
+		{"AppError 500", apperrors.InternalServerError(errors.New("fallo"), "Error interno", nil), false},
+		{"AppError 503", apperrors.WithContext(errors.New("ocupado"), 503, "Servidor ocupado", nil), false},
+		{"AppError 400", apperrors.WithContext(errors.New("inválido"), 400, "Código inválido", nil), true},
+		{"error del programa", errors.New("exit status 1"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ShouldCache(tt.err); got != tt.want {
+				t.Errorf("ShouldCache(%v) = %v, se esperaba %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCachedExecutorReexecutesAfterTimeout(t *testing.T) {
+	var calls atomic.Int32
+	base := executorFunc(func(ctx context.Context, code string, output io.Writer) error {
+		// La primera ejecución escribe una salida parcial y se queda sin tiempo
+		if calls.Add(1) == 1 {
+			io.WriteString(output, "parcial")
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		_, err := io.WriteString(output, "completa\n")
+		return err
+	})
+	ce := NewCachedExecutor(base, 10, 0, time.Minute)
+	defer ce.Stop()
+	const code = "package main"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := ce.Execute(ctx, code, io.Discard); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v, se esperaba context.DeadlineExceeded", err)
+	}
+	if ce.IsCached(code) {
+		t.Fatal("se almacenó la salida parcial de una ejecución que expiró")
+	}
+
+	var output bytes.Buffer
+	if err := ce.Execute(context.Background(), code, &output); err != nil {
+		t.Fatalf("error al repetir la ejecución: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("el código se ejecutó %d veces, se esperaba que se repitiera", calls.Load())
+	}
+	if output.String() != "completa\n" {
+		t.Errorf("salida = %q, se esperaba la de la nueva ejecución", output.String())
+	}
+}
+
+func TestCachedExecutorDoesNotCacheWhenContextExpiredWithoutError(t *testing.T) {
+	// Un ejecutor base descuidado que no informa de la cancelación
+	base := executorFunc(func(ctx context.Context, code string, output io.Writer) error {
+		io.WriteString(output, "parcial")
+		<-ctx.Done()
+		return nil
+	})
+	ce := NewCachedExecutor(base, 10, 0, time.Minute)
+	defer ce.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	ce.Execute(ctx, "package main", io.Discard)
+	// La ejecución compartida termina después de que Execute retorne
+	for range 10 {
+		if ce.IsCached("package main") {
+			t.Fatal("se almacenó la salida de una ejecución cuyo contexto expiró")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestGoExecutorTempQuotaConcurrent(t *testing.T) {
+	code := strings.Repeat("x", 1000)
+	// Cuota para cuatro directorios: el código más un go.mod de unos 30 bytes
+	const quota = 4 * 1100
+	ge := newTestGoExecutor(t, GoExecutorOptions{MaxTempBytes: quota})
+
+	var maxBytes, accepted, rejected atomic.Int64
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				workDir, err := ge.prepareWorkDirFile(context.Background(), mainFileName, code)
+				if errors.Is(err, ErrTempQuotaExceeded) {
+					rejected.Add(1)
+					continue
+				}
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				accepted.Add(1)
+				storeMax(&maxBytes, ge.activeTempBytes.Load())
+				time.Sleep(time.Millisecond)
+				ge.removeWorkDir(workDir)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if seen := maxBytes.Load(); seen > quota {
+		t.Errorf("se contabilizaron %d bytes a la vez, la cuota es %d", seen, quota)
+	}
+	usage := ge.TempUsage()
+	if accepted.Load() == 0 || rejected.Load() == 0 {
+		t.Errorf("aceptadas %d, rechazadas %d: se esperaban ambas", accepted.Load(), rejected.Load())
+	}
+	if usage.QuotaRejections != rejected.Load() {
+		t.Errorf("QuotaRejections = %d, se esperaba %d", usage.QuotaRejections, rejected.Load())
+	}
+	if usage.ActiveFiles != 0 || usage.ActiveBytes != 0 {
+		t.Errorf("quedaron %d directorios y %d bytes contabilizados", usage.ActiveFiles, usage.ActiveBytes)
+	}
+}
+
+func TestGoExecutorTempQuotaCountsMeasuredUsage(t *testing.T) {
+	ge := newTestGoExecutor(t, GoExecutorOptions{MaxTempBytes: 64 * 1024})
+
+	workDir, err := ge.prepareWorkDirFile(context.Background(), mainFileName, "package main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Lo que escribe la compilación en el directorio de trabajo también cuenta
+	build := filepath.Join(workDir, buildTmpDirName, "a.out")
+	if err := os.WriteFile(build, make([]byte, 100*1024), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ge.lastTempMeasure.Store(0)
+
+	if _, err := ge.prepareWorkDirFile(context.Background(), mainFileName, "package main"); !errors.Is(err, ErrTempQuotaExceeded) {
+		t.Fatalf("error = %v, se esperaba ErrTempQuotaExceeded", err)
+	}
+	if usage := ge.TempUsage(); usage.ActiveBytes < 100*1024 {
+		t.Errorf("ActiveBytes = %d, se esperaba al menos el tamaño medido", usage.ActiveBytes)
+	}
+
+	ge.removeWorkDir(workDir)
+	if usage := ge.TempUsage(); usage.ActiveFiles != 0 || usage.ActiveBytes != 0 {
+		t.Errorf("quedaron %d directorios y %d bytes contabilizados", usage.ActiveFiles, usage.ActiveBytes)
+	}
+}
+
+func TestGoExecutorMapsCompileErrorsToPlayground(t *testing.T) {
+	ge := newTestGoExecutor(t, GoExecutorOptions{})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	code := "package main\n\nfunc main() {\n\tif true {\n\t\tfoo()\n\t}\n}\n"
+	var output bytes.Buffer
+	_, err := ge.ExecuteWithResult(ctx, code, &output)
+	if err == nil {
+		t.Fatalf("se esperaba un error de compilación, salida: %q", output.String())
+	}
+	if !strings.Contains(output.String(), "<playground>:5:3: undefined: foo") {
+		t.Errorf("salida = %q, se esperaba <playground>:5:3", output.String())
+	}
+	if strings.Contains(output.String(), mainFileName) || strings.Contains(output.String(), os.TempDir()) {
+		t.Errorf("la salida cita el archivo temporal: %q", output.String())
+	}
+}
+
+func TestMapErrors(t *testing.T) {
+	ge := newTestGoExecutor(t, GoExecutorOptions{AutoWrapCode: true})
+	program := "package main\n\nfunc main() {\n\tfoo()\n}\n"
+	tests := []struct {
+		name string
+		raw  string
+		code string
+		want string
+	}{
+		{"ruta relativa", "./main.go:4:2: undefined: foo", program, "<playground>:4:2: undefined: foo"},
+		{"ruta absoluta de un panic", "\t/tmp/go-playground-1/main.go:4 +0x1d", program, "\t<playground>:4 +0x1d"},
+		{"línea fuera del código enviado", "./main.go:40:2: error", program, "./main.go:40:2: error"},
+		{"sin referencias", "exit status 2", program, "exit status 2"},
+		// El código envuelto añade la cabecera y func main: foo() pasa a otra línea
+		{"código envuelto", "./main.go:7:2: undefined: foo", "fmt.Println(\"a\")\nfoo()", "<playground>:2:2: undefined: foo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ge.MapErrors(tt.raw, tt.code); got != tt.want {
+				t.Errorf("MapErrors() = %q, se esperaba %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// evictionRecorder guarda las claves que recibe como EvictionCallback
+type evictionRecorder struct {
+	mu     sync.Mutex
+	hashes []string
+}
+
+func (er *evictionRecorder) record(hash string, entry *CacheEntry) {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	er.hashes = append(er.hashes, hash)
+}
+
+func (er *evictionRecorder) evicted() []string {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	return append([]string(nil), er.hashes...)
+}
+
+func TestCachedExecutorEvictionCallback(t *testing.T) {
+	recorder := &evictionRecorder{}
+	ce := NewCachedExecutor(&fakeExecutor{output: "hola\n"}, 2, 0, time.Minute, WithEvictionCallback(recorder.record))
+	defer ce.Stop()
+	ctx := context.Background()
+
+	execute := func(code string) {
+		t.Helper()
+		if err := ce.Execute(ctx, code, io.Discard); err != nil {
+			t.Fatalf("Execute(%q): %v", code, err)
+		}
+		// LastAccess distinto en cada ejecución
+		time.Sleep(2 * time.Millisecond)
+	}
+	execute("a")
+	execute("b")
+	// Usar "a" de nuevo deja a "b" como la menos usada recientemente
+	execute("a")
+	if got := recorder.evicted(); len(got) != 0 {
+		t.Fatalf("se descartaron %q sin llenar el caché", got)
+	}
+
+	execute("c")
+	if got, want := recorder.evicted(), []string{ce.CacheKey(ctx, "b")}; len(got) != 1 || got[0] != want[0] {
+		t.Fatalf("descartadas = %q, se esperaba %q", got, want)
+	}
+	if ce.IsCached("b") || !ce.IsCached("a") || !ce.IsCached("c") {
+		t.Error("el caché no contiene a y c tras descartar b")
+	}
+}
+
+func TestCachedExecutorEvictionCallbackOnExpiry(t *testing.T) {
+	recorder := &evictionRecorder{}
+	ce := NewCachedExecutor(&fakeExecutor{output: "hola\n"}, 10, 0, 10*time.Millisecond,
+		WithCleanupInterval(time.Hour), WithEvictionCallback(recorder.record))
+	defer ce.Stop()
+	ctx := context.Background()
+
+	for _, code := range []string{"a", "b"} {
+		if err := ce.Execute(ctx, code, io.Discard); err != nil {
+			t.Fatalf("Execute(%q): %v", code, err)
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	ce.cleanupCache()
+
+	got := recorder.evicted()
+	want := map[string]bool{ce.CacheKey(ctx, "a"): true, ce.CacheKey(ctx, "b"): true}
+	if len(got) != len(want) || !want[got[0]] || !want[got[1]] || got[0] == got[1] {
+		t.Errorf("descartadas = %q, se esperaban las dos entradas expiradas", got)
+	}
+}
+
+func TestCachedExecutorIdempotentReplayWithFakeTime(t *testing.T) {
+	fake := &fakeExecutor{output: "hola\n"}
+	ce := NewCachedExecutor(fake, 10, 0, time.Minute)
+	defer ce.Stop()
+	fakeTime := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	ctx := WithFakeTime(context.Background(), fakeTime)
+
+	if ce.IdempotentReplay(ctx, "clave", "code") {
+		t.Fatal("IdempotentReplay antes de ejecutar = true")
+	}
+	if _, err := ce.ExecuteIdempotent(ctx, "clave", "code", io.Discard); err != nil {
+		t.Fatalf("ExecuteIdempotent: %v", err)
+	}
+
+	// La entrada guarda la hora simulada junto al hash del código
+	if !ce.IdempotentReplay(ctx, "clave", "code") {
+		t.Error("IdempotentReplay con la misma hora simulada = false")
+	}
+	if ce.IdempotentReplay(context.Background(), "clave", "code") {
+		t.Error("IdempotentReplay sin hora simulada = true")
+	}
+	if ce.IdempotentReplay(ctx, "clave", "otro") {
+		t.Error("IdempotentReplay con otro código = true")
+	}
+
+	var output strings.Builder
+	result, err := ce.ExecuteIdempotent(ctx, "clave", "code", &output)
+	if err != nil || !result.Cached || output.String() != "hola\n" {
+		t.Errorf("reintento: Cached = %v, salida = %q, err = %v", result.Cached, output.String(), err)
+	}
+	if n := fake.calls.Load(); n != 1 {
+		t.Errorf("el código se ejecutó %d veces, se esperaba 1", n)
+	}
+}
+
+func TestGoExecutorRunsInTemporaryModule(t *testing.T) {
+	tempDir := t.TempDir()
+	ge := newTestGoExecutor(t, GoExecutorOptions{TempDir: tempDir})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// El programa informa de su directorio y del go.mod mientras existen;
+	// //go:embed solo funciona si se compila el directorio y no un archivo suelto
+	code := `package main
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+)
+
+//go:embed main.go
+var source string
+
+func main() {
+	wd, _ := os.Getwd()
+	goMod, err := os.ReadFile("go.mod")
+	fmt.Println(wd)
+	fmt.Printf("%q %v\n", goMod, err)
+	fmt.Println(len(source))
+}
+`
+	var output bytes.Buffer
+	if _, err := ge.ExecuteWithResult(ctx, code, &output); err != nil {
+		t.Fatalf("ExecuteWithResult: %v: %s", err, output.String())
+	}
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("salida = %q, se esperaban 3 líneas", output.String())
+	}
+
+	workDir := lines[0]
+	if parent, _ := filepath.EvalSymlinks(filepath.Dir(workDir)); parent != mustEvalSymlinks(t, tempDir) {
+		t.Errorf("directorio de trabajo = %s, se esperaba un subdirectorio de %s", workDir, tempDir)
+	}
+	if !strings.HasPrefix(lines[1], `"module playground/run\n\ngo `) || !strings.HasSuffix(lines[1], "<nil>") {
+		t.Errorf("go.mod = %s, se esperaba el módulo playground/run", lines[1])
+	}
+	if lines[2] != strconv.Itoa(len(code)) {
+		t.Errorf("//go:embed main.go leyó %s bytes, se esperaban %d", lines[2], len(code))
+	}
+
+	if _, err := os.Stat(workDir); !os.IsNotExist(err) {
+		t.Errorf("el directorio de trabajo sigue existiendo tras la ejecución: %v", err)
+	}
+	if entries, err := os.ReadDir(tempDir); err != nil || len(entries) != 0 {
+		t.Errorf("TempDir contiene %v (%v) tras la ejecución, se esperaba vacío", entries, err)
+	}
+}
+
+// mustEvalSymlinks resuelve los enlaces simbólicos de path
+func mustEvalSymlinks(t *testing.T, path string) string {
+	t.Helper()
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resolved
+}
+
+// benchmarkCacheEntries es el número de entradas del caché en los benchmarks
+const benchmarkCacheEntries = 10000
+
+// newBenchmarkCache crea un CachedExecutor lleno con benchmarkCacheEntries
+// entradas y devuelve sus códigos
+func newBenchmarkCache(b *testing.B) (*CachedExecutor, []string) {
+	b.Helper()
+	ce := NewCachedExecutor(&fakeExecutor{}, benchmarkCacheEntries, 0, time.Hour)
+	b.Cleanup(ce.Stop)
+	codes := make([]string, benchmarkCacheEntries)
+	for i := range codes {
+		codes[i] = fmt.Sprintf("package main // %d", i)
+		ce.store(ce.CacheKey(context.Background(), codes[i]), &CacheEntry{Result: []byte("hola\n"), LastAccess: time.Now()})
+	}
+	return ce, codes
+}
+
+// BenchmarkCachedExecutorHit mide una ejecución servida desde el caché, con
+// el hash del código y la copia de la salida
+func BenchmarkCachedExecutorHit(b *testing.B) {
+	ce, codes := newBenchmarkCache(b)
+	ctx := context.Background()
+	var next atomic.Int64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			code := codes[next.Add(1)%benchmarkCacheEntries]
+			if err := ce.Execute(ctx, code, io.Discard); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+	b.ReportMetric(float64(ce.Stats().CacheLookupLatency.Nanoseconds()), "lookup-ns")
+}
+
+// BenchmarkCachedExecutorMiss mide la consulta del caché para un código que
+// no está, lo que cuesta un fallo antes de iniciar la ejecución
+func BenchmarkCachedExecutorMiss(b *testing.B) {
+	ce, _ := newBenchmarkCache(b)
+	missing := make([]string, benchmarkCacheEntries)
+	for i := range missing {
+		missing[i] = fmt.Sprintf("package main // ausente %d", i)
+	}
+	var next atomic.Int64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if ce.IsCached(missing[next.Add(1)%benchmarkCacheEntries]) {
+				b.Error("un código ausente aparece en el caché")
+				return
+			}
+		}
+	})
+	b.ReportMetric(float64(ce.Stats().CacheLookupLatency.Nanoseconds()), "lookup-ns")
+}
+
+// outputProgram escribe outputProgramBytes en la salida estándar en bloques
+// de 64 KiB, como un programa con mucha salida
+const outputProgram = `package main
+
+import (
+	"bytes"
+	"os"
+)
+
+func main() {
+	block := bytes.Repeat([]byte("0123456789abcde\n"), 4096)
+	for i := 0; i < 128; i++ {
+		os.Stdout.Write(block)
+	}
+}
+`
+
+// outputProgramBytes es la salida total de outputProgram
+const outputProgramBytes = 8 << 20
+
+// BenchmarkGoExecutorCaptureReadBuffer compara la lectura de la salida de un
+// programa que escribe 8 MiB con buffers de 1 KiB y de 32 KiB. Se conservan
+// 4 MiB, como con un MAX_STDOUT_LENGTH alto, y el resto se lee y descarta.
+// El programa se compila una sola vez para medir solo la lectura.
+func BenchmarkGoExecutorCaptureReadBuffer(b *testing.B) {
+	goPath, err := exec.LookPath("go")
+	if err != nil {
+		b.Skip("no se encontró el ejecutable de Go")
+	}
+	dir := b.TempDir()
+	program := filepath.Join(dir, "output")
+	for name, content := range map[string]string{"main.go": outputProgram, "go.mod": workGoMod("")} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			b.Fatal(err)
+		}
+	}
+	build := exec.Command(goPath, "build", "-o", program, ".")
+	build.Dir = dir
+	if out, err := build.CombinedOutput(); err != nil {
+		b.Fatalf("go build: %v: %s", err, out)
+	}
+
+	for _, size := range []int{1 << 10, 32 << 10} {
+		b.Run(fmt.Sprintf("%dKB", size>>10), func(b *testing.B) {
+			ge := newTestGoExecutor(b, GoExecutorOptions{ReadBufferSize: size})
+			b.SetBytes(outputProgramBytes)
+			for b.Loop() {
+				cmd := exec.Command(program)
+				stdout, err := cmd.StdoutPipe()
+				if err == nil {
+					err = cmd.Start()
+				}
+				if err != nil {
+					b.Fatal(err)
+				}
+				capture := &streamCapture{name: "stdout", limit: 4 << 20, live: io.Discard}
+				if err := ge.capture(stdout, capture); err != nil {
+					b.Fatal(err)
+				}
+				if err := cmd.Wait(); err != nil {
+					b.Fatal(err)
+				}
+				if !capture.truncated || len(capture.data) != 4<<20 {
+					b.Fatalf("se capturaron %d bytes, se esperaba el límite de 4 MiB", len(capture.data))
+				}
+			}
+		})
+	}
+}
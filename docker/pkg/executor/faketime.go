@@ -0,0 +1,177 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// fakeTimeEnvVar es la variable del entorno del hijo con la hora simulada en RFC 3339
+const fakeTimeEnvVar = "FAKETIME"
+
+// fakeTimeDirName es el subdirectorio de TempDir con el overlay de la hora
+// simulada. No coincide con tempDirPattern, así que la limpieza no lo elimina.
+const fakeTimeDirName = "faketime"
+
+// ErrFakeTimeUnsupported indica que el ejecutor no puede simular la hora
+var ErrFakeTimeUnsupported = errors.New("la simulación de la hora no está disponible en este servidor")
+
+// fakeTimeKey es la clave de contexto de la hora simulada
+type fakeTimeKey struct{}
+
+// WithFakeTime devuelve un contexto con el que GoExecutor ejecuta el código
+// haciendo que time.Now devuelva siempre t
+func WithFakeTime(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, fakeTimeKey{}, t)
+}
+
+// FakeTime devuelve la hora simulada del contexto, si la tiene
+func FakeTime(ctx context.Context) (time.Time, bool) {
+	t, ok := ctx.Value(fakeTimeKey{}).(time.Time)
+	return t, ok
+}
+
+// ExecutionKey identifica una ejecución de code para el caché y la
+// deduplicación: el hash del código y, si la hay, la hora simulada del
+// contexto, ya que cambia la salida del programa.
+func ExecutionKey(ctx context.Context, code string) string {
+	key := HashCode(code)
+	if t, ok := FakeTime(ctx); ok {
+		key += "@" + t.UTC().Format(time.RFC3339Nano)
+	}
+	return key
+}
+
+// FakeTimeSupporter es implementado por los ejecutores que pueden simular la
+// hora (ver WithFakeTime)
+type FakeTimeSupporter interface {
+	SupportsFakeTime() bool
+}
+
+// SupportsFakeTime indica si ex puede ejecutar código con la hora simulada
+func SupportsFakeTime(ex CodeExecutor) bool {
+	supporter, ok := ex.(FakeTimeSupporter)
+	return ok && supporter.SupportsFakeTime()
+}
+
+// SupportsFakeTime implementa FakeTimeSupporter
+func (ge *GoExecutor) SupportsFakeTime() bool {
+	return ge.fakeTimeOverlay != ""
+}
+
+// timeNowCall reconoce la primera línea de time.Now, que lee el reloj del runtime
+// ("sec, nsec, mono := runtimeNow()" o "... := now()" según la versión de Go)
+var timeNowCall = regexp.MustCompile(`(?m)^func Now\(\) Time \{\n\tsec, nsec, mono := \w+\(\)\n`)
+
+// fakeTimePatch se añade a time.go: lee FAKETIME una sola vez y, si es válida,
+// time.Now devuelve esa hora. La lectura monotónica sigue siendo la real, por
+// lo que time.Since y los temporizadores funcionan con normalidad.
+const fakeTimePatch = `
+var playgroundFakeTime struct {
+	once sync.Once
+	sec  int64
+	nsec int32
+	ok   bool
+}
+
+func playgroundFakeNow() (int64, int32, bool) {
+	ft := &playgroundFakeTime
+	ft.once.Do(func() {
+		value, found := syscall.Getenv("` + fakeTimeEnvVar + `")
+		if !found {
+			return
+		}
+		t, err := Parse(RFC3339Nano, value)
+		if err != nil {
+			return
+		}
+		ft.sec, ft.nsec, ft.ok = t.Unix(), int32(t.Nanosecond()), true
+	})
+	return ft.sec, ft.nsec, ft.ok
+}
+`
+
+// prepareFakeTimeOverlay escribe en TempDir una copia de time.go de la
+// biblioteca estándar en la que time.Now devuelve la hora de FAKETIME, y el
+// archivo de overlay que la sustituye con 'go run -overlay'.
+//
+// No se usa libfaketime con LD_PRELOAD porque los programas Go leen el reloj
+// directamente del vDSO, sin pasar por libc. Con -overlay la instalación de Go
+// no se modifica y el caché de compilación guarda aparte la versión parcheada.
+// Devuelve la ruta del archivo de overlay.
+func (ge *GoExecutor) prepareFakeTimeOverlay() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), goVersionTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, ge.goExecutablePath, "env", "GOROOT")
+	cmd.Env = ge.env
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("error obteniendo GOROOT: %w", err)
+	}
+	original := filepath.Join(strings.TrimSpace(string(out)), "src", "time", "time.go")
+
+	source, err := os.ReadFile(original)
+	if err != nil {
+		return "", fmt.Errorf("error leyendo %s: %w", original, err)
+	}
+	patched, err := patchTimeNow(string(source))
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(ge.tempDir, fakeTimeDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("error creando %s: %w", dir, err)
+	}
+	patchedPath := filepath.Join(dir, "time.go")
+	if err := os.WriteFile(patchedPath, []byte(patched), 0644); err != nil {
+		return "", fmt.Errorf("error escribiendo %s: %w", patchedPath, err)
+	}
+
+	overlay, err := json.Marshal(map[string]map[string]string{
+		"Replace": {original: patchedPath},
+	})
+	if err != nil {
+		return "", err
+	}
+	overlayPath := filepath.Join(dir, "overlay.json")
+	if err := os.WriteFile(overlayPath, overlay, 0644); err != nil {
+		return "", fmt.Errorf("error escribiendo %s: %w", overlayPath, err)
+	}
+	return overlayPath, nil
+}
+
+// patchTimeNow aplica fakeTimePatch al código de time.go. Falla si la versión
+// de Go no tiene la forma esperada de time.Now.
+func patchTimeNow(source string) (string, error) {
+	loc := timeNowCall.FindStringIndex(source)
+	if loc == nil {
+		return "", errors.New("time.Now no tiene la forma esperada en esta versión de Go")
+	}
+	imports := "import (\n"
+	if !strings.Contains(source, imports) {
+		return "", errors.New("time.go no tiene la forma esperada en esta versión de Go")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(source[:loc[1]])
+	sb.WriteString("\tif fakeSec, fakeNsec, ok := playgroundFakeNow(); ok {\n\t\tsec, nsec = fakeSec, fakeNsec\n\t}\n")
+	sb.WriteString(source[loc[1]:])
+	sb.WriteString(fakeTimePatch)
+
+	// time ya depende de sync y syscall en Linux; basta con importarlos en time.go
+	extra := ""
+	for _, pkg := range []string{"sync", "syscall"} {
+		if !strings.Contains(source, "\t\""+pkg+"\"\n") {
+			extra += "\t\"" + pkg + "\"\n"
+		}
+	}
+	return strings.Replace(sb.String(), imports, imports+extra, 1), nil
+}
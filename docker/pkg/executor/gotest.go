@@ -0,0 +1,279 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Estados de un TestCase
+const (
+	TestStatusPass = "pass"
+	TestStatusFail = "fail"
+	TestStatusSkip = "skip"
+	// TestStatusRun indica que el test empezó pero no llegó a terminar, por
+	// ejemplo porque se agotó el tiempo
+	TestStatusRun = "run"
+)
+
+// maxTestEventLine es la longitud máxima de una línea de 'go test -json'. Las
+// líneas más largas se descartan enteras, ya que no se pueden interpretar a medias.
+const maxTestEventLine = 64 * 1024
+
+// TestCase es el resultado de un test, tal como lo informa 'go test -json'.
+// Los subtests (t.Run) cuelgan de su test padre en Subtests.
+type TestCase struct {
+	// Name es el nombre completo del test, por ejemplo TestSuma/negativos
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	ElapsedMs int64  `json:"elapsed_ms"`
+	// Output es la salida del test (t.Log, fmt.Println...), sin las líneas
+	// "=== RUN" y "--- PASS" que añade 'go test'
+	Output   string      `json:"output"`
+	Subtests []*TestCase `json:"subtests,omitempty"`
+}
+
+// TestReport contiene los resultados de una ejecución de tests
+type TestReport struct {
+	// Passed indica si 'go test' terminó correctamente. Es false si el código no
+	// compila o si algún test falla.
+	Passed bool
+	// Tests son los tests de primer nivel, en el orden en que empezaron
+	Tests []*TestCase
+	// Output es la salida que no pertenece a ningún test: errores de
+	// compilación, el resumen del paquete y la salida de error de 'go test'
+	Output string
+	// Truncated indica que se descartó salida o tests por superar el límite
+	// de la salida estándar
+	Truncated bool
+}
+
+// Tester define el comportamiento de los ejecutores capaces de ejecutar tests
+// de Go.
+type Tester interface {
+	Test(ctx context.Context, code string) (TestReport, error)
+}
+
+// Test guarda el código como main_test.go y ejecuta 'go test -json -count=1'
+// sobre él, interpretando los eventos a medida que llegan.
+//
+// El código debe declarar "package main" e importar "testing"; no se le aplica
+// AutoWrapCode. El límite de la salida estándar se aplica al conjunto de los
+// nombres y las salidas de todos los tests, no al JSON de 'go test'. Que el
+// código no compile o un test falle no es un error: se indica con Passed=false.
+// Retorna error si no se pudo lanzar 'go test' o si ctx expiró, en cuyo caso el
+// informe contiene los tests obtenidos hasta entonces.
+func (ge *GoExecutor) Test(ctx context.Context, code string) (TestReport, error) {
+	workDir, err := ge.prepareWorkDirFile(ctx, testFileName, code)
+	if err != nil {
+		return TestReport{}, err
+	}
+	defer ge.removeWorkDir(workDir)
+
+	// -count=1 evita que 'go test' sirva resultados de su propio caché
+	args := []string{"test", "-json", "-count=1"}
+	if deadline, ok := ctx.Deadline(); ok {
+		args = append(args, "-timeout="+max(time.Until(deadline), time.Second).String())
+	}
+	args = append(args, ".")
+
+	events := newTestEventParser(ge.maxStdoutLength)
+	stdout := &streamCapture{name: "stdout", live: events, unbuffered: true}
+	stderr := &streamCapture{name: "stderr", limit: ge.maxStderrLength}
+	waitErr, err := ge.runInto(ge.command(ctx, workDir, args...), stdout, stderr)
+	if err != nil {
+		return TestReport{}, err
+	}
+
+	report := events.finish()
+	report.Passed = waitErr == nil
+	var output bytes.Buffer
+	output.WriteString(report.Output)
+	stderr.writeTo(&output)
+	if report.Truncated {
+		fmt.Fprintf(&output, "\n... (tests truncated after %d bytes)", ge.maxStdoutLength)
+	}
+	report.Output = output.String()
+
+	if waitErr != nil && ctx.Err() != nil {
+		return report, fmt.Errorf("error en los tests: %w", ctx.Err())
+	}
+	return report, nil
+}
+
+// testEvent es una línea de 'go test -json' (ver 'go doc test2json')
+type testEvent struct {
+	Action  string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// testFramePrefixes son los prefijos de las líneas que 'go test -v' añade a la
+// salida de cada test, ya representadas en el árbol por Status y ElapsedMs
+var testFramePrefixes = []string{"=== RUN ", "=== PAUSE ", "=== CONT ", "=== NAME ", "--- PASS: ", "--- FAIL: ", "--- SKIP: "}
+
+// testEventParser construye el árbol de tests a partir de la salida de
+// 'go test -json', que recibe en fragmentos como io.Writer. Los nombres y las
+// salidas que conserva suman como máximo limit bytes; al superarlo descarta el
+// resto de la salida y los tests nuevos, pero sigue actualizando el estado de
+// los que ya tiene.
+type testEventParser struct {
+	remaining int
+	truncated bool
+	tests     map[string]*TestCase
+	roots     []*TestCase
+	output    string
+	pending   []byte
+	// skipping indica que se está descartando una línea demasiado larga
+	skipping bool
+}
+
+// newTestEventParser crea un intérprete que conserva como máximo limit bytes
+func newTestEventParser(limit int) *testEventParser {
+	return &testEventParser{
+		remaining: limit,
+		tests:     make(map[string]*TestCase),
+	}
+}
+
+// Write implementa io.Writer. Nunca devuelve error, para que 'go test' no se
+// bloquee escribiendo en una tubería llena.
+func (p *testEventParser) Write(data []byte) (int, error) {
+	n := len(data)
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			p.appendPending(data)
+			break
+		}
+		p.appendPending(data[:i])
+		if !p.skipping {
+			p.handleLine(p.pending)
+		}
+		p.pending = p.pending[:0]
+		p.skipping = false
+		data = data[i+1:]
+	}
+	return n, nil
+}
+
+// finish interpreta la última línea, si no terminaba en salto de línea, y
+// devuelve el informe sin Passed
+func (p *testEventParser) finish() TestReport {
+	if len(p.pending) > 0 && !p.skipping {
+		p.handleLine(p.pending)
+	}
+	p.pending = nil
+	return TestReport{
+		Tests:     p.roots,
+		Output:    p.output,
+		Truncated: p.truncated,
+	}
+}
+
+// appendPending acumula data en la línea en curso, o la descarta si supera
+// maxTestEventLine
+func (p *testEventParser) appendPending(data []byte) {
+	if p.skipping {
+		return
+	}
+	if len(p.pending)+len(data) > maxTestEventLine {
+		p.skipping = true
+		p.truncated = true
+		p.pending = p.pending[:0]
+		return
+	}
+	p.pending = append(p.pending, data...)
+}
+
+// handleLine aplica una línea de 'go test -json' al árbol
+func (p *testEventParser) handleLine(line []byte) {
+	var event testEvent
+	if err := json.Unmarshal(line, &event); err != nil || event.Action == "" {
+		// Texto que no es un evento, por ejemplo un error de compilación en
+		// versiones de Go que no lo informan como build-output
+		p.appendOutput(&p.output, string(line)+"\n")
+		return
+	}
+
+	if event.Test == "" {
+		if event.Action == "output" || event.Action == "build-output" {
+			p.appendOutput(&p.output, event.Output)
+		}
+		return
+	}
+
+	switch event.Action {
+	case "run":
+		p.addTest(event.Test)
+	case "output":
+		if tc := p.tests[event.Test]; tc != nil && !isTestFrameLine(event.Output) {
+			p.appendOutput(&tc.Output, event.Output)
+		}
+	case TestStatusPass, TestStatusFail, TestStatusSkip:
+		if tc := p.tests[event.Test]; tc != nil {
+			tc.Status = event.Action
+			tc.ElapsedMs = int64(math.Round(event.Elapsed * 1000))
+		}
+	}
+}
+
+// addTest añade el test name bajo su padre más cercano, o como test de primer
+// nivel si no lo tiene. Un subtest creado con t.Run("a/b") no tiene padre
+// "a", así que cuelga directamente del test que lo creó.
+func (p *testEventParser) addTest(name string) {
+	if _, ok := p.tests[name]; ok || !p.reserve(len(name)) {
+		return
+	}
+	tc := &TestCase{Name: name, Status: TestStatusRun}
+	p.tests[name] = tc
+
+	for i := strings.LastIndexByte(name, '/'); i >= 0; i = strings.LastIndexByte(name[:i], '/') {
+		if parent := p.tests[name[:i]]; parent != nil {
+			parent.Subtests = append(parent.Subtests, tc)
+			return
+		}
+	}
+	p.roots = append(p.roots, tc)
+}
+
+// reserve descuenta n bytes del límite. Devuelve false, y marca el informe
+// como truncado, si no caben.
+func (p *testEventParser) reserve(n int) bool {
+	if p.truncated || n > p.remaining {
+		p.truncated = true
+		return false
+	}
+	p.remaining -= n
+	return true
+}
+
+// appendOutput añade a dst lo que quepa de s
+func (p *testEventParser) appendOutput(dst *string, s string) {
+	if p.truncated {
+		return
+	}
+	if len(s) > p.remaining {
+		s = s[:p.remaining]
+		p.truncated = true
+	}
+	p.remaining -= len(s)
+	*dst += s
+}
+
+// isTestFrameLine indica si line es una de las líneas que 'go test -v' añade
+// al empezar, pausar, continuar o terminar un test
+func isTestFrameLine(line string) bool {
+	line = strings.TrimLeft(line, " ")
+	for _, prefix := range testFramePrefixes {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
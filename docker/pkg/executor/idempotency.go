@@ -0,0 +1,115 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// idempotencyKeyPrefix separa en el caché las entradas de claves de idempotencia
+// de las entradas por hash de código
+const idempotencyKeyPrefix = "idempotency:"
+
+// ErrIdempotencyKeyReused indica que la clave de idempotencia ya se usó con otro código
+var ErrIdempotencyKeyReused = errors.New("la clave de idempotencia ya se usó con otro código")
+
+// IdempotentExecutor define el comportamiento de los ejecutores que pueden
+// repetir el resultado de una ejecución anterior identificada por una clave
+// elegida por el cliente (cabecera Idempotency-Key).
+type IdempotentExecutor interface {
+	// IdempotentReplay indica si ExecuteIdempotent devolverá un resultado
+	// almacenado para key y code sin ejecutar el código. ctx debe llevar la
+	// misma hora simulada que se pasará a ExecuteIdempotent.
+	IdempotentReplay(ctx context.Context, key, code string) bool
+	ExecuteIdempotent(ctx context.Context, key, code string, output io.Writer) (ExecutionResult, error)
+}
+
+// ExecuteIdempotent ejecuta el código y guarda su resultado bajo key durante el
+// TTL del caché. Si key ya tiene un resultado, lo escribe en output sin volver
+// a ejecutar el código.
+//
+// A diferencia del caché por hash, se guarda también la salida de los programas
+// que fallan o no son deterministas, ya que el cliente pidió expresamente no
+// repetir la ejecución. Las ejecuciones cortadas por timeout, cancelación o
+// fallo interno no se guardan, y tampoco las que superan maxEntrySizeBytes: el
+// siguiente reintento vuelve a ejecutarlas. El TTL cuenta desde la ejecución,
+// no desde el último reintento.
+//
+// Retorna ErrIdempotencyKeyReused, sin escribir nada, si key se usó con otro código.
+// Implementa la interfaz IdempotentExecutor.
+func (ce *CachedExecutor) ExecuteIdempotent(ctx context.Context, key, code string, output io.Writer) (ExecutionResult, error) {
+	cacheKey := idempotencyKeyPrefix + HashCode(key)
+	codeHash := ExecutionKey(ctx, code)
+
+	if entry, found := ce.lookup(cacheKey); found {
+		if entry.CodeHash != codeHash {
+			return ExecutionResult{FormattedCode: code, ExitCode: -1}, ErrIdempotencyKeyReused
+		}
+		result := ce.PrepareCode(code)
+		result.ExitCode = entry.ExitCode
+		result.Truncated = entry.Truncated
+		result.Cached = true
+		if _, err := output.Write(entry.Result); err != nil {
+			return result, err
+		}
+		if entry.Err != "" {
+			return result, errors.New(entry.Err)
+		}
+		return result, nil
+	}
+
+	buffer := &cachingWriter{
+		buffer: make([]byte, 0, 4096),
+		limit:  ce.maxEntrySizeBytes,
+	}
+	result, err := ce.ExecuteWithResult(ctx, code, io.MultiWriter(output, buffer))
+	if !ShouldCache(err) || !ShouldCache(ctx.Err()) || buffer.overflow {
+		return result, err
+	}
+
+	entry := &CacheEntry{
+		Result:      buffer.buffer,
+		Truncated:   result.Truncated,
+		LastAccess:  time.Now(),
+		AccessCount: 1,
+		ExitCode:    result.ExitCode,
+		CodeHash:    codeHash,
+	}
+	if err != nil {
+		entry.Err = err.Error()
+	}
+	ce.store(cacheKey, entry)
+	return result, err
+}
+
+// IdempotentReplay implementa la interfaz IdempotentExecutor
+func (ce *CachedExecutor) IdempotentReplay(ctx context.Context, key, code string) bool {
+	entry, found := ce.lookup(idempotencyKeyPrefix + HashCode(key))
+	return found && entry.CodeHash == ExecutionKey(ctx, code)
+}
+
+// cachingWriter es un escritor que almacena los datos en un buffer.
+// Se utiliza para capturar la salida de ExecuteIdempotent y almacenarla bajo su clave.
+// Si limit es mayor que 0 y la salida lo supera, descarta el buffer y marca overflow.
+type cachingWriter struct {
+	buffer   []byte
+	limit    int
+	overflow bool
+}
+
+// Write implementa la interfaz io.Writer.
+// Almacena los datos escritos en el buffer interno para su posterior almacenamiento en el caché.
+// Nunca devuelve error, para no interrumpir la escritura en los demás destinos.
+func (cw *cachingWriter) Write(p []byte) (n int, err error) {
+	if cw.overflow {
+		return len(p), nil
+	}
+	if cw.limit > 0 && len(cw.buffer)+len(p) > cw.limit {
+		cw.overflow = true
+		cw.buffer = nil
+		return len(p), nil
+	}
+	cw.buffer = append(cw.buffer, p...)
+	return len(p), nil
+}
@@ -0,0 +1,71 @@
+//go:build linux
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// rlimitNproc es el identificador de RLIMIT_NPROC en Linux (no exportado por syscall)
+const rlimitNproc = 0x6
+
+// processLimitsHelperArg es el primer argumento con el que el servidor se
+// relanza a sí mismo para fijar RLIMIT_NPROC antes de ejecutar 'go'
+const processLimitsHelperArg = "-playground-process-limits"
+
+// init convierte el proceso en el lanzador de withProcessLimits si se invocó
+// como tal: fija RLIMIT_NPROC en sí mismo y se reemplaza con exec(2) por el
+// comando real, que hereda el límite desde su primera instrucción. Nunca
+// vuelve en ese caso.
+//
+// Argumentos: <ejecutable> -playground-process-limits <límite> <ruta> <argv...>
+func init() {
+	if len(os.Args) < 5 || os.Args[1] != processLimitsHelperArg {
+		return
+	}
+	limit, err := strconv.ParseUint(os.Args[2], 10, 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "límite de procesos inválido %q\n", os.Args[2])
+		os.Exit(126)
+	}
+	rlim := syscall.Rlimit{Cur: limit, Max: limit}
+	if err := syscall.Setrlimit(rlimitNproc, &rlim); err != nil {
+		fmt.Fprintf(os.Stderr, "error fijando RLIMIT_NPROC: %v\n", err)
+		os.Exit(126)
+	}
+	err = syscall.Exec(os.Args[3], os.Args[4:], os.Environ())
+	fmt.Fprintf(os.Stderr, "error ejecutando %s: %v\n", os.Args[3], err)
+	os.Exit(127)
+}
+
+// withProcessLimits hace que cmd se lance a través del propio servidor
+// (self), que fija RLIMIT_NPROC y después ejecuta el comando original. Así el
+// límite rige desde el arranque del hijo, con o sin cambio de usuario, sin
+// tocar los límites del servidor: SysProcAttr no permite fijar rlimits y
+// aplicarlos con prlimit(2) tras cmd.Start dejaría al hijo un intervalo sin
+// límite.
+func withProcessLimits(cmd *exec.Cmd, self string, limits ProcessLimits) {
+	if limits.MaxProcesses <= 0 || self == "" || cmd.Err != nil {
+		return
+	}
+	cmd.Args = append([]string{self, processLimitsHelperArg, strconv.Itoa(limits.MaxProcesses), cmd.Path}, cmd.Args...)
+	cmd.Path = self
+}
+
+// processLimitsExecutable devuelve la ruta del ejecutable del servidor que
+// lanza los hijos con withProcessLimits, o "" si no hay límite de procesos.
+// Con Credential, el usuario del hijo debe poder ejecutarlo.
+func processLimitsExecutable(limits ProcessLimits) (string, error) {
+	if limits.MaxProcesses <= 0 {
+		return "", nil
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("no se pudo localizar el ejecutable del servidor para aplicar RLIMIT_NPROC: %w", err)
+	}
+	return self, nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package executor
+
+import "os/exec"
+
+// withProcessLimits no aplica RLIMIT_NPROC fuera de Linux; solo GOMAXPROCS
+// tiene efecto en esas plataformas.
+func withProcessLimits(cmd *exec.Cmd, self string, limits ProcessLimits) {}
+
+// processLimitsExecutable no necesita el ejecutable del servidor fuera de Linux
+func processLimitsExecutable(limits ProcessLimits) (string, error) {
+	return "", nil
+}
@@ -0,0 +1,100 @@
+package executor
+
+import (
+	"bytes"
+	"io"
+)
+
+// NormalizingWriter normaliza la salida de una ejecución a medida que se
+// escribe, para que diferencias invisibles no cambien el resultado mostrado ni
+// las comparaciones: elimina los espacios, tabuladores y retornos de carro al
+// final de cada línea, reduce las líneas vacías consecutivas a una sola y
+// termina la salida con un único salto de línea.
+//
+// Los espacios en blanco se retienen hasta saber si van seguidos de texto, así
+// que hay que llamar a Flush al terminar la ejecución.
+type NormalizingWriter struct {
+	w io.Writer
+	// pending son los espacios en blanco que aún no se sabe si son finales
+	pending []byte
+	// written indica si ya se escribió algún texto
+	written bool
+	err     error
+}
+
+// NewNormalizingWriter crea un NormalizingWriter que escribe en w
+func NewNormalizingWriter(w io.Writer) *NormalizingWriter {
+	return &NormalizingWriter{w: w}
+}
+
+// Write implementa la interfaz io.Writer. Devuelve el primer error de w.
+func (nw *NormalizingWriter) Write(p []byte) (int, error) {
+	start := 0
+	for i := 0; i < len(p) && nw.err == nil; i++ {
+		if isOutputSpace(p[i]) {
+			continue
+		}
+		nw.flushPending(p[start:i])
+		end := i + 1
+		for end < len(p) && !isOutputSpace(p[end]) {
+			end++
+		}
+		nw.write(p[i:end])
+		nw.written = true
+		start, i = end, end
+	}
+	if nw.err != nil {
+		return 0, nw.err
+	}
+	nw.pending = append(nw.pending, p[start:]...)
+	return len(p), nil
+}
+
+// flushPending escribe los espacios pendientes, seguidos de more, antes de un
+// texto: de los saltos de línea conserva como mucho dos (una línea vacía) y de
+// los espacios solo los de la última línea, que son la sangría del texto
+func (nw *NormalizingWriter) flushPending(more []byte) {
+	ws := append(nw.pending, more...)
+	newlines, lineStart := 0, 0
+	for i, b := range ws {
+		if b == '\n' {
+			newlines++
+			lineStart = i + 1
+		}
+	}
+	switch {
+	case newlines == 1:
+		nw.write([]byte{'\n'})
+	case newlines > 1:
+		nw.write([]byte{'\n', '\n'})
+	}
+	nw.write(ws[lineStart:])
+	nw.pending = nw.pending[:0]
+}
+
+// write escribe p en w si no ha habido errores antes
+func (nw *NormalizingWriter) write(p []byte) {
+	if nw.err == nil && len(p) > 0 {
+		_, nw.err = nw.w.Write(p)
+	}
+}
+
+// Flush descarta los espacios finales y termina la salida con un salto de
+// línea si se escribió algún texto
+func (nw *NormalizingWriter) Flush() error {
+	nw.pending = nw.pending[:0]
+	if nw.written {
+		nw.write([]byte{'\n'})
+		nw.written = false
+	}
+	return nw.err
+}
+
+// NormalizeOutput devuelve output normalizada como en NormalizingWriter
+func NormalizeOutput(output string) string {
+	var normalized bytes.Buffer
+	nw := NewNormalizingWriter(&normalized)
+	nw.Write([]byte(output))
+	nw.Flush()
+	return normalized.String()
+}
@@ -0,0 +1,197 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// raceDelimiter es la línea con la que el detector de carreras abre y cierra cada informe
+const raceDelimiter = "=================="
+
+// raceHeader es la primera línea de un informe de carrera de datos
+const raceHeader = "WARNING: DATA RACE"
+
+// RaceFrame es una llamada de la pila de un acceso en un informe del detector de carreras
+type RaceFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// RaceSection es un bloque de un informe de carrera, por ejemplo
+// "Read at 0x00c0000181c8 by goroutine 8" o "Goroutine 8 (running) created at",
+// con su pila de llamadas
+type RaceSection struct {
+	Title  string      `json:"title"`
+	Frames []RaceFrame `json:"frames"`
+}
+
+// RaceReport es un informe "WARNING: DATA RACE" del detector de carreras
+type RaceReport struct {
+	// Sections son los accesos en conflicto y la creación de sus goroutines, en orden
+	Sections []RaceSection `json:"sections"`
+	// Report es el texto original del informe, sin los delimitadores
+	Report string `json:"report"`
+}
+
+// RaceResult es el resultado de una ejecución con el detector de carreras
+type RaceResult struct {
+	ExecutionResult
+	// Races son los informes de carrera encontrados en stderr
+	Races []RaceReport
+}
+
+// RaceExecutor define el comportamiento de los ejecutores capaces de ejecutar
+// código con el detector de carreras de Go ('go run -race').
+type RaceExecutor interface {
+	ExecuteRace(ctx context.Context, code string, output io.Writer) (RaceResult, error)
+}
+
+// ExecuteRace ejecuta el código con 'go run -race'.
+//
+// La salida se escribe igual que en Execute (stdout y después stderr), salvo
+// que los informes del detector se retiran de stderr y se escriben al final,
+// tras una línea de separación, para no mezclarlos con la salida del programa.
+// stderr usa el límite RaceMaxStderrLength, ya que los informes son extensos.
+//
+// El detector necesita cgo: el servidor debe tener un compilador de C y
+// CGO_ENABLED activo. Un programa con carreras termina con el código 66.
+func (ge *GoExecutor) ExecuteRace(ctx context.Context, code string, output io.Writer) (result RaceResult, err error) {
+	ctx, span := startSpan(ctx, "GoExecutor.ExecuteRace", code)
+	defer func(start time.Time) {
+		endSpan(span, false, time.Since(start), err)
+	}(time.Now())
+
+	result.ExecutionResult = ge.PrepareCode(code)
+	result.ExitCode = -1
+
+	workDir, err := ge.prepareWorkDir(ctx, result.FormattedCode)
+	if err != nil {
+		return result, err
+	}
+	defer ge.removeWorkDir(workDir)
+
+	cmd, err := ge.runCommand(ctx, workDir, "-race")
+	if err != nil {
+		return result, err
+	}
+	cmd.Env = append(cmd.Env, "CGO_ENABLED=1")
+	stdout := &streamCapture{name: "stdout", limit: ge.maxStdoutLength, maxLines: ge.maxOutputLines, live: output}
+	stderr := &streamCapture{name: "stderr", limit: ge.raceStderrLength}
+	waitErr, err := ge.runInto(cmd, stdout, stderr)
+	if err != nil {
+		return result, err
+	}
+
+	// Los informes incluyen la ruta absoluta del directorio temporal
+	stderr.data = bytes.ReplaceAll(stderr.data, []byte(workDir+"/"), []byte("./"))
+	stderr.data = []byte(ge.MapErrors(string(stderr.data), code))
+	rest, reports := splitRaceReports(stderr.data)
+	for _, report := range reports {
+		result.Races = append(result.Races, ParseRaceReport(report))
+	}
+
+	stdout.writeTo(output)
+	remaining := *stderr
+	remaining.data = rest
+	remaining.writeTo(output)
+	if len(reports) > 0 {
+		fmt.Fprintf(output, "\n%s\nDetector de carreras: %d carrera(s) de datos\n%s\n", raceDelimiter, len(reports), raceDelimiter)
+		for _, report := range reports {
+			fmt.Fprintf(output, "%s\n%s\n", report, raceDelimiter)
+		}
+	}
+	ge.recordTruncation(&result.ExecutionResult, stdout, stderr)
+
+	if waitErr != nil {
+		if ctx.Err() != nil {
+			return result, fmt.Errorf("error en la ejecución: %w", ctx.Err())
+		}
+		result.ExitCode = exitCode(waitErr, &remaining)
+		return result, fmt.Errorf("error en la ejecución: %w", waitErr)
+	}
+
+	result.ExitCode = 0
+	return result, nil
+}
+
+// splitRaceReports separa de stderr los informes "WARNING: DATA RACE" completos.
+// Devuelve el resto de stderr y el texto de cada informe sin sus delimitadores.
+// Un informe cortado por el límite de tamaño se deja en el resto.
+func splitRaceReports(stderr []byte) (rest []byte, reports []string) {
+	text := string(stderr)
+	opening := raceDelimiter + "\n" + raceHeader + "\n"
+	var out strings.Builder
+	for {
+		start := strings.Index(text, opening)
+		if start < 0 {
+			break
+		}
+		body := text[start+len(raceDelimiter)+1:]
+		end := strings.Index(body, "\n"+raceDelimiter+"\n")
+		if end < 0 {
+			break
+		}
+		out.WriteString(text[:start])
+		reports = append(reports, body[:end])
+		text = body[end+len(raceDelimiter)+2:]
+	}
+	out.WriteString(text)
+	return []byte(out.String()), reports
+}
+
+// ParseRaceReport interpreta el texto de un informe del detector de carreras
+// (sin los delimitadores), por ejemplo:
+//
+//     WARNING: DATA RACE
+//     Read at 0x00c0000181c8 by goroutine 8:
+//       main.main.func1()
+//           ./main.go:3 +0x2e
+//
+//     Previous write at 0x00c0000181c8 by main goroutine:
+//       main.main()
+//           ./main.go:3 +0xc4
+func ParseRaceReport(report string) RaceReport {
+	result := RaceReport{Report: report}
+	var section *RaceSection
+	var function string
+
+	scanner := bufio.NewScanner(strings.NewReader(report))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || trimmed == raceHeader:
+			section = nil
+		case !strings.HasPrefix(line, " "):
+			// Las líneas sin sangría abren una sección ("... by goroutine N:")
+			result.Sections = append(result.Sections, RaceSection{
+				Title:  strings.TrimSuffix(trimmed, ":"),
+				Frames: []RaceFrame{},
+			})
+			section = &result.Sections[len(result.Sections)-1]
+			function = ""
+		case section == nil:
+		case function == "":
+			function = trimmed
+		default:
+			// "./main.go:3 +0x2e": archivo y línea de la llamada anterior
+			location, _, _ := strings.Cut(trimmed, " ")
+			frame := RaceFrame{Function: function, File: location}
+			if i := strings.LastIndex(location, ":"); i >= 0 {
+				if n, err := strconv.Atoi(location[i+1:]); err == nil {
+					frame.File, frame.Line = location[:i], n
+				}
+			}
+			section.Frames = append(section.Frames, frame)
+			function = ""
+		}
+	}
+	return result
+}
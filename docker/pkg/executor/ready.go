@@ -0,0 +1,32 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// CheckGoExecutable comprueba que el ejecutable de Go sigue existiendo y es
+// ejecutable. Pensado como comprobación de disponibilidad (/readyz): no ejecuta
+// 'go version', que ya se verificó al crear el ejecutor.
+func (ge *GoExecutor) CheckGoExecutable(ctx context.Context) error {
+	if _, err := exec.LookPath(ge.goExecutablePath); err != nil {
+		return fmt.Errorf("ejecutable de Go no disponible: %w", err)
+	}
+	return nil
+}
+
+// CheckTempDir comprueba que se puede escribir en el directorio temporal
+// creando y eliminando un archivo vacío
+func (ge *GoExecutor) CheckTempDir(ctx context.Context) error {
+	file, err := os.CreateTemp(ge.tempDir, ".readyz-*")
+	if err != nil {
+		return fmt.Errorf("directorio temporal no escribible: %w", err)
+	}
+	file.Close()
+	if err := os.Remove(file.Name()); err != nil {
+		return fmt.Errorf("no se pudo eliminar el archivo de prueba del directorio temporal: %w", err)
+	}
+	return nil
+}
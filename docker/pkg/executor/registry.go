@@ -0,0 +1,81 @@
+package executor
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/config"
+)
+
+// DefaultLanguage es el lenguaje de las solicitudes que no indican ninguno
+const DefaultLanguage = "go"
+
+// ExecutorFactory crea el ejecutor de un lenguaje a partir de la configuración
+type ExecutorFactory func(cfg *config.Config) (CodeExecutor, error)
+
+// ExecutorRegistry relaciona cada lenguaje con su ejecutor. El resto del
+// servidor (rate limit, caché, cola, cabeceras de seguridad) no depende del
+// lenguaje, así que añadir uno consiste en registrar su ExecutorFactory.
+//
+// Register e Init se llaman al arrancar; después el registro es de solo
+// lectura y Executor admite uso concurrente.
+type ExecutorRegistry struct {
+	factories map[string]ExecutorFactory
+	executors map[string]CodeExecutor
+}
+
+// NewExecutorRegistry crea un registro de ejecutores vacío
+func NewExecutorRegistry() *ExecutorRegistry {
+	return &ExecutorRegistry{
+		factories: make(map[string]ExecutorFactory),
+		executors: make(map[string]CodeExecutor),
+	}
+}
+
+// Register asocia language con factory. Retorna error si el nombre está vacío
+// o el lenguaje ya estaba registrado.
+//
+// Ejemplo:
+//
+//     registry.Register("go", func(cfg *config.Config) (executor.CodeExecutor, error) {
+//         return executor.NewGoExecutor(executor.GoExecutorOptions{...}, appLogger)
+//     })
+func (r *ExecutorRegistry) Register(language string, factory ExecutorFactory) error {
+	if language == "" {
+		return fmt.Errorf("el nombre del lenguaje no puede estar vacío")
+	}
+	if _, exists := r.factories[language]; exists {
+		return fmt.Errorf("el lenguaje %q ya está registrado", language)
+	}
+	r.factories[language] = factory
+	return nil
+}
+
+// Init crea el ejecutor de cada lenguaje registrado con cfg. Retorna el
+// primer error de una factoría, indicando el lenguaje.
+func (r *ExecutorRegistry) Init(cfg *config.Config) error {
+	for _, language := range r.Languages() {
+		ex, err := r.factories[language](cfg)
+		if err != nil {
+			return fmt.Errorf("error creando el ejecutor de %q: %w", language, err)
+		}
+		r.executors[language] = ex
+	}
+	return nil
+}
+
+// Executor devuelve el ejecutor de language creado por Init
+func (r *ExecutorRegistry) Executor(language string) (CodeExecutor, bool) {
+	ex, ok := r.executors[language]
+	return ex, ok
+}
+
+// Languages devuelve los lenguajes registrados, ordenados por nombre
+func (r *ExecutorRegistry) Languages() []string {
+	languages := make([]string, 0, len(r.factories))
+	for language := range r.factories {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
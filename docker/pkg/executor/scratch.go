@@ -0,0 +1,87 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// scratchDirName es el subdirectorio del directorio de trabajo en el que se
+// ejecuta el programa con ScratchHome
+const scratchDirName = "scratch"
+
+// pinnedGoEnvVars son las variables de Go que dependen de HOME. Con
+// ScratchHome se fijan al arrancar para que 'go run' siga usando la caché de
+// compilación y la configuración del servidor y no las busque en el scratch.
+var pinnedGoEnvVars = []string{"GOCACHE", "GOPATH", "GOENV"}
+
+// pinGoEnv añade a env los valores de pinnedGoEnvVars que no contiene,
+// obtenidos con 'go env' en ese mismo entorno
+func pinGoEnv(goExecutablePath string, env []string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), goVersionTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, goExecutablePath, append([]string{"env"}, pinnedGoEnvVars...)...)
+	cmd.Env = env
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo %s: %w", strings.Join(pinnedGoEnvVars, ", "), err)
+	}
+
+	values := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	if len(values) != len(pinnedGoEnvVars) {
+		return nil, fmt.Errorf("salida inesperada de 'go env': %q", out)
+	}
+	pinned := env
+	for i, key := range pinnedGoEnvVars {
+		if !hasEnvVar(env, key) && values[i] != "" {
+			pinned = append(pinned, key+"="+values[i])
+		}
+	}
+	return pinned, nil
+}
+
+// hasEnvVar indica si env ("CLAVE=valor") contiene la variable key
+func hasEnvVar(env []string, key string) bool {
+	for _, kv := range env {
+		if name, _, _ := strings.Cut(kv, "="); name == key {
+			return true
+		}
+	}
+	return false
+}
+
+// runCommand construye el comando 'go run' del paquete de workDir, con flags
+// antes del paquete.
+//
+// Con ScratchHome el programa se ejecuta en el subdirectorio scratch de
+// workDir, que también es su HOME y su TMPDIR (os.TempDir() y
+// os.UserHomeDir() apuntan a él). Es el único directorio en el que el programa
+// necesita escribir, de modo que el resto del sistema de archivos puede
+// montarse de solo lectura. Se elimina junto con workDir en removeWorkDir.
+func (ge *GoExecutor) runCommand(ctx context.Context, workDir string, flags ...string) (*exec.Cmd, error) {
+	args := append([]string{"run"}, flags...)
+	if !ge.scratchHome {
+		return ge.command(ctx, workDir, append(args, ".")...), nil
+	}
+
+	scratch := filepath.Join(workDir, scratchDirName)
+	if err := os.Mkdir(scratch, 0700); err != nil {
+		return nil, fmt.Errorf("error creando directorio scratch: %w", err)
+	}
+	// Si el hijo se ejecuta con otro usuario, el scratch debe ser suyo
+	if cred := ge.limits.Credential; cred != nil {
+		if err := os.Chown(scratch, int(cred.Uid), int(cred.Gid)); err != nil {
+			return nil, fmt.Errorf("error ajustando el propietario del directorio scratch: %w", err)
+		}
+	}
+
+	cmd := ge.command(ctx, scratch, append(args, "..")...)
+	// Las últimas apariciones sustituyen a las de CHILD_ENV_VARS o del servidor,
+	// y a la de GOTMPDIR, que command calcula a partir de scratch
+	cmd.Env = append(cmd.Env, "HOME="+scratch, "TMPDIR="+scratch,
+		"GOTMPDIR="+filepath.Join(workDir, buildTmpDirName))
+	return cmd, nil
+}
@@ -0,0 +1,74 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+)
+
+// BuildTarget identifica la plataforma destino de una compilación.
+// El valor cero compila para la plataforma del servidor.
+type BuildTarget struct {
+	GOOS   string `json:"goos"`
+	GOARCH string `json:"goarch"`
+}
+
+// ErrUnsupportedTarget indica que la combinación GOOS/GOARCH no está permitida
+var ErrUnsupportedTarget = errors.New("plataforma destino no soportada")
+
+// supportedTargets es la lista de plataformas permitidas para compilación cruzada.
+// Se limita a plataformas que el toolchain compila sin cgo.
+var supportedTargets = []BuildTarget{
+	{GOOS: "linux", GOARCH: "amd64"},
+	{GOOS: "linux", GOARCH: "arm64"},
+	{GOOS: "linux", GOARCH: "arm"},
+	{GOOS: "linux", GOARCH: "386"},
+	{GOOS: "linux", GOARCH: "riscv64"},
+	{GOOS: "darwin", GOARCH: "amd64"},
+	{GOOS: "darwin", GOARCH: "arm64"},
+	{GOOS: "windows", GOARCH: "amd64"},
+	{GOOS: "windows", GOARCH: "arm64"},
+	{GOOS: "freebsd", GOARCH: "amd64"},
+	{GOOS: "js", GOARCH: "wasm"},
+	{GOOS: "wasip1", GOARCH: "wasm"},
+}
+
+// SupportedTargets devuelve una copia de las plataformas permitidas para compilación cruzada
+func SupportedTargets() []BuildTarget {
+	return append([]BuildTarget(nil), supportedTargets...)
+}
+
+// String devuelve la plataforma en formato "goos/goarch"
+func (t BuildTarget) String() string {
+	return t.GOOS + "/" + t.GOARCH
+}
+
+// IsHost indica si el destino es la plataforma del servidor (valor cero)
+func (t BuildTarget) IsHost() bool {
+	return t.GOOS == "" && t.GOARCH == ""
+}
+
+// ValidateTarget comprueba que el destino sea el del servidor o una plataforma
+// de la lista permitida. Retorna un error que envuelve ErrUnsupportedTarget si no.
+func ValidateTarget(t BuildTarget) error {
+	if t.IsHost() {
+		return nil
+	}
+	if t.GOOS == "" || t.GOARCH == "" {
+		return fmt.Errorf("%w: goos y goarch deben indicarse juntos", ErrUnsupportedTarget)
+	}
+	for _, supported := range supportedTargets {
+		if t == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrUnsupportedTarget, t)
+}
+
+// env devuelve las variables de entorno que seleccionan el destino en 'go build'
+func (t BuildTarget) env() []string {
+	if t.IsHost() {
+		return nil
+	}
+	// Sin cgo: los compiladores de C del servidor no generan código para otras plataformas
+	return []string{"GOOS=" + t.GOOS, "GOARCH=" + t.GOARCH, "CGO_ENABLED=0"}
+}
@@ -0,0 +1,40 @@
+package executor
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName identifica los spans creados por este paquete
+const tracerName = "github.com/luis198755/go_playGround_plus/docker/pkg/executor"
+
+// startSpan inicia un span hijo del span que contenga ctx, con el hash del código
+// como atributo. Si ctx no contiene un span válido (no hay tracer activo) no crea
+// nada y devuelve un span no-op.
+func startSpan(ctx context.Context, name, code string) (context.Context, trace.Span) {
+	parent := trace.SpanFromContext(ctx)
+	if !parent.SpanContext().IsValid() {
+		return ctx, noop.Span{}
+	}
+	return parent.TracerProvider().Tracer(tracerName).Start(ctx, name,
+		trace.WithAttributes(attribute.String("code.hash", HashCode(code))),
+	)
+}
+
+// endSpan añade los atributos del resultado de la ejecución y termina el span
+func endSpan(span trace.Span, cached bool, duration time.Duration, err error) {
+	span.SetAttributes(
+		attribute.Bool("cache.hit", cached),
+		attribute.Int64("execution.duration_ms", duration.Milliseconds()),
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,60 @@
+package executor
+
+import (
+	"errors"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"strings"
+)
+
+// CodePreparer es implementado por los ejecutores que pueden transformar el
+// código antes de ejecutarlo (por ejemplo, con AutoWrapCode). Permite a los
+// manejadores mostrar al usuario el código que se ejecutó realmente.
+type CodePreparer interface {
+	PrepareCode(code string) ExecutionResult
+}
+
+// PrepareCode devuelve el código que Execute, Compile y Assembly ejecutarán para code.
+//
+// Si AutoWrapCode está desactivado, o el código ya declara su paquete, el
+// código se devuelve sin cambios. En otro caso se le añade "package main" (y el
+// import de "fmt" si lo usa sin importarlo); si aun así no es un archivo Go
+// válido, las sentencias se envuelven además en func main. Las sentencias
+// sueltas no pueden declarar imports, por lo que solo "fmt" está disponible
+// en ese modo.
+func (ge *GoExecutor) PrepareCode(code string) ExecutionResult {
+	if !ge.autoWrapCode || !missingPackageClause(code) {
+		return ExecutionResult{FormattedCode: code}
+	}
+
+	header := "package main\n\n"
+	if strings.Contains(code, "fmt.") && !strings.Contains(code, `"fmt"`) {
+		header += "import \"fmt\"\n\n"
+	}
+
+	// Primero solo la cabecera, para respetar los imports y funciones del usuario
+	wrapped := header + code
+	if _, err := parser.ParseFile(token.NewFileSet(), mainFileName, wrapped, 0); err != nil {
+		wrapped = header + "func main() {\n" + code + "\n}\n"
+	}
+
+	// Si el código envuelto no compila se devuelve sin formatear y el
+	// compilador informará del error con las líneas del código envuelto
+	if formatted, err := format.Source([]byte(wrapped)); err == nil {
+		wrapped = string(formatted)
+	}
+	return ExecutionResult{FormattedCode: wrapped, Wrapped: true}
+}
+
+// missingPackageClause indica si go/parser rechaza el código tal cual
+// porque le falta la declaración de paquete.
+func missingPackageClause(code string) bool {
+	_, err := parser.ParseFile(token.NewFileSet(), mainFileName, code, parser.PackageClauseOnly)
+	var list scanner.ErrorList
+	if !errors.As(err, &list) || len(list) == 0 {
+		return false
+	}
+	return strings.HasPrefix(list[0].Msg, "expected 'package'")
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/config"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/errors"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/logger"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/quota"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/security"
+	"go.uber.org/zap"
+)
+
+// LimitsReceiver recibe los límites cambiados con PATCH /api/config, que debe
+// aplicar de forma segura para uso concurrente
+type LimitsReceiver interface {
+	SetLimits(limits config.RuntimeLimits)
+}
+
+// LimitsFunc adapta una función a LimitsReceiver, como http.HandlerFunc
+type LimitsFunc func(limits config.RuntimeLimits)
+
+// SetLimits implementa LimitsReceiver
+func (f LimitsFunc) SetLimits(limits config.RuntimeLimits) {
+	f(limits)
+}
+
+// patchableConfigFields son los campos de Config que admite PATCH /api/config
+var patchableConfigFields = []string{"MaxRequestsPerMinute", "MaxCodeLength", "ExecutionTimeout"}
+
+// AdminHandler expone la configuración efectiva del servidor para diagnosticar
+// despliegues y permite cambiar en caliente sus límites. Todas sus rutas
+// exigen "Authorization: Bearer <ADMIN_TOKEN>" y solo deben registrarse si
+// ADMIN_TOKEN está configurado.
+type AdminHandler struct {
+	mu       sync.Mutex // Serializa los cambios de config y su lectura
+	config   *config.Config
+	token    string
+	security security.SecurityValidator
+	quotas   quota.Tracker
+	limits   []LimitsReceiver
+	logger   logger.Logger
+}
+
+// NewAdminHandler crea un nuevo manejador de administración que acepta
+// cfg.AdminToken. quotas es el contador de uso de las claves de API; puede
+// ser nil. limits recibe los límites cambiados con PATCH /api/config.
+func NewAdminHandler(
+	cfg *config.Config,
+	security security.SecurityValidator,
+	quotas quota.Tracker,
+	log logger.Logger,
+	limits ...LimitsReceiver,
+) *AdminHandler {
+	return &AdminHandler{
+		config:   cfg,
+		token:    cfg.AdminToken,
+		security: security,
+		quotas:   quotas,
+		limits:   limits,
+		logger:   log,
+	}
+}
+
+// EnvVarsResponse es la respuesta de /admin/env-vars
+type EnvVarsResponse struct {
+	Variables []config.EnvVar `json:"variables"`
+}
+
+// APIKeysResponse es la respuesta de /admin/api-keys
+type APIKeysResponse struct {
+	// WindowSeconds es la ventana móvil sobre la que se cuenta el uso
+	WindowSeconds int64         `json:"window_seconds"`
+	Keys          []quota.Usage `json:"keys"`
+}
+
+// HandleConfig devuelve la configuración efectiva (tras la validación) como
+// JSON, con los valores sensibles ocultos
+func (h *AdminHandler) HandleConfig(w http.ResponseWriter, r *http.Request) {
+	reqLogger, ok := h.authorize(w, r, http.MethodGet)
+	if !ok {
+		return
+	}
+	h.mu.Lock()
+	redacted := h.config.Redacted()
+	h.mu.Unlock()
+	h.writeJSON(w, reqLogger, redacted)
+}
+
+// HandlePatchConfig cambia en caliente los límites de la configuración
+// (config.RuntimeLimits) con un JSON parcial de Config, por ejemplo
+// {"MaxRequestsPerMinute": 10}, y devuelve la configuración efectiva completa,
+// igual que HandleConfig. Cualquier otro campo se rechaza: el resto de la
+// configuración solo se lee al arrancar. Si algún valor no es válido no se
+// cambia nada.
+func (h *AdminHandler) HandlePatchConfig(w http.ResponseWriter, r *http.Request) {
+	reqLogger, ok := h.authorize(w, r, http.MethodPatch)
+	if !ok {
+		return
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		err := errors.BadRequest(
+			errors.New("content-type inválido"),
+			"Content-Type debe ser application/json",
+			map[string]interface{}{"content_type": r.Header.Get("Content-Type")},
+		)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+
+	defer r.Body.Close()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		errors.HTTPError(w, r, reqLogger, errors.BadRequest(err, "No se pudo leer la solicitud", nil))
+		return
+	}
+	// Primero solo los nombres, para rechazar los campos que no se pueden cambiar
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		errors.HTTPError(w, r, reqLogger, errors.BadRequest(err, "Formato de solicitud inválido", nil))
+		return
+	}
+	for name := range fields {
+		if !slices.Contains(patchableConfigFields, name) {
+			err := errors.BadRequest(
+				errors.New("campo no modificable"),
+				"El campo "+name+" no se puede cambiar sin reiniciar el servidor",
+				map[string]interface{}{"field": name, "patchable_fields": patchableConfigFields},
+			)
+			errors.HTTPError(w, r, reqLogger, err)
+			return
+		}
+	}
+	var patch config.LimitsPatch
+	if err := json.Unmarshal(body, &patch); err != nil {
+		errors.HTTPError(w, r, reqLogger, errors.BadRequest(err, "Formato de solicitud inválido", nil))
+		return
+	}
+
+	h.mu.Lock()
+	previous := h.config.RuntimeLimits()
+	limits, err := h.config.ApplyLimits(patch)
+	if err == nil {
+		for _, receiver := range h.limits {
+			receiver.SetLimits(limits)
+		}
+	}
+	redacted := h.config.Redacted()
+	h.mu.Unlock()
+	if err != nil {
+		errors.HTTPError(w, r, reqLogger, errors.BadRequest(err, err.Error(), nil))
+		return
+	}
+
+	reqLogger.Info("Límites cambiados en caliente",
+		zap.Any("previous", previous),
+		zap.Any("current", limits))
+	h.writeJSON(w, reqLogger, redacted)
+}
+
+// HandleEnvVars devuelve las variables de entorno que lee el servidor, con su
+// valor y si salen del entorno o se usa el valor por defecto
+func (h *AdminHandler) HandleEnvVars(w http.ResponseWriter, r *http.Request) {
+	reqLogger, ok := h.authorize(w, r, http.MethodGet)
+	if !ok {
+		return
+	}
+	h.writeJSON(w, reqLogger, EnvVarsResponse{Variables: config.EnvVars()})
+}
+
+// HandleAPIKeys devuelve el uso de cada clave de API con actividad en la
+// ventana de cuotas: ejecuciones, tiempo de CPU, tiempo real y cuota
+func (h *AdminHandler) HandleAPIKeys(w http.ResponseWriter, r *http.Request) {
+	reqLogger, ok := h.authorize(w, r, http.MethodGet)
+	if !ok {
+		return
+	}
+	resp := APIKeysResponse{
+		WindowSeconds: int64(quota.Window.Seconds()),
+		Keys:          []quota.Usage{},
+	}
+	if h.quotas != nil {
+		resp.Keys = h.quotas.Snapshot()
+	}
+	h.writeJSON(w, reqLogger, resp)
+}
+
+// authorize comprueba que el método sea method y el token de administración.
+// Si la solicitud no es válida responde con el error y devuelve false.
+func (h *AdminHandler) authorize(w http.ResponseWriter, r *http.Request, method string) (logger.Logger, bool) {
+	reqLogger := h.logger.With(
+		zap.String("client_ip", h.security.GetClientIP(r)),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+	)
+
+	if r.Method != method {
+		err := errors.WithContext(
+			errors.New("método no permitido"),
+			http.StatusMethodNotAllowed,
+			"Método no permitido",
+			map[string]interface{}{"method": r.Method},
+		)
+		errors.HTTPError(w, r, reqLogger, err)
+		return reqLogger, false
+	}
+
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		reqLogger.Warn("Acceso de administración rechazado")
+		w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+		err := errors.Unauthorized(
+			errors.New("token de administración inválido"),
+			"Se requiere un token de administración válido",
+			nil,
+		)
+		errors.HTTPError(w, r, reqLogger, err)
+		return reqLogger, false
+	}
+	return reqLogger, true
+}
+
+// writeJSON envía v como JSON sin permitir que se almacene en cachés intermedias
+func (h *AdminHandler) writeJSON(w http.ResponseWriter, reqLogger logger.Logger, v interface{}) {
+	h.security.SetSecurityHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		reqLogger.Error("Error al codificar respuesta JSON", zap.Error(err))
+	}
+}
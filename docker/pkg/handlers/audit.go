@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/audit"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/executor"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/requestctx"
+)
+
+// auditStart registra en el registro de auditoría, si lo hay, que empieza la
+// ejecución de code con ex, y devuelve la ejecución para pasarla a auditFinish.
+// ctx debe ser el de la ejecución, que puede llevar la hora simulada incluida
+// en el hash.
+func (h *APIHandler) auditStart(ctx context.Context, r *http.Request, ex executor.CodeExecutor, language, code string) audit.Execution {
+	if h.audit == nil {
+		return audit.Execution{}
+	}
+	execution := audit.Execution{
+		RequestID: requestctx.RequestID(ctx),
+		ClientIP:  h.security.GetClientIP(r),
+		APIKeyID:  requestctx.APIKeyID(r.Context()),
+		Endpoint:  r.URL.Path,
+		Language:  language,
+		CodeHash:  executor.CodeHash(ctx, ex, code),
+		Code:      code,
+	}
+	h.audit.ExecutionStarted(execution)
+	return execution
+}
+
+// auditFinish registra el resultado de una ejecución iniciada con auditStart
+// en start
+func (h *APIHandler) auditFinish(execution audit.Execution, start time.Time, result executor.ExecutionResult, err error) {
+	if h.audit == nil {
+		return
+	}
+	h.audit.ExecutionFinished(execution, audit.Outcome{
+		Duration: time.Since(start),
+		ExitCode: result.ExitCode,
+		Cached:   result.Cached,
+		Err:      err,
+	})
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// ExecutionRegistry guarda la función de cancelación de cada ejecución en
+// curso, indexada por su ID de solicitud (la cabecera X-Request-ID).
+type ExecutionRegistry struct {
+	executions sync.Map // requestID -> registeredExecution
+}
+
+// registeredExecution es una ejecución en curso y el cliente que la inició
+type registeredExecution struct {
+	clientIP string
+	cancel   context.CancelFunc
+}
+
+// NewExecutionRegistry crea un registro de ejecuciones vacío
+func NewExecutionRegistry() *ExecutionRegistry {
+	return &ExecutionRegistry{}
+}
+
+// Register guarda cancel para la ejecución requestID iniciada desde clientIP.
+// Devuelve la función que la elimina del registro al terminar.
+func (er *ExecutionRegistry) Register(requestID, clientIP string, cancel context.CancelFunc) func() {
+	er.executions.Store(requestID, registeredExecution{clientIP: clientHost(clientIP), cancel: cancel})
+	return func() {
+		er.executions.Delete(requestID)
+	}
+}
+
+// Cancel cancela la ejecución requestID si sigue en curso y la inició clientIP.
+// Devuelve false si no existe: nunca se distingue una ejecución de otro cliente
+// de una inexistente, para no revelar qué IDs están en uso.
+func (er *ExecutionRegistry) Cancel(requestID, clientIP string) bool {
+	value, ok := er.executions.Load(requestID)
+	if !ok {
+		return false
+	}
+	execution := value.(registeredExecution)
+	if execution.clientIP != clientHost(clientIP) {
+		return false
+	}
+	execution.cancel()
+	return true
+}
+
+// clientHost quita el puerto de la IP del cliente si lo tiene. Sin proxy,
+// GetClientIP devuelve RemoteAddr con el puerto, que cambia entre la conexión
+// de la ejecución y la de la cancelación.
+func clientHost(clientIP string) string {
+	if host, _, err := net.SplitHostPort(clientIP); err == nil {
+		return host
+	}
+	return clientIP
+}
+
+// CancelRequest es el cuerpo de /api/execute/cancel
+type CancelRequest struct {
+	RequestID string `json:"requestId"`
+}
+
+// CancelResponse es la respuesta de /api/execute/cancel
+type CancelResponse struct {
+	RequestID string `json:"requestId"`
+	Cancelled bool   `json:"cancelled"`
+}
+
+// HandleCancelExecution cancela una ejecución en curso de /api/execute a partir
+// de su ID de solicitud. Solo el cliente (IP) que la inició puede cancelarla.
+// Responde 200 si se canceló y 404 si no existe o ya terminó.
+func (h *APIHandler) HandleCancelExecution(w http.ResponseWriter, r *http.Request) {
+	reqLogger := h.logger.With(
+		zap.String("client_ip", h.security.GetClientIP(r)),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+	)
+
+	if r.Method != http.MethodPost {
+		err := errors.WithContext(
+			errors.New("método no permitido"),
+			http.StatusMethodNotAllowed,
+			"Método no permitido",
+			map[string]interface{}{"method": r.Method},
+		)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		err := errors.BadRequest(
+			errors.New("content-type inválido"),
+			"Content-Type debe ser application/json",
+			map[string]interface{}{"content_type": r.Header.Get("Content-Type")},
+		)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+
+	defer r.Body.Close()
+	var cancelReq CancelRequest
+	if err := json.NewDecoder(r.Body).Decode(&cancelReq); err != nil || cancelReq.RequestID == "" {
+		err := errors.BadRequest(
+			errors.New("solicitud de cancelación inválida"),
+			"Se requiere requestId",
+			nil,
+		)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+
+	reqLogger = reqLogger.With(zap.String("request_id", cancelReq.RequestID))
+	if !h.executions.Cancel(cancelReq.RequestID, h.security.GetClientIP(r)) {
+		err := errors.NotFound(
+			errors.New("ejecución no encontrada"),
+			"No hay ninguna ejecución en curso con ese ID",
+			map[string]interface{}{"request_id": cancelReq.RequestID},
+		)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+	reqLogger.Info("Ejecución cancelada por el cliente")
+
+	h.security.SetSecurityHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	resp := CancelResponse{RequestID: cancelReq.RequestID, Cancelled: true}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		reqLogger.Error("Error al codificar respuesta JSON", zap.Error(err))
+	}
+}
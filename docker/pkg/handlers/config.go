@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/config"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/errors"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/executor"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/logger"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/security"
+	"go.uber.org/zap"
+)
+
+// ClientConfig es el subconjunto de la configuración que se expone al frontend.
+//
+// Se construye campo a campo a partir de Config para no publicar nunca rutas,
+// credenciales ni detalles internos del servidor: cualquier campo nuevo debe
+// añadirse aquí de forma explícita.
+type ClientConfig struct {
+	MaxCodeLength           int                    `json:"max_code_length"`
+	MaxASTNodes             int                    `json:"max_ast_nodes"`
+	MaxStringLiteralBytes   int                    `json:"max_string_literal_bytes"`
+	MaxOutputLength         int                    `json:"max_output_length"`
+	MaxOutputLines          int                    `json:"max_output_lines"`
+	ExecutionTimeoutSeconds float64                `json:"execution_timeout_seconds"`
+	MaxRequestsPerMinute    int                    `json:"max_requests_per_minute"`
+	ThirdPartyModules       bool                   `json:"third_party_modules"`
+	AutoWrapCode            bool                   `json:"auto_wrap_code"`
+	RaceDetector            bool                   `json:"race_detector"`
+	ShareImport             bool                   `json:"share_import"`
+	CompileTargets          []executor.BuildTarget `json:"compile_targets"`
+}
+
+// NewClientConfig extrae de cfg los límites relevantes para el cliente
+func NewClientConfig(cfg *config.Config) ClientConfig {
+	return ClientConfig{
+		MaxCodeLength:           cfg.MaxCodeLength,
+		MaxASTNodes:             cfg.MaxASTNodes,
+		MaxStringLiteralBytes:   cfg.MaxStringLiteralBytes,
+		MaxOutputLength:         cfg.MaxOutputLength,
+		MaxOutputLines:          cfg.MaxOutputLines,
+		ExecutionTimeoutSeconds: cfg.ExecutionTimeout.Seconds(),
+		MaxRequestsPerMinute:    cfg.MaxRequestsPerMinute,
+		// El go.mod del directorio de trabajo no tiene dependencias, por lo que solo
+		// está disponible la biblioteca estándar
+		ThirdPartyModules: false,
+		AutoWrapCode:      cfg.AutoWrapCode,
+		RaceDetector:      cfg.RaceDetectorEnabled,
+		ShareImport:       cfg.ShareImportURL != "",
+		CompileTargets:    executor.SupportedTargets(),
+	}
+}
+
+// ConfigHandler expone la configuración saneada del servidor y su política de imports
+type ConfigHandler struct {
+	config   atomic.Pointer[ClientConfig] // Se reemplaza entera en SetLimits
+	security security.SecurityValidator
+	logger   logger.Logger
+}
+
+// NewConfigHandler crea un nuevo manejador de configuración
+func NewConfigHandler(
+	cfg *config.Config,
+	security security.SecurityValidator,
+	log logger.Logger,
+) *ConfigHandler {
+	h := &ConfigHandler{
+		security: security,
+		logger:   log,
+	}
+	clientConfig := NewClientConfig(cfg)
+	h.config.Store(&clientConfig)
+	return h
+}
+
+// SetLimits actualiza los límites que se publican en /api/config tras un
+// PATCH /api/config
+func (h *ConfigHandler) SetLimits(limits config.RuntimeLimits) {
+	clientConfig := *h.config.Load()
+	clientConfig.MaxCodeLength = limits.MaxCodeLength
+	clientConfig.ExecutionTimeoutSeconds = limits.ExecutionTimeout.Seconds()
+	clientConfig.MaxRequestsPerMinute = limits.MaxRequestsPerMinute
+	h.config.Store(&clientConfig)
+}
+
+// ImportsResponse es la respuesta de /api/imports
+type ImportsResponse struct {
+	Language string `json:"language"`
+	security.ImportPolicy
+}
+
+// HandleConfig devuelve la configuración saneada como JSON
+func (h *ConfigHandler) HandleConfig(w http.ResponseWriter, r *http.Request) {
+	h.serveJSON(w, r, h.config.Load())
+}
+
+// HandleImports devuelve como JSON la política de imports del lenguaje del
+// parámetro "language" (por defecto Go), para que el frontend avise de los
+// paquetes prohibidos antes de ejecutar el código
+func (h *ConfigHandler) HandleImports(w http.ResponseWriter, r *http.Request) {
+	language := r.URL.Query().Get("language")
+	if language == "" {
+		language = executor.DefaultLanguage
+	}
+	h.serveJSON(w, r, ImportsResponse{
+		Language:     language,
+		ImportPolicy: h.security.ImportPolicy(language),
+	})
+}
+
+// serveJSON responde a una solicitud GET con v como JSON
+func (h *ConfigHandler) serveJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	reqLogger := h.logger.With(
+		zap.String("client_ip", h.security.GetClientIP(r)),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+	)
+
+	if r.Method != http.MethodGet {
+		err := errors.WithContext(
+			errors.New("método no permitido"),
+			http.StatusMethodNotAllowed,
+			"Método no permitido",
+			map[string]interface{}{"method": r.Method},
+		)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+
+	h.security.SetSecurityHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	// Los límites pueden cambiar con PATCH /api/config, así que la caché es corta
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		reqLogger.Error("Error al codificar respuesta JSON", zap.Error(err))
+	}
+}
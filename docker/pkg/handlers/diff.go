@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/errors"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/executor"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/requestctx"
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"go.uber.org/zap"
+)
+
+// diffContextLines es el número de líneas sin cambios que rodean cada bloque del diff
+const diffContextLines = 3
+
+// DiffRequest es la solicitud de /api/diff
+type DiffRequest struct {
+	CodeA string `json:"codeA"`
+	CodeB string `json:"codeB"`
+}
+
+// DiffResponse es la respuesta de /api/diff. Diff es un diff unificado de la
+// salida de CodeA a la de CodeB, vacío si son iguales.
+type DiffResponse struct {
+	Same bool   `json:"same"`
+	Diff string `json:"diff"`
+}
+
+// HandleDiff ejecuta dos fragmentos de código y compara sus salidas, por ejemplo
+// para comprobar que una refactorización no cambia el resultado. Los dos
+// fragmentos se ejecutan uno tras otro con un único timeout compartido y pasan
+// por el caché de ejecución; el diff en sí no se guarda. Para el rate limit la
+// solicitud cuenta como dos ejecuciones.
+func (h *APIHandler) HandleDiff(w http.ResponseWriter, r *http.Request) {
+	requestID := requestctx.NewRequestID()
+	w.Header().Set("X-Request-ID", requestID)
+
+	reqLogger := h.logger.With(
+		zap.String("request_id", requestID),
+		zap.String("client_ip", h.security.GetClientIP(r)),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+	)
+
+	var diffReq DiffRequest
+	if !h.readJSONRequest(w, r, reqLogger, 2, &diffReq) {
+		return
+	}
+
+	snippets := []struct {
+		name string
+		code string
+	}{
+		{"codeA", diffReq.CodeA},
+		{"codeB", diffReq.CodeB},
+	}
+	for _, snippet := range snippets {
+		if msg := h.validateCode(snippet.code, reqLogger); msg != "" {
+			err := errors.BadRequest(errors.New("código inválido"), msg, map[string]interface{}{
+				"snippet": snippet.name,
+			}).WithCode(errors.CodeInvalidCode)
+			errors.HTTPError(w, r, reqLogger, err)
+			return
+		}
+	}
+
+	// Cada fragmento cuenta como una ejecución
+	if !h.acquireQuota(w, r, reqLogger, len(snippets)) {
+		return
+	}
+
+	ctx := requestctx.WithRequestID(context.Background(), requestID)
+	ctx = requestctx.WithClientIP(ctx, h.security.GetClientIP(r))
+
+	// Los dos fragmentos ocupan una sola plaza de la cola
+	if h.queue != nil {
+		release, _, ok := h.waitInQueue(ctx, w, r, reqLogger, true)
+		if !ok {
+			return
+		}
+		defer release()
+	}
+
+	timeout := h.currentExecutionTimeout()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	reqLogger.Info("Comparando la salida de dos fragmentos",
+		zap.Int("code_a_length", len(diffReq.CodeA)),
+		zap.Int("code_b_length", len(diffReq.CodeB)),
+		zap.Duration("timeout", timeout),
+	)
+
+	outputs := make([]string, len(snippets))
+	for i, snippet := range snippets {
+		var output bytes.Buffer
+		start := time.Now()
+		execution := h.auditStart(ctx, r, h.executor, executor.DefaultLanguage, snippet.code)
+		result, err := executor.RunWithResult(ctx, h.executor, snippet.code, &output)
+		h.auditFinish(execution, start, result, err)
+		if h.outcomes != nil {
+			h.outcomes.RecordExecution(err)
+		}
+		h.recordUsage(r, result.CPUTime, time.Since(start))
+		if err != nil {
+			if isInternalError(err) {
+				errors.ReportError(r, requestID, err)
+			}
+			reqLogger.Warn("Error al ejecutar un fragmento del diff",
+				zap.String("snippet", snippet.name),
+				zap.Error(err))
+			appErr := capacityError(err)
+			if appErr == nil {
+				appErr = errors.WithContext(err, http.StatusUnprocessableEntity,
+					fmt.Sprintf("La ejecución de %s falló", snippet.name), nil)
+			}
+			appErr.Context = map[string]interface{}{"snippet": snippet.name, "error": err.Error()}
+			errors.HTTPError(w, r, reqLogger, appErr)
+			return
+		}
+		outputs[i] = output.String()
+		if h.normalizeOutput {
+			outputs[i] = executor.NormalizeOutput(outputs[i])
+		}
+	}
+
+	resp := DiffResponse{Same: outputs[0] == outputs[1]}
+	if !resp.Same {
+		resp.Diff = unifiedDiff(outputs[0], outputs[1])
+	}
+	reqLogger.Info("Diff de salidas calculado", zap.Bool("same", resp.Same))
+
+	h.security.SetSecurityHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		reqLogger.Error("Error al codificar respuesta JSON", zap.Error(err))
+	}
+}
+
+// diffLine es una línea del diff con su operación
+type diffLine struct {
+	op   diffmatchpatch.Operation
+	text string
+}
+
+// unifiedDiff devuelve el diff unificado línea a línea de a a b, con
+// diffContextLines líneas de contexto y sin cabeceras de archivo
+func unifiedDiff(a, b string) string {
+	dmp := diffmatchpatch.New()
+	charsA, charsB, lines := dmp.DiffLinesToChars(a, b)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(charsA, charsB, false), lines)
+
+	var all []diffLine
+	for _, d := range diffs {
+		for _, text := range strings.SplitAfter(d.Text, "\n") {
+			if text != "" {
+				all = append(all, diffLine{op: d.Type, text: text})
+			}
+		}
+	}
+
+	// posA[i] y posB[i] son las líneas de a y de b anteriores a all[i]
+	posA := make([]int, len(all)+1)
+	posB := make([]int, len(all)+1)
+	var changes []int
+	for i, line := range all {
+		posA[i+1], posB[i+1] = posA[i], posB[i]
+		if line.op != diffmatchpatch.DiffInsert {
+			posA[i+1]++
+		}
+		if line.op != diffmatchpatch.DiffDelete {
+			posB[i+1]++
+		}
+		if line.op != diffmatchpatch.DiffEqual {
+			changes = append(changes, i)
+		}
+	}
+
+	var sb strings.Builder
+	for k := 0; k < len(changes); {
+		// Un bloque agrupa los cambios separados por hasta 2*diffContextLines
+		// líneas iguales, con diffContextLines de contexto a cada lado
+		first, last := changes[k], changes[k]
+		for k++; k < len(changes) && changes[k]-last-1 <= 2*diffContextLines; k++ {
+			last = changes[k]
+		}
+		start := max(first-diffContextLines, 0)
+		end := min(last+diffContextLines+1, len(all))
+
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n",
+			hunkRange(posA[start], posA[end]-posA[start]),
+			hunkRange(posB[start], posB[end]-posB[start]))
+		for _, line := range all[start:end] {
+			switch line.op {
+			case diffmatchpatch.DiffDelete:
+				sb.WriteString("-")
+			case diffmatchpatch.DiffInsert:
+				sb.WriteString("+")
+			default:
+				sb.WriteString(" ")
+			}
+			sb.WriteString(line.text)
+			if !strings.HasSuffix(line.text, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+// hunkRange formatea el rango de un bloque del diff como diff -u: "inicio" si
+// tiene una línea, "inicio,n" si tiene varias y "inicio-1,0" si está vacío
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/errors"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/executor"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/requestctx"
+	"go.uber.org/zap"
+)
+
+// TestResponse es la respuesta JSON de /api/test
+type TestResponse struct {
+	Success bool                 `json:"success"`
+	Tests   []*executor.TestCase `json:"tests"`
+	Output  string               `json:"output"`
+	// Truncated indica que faltan salida o tests por superar MaxStdoutLength
+	Truncated bool   `json:"truncated,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// HandleTest ejecuta los tests del código con 'go test -json' y devuelve el
+// árbol de tests y subtests con su estado, duración y salida. Igual que los
+// benchmarks, los tests no se cachean ni se deduplican y usan el timeout de
+// benchmarks.
+func (h *APIHandler) HandleTest(w http.ResponseWriter, r *http.Request) {
+	requestID := requestctx.NewRequestID()
+	w.Header().Set("X-Request-ID", requestID)
+
+	reqLogger := h.logger.With(
+		zap.String("request_id", requestID),
+		zap.String("client_ip", h.security.GetClientIP(r)),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+	)
+
+	codeReq, ok := h.readCodeRequest(w, r, reqLogger)
+	if !ok {
+		return
+	}
+
+	if msg := h.validateCode(codeReq.Code, reqLogger); msg != "" {
+		err := errors.BadRequest(errors.New("código inválido"), msg, nil).WithCode(errors.CodeInvalidCode)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+
+	if !codeReq.target().IsHost() {
+		err := errors.BadRequest(
+			errors.New("plataforma destino no soportada"),
+			"goos/goarch solo se admiten en /api/compile",
+			nil,
+		)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+
+	tester, ok := h.executor.(executor.Tester)
+	if !ok {
+		err := errors.InternalServerError(
+			errors.New("tests no soportados"),
+			"El ejecutor no soporta tests",
+			nil,
+		)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+
+	if !h.acquireQuota(w, r, reqLogger, 1) {
+		return
+	}
+
+	ctx := requestctx.WithRequestID(context.Background(), requestID)
+	ctx = requestctx.WithClientIP(ctx, h.security.GetClientIP(r))
+	ctx, cancel := context.WithTimeout(ctx, h.benchmarkTimeout)
+	defer cancel()
+
+	reqLogger.Info("Ejecutando tests",
+		zap.Int("code_length", len(codeReq.Code)),
+		zap.Duration("timeout", h.benchmarkTimeout),
+	)
+
+	start := time.Now()
+	execution := h.auditStart(ctx, r, h.executor, executor.DefaultLanguage, codeReq.Code)
+	report, err := tester.Test(ctx, codeReq.Code)
+	h.auditFinish(execution, start, goTestResult(report.Passed, err), err)
+	h.recordUsage(r, 0, time.Since(start))
+	resp := TestResponse{
+		Success:   report.Passed,
+		Tests:     report.Tests,
+		Output:    report.Output,
+		Truncated: report.Truncated,
+	}
+	if err != nil {
+		if appErr := capacityError(err); appErr != nil {
+			errors.HTTPError(w, r, reqLogger, appErr)
+			return
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			reqLogger.Error("Error al ejecutar tests", zap.Error(err))
+			appErr := errors.InternalServerError(err, "Error al ejecutar los tests", nil)
+			errors.HTTPError(w, r, reqLogger, appErr)
+			return
+		}
+		// Se devuelven los tests obtenidos hasta entonces; los que no
+		// terminaron quedan con estado "run"
+		reqLogger.Warn("Tiempo de tests agotado", zap.Duration("timeout", h.benchmarkTimeout))
+		resp.Success = false
+		resp.Error = fmt.Sprintf("Tiempo de ejecución agotado (%v)", h.benchmarkTimeout)
+	}
+	if resp.Tests == nil {
+		resp.Tests = []*executor.TestCase{}
+	}
+
+	h.security.SetSecurityHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		reqLogger.Error("Error al codificar respuesta JSON", zap.Error(err))
+	}
+}
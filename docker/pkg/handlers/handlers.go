@@ -1,129 +1,1008 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	iofs "io/fs"
+	"math"
 	"net/http"
+	"path"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/luis198755/go_playGround_plus/docker/pkg/audit"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/config"
 	"github.com/luis198755/go_playGround_plus/docker/pkg/errors"
 	"github.com/luis198755/go_playGround_plus/docker/pkg/executor"
 	"github.com/luis198755/go_playGround_plus/docker/pkg/limiter"
 	"github.com/luis198755/go_playGround_plus/docker/pkg/logger"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/middleware"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/notifications"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/queue"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/quota"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/requestctx"
 	"github.com/luis198755/go_playGround_plus/docker/pkg/security"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/session"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/share"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/telemetry"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
 // CodeRequest representa la solicitud de ejecución de código
 type CodeRequest struct {
 	Code string `json:"code"`
+	// Language es el lenguaje del código en /api/execute (por defecto "go")
+	Language string `json:"language,omitempty"`
+	// GOOS y GOARCH seleccionan la plataforma destino; solo se admiten en /api/compile
+	GOOS   string `json:"goos,omitempty"`
+	GOARCH string `json:"goarch,omitempty"`
+	// StatusSentinel añade al final de /api/execute el resumen tras ResultSentinel
+	StatusSentinel bool `json:"status_sentinel,omitempty"`
+	// Race ejecuta /api/execute con el detector de carreras ('go run -race')
+	Race bool `json:"race,omitempty"`
+	// FakeTime (RFC 3339) fija la hora que devuelve time.Now en /api/execute,
+	// para que la salida de los programas que la imprimen sea reproducible
+	FakeTime string `json:"fakeTime,omitempty"`
+	// Checksum añade al resumen de /api/execute el SHA-256 de la salida normalizada
+	Checksum bool `json:"checksum,omitempty"`
+}
+
+// language devuelve el lenguaje solicitado, executor.DefaultLanguage si no se indicó
+func (c CodeRequest) language() string {
+	if c.Language == "" {
+		return executor.DefaultLanguage
+	}
+	return c.Language
+}
+
+// target devuelve la plataforma destino solicitada (valor cero si no se indicó)
+func (c CodeRequest) target() executor.BuildTarget {
+	return executor.BuildTarget{GOOS: c.GOOS, GOARCH: c.GOARCH}
+}
+
+// tracerName identifica los spans creados por este paquete
+const tracerName = "github.com/luis198755/go_playGround_plus/docker/pkg/handlers"
+
+// sessionCookieName es el nombre de la cookie que identifica la sesión del navegador
+const sessionCookieName = "session_id"
+
+// historyResponseLimit es el número máximo de ejecuciones devueltas por /api/history
+const historyResponseLimit = 20
+
+// executionResultTrailer es el trailer HTTP de /api/execute con el ExecutionStatus en JSON
+const executionResultTrailer = "X-Execution-Result"
+
+// ResultSentinel separa la salida del programa del ExecutionStatus en JSON cuando
+// la solicitud pide status_sentinel. Los navegadores no exponen los trailers HTTP
+// a fetch, así que el frontend divide el cuerpo por el último ResultSentinel.
+const ResultSentinel = "\x1e"
+
+// ExecutionStatus resume cómo terminó una ejecución de /api/execute
+type ExecutionStatus struct {
+	ExitCode   int   `json:"exitCode"`
+	DurationMs int64 `json:"durationMs"`
+	Truncated  bool  `json:"truncated"`
+	// DataRaces es el número de carreras detectadas; solo se informa con "race": true
+	DataRaces int `json:"dataRaces,omitempty"`
+	// OutputSHA256 es el checksum de la salida (ver executor.OutputHasher);
+	// solo se informa con "checksum": true
+	OutputSHA256 string `json:"outputSha256,omitempty"`
 }
 
 // Handler define el comportamiento para los manejadores HTTP
 type Handler interface {
 	HandleExecuteCode(w http.ResponseWriter, r *http.Request)
+	HandleCompile(w http.ResponseWriter, r *http.Request)
+	HandleAssembly(w http.ResponseWriter, r *http.Request)
+	HandleBenchmark(w http.ResponseWriter, r *http.Request)
+	HandleTest(w http.ResponseWriter, r *http.Request)
+	HandleDiff(w http.ResponseWriter, r *http.Request)
+	HandleCancelExecution(w http.ResponseWriter, r *http.Request)
+	HandleImportShare(w http.ResponseWriter, r *http.Request)
+	HandleEncodeShareURL(w http.ResponseWriter, r *http.Request)
+	HandleSharedURL(w http.ResponseWriter, r *http.Request)
 	HandleStaticFiles(w http.ResponseWriter, r *http.Request)
+	HandleHistory(w http.ResponseWriter, r *http.Request)
 }
 
 // APIHandler implementa los manejadores HTTP para la API
 type APIHandler struct {
 	limiter          limiter.RateLimiterInterface
+	globalLimiter    limiter.GlobalLimiter
 	security         security.SecurityValidator
 	executor         executor.CodeExecutor
+	executors        *executor.ExecutorRegistry
+	sessions         session.HistoryStore
 	logger           logger.Logger
-	maxCodeLength    int
-	executionTimeout time.Duration
+	maxCodeLength    atomic.Int32 // Cambia en caliente con SetLimits
+	maxASTNodes      int
+	maxLiteralBytes  int
+	maxOutputLength  int
+	executionTimeout atomic.Int64 // time.Duration; cambia en caliente con SetLimits
+	benchmarkTimeout time.Duration
+	raceTimeout      time.Duration
+	importer         share.SnippetImporter
+	queue            queue.ExecutionQueue
+	outcomes         notifications.ExecutionRecorder
+	quotas           quota.Tracker
+	audit            audit.Recorder
+	normalizeOutput  bool
+	executions       *ExecutionRegistry
 }
 
-// NewAPIHandler crea un nuevo manejador de API
+// NewAPIHandler crea un nuevo manejador de API.
+// globalLimiter acota las ejecuciones de todos los clientes juntos después del
+// rate limit por cliente; con nil no hay límite global.
+// executors debe incluir executor.DefaultLanguage, que es el que usan todos
+// los endpoints salvo /api/execute, donde se elige con "language".
+// maxLiteralBytes limita el tamaño de cada cadena literal del código Go
+// (0 = sin límite). maxOutputLength limita los bytes de salida del programa en las respuestas en
+// streaming de /api/execute, además de los límites del ejecutor (0 = sin límite).
+// raceTimeout es el timeout de las ejecuciones con el detector de carreras;
+// 0 rechaza las solicitudes con "race": true. Con importer nil no se admite
+// la importación de enlaces compartidos del playground oficial. Con
+// executionQueue nil las ejecuciones no esperan turno en ninguna cola. outcomes
+// recibe el resultado de cada ejecución; puede ser nil. quotas cuenta el uso de
+// las solicitudes autenticadas con una clave de API y aplica su cuota; con nil
+// no se cuenta nada. auditor recibe el inicio y el final de cada ejecución
+// para el registro de auditoría; puede ser nil. Con normalizeOutput la salida de /api/execute y
+// /api/diff pasa por executor.NormalizingWriter.
 func NewAPIHandler(
 	limiter limiter.RateLimiterInterface,
+	globalLimiter limiter.GlobalLimiter,
 	security security.SecurityValidator,
-	executor executor.CodeExecutor,
+	executors *executor.ExecutorRegistry,
+	sessions session.HistoryStore,
 	log logger.Logger,
 	maxCodeLength int,
+	maxASTNodes int,
+	maxLiteralBytes int,
+	maxOutputLength int,
 	executionTimeout time.Duration,
+	benchmarkTimeout time.Duration,
+	raceTimeout time.Duration,
+	importer share.SnippetImporter,
+	executionQueue queue.ExecutionQueue,
+	outcomes notifications.ExecutionRecorder,
+	quotas quota.Tracker,
+	auditor audit.Recorder,
+	normalizeOutput bool,
 ) *APIHandler {
-	return &APIHandler{
+	defaultExecutor, _ := executors.Executor(executor.DefaultLanguage)
+	h := &APIHandler{
 		limiter:          limiter,
+		globalLimiter:    globalLimiter,
 		security:         security,
-		executor:         executor,
+		executor:         defaultExecutor,
+		executors:        executors,
+		sessions:         sessions,
 		logger:           log,
-		maxCodeLength:    maxCodeLength,
-		executionTimeout: executionTimeout,
+		maxASTNodes:      maxASTNodes,
+		maxLiteralBytes:  maxLiteralBytes,
+		maxOutputLength:  maxOutputLength,
+		benchmarkTimeout: benchmarkTimeout,
+		raceTimeout:      raceTimeout,
+		importer:         importer,
+		queue:            executionQueue,
+		outcomes:         outcomes,
+		quotas:           quotas,
+		audit:            auditor,
+		normalizeOutput:  normalizeOutput,
+		executions:       NewExecutionRegistry(),
 	}
+	h.maxCodeLength.Store(int32(maxCodeLength))
+	h.executionTimeout.Store(int64(executionTimeout))
+	return h
+}
+
+// SetLimits aplica en caliente el tamaño máximo del código y el timeout de
+// ejecución de limits, por ejemplo desde PATCH /api/config. Las solicitudes
+// que ya pasaron la validación terminan con los límites anteriores.
+func (h *APIHandler) SetLimits(limits config.RuntimeLimits) {
+	h.maxCodeLength.Store(int32(limits.MaxCodeLength))
+	h.executionTimeout.Store(int64(limits.ExecutionTimeout))
+}
+
+// currentMaxCodeLength devuelve el tamaño máximo del código en vigor
+func (h *APIHandler) currentMaxCodeLength() int {
+	return int(h.maxCodeLength.Load())
+}
+
+// currentExecutionTimeout devuelve el timeout de ejecución en vigor
+func (h *APIHandler) currentExecutionTimeout() time.Duration {
+	return time.Duration(h.executionTimeout.Load())
 }
 
 // HandleExecuteCode maneja las solicitudes de ejecución de código
 func (h *APIHandler) HandleExecuteCode(w http.ResponseWriter, r *http.Request) {
+	// Identificar la solicitud para poder correlacionar logs y archivos temporales
+	requestID := requestctx.NewRequestID()
+	w.Header().Set("X-Request-ID", requestID)
+
+	// Span raíz de la solicitud; los spans del caché y del ejecutor cuelgan de él
+	traceCtx, span := telemetry.StartRequestSpan(r, tracerName, "HandleExecuteCode")
+	defer span.End()
+	span.SetAttributes(attribute.String("request.id", requestID))
+
 	// Crear logger con contexto para esta solicitud
 	reqLogger := h.logger.With(
+		zap.String("request_id", requestID),
 		zap.String("client_ip", h.security.GetClientIP(r)),
 		zap.String("method", r.Method),
 		zap.String("path", r.URL.Path),
 	)
 
-	// Verificar método HTTP
-	if r.Method != http.MethodPost {
-		err := errors.WithContext(
-			errors.New("método no permitido"),
-			http.StatusMethodNotAllowed,
-			"Método no permitido",
-			map[string]interface{}{"method": r.Method},
+	// Verificar método, rate limit y Content-Type, y decodificar la solicitud
+	codeReq, ok := h.readCodeRequest(w, r, reqLogger)
+	if !ok {
+		telemetry.RecordError(span, errors.New("solicitud rechazada antes de la validación"))
+		return
+	}
+	clientIP := h.security.GetClientIP(r)
+	language := codeReq.language()
+	timeout := h.currentExecutionTimeout()
+	if codeReq.Race {
+		timeout = h.raceTimeout
+	}
+	span.AddEvent("rate_limit.allowed")
+	span.SetAttributes(
+		attribute.String("code.hash", executor.HashCode(codeReq.Code)),
+		attribute.String("code.language", language),
+		attribute.Float64("execution.timeout_seconds", timeout.Seconds()),
+		attribute.Bool("execution.race", codeReq.Race),
+	)
+
+	// Elegir la representación según Accept: JSON con la salida completa o texto en streaming
+	wantsJSON := prefersJSON(r.Header.Get("Accept"))
+	w.Header().Add("Vary", "Accept")
+
+	// Establecer headers de seguridad y para streaming. El tipo del texto en
+	// streaming se fija aquí: si no, net/http lo deduce de la salida del
+	// programa y un programa que imprime HTML se serviría como text/html.
+	// Las respuestas JSON y los errores de HTTPError fijan el suyo.
+	h.security.SetSecurityHeaders(w)
+	if !wantsJSON {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+
+	// Verificar que el ResponseWriter soporte flushing
+	flusher, ok := w.(http.Flusher)
+	if !ok && !wantsJSON {
+		err := errors.InternalServerError(
+			errors.New("streaming no soportado"),
+			"El servidor no soporta streaming de respuestas",
+			nil,
 		)
 		errors.HTTPError(w, r, reqLogger, err)
 		return
 	}
 
-	// Rate limiting
-	clientIP := h.security.GetClientIP(r)
-	if !h.limiter.IsAllowed(clientIP) {
-		reqLogger.Warn("Rate limit exceeded",
-			zap.String("client_ip", clientIP),
+	// Elegir el ejecutor del lenguaje solicitado
+	codeExecutor, ok := h.executors.Executor(language)
+	if !ok {
+		h.rejectExecution(w, r, reqLogger, wantsJSON, fmt.Sprintf("Lenguaje no soportado: %s (disponibles: %s)",
+			language, strings.Join(h.executors.Languages(), ", ")))
+		return
+	}
+
+	// Validar el código
+	if msg := h.validateLanguageCode(language, codeReq.Code, reqLogger); msg != "" {
+		telemetry.RecordError(span, errors.New(msg))
+		h.rejectExecution(w, r, reqLogger, wantsJSON, msg)
+		return
+	}
+	span.AddEvent("blacklist.passed")
+
+	// Un binario compilado para otra plataforma no puede ejecutarse aquí
+	if !codeReq.target().IsHost() {
+		h.rejectExecution(w, r, reqLogger, wantsJSON, "goos/goarch solo se admiten en /api/compile")
+		return
+	}
+
+	// El detector de carreras debe estar habilitado y soportado por el ejecutor
+	raceExecutor, raceSupported := codeExecutor.(executor.RaceExecutor)
+	if codeReq.Race && (h.raceTimeout <= 0 || !raceSupported) {
+		h.rejectExecution(w, r, reqLogger, wantsJSON, "el detector de carreras no está habilitado en este servidor")
+		return
+	}
+
+	// La hora simulada debe ser válida y estar soportada por el ejecutor
+	var fakeTime time.Time
+	if codeReq.FakeTime != "" {
+		var err error
+		if fakeTime, err = time.Parse(time.RFC3339, codeReq.FakeTime); err != nil {
+			h.rejectExecution(w, r, reqLogger, wantsJSON, "fakeTime debe tener el formato RFC 3339, por ejemplo 2024-01-01T00:00:00Z")
+			return
+		}
+		if !executor.SupportsFakeTime(codeExecutor) {
+			h.rejectExecution(w, r, reqLogger, wantsJSON, executor.ErrFakeTimeUnsupported.Error())
+			return
+		}
+	}
+
+	// Los reintentos con la misma Idempotency-Key reciben la respuesta de la
+	// primera ejecución. Las ejecuciones con -race nunca se almacenan.
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		h.rejectExecution(w, r, reqLogger, wantsJSON,
+			fmt.Sprintf("Idempotency-Key no puede superar %d caracteres", maxIdempotencyKeyLength))
+		return
+	}
+	idempotentExecutor, idempotent := codeExecutor.(executor.IdempotentExecutor)
+	idempotent = idempotent && idempotencyKey != "" && !codeReq.Race
+	// La clave solo es válida para el cliente que la envió
+	idempotencyKey = clientHost(clientIP) + " " + idempotencyKey
+
+	// Descontar la ejecución de la cuota de la clave de API
+	if !h.acquireQuota(w, r, reqLogger, 1) {
+		return
+	}
+
+	// Identificar la sesión del navegador antes de escribir la respuesta
+	sessionID := h.ensureSession(w, r)
+
+	// Crear contexto que transporta el ID de solicitud y la IP del cliente
+	ctx := requestctx.WithRequestID(traceCtx, requestID)
+	ctx = requestctx.WithClientIP(ctx, clientIP)
+	if codeReq.FakeTime != "" {
+		ctx = executor.WithFakeTime(ctx, fakeTime)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Permitir que el cliente cancele la ejecución con /api/execute/cancel,
+	// también mientras espera en la cola
+	defer h.executions.Register(requestID, clientIP, cancel)()
+
+	// Registrar ejecución
+	reqLogger.Info("Ejecutando código",
+		zap.String("language", language),
+		zap.Int("code_length", len(codeReq.Code)),
+		zap.Duration("timeout", timeout),
+		zap.Bool("json_response", wantsJSON),
+		zap.Bool("race", codeReq.Race),
+		zap.String("fake_time", codeReq.FakeTime),
+	)
+
+	// Comprobar si el resultado saldrá del caché (las ejecuciones con -race nunca
+	// se cachean; con fakeTime la clave incluye la hora y no se consulta aquí)
+	cacheHit := false
+	if inspector, ok := codeExecutor.(executor.CacheInspector); ok && !codeReq.Race && codeReq.FakeTime == "" {
+		cacheHit = inspector.IsCached(codeReq.Code)
+	}
+	if idempotent && idempotentExecutor.IdempotentReplay(ctx, idempotencyKey, codeReq.Code) {
+		cacheHit = true
+		w.Header().Set("Idempotent-Replayed", "true")
+		reqLogger.Info("Respuesta repetida por Idempotency-Key")
+	}
+	span.SetAttributes(attribute.Bool("cache.hit", cacheHit))
+
+	// Indicar al cliente si el código se envolvió antes de ejecutarse
+	if prepareCode(codeExecutor, codeReq.Code).Wrapped {
+		w.Header().Set("X-Code-Wrapped", "true")
+	}
+
+	// Capturar un extracto de la salida para el historial de la sesión
+	capture := &captureWriter{limit: session.MaxSummaryOutputLength + 1}
+
+	// En streaming la salida va directa a la respuesta, con un flush por cada
+	// escritura del ejecutor, y el resumen en un trailer declarado antes del
+	// cuerpo; en JSON se acumula para responder al final. El límite de la
+	// respuesta en streaming solo cuenta la salida del programa: el resumen
+	// final se escribe siempre. Los informes del detector de carreras tienen
+	// su propio límite (RACE_MAX_STDERR_LENGTH) y no se cortan aquí.
+	var body io.Writer
+	var buffered bytes.Buffer
+	var limited *middleware.LimitedResponseWriter
+	if wantsJSON {
+		body = &buffered
+	} else {
+		maxOutputLength := h.maxOutputLength
+		if codeReq.Race {
+			maxOutputLength = 0
+		}
+		limited = middleware.NewLimitedResponseWriter(w, maxOutputLength)
+		body = &flushWriter{w: limited, flusher: limited}
+		w.Header().Set("Trailer", executionResultTrailer)
+	}
+
+	// Esperar turno en la cola; los resultados del caché no ocupan plaza
+	queued := false
+	if h.queue != nil && !cacheHit {
+		var release func()
+		release, queued, ok = h.waitInQueue(ctx, w, r, reqLogger, wantsJSON)
+		if !ok {
+			return
+		}
+		defer release()
+	}
+
+	// El timeout de ejecución empieza a contar al salir de la cola
+	ctx, cancelTimeout := context.WithTimeout(ctx, timeout)
+	defer cancelTimeout()
+	start := time.Now()
+
+	// Calcular el checksum de la salida a medida que se escribe. body va el
+	// último: al superar el límite de la respuesta devuelve io.ErrShortWrite y
+	// io.MultiWriter ya no escribe en los siguientes.
+	output := io.MultiWriter(capture, body)
+	var hasher *executor.OutputHasher
+	if codeReq.Checksum {
+		hasher = executor.NewOutputHasher()
+		output = io.MultiWriter(capture, hasher, body)
+	}
+	// La normalización se aplica antes que todo lo demás, así que el
+	// checksum y el historial ven la misma salida que el cliente
+	var normalizer *executor.NormalizingWriter
+	if h.normalizeOutput {
+		normalizer = executor.NewNormalizingWriter(output)
+		output = normalizer
+	}
+
+	// Ejecutar el código
+	execution := h.auditStart(ctx, r, codeExecutor, language, codeReq.Code)
+	var result executor.ExecutionResult
+	var races []executor.RaceReport
+	var err error
+	if codeReq.Race {
+		var raceResult executor.RaceResult
+		raceResult, err = raceExecutor.ExecuteRace(ctx, codeReq.Code, output)
+		result, races = raceResult.ExecutionResult, raceResult.Races
+		if len(races) > 0 {
+			reqLogger.Info("Carreras de datos detectadas", zap.Int("data_races", len(races)))
+		}
+	} else if idempotent {
+		result, err = idempotentExecutor.ExecuteIdempotent(ctx, idempotencyKey, codeReq.Code, output)
+	} else {
+		result, err = executor.RunWithResult(ctx, codeExecutor, codeReq.Code, output)
+	}
+	h.auditFinish(execution, start, result, err)
+	if normalizer != nil {
+		normalizer.Flush()
+	}
+	responseTruncated := limited != nil && limited.Exceeded()
+	if responseTruncated {
+		reqLogger.Warn("Respuesta en streaming cortada por el límite de salida",
+			zap.Int("max_output_length", h.maxOutputLength))
+		fmt.Fprintf(w, "\n... (response truncated after %d bytes)", h.maxOutputLength)
+		flusher.Flush()
+	}
+	if appErr := capacityError(err); appErr != nil && !queued {
+		// Rechazada antes de escribir nada: se puede responder con un 503
+		w.Header().Del("Trailer")
+		errors.HTTPError(w, r, reqLogger, appErr)
+		return
+	}
+	if errors.Is(err, executor.ErrIdempotencyKeyReused) && !queued {
+		// También antes de escribir nada
+		w.Header().Del("Trailer")
+		appErr := errors.WithContext(err, http.StatusUnprocessableEntity,
+			"Idempotency-Key ya se usó con otro código", nil).WithCode(errors.CodeIdempotencyKeyReused)
+		errors.HTTPError(w, r, reqLogger, appErr)
+		return
+	}
+	if h.outcomes != nil {
+		h.outcomes.RecordExecution(err)
+	}
+	h.recordUsage(r, result.CPUTime, time.Since(start))
+	if isInternalError(err) {
+		errors.ReportError(r, requestID, err)
+	}
+	if err != nil {
+		telemetry.RecordError(span, err)
+		reqLogger.Error("Error al ejecutar código", 
+			zap.Error(errors.Wrap(err, "error de ejecución")),
 		)
-		err := errors.TooManyRequests(
-			errors.New("rate limit exceeded"),
-			"Demasiadas peticiones. Por favor, espere un minuto.",
-			map[string]interface{}{"client_ip": clientIP},
+		if !wantsJSON {
+			fmt.Fprintf(w, "\nError: %v", err)
+			flusher.Flush()
+		}
+	} else {
+		reqLogger.Info("Código ejecutado correctamente")
+	}
+	if result.Truncated {
+		// Permite ajustar MAX_OUTPUT_LENGTH según el uso real
+		reqLogger.Info("Salida truncada por los límites de salida",
+			zap.String("code_hash", executor.HashCode(codeReq.Code)),
+			zap.Strings("streams", result.TruncatedStreams),
+			zap.Bool("cache_hit", cacheHit))
+	}
+
+	status := ExecutionStatus{
+		ExitCode:   result.ExitCode,
+		DurationMs: time.Since(start).Milliseconds(),
+		Truncated:  result.Truncated || responseTruncated,
+		DataRaces:  len(races),
+	}
+	if hasher != nil {
+		status.OutputSHA256 = hasher.Sum()
+	}
+	if wantsJSON {
+		resp := ExecuteResponse{Output: buffered.String(), ExecutionStatus: status, Races: races}
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			reqLogger.Error("Error al codificar respuesta JSON", zap.Error(err))
+		}
+	} else {
+		h.writeExecutionStatus(w, codeReq.StatusSentinel, status, reqLogger)
+		flusher.Flush()
+	}
+
+	if sessionID != "" {
+		h.sessions.Append(sessionID, session.ExecutionSummary{
+			CodeHash:        executor.HashCode(codeReq.Code),
+			Timestamp:       time.Now(),
+			TruncatedOutput: session.TruncateOutput(capture.buf),
+			CacheHit:        cacheHit,
+		})
+	}
+}
+
+// QueuedStatusFormat es la línea que recibe en streaming una solicitud que
+// espera en la cola de ejecución, cada vez que cambia su posición
+const QueuedStatusFormat = "queued, position %d\n"
+
+// waitInQueue espera una plaza en la cola de ejecución. En streaming envía la
+// posición con QueuedStatusFormat cada vez que cambia, lo que inicia la
+// respuesta. Devuelve la función que libera la plaza y si la respuesta ya se
+// inició, u ok=false si la solicitud ya se respondió: 503 con la cola llena, o
+// abandonada por el cliente (desconexión o /api/execute/cancel).
+func (h *APIHandler) waitInQueue(ctx context.Context, w http.ResponseWriter, r *http.Request, reqLogger logger.Logger, wantsJSON bool) (release func(), queued bool, ok bool) {
+	var onPosition func(int)
+	if !wantsJSON {
+		flusher := w.(http.Flusher)
+		onPosition = func(position int) {
+			queued = true
+			fmt.Fprintf(w, QueuedStatusFormat, position)
+			flusher.Flush()
+		}
+	}
+
+	// La ejecución no depende de la conexión, pero la espera sí: quien se
+	// desconecta deja su sitio en la cola
+	waitCtx, cancelWait := context.WithCancel(ctx)
+	defer cancelWait()
+	stop := context.AfterFunc(r.Context(), cancelWait)
+	defer stop()
+
+	release, err := h.queue.Acquire(waitCtx, onPosition)
+	switch {
+	case err == nil:
+		return release, queued, true
+	case errors.Is(err, queue.ErrQueueFull):
+		reqLogger.Warn("Cola de ejecución llena")
+		w.Header().Del("Trailer")
+		w.Header().Set("Retry-After", "1")
+		appErr := errors.ServiceUnavailable(err, "El servidor está ocupado, inténtelo de nuevo en unos segundos", nil).
+			WithCode(errors.CodeServerBusy)
+		errors.HTTPError(w, r, reqLogger, appErr)
+	default:
+		reqLogger.Info("Solicitud abandonada en la cola de ejecución", zap.Error(err))
+		if queued {
+			// La respuesta ya empezó con la posición en la cola
+			fmt.Fprintf(w, "Error: %v", err)
+			w.(http.Flusher).Flush()
+		} else {
+			w.Header().Del("Trailer")
+			appErr := errors.ServiceUnavailable(err, "La ejecución se canceló mientras esperaba en la cola", nil)
+			errors.HTTPError(w, r, reqLogger, appErr)
+		}
+	}
+	return nil, queued, false
+}
+
+// maxIdempotencyKeyLength es la longitud máxima de la cabecera Idempotency-Key
+const maxIdempotencyKeyLength = 255
+
+// ExecuteResponse es la respuesta de /api/execute cuando Accept prefiere application/json
+type ExecuteResponse struct {
+	Output string `json:"output"`
+	ExecutionStatus
+	// Races son los informes del detector de carreras, si se pidió "race": true
+	Races []executor.RaceReport `json:"races,omitempty"`
+	Error string                `json:"error,omitempty"`
+}
+
+// rejectExecution responde a una solicitud de ejecución inválida: con un 400 en
+// JSON si el cliente lo prefiere, o con el texto "Error: ..." del streaming
+func (h *APIHandler) rejectExecution(w http.ResponseWriter, r *http.Request, reqLogger logger.Logger, wantsJSON bool, msg string) {
+	if wantsJSON {
+		err := errors.BadRequest(errors.New("código inválido"), msg, nil).WithCode(errors.CodeInvalidCode)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+	fmt.Fprintf(w, "Error: %s", msg)
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// writeExecutionStatus envía el resumen de la ejecución en el trailer HTTP y,
+// si se solicitó, también al final del cuerpo tras ResultSentinel.
+func (h *APIHandler) writeExecutionStatus(w http.ResponseWriter, sentinel bool, status ExecutionStatus, reqLogger logger.Logger) {
+	data, err := json.Marshal(status)
+	if err != nil {
+		reqLogger.Error("Error al codificar el resumen de ejecución", zap.Error(err))
+		return
+	}
+	if sentinel {
+		fmt.Fprintf(w, "\n%s%s\n", ResultSentinel, data)
+	}
+	w.Header().Set(executionResultTrailer, string(data))
+}
+
+// CompileResponse es la respuesta JSON de /api/compile
+type CompileResponse struct {
+	Success       bool   `json:"success"`
+	Diagnostics   string `json:"diagnostics,omitempty"`
+	FormattedCode string `json:"formatted_code,omitempty"`
+}
+
+// HandleCompile comprueba que el código compila sin ejecutarlo.
+// Responde con un CompileResponse que incluye los diagnósticos del compilador si falla.
+func (h *APIHandler) HandleCompile(w http.ResponseWriter, r *http.Request) {
+	requestID := requestctx.NewRequestID()
+	w.Header().Set("X-Request-ID", requestID)
+
+	reqLogger := h.logger.With(
+		zap.String("request_id", requestID),
+		zap.String("client_ip", h.security.GetClientIP(r)),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+	)
+
+	codeReq, ok := h.readCodeRequest(w, r, reqLogger)
+	if !ok {
+		return
+	}
+
+	if msg := h.validateCode(codeReq.Code, reqLogger); msg != "" {
+		err := errors.BadRequest(errors.New("código inválido"), msg, nil).WithCode(errors.CodeInvalidCode)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+
+	target := codeReq.target()
+	if err := executor.ValidateTarget(target); err != nil {
+		reqLogger.Warn("Plataforma destino no soportada", zap.String("target", target.String()))
+		appErr := errors.BadRequest(err, err.Error(), map[string]interface{}{
+			"supported_targets": executor.SupportedTargets(),
+		})
+		errors.HTTPError(w, r, reqLogger, appErr)
+		return
+	}
+
+	compiler, ok := h.executor.(executor.Compiler)
+	if !ok {
+		err := errors.InternalServerError(
+			errors.New("compilación no soportada"),
+			"El ejecutor no soporta compilación",
+			nil,
 		)
 		errors.HTTPError(w, r, reqLogger, err)
 		return
 	}
 
-	// Verificar Content-Type
-	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+	ctx := requestctx.WithRequestID(context.Background(), requestID)
+	ctx = requestctx.WithClientIP(ctx, h.security.GetClientIP(r))
+	timeout := h.currentExecutionTimeout()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	reqLogger.Info("Compilando código Go",
+		zap.Int("code_length", len(codeReq.Code)),
+		zap.String("target", target.String()),
+		zap.Duration("timeout", timeout),
+	)
+
+	resp := CompileResponse{Success: true}
+	if err := compiler.Compile(ctx, codeReq.Code, target); err != nil {
+		if appErr := capacityError(err); appErr != nil {
+			errors.HTTPError(w, r, reqLogger, appErr)
+			return
+		}
+		var compileErr *executor.CompileError
+		if !errors.As(err, &compileErr) {
+			reqLogger.Error("Error al compilar código", zap.Error(err))
+			appErr := errors.InternalServerError(err, "Error al compilar el código", nil)
+			errors.HTTPError(w, r, reqLogger, appErr)
+			return
+		}
+		resp = CompileResponse{Success: false, Diagnostics: compileErr.Diagnostics}
+	}
+	if prepared := h.prepareCode(codeReq.Code); prepared.Wrapped {
+		resp.FormattedCode = prepared.FormattedCode
+	}
+	reqLogger.Info("Compilación finalizada", zap.Bool("success", resp.Success))
+
+	h.security.SetSecurityHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		reqLogger.Error("Error al codificar respuesta JSON", zap.Error(err))
+	}
+}
+
+// AssemblyResponse es la respuesta JSON de /api/asm
+type AssemblyResponse struct {
+	Success       bool   `json:"success"`
+	Assembly      string `json:"assembly,omitempty"`
+	Diagnostics   string `json:"diagnostics,omitempty"`
+	FormattedCode string `json:"formatted_code,omitempty"`
+}
+
+// HandleAssembly devuelve el ensamblador generado por el compilador para el código.
+// Si el código no compila, responde con los diagnósticos del compilador.
+func (h *APIHandler) HandleAssembly(w http.ResponseWriter, r *http.Request) {
+	requestID := requestctx.NewRequestID()
+	w.Header().Set("X-Request-ID", requestID)
+
+	reqLogger := h.logger.With(
+		zap.String("request_id", requestID),
+		zap.String("client_ip", h.security.GetClientIP(r)),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+	)
+
+	codeReq, ok := h.readCodeRequest(w, r, reqLogger)
+	if !ok {
+		return
+	}
+
+	if msg := h.validateCode(codeReq.Code, reqLogger); msg != "" {
+		err := errors.BadRequest(errors.New("código inválido"), msg, nil).WithCode(errors.CodeInvalidCode)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+
+	if !codeReq.target().IsHost() {
 		err := errors.BadRequest(
-			errors.New("content-type inválido"),
-			"Content-Type debe ser application/json",
-			map[string]interface{}{"content_type": r.Header.Get("Content-Type")},
+			errors.New("plataforma destino no soportada"),
+			"goos/goarch solo se admiten en /api/compile",
+			nil,
 		)
 		errors.HTTPError(w, r, reqLogger, err)
 		return
 	}
 
-	// Establecer headers de seguridad y para streaming
+	disassembler, ok := h.executor.(executor.Disassembler)
+	if !ok {
+		err := errors.InternalServerError(
+			errors.New("ensamblador no soportado"),
+			"El ejecutor no soporta la vista de ensamblador",
+			nil,
+		)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+
+	ctx := requestctx.WithRequestID(context.Background(), requestID)
+	ctx = requestctx.WithClientIP(ctx, h.security.GetClientIP(r))
+	timeout := h.currentExecutionTimeout()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	reqLogger.Info("Generando ensamblador",
+		zap.Int("code_length", len(codeReq.Code)),
+		zap.Duration("timeout", timeout),
+	)
+
+	var resp AssemblyResponse
+	assembly, err := disassembler.Assembly(ctx, codeReq.Code)
+	if err != nil {
+		if appErr := capacityError(err); appErr != nil {
+			errors.HTTPError(w, r, reqLogger, appErr)
+			return
+		}
+		var compileErr *executor.CompileError
+		if !errors.As(err, &compileErr) {
+			reqLogger.Error("Error al generar ensamblador", zap.Error(err))
+			appErr := errors.InternalServerError(err, "Error al generar el ensamblador", nil)
+			errors.HTTPError(w, r, reqLogger, appErr)
+			return
+		}
+		resp = AssemblyResponse{Success: false, Diagnostics: compileErr.Diagnostics}
+	} else {
+		resp = AssemblyResponse{Success: true, Assembly: assembly}
+	}
+	if prepared := h.prepareCode(codeReq.Code); prepared.Wrapped {
+		resp.FormattedCode = prepared.FormattedCode
+	}
+
 	h.security.SetSecurityHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		reqLogger.Error("Error al codificar respuesta JSON", zap.Error(err))
+	}
+}
 
-	// Verificar que el ResponseWriter soporte flushing
-	flusher, ok := w.(http.Flusher)
+// BenchmarkResponse es la respuesta JSON de /api/benchmark
+type BenchmarkResponse struct {
+	Success    bool                       `json:"success"`
+	Benchmarks []executor.BenchmarkResult `json:"benchmarks"`
+	Output     string                     `json:"output"`
+	Error      string                     `json:"error,omitempty"`
+}
+
+// HandleBenchmark ejecuta los benchmarks del código con 'go test -bench' y
+// devuelve los resultados ya interpretados junto con la salida completa.
+// El código no se cachea ni se deduplica: cada solicitud mide su propia ejecución.
+func (h *APIHandler) HandleBenchmark(w http.ResponseWriter, r *http.Request) {
+	requestID := requestctx.NewRequestID()
+	w.Header().Set("X-Request-ID", requestID)
+
+	reqLogger := h.logger.With(
+		zap.String("request_id", requestID),
+		zap.String("client_ip", h.security.GetClientIP(r)),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+	)
+
+	codeReq, ok := h.readCodeRequest(w, r, reqLogger)
+	if !ok {
+		return
+	}
+
+	if msg := h.validateCode(codeReq.Code, reqLogger); msg != "" {
+		err := errors.BadRequest(errors.New("código inválido"), msg, nil).WithCode(errors.CodeInvalidCode)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+
+	if !codeReq.target().IsHost() {
+		err := errors.BadRequest(
+			errors.New("plataforma destino no soportada"),
+			"goos/goarch solo se admiten en /api/compile",
+			nil,
+		)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+
+	benchmarker, ok := h.executor.(executor.Benchmarker)
 	if !ok {
 		err := errors.InternalServerError(
-			errors.New("streaming no soportado"),
-			"El servidor no soporta streaming de respuestas",
+			errors.New("benchmarks no soportados"),
+			"El ejecutor no soporta benchmarks",
 			nil,
 		)
 		errors.HTTPError(w, r, reqLogger, err)
 		return
 	}
 
-	// Decodificar la solicitud
+	if !h.acquireQuota(w, r, reqLogger, 1) {
+		return
+	}
+
+	ctx := requestctx.WithRequestID(context.Background(), requestID)
+	ctx = requestctx.WithClientIP(ctx, h.security.GetClientIP(r))
+	ctx, cancel := context.WithTimeout(ctx, h.benchmarkTimeout)
+	defer cancel()
+
+	reqLogger.Info("Ejecutando benchmarks",
+		zap.Int("code_length", len(codeReq.Code)),
+		zap.Duration("timeout", h.benchmarkTimeout),
+	)
+
+	start := time.Now()
+	execution := h.auditStart(ctx, r, h.executor, executor.DefaultLanguage, codeReq.Code)
+	report, err := benchmarker.Benchmark(ctx, codeReq.Code)
+	h.auditFinish(execution, start, goTestResult(report.Passed, err), err)
+	h.recordUsage(r, 0, time.Since(start))
+	resp := BenchmarkResponse{
+		Success:    report.Passed,
+		Benchmarks: report.Results,
+		Output:     report.Output,
+	}
+	if err != nil {
+		if appErr := capacityError(err); appErr != nil {
+			errors.HTTPError(w, r, reqLogger, appErr)
+			return
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			reqLogger.Error("Error al ejecutar benchmarks", zap.Error(err))
+			appErr := errors.InternalServerError(err, "Error al ejecutar los benchmarks", nil)
+			errors.HTTPError(w, r, reqLogger, appErr)
+			return
+		}
+		// Se devuelven los benchmarks que llegaron a completarse
+		reqLogger.Warn("Tiempo de benchmarks agotado", zap.Duration("timeout", h.benchmarkTimeout))
+		resp.Success = false
+		resp.Error = fmt.Sprintf("Tiempo de ejecución agotado (%v)", h.benchmarkTimeout)
+	}
+	if resp.Benchmarks == nil {
+		resp.Benchmarks = []executor.BenchmarkResult{}
+	}
+
+	h.security.SetSecurityHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		reqLogger.Error("Error al codificar respuesta JSON", zap.Error(err))
+	}
+}
+
+// goTestResult expresa el resultado de 'go test' como el de una ejecución:
+// 0 si pasó, 1 si falló y -1 si no llegó a terminar
+func goTestResult(passed bool, err error) executor.ExecutionResult {
+	switch {
+	case err != nil:
+		return executor.ExecutionResult{ExitCode: -1}
+	case !passed:
+		return executor.ExecutionResult{ExitCode: 1}
+	}
+	return executor.ExecutionResult{}
+}
+
+// readCodeRequest verifica el método, el rate limit y el Content-Type de una
+// solicitud de código y decodifica su cuerpo. Si algo falla responde con el
+// error HTTP correspondiente y devuelve false.
+func (h *APIHandler) readCodeRequest(w http.ResponseWriter, r *http.Request, reqLogger logger.Logger) (CodeRequest, bool) {
 	var codeReq CodeRequest
+	ok := h.readJSONRequest(w, r, reqLogger, 1, &codeReq)
+	return codeReq, ok
+}
+
+// readJSONRequest es como readCodeRequest, pero decodifica el cuerpo en v y la
+// solicitud cuenta como cost solicitudes para el rate limit.
+func (h *APIHandler) readJSONRequest(w http.ResponseWriter, r *http.Request, reqLogger logger.Logger, cost int, v interface{}) bool {
+	// Verificar método HTTP
+	if r.Method != http.MethodPost {
+		err := errors.WithContext(
+			errors.New("método no permitido"),
+			http.StatusMethodNotAllowed,
+			"Método no permitido",
+			map[string]interface{}{"method": r.Method},
+		)
+		errors.HTTPError(w, r, reqLogger, err)
+		return false
+	}
+
+	// Rate limiting: cada unidad de cost consume un token
+	for i := 0; i < cost; i++ {
+		if !h.checkRateLimit(w, r, reqLogger) {
+			return false
+		}
+	}
+	if !h.checkGlobalRateLimit(w, r, reqLogger, cost) {
+		return false
+	}
+
+	// Verificar Content-Type
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		err := errors.BadRequest(
+			errors.New("content-type inválido"),
+			"Content-Type debe ser application/json",
+			map[string]interface{}{"content_type": r.Header.Get("Content-Type")},
+		)
+		errors.HTTPError(w, r, reqLogger, err)
+		return false
+	}
+
+	// Decodificar la solicitud
 	// Asegurar que el body se cierre adecuadamente
 	defer r.Body.Close()
-	
-	if err := json.NewDecoder(r.Body).Decode(&codeReq); err != nil {
+
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		// Cuerpo gzip que supera el límite al descomprimirlo (middleware.RequestDecompression)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			err := errors.WithContext(
+				err,
+				http.StatusRequestEntityTooLarge,
+				"La solicitud descomprimida es demasiado grande",
+				map[string]interface{}{"max_bytes": maxBytesErr.Limit},
+			)
+			errors.HTTPError(w, r, reqLogger, err)
+			return false
+		}
 		reqLogger.Error("Error al decodificar la solicitud", zap.Error(err))
 		err := errors.BadRequest(
 			errors.Wrap(err, "error al decodificar JSON"),
@@ -131,73 +1010,310 @@ func (h *APIHandler) HandleExecuteCode(w http.ResponseWriter, r *http.Request) {
 			nil,
 		)
 		errors.HTTPError(w, r, reqLogger, err)
-		return
+		return false
 	}
 
-	// Validar el código
-	if codeReq.Code == "" {
+	return true
+}
+
+// checkRateLimit responde con 429 si el cliente superó el rate limit y
+// devuelve si la solicitud puede continuar. Las solicitudes autenticadas con
+// una clave de API cuentan para la clave y no para la IP.
+func (h *APIHandler) checkRateLimit(w http.ResponseWriter, r *http.Request, reqLogger logger.Logger) bool {
+	clientIP := h.security.GetClientIP(r)
+	if h.limiter.IsAllowed(requestctx.RateLimitKey(r.Context(), clientIP)) {
+		return true
+	}
+	reqLogger.Warn("Rate limit exceeded",
+		zap.String("client_ip", clientIP),
+	)
+	err := errors.TooManyRequests(
+		errors.New("rate limit exceeded"),
+		"Demasiadas peticiones. Por favor, espere un minuto.",
+		map[string]interface{}{"client_ip": clientIP},
+	)
+	errors.HTTPError(w, r, reqLogger, err)
+	return false
+}
+
+// checkGlobalRateLimit responde con 503 y Retry-After si el servidor superó
+// el límite de ejecuciones de todos los clientes juntos, y devuelve si la
+// solicitud puede continuar. Se comprueba después del rate limit por cliente
+// para que un único cliente no agote el límite global con solicitudes que
+// se iban a rechazar de todos modos.
+func (h *APIHandler) checkGlobalRateLimit(w http.ResponseWriter, r *http.Request, reqLogger logger.Logger, cost int) bool {
+	if h.globalLimiter == nil {
+		return true
+	}
+	allowed, wait := h.globalLimiter.Allow(cost)
+	if allowed {
+		return true
+	}
+	reqLogger.Warn("Límite global de ejecuciones excedido",
+		zap.Duration("retry_after", wait),
+	)
+	w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(wait.Seconds())), 1)))
+	err := errors.ServiceUnavailable(
+		errors.New("límite global de ejecuciones excedido"),
+		"El servidor está ocupado, inténtelo de nuevo en unos segundos",
+		nil,
+	).WithCode(errors.CodeServerBusy)
+	errors.HTTPError(w, r, reqLogger, err)
+	return false
+}
+
+// capacityError convierte los rechazos del ejecutor por falta de capacidad
+// (directorios o cuota de disco temporales agotados) en un error 503.
+// Retorna nil para cualquier otro error.
+func capacityError(err error) *errors.AppError {
+	if errors.Is(err, executor.ErrTooManyTempFiles) || errors.Is(err, executor.ErrTempQuotaExceeded) {
+		return errors.ServiceUnavailable(err, "El servidor está ocupado, inténtelo de nuevo en unos segundos", nil).
+			WithCode(errors.CodeServerBusy)
+	}
+	return nil
+}
+
+// isInternalError indica si err es un fallo del servidor y no del código del
+// usuario: los timeouts, las cancelaciones y los rechazos por capacidad no lo son
+func isInternalError(err error) bool {
+	return err != nil &&
+		!errors.Is(err, context.DeadlineExceeded) &&
+		!errors.Is(err, context.Canceled) &&
+		capacityError(err) == nil &&
+		!errors.Is(err, executor.ErrIdempotencyKeyReused)
+}
+
+// prepareCode devuelve el código que el ejecutor de Go ejecutará realmente, o
+// el código sin cambios si el ejecutor no lo transforma.
+func (h *APIHandler) prepareCode(code string) executor.ExecutionResult {
+	return prepareCode(h.executor, code)
+}
+
+// prepareCode devuelve el código que ex ejecutará realmente, o el código sin
+// cambios si ex no lo transforma.
+func prepareCode(ex executor.CodeExecutor, code string) executor.ExecutionResult {
+	if preparer, ok := ex.(executor.CodePreparer); ok {
+		return preparer.PrepareCode(code)
+	}
+	return executor.ExecutionResult{FormattedCode: code}
+}
+
+// validateCode aplica las validaciones de tamaño y seguridad al código Go recibido.
+// Devuelve un mensaje para el usuario si el código no es válido, o una cadena vacía.
+func (h *APIHandler) validateCode(code string, reqLogger logger.Logger) string {
+	return h.validateLanguageCode(executor.DefaultLanguage, code, reqLogger)
+}
+
+// validateLanguageCode es como validateCode para código de language. El límite
+// de nodos del AST y el rechazo de cgo y directivas solo se aplican al código Go.
+func (h *APIHandler) validateLanguageCode(language, code string, reqLogger logger.Logger) string {
+	if code == "" {
 		reqLogger.Warn("Código vacío recibido")
-		fmt.Fprint(w, "Error: El código no puede estar vacío")
-		flusher.Flush()
-		return
+		return "El código no puede estar vacío"
 	}
 
-	if len(codeReq.Code) > h.maxCodeLength {
+	if maxCodeLength := h.currentMaxCodeLength(); len(code) > maxCodeLength {
 		reqLogger.Warn("Código excede límite de tamaño",
-			zap.Int("code_length", len(codeReq.Code)),
-			zap.Int("max_length", h.maxCodeLength),
+			zap.Int("code_length", len(code)),
+			zap.Int("max_length", maxCodeLength),
 		)
-		fmt.Fprintf(w, "Error: El código excede el límite de %d bytes", h.maxCodeLength)
-		flusher.Flush()
-		return
+		return fmt.Sprintf("El código excede el límite de %d bytes", maxCodeLength)
+	}
+
+	// El código con errores de sintaxis no se rechaza aquí: el compilador los
+	// notifica con su formato habitual
+	if h.maxASTNodes > 0 && language == executor.DefaultLanguage {
+		if nodes, err := h.security.ASTNodeCount(h.prepareCode(code).FormattedCode); err == nil && nodes > h.maxASTNodes {
+			reqLogger.Warn("Código excede límite de nodos AST",
+				zap.Int("ast_nodes", nodes),
+				zap.Int("max_ast_nodes", h.maxASTNodes),
+			)
+			return fmt.Sprintf("El código es demasiado complejo: %d nodos AST (máximo %d)", nodes, h.maxASTNodes)
+		}
 	}
 
-	if hasBlacklisted, pkg := h.security.ContainsBlacklistedImports(codeReq.Code); hasBlacklisted {
+	if h.maxLiteralBytes > 0 && language == executor.DefaultLanguage {
+		if oversized, size := h.security.ContainsOversizedLiterals(code, h.maxLiteralBytes); oversized {
+			reqLogger.Warn("Código con una cadena literal demasiado grande",
+				zap.Int("literal_bytes", size),
+				zap.Int("max_literal_bytes", h.maxLiteralBytes),
+			)
+			return fmt.Sprintf("El código contiene una cadena literal de %d bytes (máximo %d)", size, h.maxLiteralBytes)
+		}
+	}
+
+	if hasBlacklisted, pkg := h.security.ContainsBlacklistedImports(language, code); hasBlacklisted {
 		reqLogger.Warn("Intento de usar import prohibido",
 			zap.String("blacklisted_package", pkg),
 		)
-		fmt.Fprintf(w, "Error: Import prohibido por seguridad: %s", pkg)
-		flusher.Flush()
-		return
+		return fmt.Sprintf("Import prohibido por seguridad: %s", pkg)
 	}
 
-	// Crear contexto con timeout
-	ctx, cancel := context.WithTimeout(context.Background(), h.executionTimeout)
-	defer cancel()
+	if language == executor.DefaultLanguage {
+		// Se comprueba el código que se ejecutará: sin la declaración de paquete
+		// que añade AutoWrapCode, go/parser no encontraría el import "C"
+		prepared := h.prepareCode(code).FormattedCode
+		if construct, found := h.security.FindForbiddenConstruct(prepared); found {
+			reqLogger.Warn("Intento de usar cgo o una directiva prohibida",
+				zap.String("construct", construct),
+			)
+			return fmt.Sprintf("No se admite %s por seguridad", construct)
+		}
 
-	// Registrar ejecución
-	reqLogger.Info("Ejecutando código Go",
-		zap.Int("code_length", len(codeReq.Code)),
-		zap.Duration("timeout", h.executionTimeout),
+		if call, denied := h.security.FindDeniedCall(prepared); denied {
+			reqLogger.Warn("Código con una llamada prohibida",
+				zap.String("function", call),
+			)
+			return fmt.Sprintf("No se permite usar %s por seguridad", call)
+		}
+	}
+
+	if rule, denied := h.security.MatchDenyRule(code); denied {
+		reqLogger.Warn("Código con un patrón prohibido",
+			zap.String("deny_rule", rule),
+		)
+		return fmt.Sprintf("El código contiene un patrón prohibido por seguridad: %s", rule)
+	}
+
+	return ""
+}
+
+// HandleHistory devuelve las últimas ejecuciones de la sesión actual como JSON
+func (h *APIHandler) HandleHistory(w http.ResponseWriter, r *http.Request) {
+	reqLogger := h.logger.With(
+		zap.String("client_ip", h.security.GetClientIP(r)),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
 	)
 
-	// Ejecutar el código
-	err := h.executor.Execute(ctx, codeReq.Code, w)
-	if err != nil {
-		reqLogger.Error("Error al ejecutar código", 
-			zap.Error(errors.Wrap(err, "error de ejecución")),
+	if r.Method != http.MethodGet {
+		err := errors.WithContext(
+			errors.New("método no permitido"),
+			http.StatusMethodNotAllowed,
+			"Método no permitido",
+			map[string]interface{}{"method": r.Method},
 		)
-		fmt.Fprintf(w, "\nError: %v", err)
-		flusher.Flush()
-	} else {
-		reqLogger.Info("Código ejecutado correctamente")
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+
+	history := []session.ExecutionSummary{}
+	if cookie, err := r.Cookie(sessionCookieName); err == nil && cookie.Value != "" {
+		history = h.sessions.History(cookie.Value, historyResponseLimit)
+	}
+
+	h.security.SetSecurityHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(history); err != nil {
+		reqLogger.Error("Error al codificar historial", zap.Error(err))
 	}
 }
 
-// FileServer representa un servidor de archivos estáticos
+// ensureSession devuelve el ID de sesión de la cookie del cliente, creando
+// una sesión nueva (y su cookie) si no existe o ha expirado.
+// Devuelve una cadena vacía si no se pudo generar un ID.
+func (h *APIHandler) ensureSession(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(sessionCookieName); err == nil && h.sessions.Exists(cookie.Value) {
+		return cookie.Value
+	}
+
+	id, err := session.NewSessionID()
+	if err != nil {
+		h.logger.Error("Error al crear sesión", zap.Error(err))
+		return ""
+	}
+	h.sessions.Create(id)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    id,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return id
+}
+
+// flushWriter hace flush de la respuesta tras cada escritura, para que el
+// cliente reciba la salida de los programas lentos a medida que se produce
+type flushWriter struct {
+	w       io.Writer
+	flusher http.Flusher
+}
+
+// Write implementa la interfaz io.Writer
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if n > 0 {
+		fw.flusher.Flush()
+	}
+	return n, err
+}
+
+// captureWriter guarda hasta limit bytes de lo escrito, descartando el resto
+type captureWriter struct {
+	buf   []byte
+	limit int
+}
+
+// Write implementa la interfaz io.Writer
+func (cw *captureWriter) Write(p []byte) (int, error) {
+	if remaining := cw.limit - len(cw.buf); remaining > 0 {
+		if len(p) > remaining {
+			cw.buf = append(cw.buf, p[:remaining]...)
+		} else {
+			cw.buf = append(cw.buf, p...)
+		}
+	}
+	return len(p), nil
+}
+
+// FileServer representa un servidor de archivos estáticos con soporte para SPA.
+//
+// Las rutas que no corresponden a un archivo existente se resuelven sirviendo
+// fallbackFile (normalmente index.html), salvo que empiecen por alguno de los
+// prefijos de noFallbackPrefixes (por ejemplo "/api"), que responden 404.
 type FileServer struct {
-	fs      http.Handler
-	security security.SecurityValidator
-	root     string
+	fs                 http.Handler
+	security           security.SecurityValidator
+	root               iofs.FS
+	fallbackFile       string
+	noFallbackPrefixes []string
 }
 
-// NewFileServer crea un nuevo servidor de archivos estáticos
-func NewFileServer(root string, security security.SecurityValidator) *FileServer {
+// NewFileServer crea un nuevo servidor de archivos estáticos que sirve root:
+// normalmente os.DirFS del directorio configurado, o un sistema de archivos
+// embebido
+func NewFileServer(
+	root iofs.FS,
+	security security.SecurityValidator,
+	fallbackFile string,
+	noFallbackPrefixes []string,
+) *FileServer {
 	return &FileServer{
-		fs:      http.FileServer(http.Dir(root)),
-		security: security,
-		root:     root,
+		fs:                 http.FileServerFS(root),
+		security:           security,
+		root:               root,
+		fallbackFile:       fallbackFile,
+		noFallbackPrefixes: noFallbackPrefixes,
+	}
+}
+
+// shouldFallback indica si una ruta inexistente debe resolverse con el archivo de fallback
+func (fs *FileServer) shouldFallback(urlPath string) bool {
+	if fs.fallbackFile == "" {
+		return false
 	}
+	for _, prefix := range fs.noFallbackPrefixes {
+		prefix = strings.TrimSpace(prefix)
+		if prefix == "" {
+			continue
+		}
+		if urlPath == prefix || strings.HasPrefix(urlPath, strings.TrimSuffix(prefix, "/")+"/") {
+			return false
+		}
+	}
+	return true
 }
 
 // ServeHTTP implementa la interfaz http.Handler
@@ -206,17 +1322,33 @@ func (fs *FileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	fs.security.SetSecurityHeaders(w)
 	
 	// Establecer el tipo de contenido correcto según la extensión del archivo
-	path := r.URL.Path
-	if strings.HasSuffix(path, ".css") {
+	reqPath := r.URL.Path
+	if strings.HasSuffix(reqPath, ".css") {
 		w.Header().Set("Content-Type", "text/css; charset=utf-8")
-	} else if strings.HasSuffix(path, ".js") {
+	} else if strings.HasSuffix(reqPath, ".js") {
 		w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
-	} else if strings.HasSuffix(path, ".svg") {
+	} else if strings.HasSuffix(reqPath, ".svg") {
 		w.Header().Set("Content-Type", "image/svg+xml")
-	} else if strings.HasSuffix(path, ".html") {
+	} else if strings.HasSuffix(reqPath, ".html") {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	}
 	
+	// Resolver rutas desconocidas de la SPA
+	cleanPath := path.Clean("/" + r.URL.Path)
+	name := strings.TrimPrefix(cleanPath, "/")
+	if name == "" {
+		name = "."
+	}
+	if _, err := iofs.Stat(fs.root, name); errors.Is(err, iofs.ErrNotExist) {
+		if !fs.shouldFallback(cleanPath) {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		http.ServeFileFS(w, r, fs.root, fs.fallbackFile)
+		return
+	}
+
 	// Servir el archivo
 	fs.fs.ServeHTTP(w, r)
 }
@@ -0,0 +1,518 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/config"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/executor"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/limiter"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/logger"
+	logtest "github.com/luis198755/go_playGround_plus/docker/pkg/logger/test"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/security"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/session"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// echoExecutor escribe output como salida de cualquier código
+type echoExecutor struct {
+	output string
+}
+
+func (e echoExecutor) Execute(ctx context.Context, code string, output io.Writer) error {
+	_, err := io.WriteString(output, e.output)
+	return err
+}
+
+// newTestAPIHandler crea un APIHandler que ejecuta el código Go con ex, sin
+// límite global, cola, cuotas ni auditoría, y el LogObserver de su logger
+func newTestAPIHandler(t *testing.T, ex executor.CodeExecutor) (*APIHandler, *logtest.LogObserver) {
+	t.Helper()
+	return newTestLanguagesHandler(t, map[string]executor.CodeExecutor{executor.DefaultLanguage: ex})
+}
+
+// newTestLanguagesHandler es como newTestAPIHandler con un ejecutor por lenguaje
+func newTestLanguagesHandler(t *testing.T, languages map[string]executor.CodeExecutor) (*APIHandler, *logtest.LogObserver) {
+	t.Helper()
+	executors := executor.NewExecutorRegistry()
+	for language, ex := range languages {
+		err := executors.Register(language, func(*config.Config) (executor.CodeExecutor, error) {
+			return ex, nil
+		})
+		if err != nil {
+			t.Fatalf("registro de ejecutores: %v", err)
+		}
+	}
+	if err := executors.Init(&config.Config{}); err != nil {
+		t.Fatalf("registro de ejecutores: %v", err)
+	}
+
+	log, observed := logtest.NewTestLogger(t)
+	h := NewAPIHandler(
+		limiter.NewRateLimiter(60, 10, nil),
+		nil,
+		security.NewCodeValidator(security.SecurityHeaders{}, nil, nil, true),
+		executors,
+		session.NewSessionStore(10, time.Hour),
+		log,
+		64*1024,
+		0,
+		0,
+		0,
+		5*time.Second,
+		5*time.Second,
+		0,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		false,
+	)
+	return h, observed
+}
+
+func TestHandleExecuteCodeLogsExecution(t *testing.T) {
+	h, observed := newTestAPIHandler(t, echoExecutor{output: "hola\n"})
+
+	r := httptest.NewRequest(http.MethodPost, "/api/execute", strings.NewReader(`{"code":"package main\n\nfunc main() {}\n"}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.HandleExecuteCode(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, se esperaba 200: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "hola") {
+		t.Errorf("respuesta = %q, se esperaba la salida del programa", w.Body.String())
+	}
+	if n := observed.FilterMessage("Ejecutando código").Len(); n != 1 {
+		t.Errorf("se registró %d veces \"Ejecutando código\", se esperaba 1", n)
+	}
+}
+
+// newTestGoExecutor crea un GoExecutor con el toolchain de Go del PATH, o
+// salta el test si no lo hay
+func newTestGoExecutor(t *testing.T, log logger.Logger, autoWrap bool) *executor.GoExecutor {
+	t.Helper()
+	goPath, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("no se encontró el ejecutable de Go")
+	}
+	ge, err := executor.NewGoExecutor(executor.GoExecutorOptions{
+		GoExecutablePath: goPath,
+		MaxStdoutLength:  10000,
+		MaxStderrLength:  10000,
+		TempDir:          t.TempDir(),
+		AutoWrapCode:     autoWrap,
+	}, log)
+	if err != nil {
+		t.Fatalf("NewGoExecutor: %v", err)
+	}
+	return ge
+}
+
+// newValidatingHandler crea un APIHandler con lo justo para validateCode
+func newValidatingHandler(t *testing.T, autoWrap, rejectDirectives bool) (*APIHandler, logger.Logger) {
+	t.Helper()
+	log, _ := logtest.NewTestLogger(t)
+	h := &APIHandler{
+		security: security.NewCodeValidator(security.SecurityHeaders{}, nil, nil, rejectDirectives),
+		executor: newTestGoExecutor(t, log, autoWrap),
+		logger:   log,
+	}
+	h.maxCodeLength.Store(64 * 1024)
+	return h, log
+}
+
+func TestValidateCodeRejectsCgoInWrappedCode(t *testing.T) {
+	h, log := newValidatingHandler(t, true, true)
+
+	// Sin declaración de paquete, solo el código envuelto por AutoWrapCode
+	// tiene un import "C" que go/parser pueda encontrar
+	msg := h.validateCode("import \"C\"\nfunc main(){}", log)
+	if !strings.Contains(msg, `import "C"`) {
+		t.Errorf("validateCode() = %q, se esperaba el rechazo de cgo", msg)
+	}
+}
+
+func TestValidateCodeCgoRejectionToggle(t *testing.T) {
+	code := "package main\n\nimport \"C\"\n\nfunc main() {}\n"
+
+	h, log := newValidatingHandler(t, false, true)
+	if msg := h.validateCode(code, log); !strings.Contains(msg, `import "C"`) {
+		t.Errorf("con el rechazo activado validateCode() = %q, se esperaba el rechazo de cgo", msg)
+	}
+
+	h, log = newValidatingHandler(t, true, false)
+	if msg := h.validateCode("import \"C\"\nfunc main(){}", log); msg != "" {
+		t.Errorf("con el rechazo desactivado validateCode() = %q, se esperaba que se aceptara", msg)
+	}
+}
+
+func TestValidateCodeRejectsForbiddenDirectiveInWrappedCode(t *testing.T) {
+	h, log := newValidatingHandler(t, true, true)
+
+	msg := h.validateCode("//go:linkname now time.now\nfunc now() int64\nfunc main(){}", log)
+	if !strings.Contains(msg, "//go:linkname") {
+		t.Errorf("validateCode() = %q, se esperaba el rechazo de //go:linkname", msg)
+	}
+	if msg := h.validateCode("fmt.Println(\"hola\")", log); msg != "" {
+		t.Errorf("validateCode() rechazó código válido: %q", msg)
+	}
+}
+
+// recordSpans instala durante el test un TracerProvider global que guarda los
+// spans terminados en el SpanRecorder devuelto
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+// spanAttributes devuelve los atributos de span por clave
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestHandleExecuteCodeTracesRequestPath(t *testing.T) {
+	recorder := recordSpans(t)
+	cached := executor.NewCachedExecutor(echoExecutor{output: "hola\n"}, 10, 0, time.Minute)
+	defer cached.Stop()
+	h, _ := newTestAPIHandler(t, cached)
+
+	const code = "package main\n\nfunc main() {}\n"
+	for range 2 {
+		r := httptest.NewRequest(http.MethodPost, "/api/execute", strings.NewReader(`{"code":"package main\n\nfunc main() {}\n"}`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.HandleExecuteCode(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	var roots, executions []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		switch span.Name() {
+		case "HandleExecuteCode":
+			roots = append(roots, span)
+		case "CachedExecutor.Execute":
+			executions = append(executions, span)
+		}
+	}
+	if len(roots) != 2 || len(executions) != 2 {
+		t.Fatalf("se registraron %d spans HandleExecuteCode y %d CachedExecutor.Execute, se esperaban 2 y 2", len(roots), len(executions))
+	}
+
+	for i, span := range executions {
+		if span.Parent().SpanID() != roots[i].SpanContext().SpanID() {
+			t.Errorf("el span %d de CachedExecutor.Execute no es hijo de HandleExecuteCode", i)
+		}
+		attrs := spanAttributes(span)
+		if got := attrs["code.hash"].AsString(); got != executor.HashCode(code) {
+			t.Errorf("code.hash = %q, se esperaba %q", got, executor.HashCode(code))
+		}
+		if wantHit := i == 1; attrs["cache.hit"].AsBool() != wantHit {
+			t.Errorf("cache.hit de la ejecución %d = %v, se esperaba %v", i+1, attrs["cache.hit"].AsBool(), wantHit)
+		}
+	}
+	if got := spanAttributes(roots[0])["execution.timeout_seconds"].AsFloat64(); got != 5 {
+		t.Errorf("execution.timeout_seconds = %v, se esperaba 5", got)
+	}
+}
+
+// slowExecutor escribe una línea y espera a que se cancele el contexto, o a
+// que pase un minuto. Al terminar cierra stopped.
+type slowExecutor struct {
+	stopped chan struct{}
+}
+
+func (e slowExecutor) Execute(ctx context.Context, code string, output io.Writer) error {
+	defer close(e.stopped)
+	if _, err := io.WriteString(output, "empezando\n"); err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Minute):
+		return nil
+	}
+}
+
+func TestHandleCancelExecutionStopsExecution(t *testing.T) {
+	ex := slowExecutor{stopped: make(chan struct{})}
+	h, _ := newTestAPIHandler(t, ex)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/execute", h.HandleExecuteCode)
+	mux.HandleFunc("/api/execute/cancel", h.HandleCancelExecution)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/api/execute", "application/json", strings.NewReader(`{"code":"package main\n\nfunc main() { for {} }\n"}`))
+	if err != nil {
+		t.Fatalf("POST /api/execute: %v", err)
+	}
+	defer resp.Body.Close()
+	body := bufio.NewReader(resp.Body)
+	if line, err := body.ReadString('\n'); err != nil || line != "empezando\n" {
+		t.Fatalf("primera línea = %q, %v; se esperaba la salida del programa", line, err)
+	}
+	requestID := resp.Header.Get("X-Request-ID")
+	if requestID == "" {
+		t.Fatal("la respuesta no incluye X-Request-ID")
+	}
+
+	// Un ID desconocido no cancela nada
+	cancel := func(id string) int {
+		t.Helper()
+		resp, err := http.Post(srv.URL+"/api/execute/cancel", "application/json", strings.NewReader(`{"requestId":"`+id+`"}`))
+		if err != nil {
+			t.Fatalf("POST /api/execute/cancel: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if status := cancel("desconocido"); status != http.StatusNotFound {
+		t.Errorf("cancelar un ID desconocido: status = %d, se esperaba 404", status)
+	}
+
+	start := time.Now()
+	if status := cancel(requestID); status != http.StatusOK {
+		t.Fatalf("cancelar la ejecución: status = %d, se esperaba 200", status)
+	}
+	select {
+	case <-ex.stopped:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("la ejecución no se detuvo en 500ms tras cancelarla")
+	}
+	if _, err := io.ReadAll(body); err != nil {
+		t.Fatalf("leyendo el resto de la respuesta: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("la respuesta terminó %v después de cancelar, se esperaba menos de 500ms", elapsed)
+	}
+
+	// Una ejecución terminada ya no está registrada
+	if status := cancel(requestID); status != http.StatusNotFound {
+		t.Errorf("cancelar una ejecución terminada: status = %d, se esperaba 404", status)
+	}
+}
+
+func TestHandleExecuteCodeRejectsTooManyASTNodes(t *testing.T) {
+	h, _ := newTestAPIHandler(t, echoExecutor{output: "hola\n"})
+	h.maxASTNodes = 1000
+
+	// Unos 2,5 KB de código, muy por debajo de MAX_CODE_LENGTH
+	nested := "package main\n\nfunc main() {\n" +
+		strings.Repeat("for i := 0; i < 1; i++ {\n", 100) +
+		strings.Repeat("}\n", 100) + "}\n"
+	body, err := json.Marshal(map[string]string{"code": nested})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/api/execute", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	h.HandleExecuteCode(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, se esperaba 400: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "demasiado complejo") {
+		t.Errorf("respuesta = %q, se esperaba el rechazo por nodos AST", w.Body.String())
+	}
+
+	// El código mal formado no lo rechaza el límite de nodos sino el compilador
+	if msg := h.validateCode("package main\n\nfunc main() {", h.logger); msg != "" {
+		t.Errorf("validateCode() con código mal formado = %q, se esperaba que pasara al compilador", msg)
+	}
+}
+
+// flushRecorder es un httptest.ResponseRecorder que guarda una copia del cuerpo
+// en cada Flush, con el momento en que se hizo
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	mu      sync.Mutex
+	flushes []flushSnapshot
+}
+
+// flushSnapshot es el cuerpo escrito hasta un Flush
+type flushSnapshot struct {
+	body string
+	at   time.Time
+}
+
+func (fr *flushRecorder) Flush() {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.ResponseRecorder.Flush()
+	fr.flushes = append(fr.flushes, flushSnapshot{body: fr.Body.String(), at: time.Now()})
+}
+
+func TestHandleExecuteCodeFlushesOutputIncrementally(t *testing.T) {
+	log, _ := logtest.NewTestLogger(t)
+	h, _ := newTestAPIHandler(t, newTestGoExecutor(t, log, false))
+
+	code := "package main\n\nimport (\n\t\"fmt\"\n\t\"time\"\n)\n\nfunc main() {\n" +
+		"\tfor i := 1; i <= 5; i++ {\n\t\tfmt.Println(\"línea\", i)\n\t\ttime.Sleep(100 * time.Millisecond)\n\t}\n}\n"
+	body, err := json.Marshal(map[string]string{"code": code})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/api/execute", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	h.HandleExecuteCode(w, r)
+	done := time.Now()
+
+	if !strings.Contains(w.Body.String(), "línea 5") {
+		t.Fatalf("respuesta = %q, se esperaba la salida completa", w.Body.String())
+	}
+	var first *flushSnapshot
+	for i := range w.flushes {
+		if strings.Contains(w.flushes[i].body, "línea 1") {
+			first = &w.flushes[i]
+			break
+		}
+	}
+	if first == nil {
+		t.Fatal("la salida no se envió con Flush")
+	}
+	if strings.Contains(first.body, "línea 5") {
+		t.Errorf("la primera línea no se envió hasta el final: %q", first.body)
+	}
+	// Quedaban al menos cuatro esperas de 100ms tras imprimir la primera línea
+	if early := done.Sub(first.at); early < 300*time.Millisecond {
+		t.Errorf("la primera línea se envió solo %v antes de terminar", early)
+	}
+}
+
+// echoCodeExecutor devuelve el propio código como salida
+type echoCodeExecutor struct{}
+
+func (echoCodeExecutor) Execute(ctx context.Context, code string, output io.Writer) error {
+	_, err := io.WriteString(output, code)
+	return err
+}
+
+func TestHandleExecuteCodeDispatchesByLanguage(t *testing.T) {
+	h, _ := newTestLanguagesHandler(t, map[string]executor.CodeExecutor{
+		executor.DefaultLanguage: echoExecutor{output: "go\n"},
+		"echo":                   echoCodeExecutor{},
+	})
+
+	execute := func(body string) string {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodPost, "/api/execute", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.HandleExecuteCode(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, se esperaba 200: %s", w.Code, w.Body.String())
+		}
+		return w.Body.String()
+	}
+
+	if got := execute(`{"language":"echo","code":"hola eco"}`); !strings.HasPrefix(got, "hola eco") {
+		t.Errorf("lenguaje echo: respuesta = %q, se esperaba el código de vuelta", got)
+	}
+	// Sin lenguaje se usa Go
+	if got := execute(`{"code":"package main\n\nfunc main() {}\n"}`); !strings.HasPrefix(got, "go\n") {
+		t.Errorf("sin lenguaje: respuesta = %q, se esperaba la salida del ejecutor de Go", got)
+	}
+	// Los imports prohibidos son los de cada lenguaje: os/exec solo lo es en Go
+	if got := execute(`{"language":"echo","code":"import \"os/exec\""}`); !strings.HasPrefix(got, `import "os/exec"`) {
+		t.Errorf("lenguaje echo con os/exec: respuesta = %q, se esperaba que se ejecutara", got)
+	}
+	if got := execute(`{"code":"package main\n\nimport \"os/exec\"\n\nfunc main() { exec.Command(\"ls\") }\n"}`); !strings.Contains(got, "Import prohibido") {
+		t.Errorf("Go con os/exec: respuesta = %q, se esperaba el rechazo", got)
+	}
+	if got := execute(`{"language":"python","code":"print(1)"}`); !strings.Contains(got, "Lenguaje no soportado: python (disponibles: echo, go)") {
+		t.Errorf("lenguaje no registrado: respuesta = %q, se esperaba el rechazo", got)
+	}
+}
+
+func TestHandleExecuteCodeRejectsOversizedLiterals(t *testing.T) {
+	h, _ := newTestAPIHandler(t, echoExecutor{output: "hola\n"})
+	h.maxCodeLength.Store(256 * 1024)
+	h.maxLiteralBytes = 10 * 1024
+
+	code := "package main\n\nimport \"fmt\"\n\nvar payload = \"" + strings.Repeat("QUFB", 25*1024) + "\"\n\nfunc main() { fmt.Println(len(payload)) }\n"
+	body, err := json.Marshal(map[string]string{"code": code})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/api/execute", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	h.HandleExecuteCode(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, se esperaba 400: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "cadena literal de 102400 bytes (máximo 10240)") {
+		t.Errorf("respuesta = %q, se esperaba el rechazo por la cadena literal", w.Body.String())
+	}
+}
+
+func TestFileServerSPAFallback(t *testing.T) {
+	root := fstest.MapFS{
+		"index.html":    {Data: []byte("<html>playground</html>")},
+		"assets/app.js": {Data: []byte("console.log('app')")},
+	}
+	fileServer := NewFileServer(root, security.NewCodeValidator(security.SecurityHeaders{}, nil, nil, true), "index.html", []string{"/api"})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		fileServer.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	// Las rutas de la SPA que no existen como archivo reciben index.html
+	w := serve("/some/route")
+	if w.Code != http.StatusOK || w.Body.String() != "<html>playground</html>" {
+		t.Errorf("/some/route: status = %d, cuerpo = %q, se esperaba index.html", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/html") {
+		t.Errorf("/some/route: Content-Type = %q, se esperaba text/html", got)
+	}
+
+	// Las rutas de la API desconocidas no se resuelven con la SPA
+	if w := serve("/api/unknown"); w.Code != http.StatusNotFound {
+		t.Errorf("/api/unknown: status = %d, se esperaba 404", w.Code)
+	}
+
+	// Los archivos existentes se sirven tal cual
+	w = serve("/assets/app.js")
+	if w.Code != http.StatusOK || w.Body.String() != "console.log('app')" {
+		t.Errorf("/assets/app.js: status = %d, cuerpo = %q", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "application/javascript") {
+		t.Errorf("/assets/app.js: Content-Type = %q, se esperaba application/javascript", got)
+	}
+}
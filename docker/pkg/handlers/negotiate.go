@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// prefersJSON indica si la cabecera Accept prefiere application/json frente al
+// texto plano de /api/execute.
+//
+// Se elige JSON solo si application/json tiene un valor q mayor que text/plain,
+// text/* y */*. Ante un empate, una cabecera vacía o tipos desconocidos se
+// mantiene el streaming de texto, que es la representación por defecto.
+func prefersJSON(accept string) bool {
+	jsonQ, textQ := 0.0, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+
+		switch mediaType {
+		case "application/json":
+			jsonQ = max(jsonQ, q)
+		case "text/plain", "text/*", "*/*":
+			textQ = max(textQ, q)
+		}
+	}
+	return jsonQ > textQ
+}
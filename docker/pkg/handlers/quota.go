@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/errors"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/logger"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/requestctx"
+	"go.uber.org/zap"
+)
+
+// acquireQuota cuenta n ejecuciones en la cuota de la clave de API con la que
+// se autenticó la solicitud. Si la cuota está agotada responde con 429 y
+// devuelve false. Sin cuotas configuradas o sin clave de API no hace nada.
+func (h *APIHandler) acquireQuota(w http.ResponseWriter, r *http.Request, reqLogger logger.Logger, n int) bool {
+	keyID := requestctx.APIKeyID(r.Context())
+	if h.quotas == nil || keyID == "" {
+		return true
+	}
+	usage, ok := h.quotas.Acquire(keyID, n)
+	if ok {
+		if remaining := usage.Remaining(); remaining >= 0 {
+			w.Header().Set("X-Quota-Remaining", strconv.Itoa(remaining))
+		}
+		return true
+	}
+	reqLogger.Warn("Cuota de la clave de API agotada",
+		zap.String("api_key_id", keyID),
+		zap.Int("quota", usage.Quota))
+	w.Header().Set("X-Quota-Remaining", "0")
+	err := errors.TooManyRequests(
+		errors.New("cuota agotada"),
+		fmt.Sprintf("Cuota agotada: la clave de API admite %d ejecuciones cada 24 horas", usage.Quota),
+		map[string]interface{}{"api_key_id": keyID, "quota": usage.Quota, "executions": usage.Executions},
+	).WithCode(errors.CodeQuotaExceeded)
+	errors.HTTPError(w, r, reqLogger, err)
+	return false
+}
+
+// recordUsage suma el tiempo de CPU y el tiempo real de una ejecución al uso
+// de la clave de API de la solicitud, si tiene
+func (h *APIHandler) recordUsage(r *http.Request, cpuTime, wallTime time.Duration) {
+	if keyID := requestctx.APIKeyID(r.Context()); h.quotas != nil && keyID != "" {
+		h.quotas.Record(keyID, cpuTime, wallTime)
+	}
+}
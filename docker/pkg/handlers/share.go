@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/errors"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/logger"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/share"
+	"go.uber.org/zap"
+)
+
+// ImportResponse es la respuesta de /api/import/{id}
+type ImportResponse struct {
+	ID   string `json:"id"`
+	Code string `json:"code"`
+}
+
+// HandleImportShare descarga el código de un enlace compartido del playground
+// oficial, identificado por el parámetro {id} de la ruta, y lo devuelve para
+// ejecutarlo aquí. El código pasa las mismas validaciones que en /api/execute.
+func (h *APIHandler) HandleImportShare(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	reqLogger := h.logger.With(
+		zap.String("client_ip", h.security.GetClientIP(r)),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.String("share_id", id),
+	)
+
+	if r.Method != http.MethodGet {
+		err := errors.WithContext(
+			errors.New("método no permitido"),
+			http.StatusMethodNotAllowed,
+			"Método no permitido",
+			map[string]interface{}{"method": r.Method},
+		)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+
+	if h.importer == nil {
+		err := errors.NotFound(
+			errors.New("importación desactivada"),
+			"La importación desde el playground oficial no está habilitada",
+			nil,
+		)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+
+	// Cada importación es una solicitud saliente: cuenta para el rate limit
+	if !h.checkRateLimit(w, r, reqLogger) {
+		return
+	}
+
+	code, err := h.importer.Fetch(r.Context(), id)
+	if err != nil {
+		errors.HTTPError(w, r, reqLogger, importError(err, id, h.currentMaxCodeLength()))
+		return
+	}
+
+	if msg := h.validateCode(code, reqLogger); msg != "" {
+		err := errors.BadRequest(errors.New("código inválido"), msg, map[string]interface{}{"id": id}).
+			WithCode(errors.CodeInvalidCode)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+	reqLogger.Info("Código importado del playground", zap.Int("code_length", len(code)))
+
+	h.security.SetSecurityHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(ImportResponse{ID: id, Code: code}); err != nil {
+		reqLogger.Error("Error al codificar respuesta JSON", zap.Error(err))
+	}
+}
+
+// importError convierte un error de share.Importer en la respuesta para el cliente
+func importError(err error, id string, maxCodeLength int) *errors.AppError {
+	details := map[string]interface{}{"id": id}
+	var netErr net.Error
+	switch {
+	case errors.Is(err, share.ErrInvalidID):
+		return errors.BadRequest(err, "El ID del enlace compartido no es válido", details)
+	case errors.Is(err, share.ErrNotFound):
+		return errors.NotFound(err, "No existe ningún código compartido con ese ID", details)
+	case errors.Is(err, share.ErrTooLarge):
+		details["max_length"] = maxCodeLength
+		return errors.BadRequest(err, "El código compartido excede el tamaño máximo", details).
+			WithCode(errors.CodeInvalidCode)
+	case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()):
+		return errors.WithContext(err, http.StatusGatewayTimeout,
+			"El playground oficial no respondió a tiempo, inténtelo de nuevo más tarde", details)
+	default:
+		return errors.WithContext(err, http.StatusBadGateway,
+			"No se pudo obtener el código del playground oficial", details)
+	}
+}
+
+// ShareURLRequest es la solicitud de /api/share/encode
+type ShareURLRequest struct {
+	Code string `json:"code"`
+}
+
+// ShareURLResponse es la respuesta de /api/share/encode
+type ShareURLResponse struct {
+	URL string `json:"url"`
+}
+
+// SharedCodeResponse es la respuesta de /s
+type SharedCodeResponse struct {
+	Code string `json:"code"`
+}
+
+// HandleEncodeShareURL genera un enlace "/s?c=..." que contiene el código
+// comprimido, para compartirlo sin guardarlo en el servidor. Acepta GET y POST
+// con un cuerpo JSON {"code": "..."}.
+func (h *APIHandler) HandleEncodeShareURL(w http.ResponseWriter, r *http.Request) {
+	reqLogger := h.logger.With(
+		zap.String("client_ip", h.security.GetClientIP(r)),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+	)
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		err := errors.WithContext(
+			errors.New("método no permitido"),
+			http.StatusMethodNotAllowed,
+			"Método no permitido",
+			map[string]interface{}{"method": r.Method},
+		)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		err := errors.BadRequest(
+			errors.New("content-type inválido"),
+			"Content-Type debe ser application/json",
+			map[string]interface{}{"content_type": r.Header.Get("Content-Type")},
+		)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+
+	defer r.Body.Close()
+	var req ShareURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		err := errors.BadRequest(errors.Wrap(err, "error al decodificar JSON"), "Solicitud inválida", nil)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+
+	if msg := h.validateSharedCode(req.Code, reqLogger); msg != "" {
+		err := errors.BadRequest(errors.New("código inválido"), msg, nil).WithCode(errors.CodeInvalidCode)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+
+	link, err := share.EncodeURL(req.Code)
+	if err != nil {
+		appErr := errors.WithContext(err, http.StatusRequestEntityTooLarge,
+			"El código es demasiado grande para incluirlo en un enlace",
+			map[string]interface{}{"max_url_length": share.MaxURLLength})
+		errors.HTTPError(w, r, reqLogger, appErr)
+		return
+	}
+	reqLogger.Info("Enlace con el código generado",
+		zap.Int("code_length", len(req.Code)),
+		zap.Int("url_length", len(link)))
+
+	h.security.SetSecurityHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(ShareURLResponse{URL: link}); err != nil {
+		reqLogger.Error("Error al codificar respuesta JSON", zap.Error(err))
+	}
+}
+
+// HandleSharedURL devuelve, sin ejecutarlo, el código incluido en un enlace de
+// HandleEncodeShareURL ("/s?c=..."), para que el cliente lo cargue en el editor.
+// El código pasa las mismas validaciones que al generar el enlace.
+func (h *APIHandler) HandleSharedURL(w http.ResponseWriter, r *http.Request) {
+	reqLogger := h.logger.With(
+		zap.String("client_ip", h.security.GetClientIP(r)),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+	)
+
+	if r.Method != http.MethodGet {
+		err := errors.WithContext(
+			errors.New("método no permitido"),
+			http.StatusMethodNotAllowed,
+			"Método no permitido",
+			map[string]interface{}{"method": r.Method},
+		)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+
+	param := r.URL.Query().Get("c")
+	if param == "" {
+		err := errors.BadRequest(errors.New("falta el parámetro c"), "El enlace no incluye ningún código", nil)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+
+	maxCodeLength := h.currentMaxCodeLength()
+	code, err := share.DecodeURLCode(param, maxCodeLength)
+	if err != nil {
+		msg := "El enlace no contiene un código válido"
+		details := map[string]interface{}{}
+		switch {
+		case errors.Is(err, share.ErrURLTooLong):
+			msg = "El enlace es demasiado largo"
+			details["max_url_length"] = share.MaxURLLength
+		case errors.Is(err, share.ErrTooLarge):
+			msg = "El código del enlace excede el tamaño máximo"
+			details["max_length"] = maxCodeLength
+		}
+		errors.HTTPError(w, r, reqLogger, errors.BadRequest(err, msg, details))
+		return
+	}
+
+	// El código se carga en el editor del cliente: solo se devuelve si es Go válido
+	if msg := h.validateSharedCode(code, reqLogger); msg != "" {
+		err := errors.BadRequest(errors.New("código inválido"), msg, nil).WithCode(errors.CodeInvalidCode)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+	reqLogger.Info("Código del enlace decodificado", zap.Int("code_length", len(code)))
+
+	h.security.SetSecurityHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(SharedCodeResponse{Code: code}); err != nil {
+		reqLogger.Error("Error al codificar respuesta JSON", zap.Error(err))
+	}
+}
+
+// validateSharedCode aplica validateCode y, además, exige que el código sea Go
+// sintácticamente válido, para que un enlace no pueda inyectar contenido
+// arbitrario en el editor. Devuelve un mensaje para el usuario o una cadena vacía.
+func (h *APIHandler) validateSharedCode(code string, reqLogger logger.Logger) string {
+	if msg := h.validateCode(code, reqLogger); msg != "" {
+		return msg
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "main.go", h.prepareCode(code).FormattedCode, 0); err != nil {
+		reqLogger.Warn("Código compartido con errores de sintaxis", zap.Error(err))
+		return "El código compartido no es Go válido: " + err.Error()
+	}
+	return ""
+}
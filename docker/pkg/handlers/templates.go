@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/errors"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/logger"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/security"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/templates"
+	"go.uber.org/zap"
+)
+
+// TemplateHandler implementa los manejadores HTTP de la biblioteca de plantillas
+type TemplateHandler struct {
+	library  *templates.Library
+	security security.SecurityValidator
+	logger   logger.Logger
+}
+
+// NewTemplateHandler crea un nuevo manejador de plantillas
+func NewTemplateHandler(
+	library *templates.Library,
+	security security.SecurityValidator,
+	log logger.Logger,
+) *TemplateHandler {
+	return &TemplateHandler{
+		library:  library,
+		security: security,
+		logger:   log,
+	}
+}
+
+// HandleListTemplates devuelve todas las plantillas como un arreglo JSON
+func (h *TemplateHandler) HandleListTemplates(w http.ResponseWriter, r *http.Request) {
+	reqLogger := h.requestLogger(r)
+	if !h.checkGet(w, r, reqLogger) {
+		return
+	}
+
+	h.writeJSON(w, reqLogger, h.library.All())
+}
+
+// HandleGetTemplate devuelve la plantilla identificada por el parámetro {id} de la ruta
+func (h *TemplateHandler) HandleGetTemplate(w http.ResponseWriter, r *http.Request) {
+	reqLogger := h.requestLogger(r)
+	if !h.checkGet(w, r, reqLogger) {
+		return
+	}
+
+	id := r.PathValue("id")
+	tpl, ok := h.library.Get(id)
+	if !ok {
+		err := errors.NotFound(
+			errors.New("plantilla no encontrada"),
+			"Plantilla no encontrada",
+			map[string]interface{}{"id": id},
+		)
+		errors.HTTPError(w, r, reqLogger, err)
+		return
+	}
+
+	h.writeJSON(w, reqLogger, tpl)
+}
+
+// requestLogger crea un logger con el contexto de la solicitud
+func (h *TemplateHandler) requestLogger(r *http.Request) logger.Logger {
+	return h.logger.With(
+		zap.String("client_ip", h.security.GetClientIP(r)),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+	)
+}
+
+// checkGet responde con 405 si el método no es GET y devuelve si la solicitud puede continuar
+func (h *TemplateHandler) checkGet(w http.ResponseWriter, r *http.Request, reqLogger logger.Logger) bool {
+	if r.Method == http.MethodGet {
+		return true
+	}
+	err := errors.WithContext(
+		errors.New("método no permitido"),
+		http.StatusMethodNotAllowed,
+		"Método no permitido",
+		map[string]interface{}{"method": r.Method},
+	)
+	errors.HTTPError(w, r, reqLogger, err)
+	return false
+}
+
+// writeJSON escribe v como respuesta JSON con los encabezados de seguridad
+func (h *TemplateHandler) writeJSON(w http.ResponseWriter, reqLogger logger.Logger, v interface{}) {
+	h.security.SetSecurityHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		reqLogger.Error("Error al codificar respuesta JSON", zap.Error(err))
+	}
+}
@@ -0,0 +1,75 @@
+package limiter
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// GlobalLimiter define el comportamiento de un limitador común a todos los
+// clientes, que acota el total de ejecuciones del servidor
+type GlobalLimiter interface {
+	// Allow consume n tokens si están disponibles. Si no, no consume nada y
+	// devuelve cuánto falta para que lo estén.
+	Allow(n int) (bool, time.Duration)
+}
+
+// GlobalStats es el estado de un GlobalTokenBucket
+type GlobalStats struct {
+	// Available son los tokens disponibles ahora mismo (nunca negativo)
+	Available float64
+	// Rejected cuenta las solicitudes rechazadas desde el arranque
+	Rejected uint64
+}
+
+// GlobalTokenBucket implementa GlobalLimiter con un único token bucket
+// compartido por todos los clientes. Protege frente a avalanchas repartidas
+// entre muchas IPs, que el rate limit por IP no detecta.
+type GlobalTokenBucket struct {
+	bucket   TokenBucket
+	rejected atomic.Uint64
+}
+
+// NewGlobalTokenBucket crea un limitador global que admite perSecond
+// ejecuciones por segundo, con ráfagas de hasta burstSize
+func NewGlobalTokenBucket(perSecond, burstSize int) *GlobalTokenBucket {
+	return &GlobalTokenBucket{
+		bucket: TokenBucket{
+			tokens:         float64(burstSize),
+			capacity:       float64(burstSize),
+			refillRate:     float64(perSecond),
+			lastRefillTime: time.Now(),
+		},
+	}
+}
+
+// Allow implementa GlobalLimiter
+func (g *GlobalTokenBucket) Allow(n int) (bool, time.Duration) {
+	b := &g.bucket
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(time.Now())
+	// Una solicitud que cuesta más que la ráfaga se admite con el bucket
+	// lleno y deja los tokens en negativo, que se recuperan con la recarga
+	need := math.Min(float64(n), b.capacity)
+	if b.tokens >= need {
+		b.tokens -= float64(n)
+		return true, 0
+	}
+	g.rejected.Add(1)
+	return false, time.Duration((need - b.tokens) / b.refillRate * float64(time.Second))
+}
+
+// Stats devuelve el estado actual, por ejemplo para metrics.RegisterGlobalRateLimit
+func (g *GlobalTokenBucket) Stats() GlobalStats {
+	g.bucket.mu.Lock()
+	g.bucket.refill(time.Now())
+	available := math.Max(g.bucket.tokens, 0)
+	g.bucket.mu.Unlock()
+
+	return GlobalStats{
+		Available: available,
+		Rejected:  g.rejected.Load(),
+	}
+}
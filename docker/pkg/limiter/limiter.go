@@ -1,7 +1,11 @@
 package limiter
 
 import (
+	"context"
+	"errors"
+	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -10,8 +14,24 @@ type RateLimiterInterface interface {
 	IsAllowed(ip string) bool
 }
 
+// RateLimitObserver recibe el resultado de cada decisión del limitador, por
+// ejemplo para publicar métricas (ver metrics.PrometheusRateLimitObserver).
+// Se llama de forma síncrona en IsAllowed, por lo que debe ser rápido y seguro
+// para uso concurrente.
+type RateLimitObserver interface {
+	ObserveAllowed(ip string)
+	ObserveDenied(ip string)
+}
+
+// nopObserver es el RateLimitObserver que se usa si no se indica ninguno
+type nopObserver struct{}
+
+func (nopObserver) ObserveAllowed(string) {}
+func (nopObserver) ObserveDenied(string)  {}
+
 // TokenBucket implementa el algoritmo de token bucket para rate limiting
 type TokenBucket struct {
+	mu            sync.Mutex // Serializa las actualizaciones de los tokens
 	tokens        float64    // Tokens actuales en el bucket
 	capacity      float64    // Capacidad máxima del bucket
 	refillRate    float64    // Tokens por segundo que se añaden
@@ -20,61 +40,83 @@ type TokenBucket struct {
 
 // RateLimiter implementa un limitador de tasa basado en IP usando token bucket
 type RateLimiter struct {
-	buckets       map[string]*TokenBucket
-	mu           sync.RWMutex
+	store        Store   // Almacenamiento de los buckets por IP
 	capacity     float64 // Capacidad máxima del bucket
-	refillRate   float64 // Tokens por segundo que se añaden
+	refillRate   atomic.Uint64 // Tokens por segundo que se añaden (bits de un float64, ver SetRate)
+	observer     RateLimitObserver // Recibe cada decisión (permitida o denegada)
 }
 
 // NewRateLimiter crea un nuevo limitador de tasa con algoritmo token bucket
-func NewRateLimiter(maxRequestsPerMin int) *RateLimiter {
-	// Convertimos solicitudes por minuto a tokens por segundo
-	refillRate := float64(maxRequestsPerMin) / 60.0
-	
-	// La capacidad del bucket es igual al máximo de solicitudes por minuto
-	// para permitir ráfagas controladas
-	return &RateLimiter{
-		buckets:     make(map[string]*TokenBucket),
-		capacity:    float64(maxRequestsPerMin),
-		refillRate:  refillRate,
+// que guarda los buckets en memoria, particionados por IP (ver ShardedStore).
+// Cada IP puede hacer hasta burstSize solicitudes seguidas y después
+// maxRequestsPerMin por minuto. observer recibe cada decisión; nil no observa nada.
+func NewRateLimiter(maxRequestsPerMin, burstSize int, observer RateLimitObserver) *RateLimiter {
+	return NewRateLimiterWithStore(maxRequestsPerMin, burstSize, NewShardedStore(DefaultShardCount), observer)
+}
+
+// NewRateLimiterWithStore crea un limitador de tasa igual que NewRateLimiter,
+// pero guardando los buckets en store
+func NewRateLimiterWithStore(maxRequestsPerMin, burstSize int, store Store, observer RateLimitObserver) *RateLimiter {
+	if observer == nil {
+		observer = nopObserver{}
 	}
+
+
+	// La capacidad del bucket es la ráfaga máxima, independiente del ritmo
+	// sostenido: con burst=10 y 30/min caben 10 tokens que se recargan a 0.5/s
+	rl := &RateLimiter{
+		store:       store,
+		capacity:    float64(burstSize),
+		observer:    observer,
+	}
+	rl.SetRate(maxRequestsPerMin)
+	return rl
 }
 
-// IsAllowed verifica si una IP está permitida para hacer una solicitud usando token bucket
+// SetRate cambia en caliente el ritmo sostenido a maxRequestsPerMin
+// solicitudes por minuto, por ejemplo desde PATCH /api/config. Se aplica a
+// todos los buckets, también a los existentes, a partir de su próxima
+// solicitud; la ráfaga no cambia.
+func (rl *RateLimiter) SetRate(maxRequestsPerMin int) {
+	// Convertimos solicitudes por minuto a tokens por segundo
+	rl.refillRate.Store(math.Float64bits(float64(maxRequestsPerMin) / 60.0))
+}
+
+// IsAllowed verifica si una IP está permitida para hacer una solicitud usando
+// token bucket, e informa de la decisión al observador
 func (rl *RateLimiter) IsAllowed(ip string) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
+	allowed := rl.allow(ip)
+	if allowed {
+		rl.observer.ObserveAllowed(ip)
+	} else {
+		rl.observer.ObserveDenied(ip)
+	}
+	return allowed
+}
 
+// allow aplica el algoritmo token bucket al bucket de ip
+func (rl *RateLimiter) allow(ip string) bool {
 	now := time.Now()
+	refillRate := math.Float64frombits(rl.refillRate.Load())
 	
-	// Obtener o crear el bucket para esta IP
-	bucket, exists := rl.buckets[ip]
+	// Obtener o crear el bucket para esta IP. Las nuevas IPs empiezan con el
+	// bucket lleno; si varias solicitudes de una IP nueva llegan a la vez,
+	// LoadOrStore garantiza que todas comparten el mismo bucket
+	bucket, exists := rl.store.Get(ip)
 	if !exists {
-		// Para nuevas IPs, crear un bucket lleno
-		bucket = &TokenBucket{
-			tokens:        rl.capacity,
-			capacity:      rl.capacity,
-			refillRate:    rl.refillRate,
+		bucket, _ = rl.store.LoadOrStore(ip, &TokenBucket{
+			tokens:         rl.capacity,
+			capacity:       rl.capacity,
+			refillRate:     refillRate,
 			lastRefillTime: now,
-		}
-		rl.buckets[ip] = bucket
-		return true
+		})
 	}
 	
-	// Calcular cuánto tiempo ha pasado desde la última recarga
-	elapsed := now.Sub(bucket.lastRefillTime).Seconds()
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
 	
-	// Añadir tokens basados en el tiempo transcurrido
-	newTokens := elapsed * bucket.refillRate
-	bucket.tokens += newTokens
-	
-	// Limitar tokens a la capacidad máxima
-	if bucket.tokens > bucket.capacity {
-		bucket.tokens = bucket.capacity
-	}
-	
-	// Actualizar el tiempo de la última recarga
-	bucket.lastRefillTime = now
+	bucket.refillRate = refillRate
+	bucket.refill(now)
 	
 	// Verificar si hay suficientes tokens para esta solicitud
 	if bucket.tokens >= 1.0 {
@@ -85,3 +127,43 @@ func (rl *RateLimiter) IsAllowed(ip string) bool {
 	
 	return false
 }
+
+// refill añade los tokens acumulados desde la última recarga, sin superar la
+// capacidad. Debe llamarse con mu bloqueado.
+//
+// now y lastRefillTime vienen de time.Now(), así que la resta usa el reloj
+// monótono y un ajuste del reloj del sistema (NTP, cambio de hora) no le
+// afecta. Aun así el tiempo transcurrido nunca se toma negativo, por si alguna
+// de las dos marcas perdió la lectura monótona (por ejemplo al pasar por
+// Round o al serializarse): restaría tokens y bloquearía la IP. En ese caso
+// tampoco se retrasa lastRefillTime, para no contar dos veces el mismo
+// intervalo.
+func (b *TokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.lastRefillTime)
+	if elapsed <= 0 {
+		return
+	}
+	b.tokens = math.Min(b.capacity, b.tokens+elapsed.Seconds()*b.refillRate)
+	b.lastRefillTime = now
+}
+
+// Ready comprueba que el limitador está inicializado y que su almacén de
+// buckets responde. Pensado como comprobación de disponibilidad (/readyz).
+func (rl *RateLimiter) Ready(ctx context.Context) error {
+	if rl.store == nil {
+		return errors.New("el rate limiter no está inicializado")
+	}
+
+	responded := make(chan struct{})
+	go func() {
+		rl.store.Get("")
+		close(responded)
+	}()
+
+	select {
+	case <-responded:
+		return nil
+	case <-ctx.Done():
+		return errors.New("el rate limiter no responde")
+	}
+}
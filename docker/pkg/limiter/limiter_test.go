@@ -0,0 +1,254 @@
+package limiter
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// benchmarkIsAllowed mide IsAllowed con muchas IPs distintas en paralelo
+// guardando los buckets en store
+func benchmarkIsAllowed(b *testing.B, store Store) {
+	const ips = 10000
+	keys := make([]string, ips)
+	for i := range keys {
+		keys[i] = "10.0." + strconv.Itoa(i/256) + "." + strconv.Itoa(i%256)
+	}
+	rl := NewRateLimiterWithStore(1000000, 1000000, store, nil)
+
+	var next atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := int(next.Add(1)) * 7919
+		for pb.Next() {
+			rl.IsAllowed(keys[i%ips])
+			i++
+		}
+	})
+}
+
+// BenchmarkIsAllowedSingleMutex usa un único MemoryStore, con un solo mutex
+// para todas las IPs, como referencia frente a BenchmarkIsAllowedSharded
+func BenchmarkIsAllowedSingleMutex(b *testing.B) {
+	benchmarkIsAllowed(b, NewMemoryStore())
+}
+
+// BenchmarkIsAllowedSharded usa el ShardedStore de NewRateLimiter. Con
+// -cpu=8 o más, las IPs distintas apenas compiten por el mismo mutex.
+func BenchmarkIsAllowedSharded(b *testing.B) {
+	benchmarkIsAllowed(b, NewShardedStore(DefaultShardCount))
+}
+
+func TestShardedStore(t *testing.T) {
+	store := NewShardedStore(4)
+	for i := range 100 {
+		store.Set(strconv.Itoa(i), &TokenBucket{tokens: float64(i)})
+	}
+	for i := range 100 {
+		bucket, ok := store.Get(strconv.Itoa(i))
+		if !ok || bucket.tokens != float64(i) {
+			t.Fatalf("Get(%d) = %v, %v", i, bucket, ok)
+		}
+	}
+
+	store.Delete("42")
+	if _, ok := store.Get("42"); ok {
+		t.Error("la clave eliminada sigue en el almacén")
+	}
+	count := 0
+	store.Range(func(string, *TokenBucket) bool {
+		count++
+		return true
+	})
+	if count != 99 {
+		t.Errorf("Range recorrió %d claves, se esperaban 99", count)
+	}
+
+	// Las claves se reparten entre todas las particiones
+	for i, shard := range store.shards {
+		if len(shard.buckets) == 0 {
+			t.Errorf("la partición %d está vacía", i)
+		}
+	}
+}
+
+// countingObserver cuenta las decisiones del limitador
+type countingObserver struct {
+	allowed, denied atomic.Int64
+}
+
+func (o *countingObserver) ObserveAllowed(string) { o.allowed.Add(1) }
+func (o *countingObserver) ObserveDenied(string)  { o.denied.Add(1) }
+
+func TestRateLimiterObserver(t *testing.T) {
+	observer := &countingObserver{}
+	rl := NewRateLimiter(60, 3, observer)
+	for range 5 {
+		rl.IsAllowed("192.0.2.1")
+	}
+	rl.IsAllowed("192.0.2.2")
+
+	if got := observer.allowed.Load(); got != 4 {
+		t.Errorf("ObserveAllowed se llamó %d veces, se esperaban 4", got)
+	}
+	if got := observer.denied.Load(); got != 2 {
+		t.Errorf("ObserveDenied se llamó %d veces, se esperaban 2", got)
+	}
+}
+
+func TestRateLimiterWithoutObserver(t *testing.T) {
+	rl := NewRateLimiter(60, 1, nil)
+	if !rl.IsAllowed("192.0.2.1") || rl.IsAllowed("192.0.2.1") {
+		t.Error("el limitador sin observador no aplica la ráfaga de 1")
+	}
+}
+
+// rewind adelanta el reloj del bucket de ip, como si hubiera pasado elapsed
+// desde su última recarga
+func rewind(t *testing.T, rl *RateLimiter, ip string, elapsed time.Duration) {
+	t.Helper()
+	bucket, ok := rl.store.Get(ip)
+	if !ok {
+		t.Fatalf("no hay bucket para %s", ip)
+	}
+	bucket.mu.Lock()
+	bucket.lastRefillTime = bucket.lastRefillTime.Add(-elapsed)
+	bucket.mu.Unlock()
+}
+
+// countAllowed hace n solicitudes seguidas de ip y devuelve cuántas se permiten
+func countAllowed(rl *RateLimiter, ip string, n int) int {
+	allowed := 0
+	for range n {
+		if rl.IsAllowed(ip) {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func TestRateLimiterBurstSeparateFromRate(t *testing.T) {
+	const ip = "192.0.2.1"
+	// Ráfaga de 5 y 30 por minuto: 5 tokens que se recargan a 0.5/s
+	rl := NewRateLimiter(30, 5, nil)
+
+	if allowed := countAllowed(rl, ip, 10); allowed != 5 {
+		t.Fatalf("de 10 solicitudes seguidas se permitieron %d, se esperaban 5", allowed)
+	}
+
+	// En 10 segundos se recargan 5 tokens
+	rewind(t, rl, ip, 10*time.Second)
+	if allowed := countAllowed(rl, ip, 10); allowed != 5 {
+		t.Errorf("tras 10 segundos se permitieron %d solicitudes, se esperaban 5", allowed)
+	}
+}
+
+// setLastRefill fija la última recarga del bucket de ip
+func setLastRefill(t *testing.T, rl *RateLimiter, ip string, lastRefill time.Time) {
+	t.Helper()
+	bucket, ok := rl.store.Get(ip)
+	if !ok {
+		t.Fatalf("no hay bucket para %s", ip)
+	}
+	bucket.mu.Lock()
+	bucket.lastRefillTime = lastRefill
+	bucket.mu.Unlock()
+}
+
+// bucketTokens devuelve los tokens del bucket de ip
+func bucketTokens(t *testing.T, rl *RateLimiter, ip string) float64 {
+	t.Helper()
+	bucket, ok := rl.store.Get(ip)
+	if !ok {
+		t.Fatalf("no hay bucket para %s", ip)
+	}
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+	return bucket.tokens
+}
+
+func TestRateLimiterClockJumps(t *testing.T) {
+	const ip = "192.0.2.1"
+	rl := NewRateLimiter(60, 5, nil)
+	countAllowed(rl, ip, 3)
+
+	// Una recarga de hace 100 horas llena el bucket sin pasar de la capacidad
+	setLastRefill(t, rl, ip, time.Now().Add(-100*time.Hour))
+	if allowed := countAllowed(rl, ip, 10); allowed != 5 {
+		t.Errorf("tras 100 horas se permitieron %d solicitudes, se esperaba la ráfaga de 5", allowed)
+	}
+
+	// Una última recarga en el futuro, sin lectura monótona, como si el reloj
+	// del sistema hubiera retrocedido 100 horas: no resta tokens
+	rl = NewRateLimiter(60, 5, nil)
+	countAllowed(rl, ip, 3)
+	setLastRefill(t, rl, ip, time.Now().Add(100*time.Hour).Round(0))
+	if allowed := countAllowed(rl, ip, 10); allowed != 2 {
+		t.Errorf("tras retroceder el reloj se permitieron %d solicitudes, se esperaban los 2 tokens restantes", allowed)
+	}
+	if tokens := bucketTokens(t, rl, ip); tokens < 0 {
+		t.Errorf("el bucket quedó con %v tokens", tokens)
+	}
+}
+
+// missBarrierStore retiene cada Get de una clave inexistente hasta que n
+// llamadas han fallado (o pasa un segundo), para que las solicitudes
+// concurrentes de una IP nueva coincidan aunque haya una sola CPU
+type missBarrierStore struct {
+	Store
+	n      int32
+	misses atomic.Int32
+	ready  chan struct{}
+}
+
+func newMissBarrierStore(store Store, n int32) *missBarrierStore {
+	return &missBarrierStore{Store: store, n: n, ready: make(chan struct{})}
+}
+
+func (s *missBarrierStore) Get(key string) (*TokenBucket, bool) {
+	bucket, ok := s.Store.Get(key)
+	if ok {
+		return bucket, ok
+	}
+	if s.misses.Add(1) == s.n {
+		close(s.ready)
+	}
+	select {
+	case <-s.ready:
+	case <-time.After(time.Second):
+	}
+	return nil, false
+}
+
+func TestRateLimiterConcurrentNewIP(t *testing.T) {
+	const (
+		ip       = "192.0.2.1"
+		burst    = 5
+		requests = 50
+	)
+	for _, store := range []Store{NewMemoryStore(), NewShardedStore(0)} {
+		rl := NewRateLimiterWithStore(1, burst, newMissBarrierStore(store, requests), nil)
+
+		var allowed atomic.Int32
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for range requests {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				if rl.IsAllowed(ip) {
+					allowed.Add(1)
+				}
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		if n := allowed.Load(); n != burst {
+			t.Errorf("%T: de %d solicitudes simultáneas de una IP nueva se permitieron %d, se esperaba la ráfaga de %d", store, requests, n, burst)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package limiter
+
+import "sync"
+
+// Store define el almacenamiento de los buckets del limitador, indexados por clave (IP).
+//
+// Las implementaciones deben ser seguras para uso concurrente. Store solo
+// protege el acceso a los buckets; la actualización de los tokens de cada
+// bucket la serializa el propio TokenBucket.
+type Store interface {
+	// Get devuelve el bucket de key, o false si no existe
+	Get(key string) (*TokenBucket, bool)
+	// Set almacena bucket para key, sustituyendo el anterior si existía
+	Set(key string, bucket *TokenBucket)
+	// LoadOrStore devuelve el bucket de key si existe y, si no, almacena y
+	// devuelve bucket, de forma atómica. loaded indica si ya existía.
+	LoadOrStore(key string, bucket *TokenBucket) (actual *TokenBucket, loaded bool)
+	// Range llama a fn para cada bucket hasta que fn devuelva false
+	Range(fn func(key string, bucket *TokenBucket) bool)
+	// Delete elimina el bucket de key
+	Delete(key string)
+}
+
+// MemoryStore es la implementación de Store en memoria, con un mapa protegido por un RWMutex
+type MemoryStore struct {
+	buckets map[string]*TokenBucket
+	mu      sync.RWMutex
+}
+
+// NewMemoryStore crea un almacén de buckets en memoria vacío
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*TokenBucket),
+	}
+}
+
+// Get implementa Store
+func (s *MemoryStore) Get(key string) (*TokenBucket, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	bucket, ok := s.buckets[key]
+	return bucket, ok
+}
+
+// Set implementa Store
+func (s *MemoryStore) Set(key string, bucket *TokenBucket) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buckets[key] = bucket
+}
+
+// LoadOrStore implementa Store
+func (s *MemoryStore) LoadOrStore(key string, bucket *TokenBucket) (*TokenBucket, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.buckets[key]; ok {
+		return existing, true
+	}
+	s.buckets[key] = bucket
+	return bucket, false
+}
+
+// Range implementa Store. fn se llama con el mutex de lectura tomado, por lo que
+// no debe llamar a Set ni a Delete.
+func (s *MemoryStore) Range(fn func(key string, bucket *TokenBucket) bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for key, bucket := range s.buckets {
+		if !fn(key, bucket) {
+			return
+		}
+	}
+}
+
+// Delete implementa Store
+func (s *MemoryStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.buckets, key)
+}
+
+// DefaultShardCount es el número de particiones de NewShardedStore por defecto
+const DefaultShardCount = 32
+
+// ShardedStore reparte los buckets entre varios MemoryStore según el hash de
+// la clave, de modo que las solicitudes de clientes distintos casi nunca
+// compiten por el mismo mutex.
+type ShardedStore struct {
+	shards []*MemoryStore
+}
+
+// NewShardedStore crea un almacén en memoria con shardCount particiones.
+// Si shardCount es menor que 1 se usa DefaultShardCount.
+func NewShardedStore(shardCount int) *ShardedStore {
+	if shardCount < 1 {
+		shardCount = DefaultShardCount
+	}
+	shards := make([]*MemoryStore, shardCount)
+	for i := range shards {
+		shards[i] = NewMemoryStore()
+	}
+	return &ShardedStore{shards: shards}
+}
+
+// shard devuelve la partición que guarda key. Usa FNV-1a calculado a mano
+// para no reservar memoria en cada solicitud.
+func (s *ShardedStore) shard(key string) *MemoryStore {
+	h := uint32(fnvOffset32)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= fnvPrime32
+	}
+	return s.shards[h%uint32(len(s.shards))]
+}
+
+// Constantes de FNV-1a de 32 bits
+const (
+	fnvOffset32 = 2166136261
+	fnvPrime32  = 16777619
+)
+
+// Get implementa Store
+func (s *ShardedStore) Get(key string) (*TokenBucket, bool) {
+	return s.shard(key).Get(key)
+}
+
+// Set implementa Store
+func (s *ShardedStore) Set(key string, bucket *TokenBucket) {
+	s.shard(key).Set(key, bucket)
+}
+
+// LoadOrStore implementa Store
+func (s *ShardedStore) LoadOrStore(key string, bucket *TokenBucket) (*TokenBucket, bool) {
+	return s.shard(key).LoadOrStore(key, bucket)
+}
+
+// Range implementa Store recorriendo las particiones una a una, por lo que no
+// es una instantánea consistente de todo el almacén
+func (s *ShardedStore) Range(fn func(key string, bucket *TokenBucket) bool) {
+	for _, shard := range s.shards {
+		keepGoing := true
+		shard.Range(func(key string, bucket *TokenBucket) bool {
+			keepGoing = fn(key, bucket)
+			return keepGoing
+		})
+		if !keepGoing {
+			return
+		}
+	}
+}
+
+// Delete implementa Store
+func (s *ShardedStore) Delete(key string) {
+	s.shard(key).Delete(key)
+}
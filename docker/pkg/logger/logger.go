@@ -8,8 +8,8 @@ import (
 )
 
 var (
-	log  *zap.Logger
-	once sync.Once
+	defaultLogger *ZapLogger
+	defaultOnce   sync.Once
 )
 
 // Logger es la interfaz para el logging estructurado
@@ -22,69 +22,90 @@ type Logger interface {
 	With(fields ...zap.Field) Logger
 }
 
-// zapLogger implementa la interfaz Logger usando zap
-type zapLogger struct {
+// ZapLogger implementa la interfaz Logger usando zap
+type ZapLogger struct {
 	logger *zap.Logger
 }
 
-// NewLogger crea una nueva instancia de Logger
-func NewLogger(development bool) Logger {
-	once.Do(func() {
-		var config zap.Config
-		if development {
-			// Configuración para desarrollo: más verbosa, salida legible por humanos
-			config = zap.NewDevelopmentConfig()
-			config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
-		} else {
-			// Configuración para producción: JSON estructurado
-			config = zap.NewProductionConfig()
-		}
-		
-		var err error
-		log, err = config.Build()
-		if err != nil {
-			// Si hay un error al construir el logger, fallback a un logger básico
-			log = zap.New(zapcore.NewCore(
-				zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig()),
-				zapcore.AddSync(os.Stdout),
-				zapcore.InfoLevel,
-			))
-		}
-	})
+// NewLogger crea una nueva instancia de Logger con su propio zap.Logger.
+// Cada llamada es independiente, por lo que dos loggers con distinto valor de
+// development pueden convivir en el mismo proceso (por ejemplo, en tests).
+func NewLogger(development bool) *ZapLogger {
+	var config zap.Config
+	if development {
+		// Configuración para desarrollo: más verbosa, salida legible por humanos
+		config = zap.NewDevelopmentConfig()
+		config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	} else {
+		// Configuración para producción: JSON estructurado
+		config = zap.NewProductionConfig()
+	}
+	
+	log, err := config.Build()
+	if err != nil {
+		// Si hay un error al construir el logger, fallback a un logger básico
+		log = zap.New(zapcore.NewCore(
+			zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig()),
+			zapcore.AddSync(os.Stdout),
+			zapcore.InfoLevel,
+		))
+	}
 	
-	return &zapLogger{
+	return &ZapLogger{
 		logger: log,
 	}
 }
 
+// NewFromZap envuelve un zap.Logger ya construido, por ejemplo uno con un core
+// de observación para tests
+func NewFromZap(log *zap.Logger) *ZapLogger {
+	return &ZapLogger{
+		logger: log,
+	}
+}
+
+// DefaultLogger devuelve un logger de producción compartido por todo el
+// proceso, creado con NewLogger(false) la primera vez que se llama
+func DefaultLogger() Logger {
+	defaultOnce.Do(func() {
+		defaultLogger = NewLogger(false)
+	})
+	return defaultLogger
+}
+
+// Sync vacía los mensajes pendientes del logger
+func (l *ZapLogger) Sync() error {
+	return l.logger.Sync()
+}
+
 // Info registra un mensaje a nivel INFO
-func (l *zapLogger) Info(msg string, fields ...zap.Field) {
+func (l *ZapLogger) Info(msg string, fields ...zap.Field) {
 	l.logger.Info(msg, fields...)
 }
 
 // Error registra un mensaje a nivel ERROR
-func (l *zapLogger) Error(msg string, fields ...zap.Field) {
+func (l *ZapLogger) Error(msg string, fields ...zap.Field) {
 	l.logger.Error(msg, fields...)
 }
 
 // Debug registra un mensaje a nivel DEBUG
-func (l *zapLogger) Debug(msg string, fields ...zap.Field) {
+func (l *ZapLogger) Debug(msg string, fields ...zap.Field) {
 	l.logger.Debug(msg, fields...)
 }
 
 // Warn registra un mensaje a nivel WARN
-func (l *zapLogger) Warn(msg string, fields ...zap.Field) {
+func (l *ZapLogger) Warn(msg string, fields ...zap.Field) {
 	l.logger.Warn(msg, fields...)
 }
 
 // Fatal registra un mensaje a nivel FATAL y termina la aplicación
-func (l *zapLogger) Fatal(msg string, fields ...zap.Field) {
+func (l *ZapLogger) Fatal(msg string, fields ...zap.Field) {
 	l.logger.Fatal(msg, fields...)
 }
 
 // With crea un nuevo logger con campos adicionales
-func (l *zapLogger) With(fields ...zap.Field) Logger {
-	return &zapLogger{
+func (l *ZapLogger) With(fields ...zap.Field) Logger {
+	return &ZapLogger{
 		logger: l.logger.With(fields...),
 	}
 }
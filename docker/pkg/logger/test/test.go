@@ -0,0 +1,81 @@
+// Package test contiene utilidades para comprobar en tests lo que se registra
+// a través de logger.Logger.
+package test
+
+import (
+	"testing"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// ObservedLog es un mensaje registrado durante el test
+type ObservedLog struct {
+	Level   zapcore.Level
+	Message string
+	// Fields contiene los campos del mensaje, incluidos los añadidos con With
+	Fields map[string]interface{}
+}
+
+// LogObserver da acceso a los mensajes registrados por un logger de test
+type LogObserver struct {
+	logs *observer.ObservedLogs
+}
+
+// NewTestLogger crea un Logger que registra todos los niveles (desde DEBUG) y
+// guarda cada mensaje en el LogObserver devuelto. Los mensajes también se
+// escriben con t.Log, así que solo se muestran si el test falla o con -v.
+//
+// Fatal no termina el proceso: registra el mensaje y detiene la goroutine del
+// test con runtime.Goexit, lo que hace fallar el test.
+//
+// Ejemplo:
+//
+//     log, observed := test.NewTestLogger(t)
+//     handler := handlers.NewAPIHandler(..., log, ...)
+//     handler.HandleExecuteCode(w, r)
+//     if observed.FilterMessage("Ejecutando código").Len() != 1 { ... }
+func NewTestLogger(t testing.TB) (logger.Logger, *LogObserver) {
+	t.Helper()
+	core, logs := observer.New(zapcore.DebugLevel)
+	testCore := zaptest.NewLogger(t).Core()
+	log := zap.New(zapcore.NewTee(core, testCore), zap.WithFatalHook(zapcore.WriteThenGoexit))
+	return logger.NewFromZap(log), &LogObserver{logs: logs}
+}
+
+// Entries devuelve los mensajes observados, en el orden en que se registraron
+func (o *LogObserver) Entries() []ObservedLog {
+	entries := o.logs.AllUntimed()
+	result := make([]ObservedLog, len(entries))
+	for i, entry := range entries {
+		result[i] = ObservedLog{
+			Level:   entry.Level,
+			Message: entry.Message,
+			Fields:  entry.ContextMap(),
+		}
+	}
+	return result
+}
+
+// Len devuelve el número de mensajes observados
+func (o *LogObserver) Len() int {
+	return o.logs.Len()
+}
+
+// FilterLevel devuelve un LogObserver con solo los mensajes de nivel level
+func (o *LogObserver) FilterLevel(level zapcore.Level) *LogObserver {
+	return &LogObserver{logs: o.logs.FilterLevelExact(level)}
+}
+
+// FilterMessage devuelve un LogObserver con solo los mensajes cuyo texto es exactamente msg
+func (o *LogObserver) FilterMessage(msg string) *LogObserver {
+	return &LogObserver{logs: o.logs.FilterMessage(msg)}
+}
+
+// FilterField devuelve un LogObserver con solo los mensajes que incluyen el campo field
+func (o *LogObserver) FilterField(field zap.Field) *LogObserver {
+	return &LogObserver{logs: o.logs.FilterField(field)}
+}
@@ -0,0 +1,43 @@
+package test
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewTestLoggerObservesEntries(t *testing.T) {
+	log, observed := NewTestLogger(t)
+	reqLogger := log.With(zap.String("request_id", "abc"))
+
+	log.Debug("Arrancando")
+	reqLogger.Info("Ejecutando código", zap.Int("code_length", 42))
+	reqLogger.Warn("Código vacío recibido")
+
+	if n := observed.Len(); n != 3 {
+		t.Fatalf("Len() = %d, se esperaba 3", n)
+	}
+
+	entries := observed.FilterMessage("Ejecutando código").Entries()
+	if len(entries) != 1 {
+		t.Fatalf("FilterMessage() devolvió %d mensajes, se esperaba 1", len(entries))
+	}
+	entry := entries[0]
+	if entry.Level != zapcore.InfoLevel {
+		t.Errorf("Level = %v, se esperaba info", entry.Level)
+	}
+	if entry.Fields["request_id"] != "abc" || entry.Fields["code_length"] != int64(42) {
+		t.Errorf("Fields = %v, se esperaban request_id y code_length", entry.Fields)
+	}
+
+	if n := observed.FilterLevel(zapcore.WarnLevel).Len(); n != 1 {
+		t.Errorf("FilterLevel(warn) devolvió %d mensajes, se esperaba 1", n)
+	}
+	if n := observed.FilterField(zap.String("request_id", "abc")).Len(); n != 2 {
+		t.Errorf("FilterField(request_id) devolvió %d mensajes, se esperaban 2", n)
+	}
+	if n := observed.FilterMessage("Ejecutando código Go").Len(); n != 0 {
+		t.Errorf("FilterMessage() con otro texto devolvió %d mensajes, se esperaba 0", n)
+	}
+}
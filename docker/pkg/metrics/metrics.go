@@ -0,0 +1,239 @@
+// Package metrics expone métricas del servidor en formato Prometheus.
+//
+// Los paquetes de dominio (executor, limiter, ...) no dependen de Prometheus:
+// este paquete lee sus contadores mediante funciones y los publica en /metrics.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/executor"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/limiter"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/middleware"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/notifications"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/queue"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// RegisterTempUsage registra las métricas de uso de los directorios temporales.
+// usage se invoca en cada lectura de /metrics, por ejemplo GoExecutor.TempUsage.
+func RegisterTempUsage(usage func() executor.TempUsage) {
+	prometheus.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "goplayground_temp_files_active",
+			Help: "Directorios temporales de trabajo existentes.",
+		}, func() float64 {
+			return float64(usage().ActiveFiles)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "goplayground_temp_bytes_active",
+			Help: "Bytes escritos por el ejecutor en los directorios temporales existentes.",
+		}, func() float64 {
+			return float64(usage().ActiveBytes)
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "goplayground_temp_quota_rejections_total",
+			Help: "Ejecuciones rechazadas por superar la cuota de archivos temporales.",
+		}, func() float64 {
+			return float64(usage().QuotaRejections)
+		}),
+	)
+}
+
+// RegisterOutputTruncation registra cuántas ejecuciones han superado los
+// límites de salida, con la etiqueta stream ("stdout" o "stderr"). stats se
+// invoca en cada lectura de /metrics, por ejemplo GoExecutor.OutputStats.
+// Los resultados servidos desde el caché no se vuelven a contar.
+func RegisterOutputTruncation(stats func() executor.OutputStats) {
+	prometheus.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name:        "goplayground_output_truncations_total",
+			Help:        "Ejecuciones cuya salida se recortó por MAX_STDOUT_LENGTH, MAX_STDERR_LENGTH o MAX_OUTPUT_LINES.",
+			ConstLabels: prometheus.Labels{"stream": "stdout"},
+		}, func() float64 {
+			return float64(stats().StdoutTruncations)
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name:        "goplayground_output_truncations_total",
+			Help:        "Ejecuciones cuya salida se recortó por MAX_STDOUT_LENGTH, MAX_STDERR_LENGTH o MAX_OUTPUT_LINES.",
+			ConstLabels: prometheus.Labels{"stream": "stderr"},
+		}, func() float64 {
+			return float64(stats().StderrTruncations)
+		}),
+	)
+}
+
+// RegisterLoadShedding registra las métricas del rechazo de carga por exceso
+// de goroutines. stats se invoca en cada lectura de /metrics, por ejemplo
+// LoadShedder.Stats. El número de goroutines ya lo publica go_goroutines.
+func RegisterLoadShedding(stats func() middleware.LoadShedStats) {
+	prometheus.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "goplayground_load_shedding_active",
+			Help: "1 mientras se rechazan ejecuciones por exceso de goroutines.",
+		}, func() float64 {
+			if stats().Shedding {
+				return 1
+			}
+			return 0
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "goplayground_load_shedding_engaged_total",
+			Help: "Veces que se ha activado el rechazo de ejecuciones por exceso de goroutines.",
+		}, func() float64 {
+			return float64(stats().Engagements)
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "goplayground_load_shed_rejections_total",
+			Help: "Solicitudes rechazadas con 503 por exceso de goroutines.",
+		}, func() float64 {
+			return float64(stats().Rejected)
+		}),
+	)
+}
+
+// RegisterExecutionQueue registra las métricas de la cola de ejecución.
+// stats se invoca en cada lectura de /metrics, por ejemplo Queue.Stats.
+func RegisterExecutionQueue(stats func() queue.Stats) {
+	prometheus.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "goplayground_execution_queue_running",
+			Help: "Ejecuciones que ocupan una plaza de la cola de ejecución.",
+		}, func() float64 {
+			return float64(stats().Running)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "goplayground_execution_queue_waiting",
+			Help: "Solicitudes esperando plaza en la cola de ejecución.",
+		}, func() float64 {
+			return float64(stats().Waiting)
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "goplayground_execution_queue_queued_total",
+			Help: "Solicitudes que han esperado en la cola de ejecución.",
+		}, func() float64 {
+			return float64(stats().Queued)
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "goplayground_execution_queue_rejections_total",
+			Help: "Solicitudes rechazadas con 503 por tener la cola de ejecución llena.",
+		}, func() float64 {
+			return float64(stats().Rejected)
+		}),
+	)
+}
+
+// RegisterExecutionOutcomes registra los contadores de ejecuciones y fallos.
+// stats se invoca en cada lectura de /metrics, por ejemplo FailureRateMonitor.Stats.
+func RegisterExecutionOutcomes(stats func() notifications.ExecutionStats) {
+	prometheus.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "goplayground_executions_total",
+			Help: "Ejecuciones de /api/execute, sin contar las canceladas por el cliente.",
+		}, func() float64 {
+			return float64(stats().Total)
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "goplayground_execution_failures_total",
+			Help: "Ejecuciones de /api/execute que terminaron con un error del ejecutor.",
+		}, func() float64 {
+			return float64(stats().Failed)
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "goplayground_failure_rate_alerts_total",
+			Help: "Alertas enviadas al webhook por superar la tasa de fallos.",
+		}, func() float64 {
+			return float64(stats().Alerts)
+		}),
+	)
+}
+
+// RegisterGlobalRateLimit registra las métricas del límite global de
+// ejecuciones. stats se invoca en cada lectura de /metrics, por ejemplo
+// GlobalTokenBucket.Stats.
+func RegisterGlobalRateLimit(stats func() limiter.GlobalStats) {
+	prometheus.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "goplayground_global_rate_limit_available",
+			Help: "Ejecuciones que admite ahora mismo el límite global antes de responder 503.",
+		}, func() float64 {
+			return stats().Available
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "goplayground_global_rate_limit_rejections_total",
+			Help: "Solicitudes rechazadas con 503 por el límite global de ejecuciones.",
+		}, func() float64 {
+			return float64(stats().Rejected)
+		}),
+	)
+}
+
+// NewCacheEvictionCounter registra el contador de entradas descartadas del
+// caché de ejecuciones y devuelve la función que lo incrementa, para pasarla a
+// executor.WithEvictionCallback
+func NewCacheEvictionCounter() executor.EvictionCallback {
+	evictions := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "goplayground_cache_evictions_total",
+		Help: "Entradas descartadas del caché de ejecuciones, por LRU o por expiración.",
+	})
+	prometheus.MustRegister(evictions)
+	return func(hash string, entry *executor.CacheEntry) {
+		evictions.Inc()
+	}
+}
+
+// RegisterCacheLookupLatency registra la duración media de las búsquedas en el
+// caché de ejecuciones. stats se invoca en cada lectura de /metrics, por
+// ejemplo CachedExecutor.Stats.
+func RegisterCacheLookupLatency(stats func() executor.CacheStats) {
+	prometheus.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "goplayground_cache_lookup_latency_seconds",
+			Help: "Media móvil exponencial de la duración de las búsquedas en el caché de ejecuciones, incluida la espera por su cerrojo.",
+		}, func() float64 {
+			return stats().CacheLookupLatency.Seconds()
+		}),
+	)
+}
+
+// PrometheusRateLimitObserver implementa limiter.RateLimitObserver contando
+// las solicitudes permitidas y denegadas por el rate limiter.
+//
+// Los contadores no llevan la IP como etiqueta: una serie por cliente haría
+// crecer sin límite el número de series de Prometheus.
+type PrometheusRateLimitObserver struct {
+	allowed prometheus.Counter
+	denied  prometheus.Counter
+}
+
+// NewPrometheusRateLimitObserver crea el observador y registra sus contadores
+func NewPrometheusRateLimitObserver() *PrometheusRateLimitObserver {
+	o := &PrometheusRateLimitObserver{
+		allowed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "goplayground_rate_limit_allowed_total",
+			Help: "Solicitudes permitidas por el rate limiter.",
+		}),
+		denied: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "goplayground_rate_limit_denied_total",
+			Help: "Solicitudes rechazadas por el rate limiter (429).",
+		}),
+	}
+	prometheus.MustRegister(o.allowed, o.denied)
+	return o
+}
+
+// ObserveAllowed implementa limiter.RateLimitObserver
+func (o *PrometheusRateLimitObserver) ObserveAllowed(ip string) {
+	o.allowed.Inc()
+}
+
+// ObserveDenied implementa limiter.RateLimitObserver
+func (o *PrometheusRateLimitObserver) ObserveDenied(ip string) {
+	o.denied.Inc()
+}
+
+// Handler devuelve el manejador HTTP que expone todas las métricas registradas
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/errors"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/logger"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/requestctx"
+	"go.uber.org/zap"
+)
+
+// apiKeyIDLength es el número de caracteres del hash que identifican a una
+// clave en los logs y en el rate limit
+const apiKeyIDLength = 8
+
+// APIKeyAuth exige "Authorization: Bearer <clave>" con una de las claves de API
+// configuradas. El servidor solo conoce el SHA-256 de cada clave, de modo que
+// la configuración no permite recuperarlas.
+//
+// Sin claves configuradas el servicio sigue abierto y Require no hace nada.
+type APIKeyAuth struct {
+	hashes   [][]byte
+	clientIP func(r *http.Request) string
+	log      logger.Logger
+}
+
+// NewAPIKeyAuth crea un autenticador que acepta las claves cuyo SHA-256 en
+// hexadecimal está en hashes. clientIP obtiene la IP que se registra en los
+// intentos rechazados.
+func NewAPIKeyAuth(hashes []string, clientIP func(r *http.Request) string, log logger.Logger) *APIKeyAuth {
+	decoded := make([][]byte, 0, len(hashes))
+	for _, hash := range hashes {
+		if sum, err := hex.DecodeString(hash); err == nil && len(sum) == sha256.Size {
+			decoded = append(decoded, sum)
+		}
+	}
+	return &APIKeyAuth{
+		hashes:   decoded,
+		clientIP: clientIP,
+		log:      log,
+	}
+}
+
+// Enabled indica si hay claves configuradas
+func (a *APIKeyAuth) Enabled() bool {
+	return len(a.hashes) > 0
+}
+
+// Require envuelve next para que solo atienda solicitudes con una clave válida
+// y responde con 401 a las demás. Guarda en el contexto de la solicitud el
+// identificador de la clave (requestctx.APIKeyID), con el que el rate limit
+// cuenta las peticiones por clave en lugar de por IP.
+func (a *APIKeyAuth) Require(next http.Handler) http.Handler {
+	if !a.Enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := a.authenticate(r)
+		if ok {
+			next.ServeHTTP(w, r.WithContext(requestctx.WithAPIKeyID(r.Context(), id)))
+			return
+		}
+		a.log.Warn("Clave de API rechazada",
+			zap.String("client_ip", a.clientIP(r)),
+			zap.String("path", r.URL.Path))
+		w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+		err := errors.Unauthorized(
+			errors.New("clave de API ausente o inválida"),
+			"Se requiere una clave de API válida (Authorization: Bearer <clave>)",
+			nil,
+		)
+		errors.HTTPError(w, r, a.log, err)
+	})
+}
+
+// authenticate devuelve el identificador de la clave de la solicitud si es una
+// de las configuradas. Se compara con todos los hashes en tiempo constante para
+// no revelar cuál coincide.
+func (a *APIKeyAuth) authenticate(r *http.Request) (string, bool) {
+	key, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || key == "" {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(key))
+	var match []byte
+	for _, hash := range a.hashes {
+		if subtle.ConstantTimeCompare(sum[:], hash) == 1 {
+			match = hash
+		}
+	}
+	if match == nil {
+		return "", false
+	}
+	return hex.EncodeToString(match)[:apiKeyIDLength], true
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/security"
+)
+
+// corsExposedHeaders son las cabeceras de respuesta que el frontend puede leer
+// desde otro origen
+var corsExposedHeaders = strings.Join([]string{
+	"X-Request-ID",
+	"X-Code-Wrapped",
+	"X-Execution-Result",
+	"Idempotent-Replayed",
+	"X-Quota-Remaining",
+	RequestDurationHeader,
+}, ", ")
+
+// CORS añade las cabeceras CORS a las respuestas cuyo origen admite origins y
+// responde directamente a las solicitudes preflight (OPTIONS).
+//
+// Las solicitudes de un origen no permitido se atienden igual pero sin
+// cabeceras CORS, de modo que es el navegador quien bloquea la respuesta.
+func CORS(next http.Handler, origins *security.OriginMatcher) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		w.Header().Add("Vary", "Origin")
+
+		allowed := origin != "" && origins.Allowed(origin)
+		if allowed {
+			if origins.AllowAll() {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+		}
+
+		// Preflight: la respuesta no llega a los manejadores
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			if allowed {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Encoding, Accept, Idempotency-Key, Authorization")
+				w.Header().Set("Access-Control-Max-Age", "600")
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/errors"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/logger"
+)
+
+// gzipBody cierra el lector gzip y el cuerpo original de la solicitud
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (gb *gzipBody) Close() error {
+	gb.Reader.Close()
+	return gb.body.Close()
+}
+
+// RequestDecompression descomprime de forma transparente los cuerpos enviados
+// con "Content-Encoding: gzip", para que los clientes con poco ancho de banda
+// puedan comprimir los códigos grandes. Los manejadores leen r.Body como si
+// la solicitud no estuviera comprimida.
+//
+// El cuerpo descomprimido se limita a maxBytes con http.MaxBytesReader, de modo
+// que una bomba gzip no pueda ocupar memoria sin límite: al superarlo, la lectura
+// devuelve un *http.MaxBytesError. Un cuerpo que no es gzip válido responde 400 y
+// cualquier otra codificación distinta de "identity" responde 415.
+func RequestDecompression(next http.Handler, maxBytes int64, log logger.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+		switch encoding {
+		case "", "identity":
+			next.ServeHTTP(w, r)
+			return
+		case "gzip", "x-gzip":
+		default:
+			err := errors.WithContext(
+				fmt.Errorf("codificación %q no soportada", encoding),
+				http.StatusUnsupportedMediaType,
+				"Content-Encoding no soportado, use gzip",
+				map[string]interface{}{"content_encoding": encoding},
+			)
+			errors.HTTPError(w, r, log, err)
+			return
+		}
+
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			err := errors.BadRequest(
+				errors.Wrap(err, "error al descomprimir el cuerpo"),
+				"El cuerpo no es un gzip válido",
+				nil,
+			)
+			errors.HTTPError(w, r, log, err)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, &gzipBody{Reader: zr, body: r.Body}, maxBytes)
+		// Para los manejadores el cuerpo ya no está comprimido y su tamaño es desconocido
+		r.Header.Del("Content-Encoding")
+		r.Header.Del("Content-Length")
+		r.ContentLength = -1
+		next.ServeHTTP(w, r)
+	})
+}
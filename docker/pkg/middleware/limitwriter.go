@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+)
+
+// LimitedResponseWriter limita los bytes que se escriben en el cuerpo de una
+// respuesta, igual que http.MaxBytesReader limita los que se leen de una
+// solicitud. Al alcanzar el límite escribe lo que cabe y devuelve
+// io.ErrShortWrite, de modo que quien escribe deja de hacerlo; las
+// escrituras siguientes no envían nada.
+//
+// Sirve para acotar la respuesta en streaming aunque el writer que recibe el
+// ejecutor esté envuelto por capas que añaden bytes a la salida del programa.
+type LimitedResponseWriter struct {
+	http.ResponseWriter
+	limit    int64
+	written  int64
+	exceeded bool
+}
+
+// NewLimitedResponseWriter envuelve w para que no se escriban más de limit
+// bytes en el cuerpo. Con limit menor o igual a 0 no se limita nada.
+func NewLimitedResponseWriter(w http.ResponseWriter, limit int) *LimitedResponseWriter {
+	return &LimitedResponseWriter{ResponseWriter: w, limit: int64(limit)}
+}
+
+// Write implementa http.ResponseWriter
+func (lw *LimitedResponseWriter) Write(p []byte) (int, error) {
+	if lw.limit <= 0 {
+		return lw.ResponseWriter.Write(p)
+	}
+	remaining := lw.limit - lw.written
+	if int64(len(p)) <= remaining {
+		n, err := lw.ResponseWriter.Write(p)
+		lw.written += int64(n)
+		return n, err
+	}
+	lw.exceeded = true
+	n, err := lw.ResponseWriter.Write(p[:max(remaining, 0)])
+	lw.written += int64(n)
+	if err == nil {
+		err = io.ErrShortWrite
+	}
+	return n, err
+}
+
+// Flush implementa http.Flusher para no romper las respuestas en streaming
+func (lw *LimitedResponseWriter) Flush() {
+	if flusher, ok := lw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap permite a http.ResponseController acceder al writer original
+func (lw *LimitedResponseWriter) Unwrap() http.ResponseWriter {
+	return lw.ResponseWriter
+}
+
+// Exceeded indica si se intentó escribir más allá del límite
+func (lw *LimitedResponseWriter) Exceeded() bool {
+	return lw.exceeded
+}
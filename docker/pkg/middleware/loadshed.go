@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/errors"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// loadShedSampleInterval es la frecuencia con la que se cuenta el número de goroutines
+const loadShedSampleInterval = time.Second
+
+// LoadShedStats resume el estado del LoadShedder para /metrics
+type LoadShedStats struct {
+	// Shedding indica si se están rechazando solicitudes
+	Shedding bool
+	// Goroutines es el número de goroutines en la última muestra
+	Goroutines int
+	// Engagements es el número de veces que se ha activado el rechazo
+	Engagements uint64
+	// Rejected es el número de solicitudes rechazadas con 503
+	Rejected uint64
+}
+
+// LoadShedder rechaza con 503 las solicitudes que envuelve mientras el número
+// de goroutines del servidor supera un umbral, para que una acumulación de
+// ejecuciones no termine tumbando el servidor entero.
+//
+// El número de goroutines se muestrea cada loadShedSampleInterval. El rechazo
+// se activa al superar maxGoroutines y se desactiva al bajar del 90% del
+// umbral, para no alternar en cada muestra. Solo deben envolverse las rutas que
+// ejecutan código: las sondas y los archivos estáticos se siguen sirviendo.
+type LoadShedder struct {
+	maxGoroutines int
+	log           logger.Logger
+
+	shedding    atomic.Bool
+	goroutines  atomic.Int64
+	engagements atomic.Uint64
+	rejected    atomic.Uint64
+}
+
+// NewLoadShedder crea un LoadShedder con el umbral maxGoroutines.
+// Con maxGoroutines <= 0 está desactivado y Shed devuelve el manejador sin cambios.
+func NewLoadShedder(maxGoroutines int, log logger.Logger) *LoadShedder {
+	return &LoadShedder{
+		maxGoroutines: maxGoroutines,
+		log:           log,
+	}
+}
+
+// Enabled indica si hay un umbral configurado
+func (ls *LoadShedder) Enabled() bool {
+	return ls.maxGoroutines > 0
+}
+
+// Start muestrea el número de goroutines en segundo plano hasta que ctx se cancele.
+// No hace nada si el LoadShedder está desactivado.
+func (ls *LoadShedder) Start(ctx context.Context) {
+	if !ls.Enabled() {
+		return
+	}
+	ls.sample()
+	go func() {
+		ticker := time.NewTicker(loadShedSampleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ls.sample()
+			}
+		}
+	}()
+}
+
+// sample cuenta las goroutines y activa o desactiva el rechazo
+func (ls *LoadShedder) sample() {
+	n := runtime.NumGoroutine()
+	ls.goroutines.Store(int64(n))
+
+	switch {
+	case n > ls.maxGoroutines && !ls.shedding.Load():
+		ls.shedding.Store(true)
+		ls.engagements.Add(1)
+		ls.log.Warn("Servidor sobrecargado, se rechazan nuevas ejecuciones",
+			zap.Int("goroutines", n),
+			zap.Int("max_goroutines", ls.maxGoroutines))
+	case n < ls.maxGoroutines*9/10 && ls.shedding.Load():
+		ls.shedding.Store(false)
+		ls.log.Info("Carga normalizada, se aceptan de nuevo ejecuciones",
+			zap.Int("goroutines", n),
+			zap.Int("max_goroutines", ls.maxGoroutines),
+			zap.Uint64("rejected_total", ls.rejected.Load()))
+	}
+}
+
+// Shed envuelve next para responder 503 (ERR_SERVER_BUSY) mientras el servidor
+// esté sobrecargado. La respuesta incluye Retry-After con el intervalo de muestreo.
+func (ls *LoadShedder) Shed(next http.Handler) http.Handler {
+	if !ls.Enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ls.shedding.Load() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ls.rejected.Add(1)
+		w.Header().Set("Retry-After", strconv.Itoa(int(loadShedSampleInterval/time.Second)))
+		err := errors.ServiceUnavailable(
+			fmt.Errorf("%d goroutines superan el máximo de %d", ls.goroutines.Load(), ls.maxGoroutines),
+			"El servidor está ocupado, inténtelo de nuevo en unos segundos",
+			nil,
+		).WithCode(errors.CodeServerBusy)
+		errors.HTTPError(w, r, ls.log, err)
+	})
+}
+
+// Stats devuelve el estado actual, por ejemplo para metrics.RegisterLoadShedding
+func (ls *LoadShedder) Stats() LoadShedStats {
+	return LoadShedStats{
+		Shedding:    ls.shedding.Load(),
+		Goroutines:  int(ls.goroutines.Load()),
+		Engagements: ls.engagements.Load(),
+		Rejected:    ls.rejected.Load(),
+	}
+}
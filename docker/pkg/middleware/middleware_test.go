@@ -0,0 +1,242 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	apperrors "github.com/luis198755/go_playGround_plus/docker/pkg/errors"
+	logtest "github.com/luis198755/go_playGround_plus/docker/pkg/logger/test"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/requestctx"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/security"
+)
+
+// gzipBytes comprime data con gzip
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// decompressRequest envía body con Content-Encoding encoding a
+// RequestDecompression, con next como manejador
+func decompressRequest(t *testing.T, maxBytes int64, encoding string, body []byte, next http.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	log, _ := logtest.NewTestLogger(t)
+	r := httptest.NewRequest(http.MethodPost, "/api/execute", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Content-Encoding", encoding)
+	w := httptest.NewRecorder()
+	RequestDecompression(next, maxBytes, log).ServeHTTP(w, r)
+	return w
+}
+
+func TestRequestDecompressionDecodesGzipJSON(t *testing.T) {
+	code := "package main\n\nfunc main() {\n" + strings.Repeat("\tprintln(\"hola\")\n", 200) + "}\n"
+	payload, err := json.Marshal(map[string]string{"code": code})
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed := gzipBytes(t, payload)
+
+	var decoded struct {
+		Code string `json:"code"`
+	}
+	w := decompressRequest(t, 1<<20, "gzip", compressed, func(w http.ResponseWriter, r *http.Request) {
+		if encoding := r.Header.Get("Content-Encoding"); encoding != "" {
+			t.Errorf("Content-Encoding = %q, se esperaba que se eliminara", encoding)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&decoded); err != nil {
+			t.Errorf("decodificando el cuerpo: %v", err)
+		}
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if decoded.Code != code {
+		t.Errorf("código decodificado = %q, se esperaba el original", decoded.Code)
+	}
+}
+
+func TestRequestDecompressionLimitsDecompressedSize(t *testing.T) {
+	// 1 MiB de ceros se comprime a unos pocos KiB
+	compressed := gzipBytes(t, make([]byte, 1<<20))
+
+	var readErr error
+	decompressRequest(t, 64*1024, "gzip", compressed, func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.Copy(io.Discard, r.Body)
+	})
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(readErr, &maxBytesErr) {
+		t.Errorf("error al leer = %v, se esperaba *http.MaxBytesError", readErr)
+	}
+}
+
+func TestRequestDecompressionRejectsInvalidBodies(t *testing.T) {
+	next := func(w http.ResponseWriter, r *http.Request) {
+		t.Error("se llamó al manejador con un cuerpo inválido")
+	}
+	if w := decompressRequest(t, 1<<20, "gzip", []byte(`{"code":"x"}`), next); w.Code != http.StatusBadRequest {
+		t.Errorf("gzip inválido: status = %d, se esperaba 400", w.Code)
+	}
+	if w := decompressRequest(t, 1<<20, "br", []byte(`{"code":"x"}`), next); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("codificación br: status = %d, se esperaba 415", w.Code)
+	}
+}
+
+func TestRequestTimingHeader(t *testing.T) {
+	log, _ := logtest.NewTestLogger(t)
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+	}{
+		{"respuesta correcta", func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(5 * time.Millisecond)
+			w.Write([]byte("hola"))
+		}, http.StatusOK},
+		{"respuesta de error", func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(5 * time.Millisecond)
+			apperrors.HTTPError(w, r, log, apperrors.TooManyRequests(nil, "Demasiadas solicitudes", nil))
+		}, http.StatusTooManyRequests},
+		{"sin cuerpo", func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(5 * time.Millisecond)
+			w.WriteHeader(http.StatusNoContent)
+		}, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			RequestTiming(tt.handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/execute", nil))
+			// Result devuelve las cabeceras enviadas con WriteHeader, no las
+			// fijadas después
+			resp := w.Result()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, se esperaba %d", resp.StatusCode, tt.status)
+			}
+			value := resp.Header.Get(RequestDurationHeader)
+			if !strings.HasSuffix(value, "ms") {
+				t.Fatalf("%s = %q, se esperaba una duración en ms", RequestDurationHeader, value)
+			}
+			duration, err := time.ParseDuration(value)
+			if err != nil {
+				t.Fatalf("%s = %q no es una duración válida: %v", RequestDurationHeader, value, err)
+			}
+			if duration < 5*time.Millisecond {
+				t.Errorf("%s = %v, se esperaban al menos los 5ms del manejador", RequestDurationHeader, duration)
+			}
+		})
+	}
+}
+
+func TestEndpointRateLimiterKeysByAPIKey(t *testing.T) {
+	log, _ := logtest.NewTestLogger(t)
+	clientIP := func(r *http.Request) string { return "192.0.2.1" }
+	limited := NewEndpointRateLimiter(map[string]int{"/api/execute": 2}, nil, clientIP, log).
+		Limit("/api/execute", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// allowed hace n solicitudes con la clave keyID (vacía = sin autenticar)
+	// desde la misma IP y devuelve cuántas se permiten
+	allowed := func(keyID string, n int) int {
+		count := 0
+		for range n {
+			r := httptest.NewRequest(http.MethodPost, "/api/execute", nil)
+			if keyID != "" {
+				r = r.WithContext(requestctx.WithAPIKeyID(r.Context(), keyID))
+			}
+			w := httptest.NewRecorder()
+			limited.ServeHTTP(w, r)
+			if w.Code == http.StatusOK {
+				count++
+			}
+		}
+		return count
+	}
+
+	if n := allowed("", 5); n != 2 {
+		t.Errorf("sin clave se permitieron %d solicitudes, se esperaban 2", n)
+	}
+	// Cada clave tiene su propio límite aunque comparta IP con las demás
+	for _, keyID := range []string{"equipo-a", "equipo-b"} {
+		if n := allowed(keyID, 5); n != 2 {
+			t.Errorf("con la clave %s se permitieron %d solicitudes, se esperaban 2", keyID, n)
+		}
+	}
+}
+
+// corsPreflight envía a CORS una solicitud preflight desde origin y devuelve
+// las cabeceras de la respuesta
+func corsPreflight(t *testing.T, origin, method string) http.Header {
+	t.Helper()
+	origins, err := security.NewOriginMatcher([]string{"https://admin.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := CORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("el preflight llegó al manejador")
+	}), origins)
+
+	r := httptest.NewRequest(http.MethodOptions, "/api/execute", nil)
+	r.Header.Set("Origin", origin)
+	r.Header.Set("Access-Control-Request-Method", method)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("preflight: status = %d, se esperaba 204", w.Code)
+	}
+	return w.Header()
+}
+
+// headerListContains indica si la lista separada por comas list incluye value
+func headerListContains(list, value string) bool {
+	for _, item := range strings.Split(list, ",") {
+		if strings.EqualFold(strings.TrimSpace(item), value) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCORSPreflight(t *testing.T) {
+	header := corsPreflight(t, "https://admin.example.com", http.MethodPost)
+	if got := header.Get("Access-Control-Allow-Origin"); got != "https://admin.example.com" {
+		t.Errorf("Access-Control-Allow-Origin = %q", got)
+	}
+	// Los clientes con clave de API la envían en Authorization
+	for _, name := range []string{"Content-Type", "Content-Encoding", "Idempotency-Key", "Authorization"} {
+		if !headerListContains(header.Get("Access-Control-Allow-Headers"), name) {
+			t.Errorf("Access-Control-Allow-Headers = %q, falta %s", header.Get("Access-Control-Allow-Headers"), name)
+		}
+	}
+
+	// El frontend lee la cuota restante de la clave desde otro origen
+	if !headerListContains(header.Get("Access-Control-Expose-Headers"), "X-Quota-Remaining") {
+		t.Errorf("Access-Control-Expose-Headers = %q, falta X-Quota-Remaining", header.Get("Access-Control-Expose-Headers"))
+	}
+
+	// PATCH /api/config desde una interfaz de administración en otro origen
+	header = corsPreflight(t, "https://admin.example.com", http.MethodPatch)
+	if !headerListContains(header.Get("Access-Control-Allow-Methods"), http.MethodPatch) {
+		t.Errorf("Access-Control-Allow-Methods = %q, falta PATCH", header.Get("Access-Control-Allow-Methods"))
+	}
+
+	header = corsPreflight(t, "https://otro.example.com", http.MethodPost)
+	if got := header.Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("origen no permitido: Access-Control-Allow-Origin = %q", got)
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/errors"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/limiter"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/logger"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/requestctx"
+	"go.uber.org/zap"
+)
+
+// EndpointRateLimiter aplica a algunas rutas un límite de solicitudes por IP
+// propio, para que los endpoints caros (ejecutar, benchmarks) sean más
+// estrictos que los baratos. Se suma al rate limit global de los manejadores:
+// una solicitud debe pasar ambos. Como el de los manejadores, las solicitudes
+// autenticadas con una clave de API se limitan por clave y no por IP (ver
+// requestctx.RateLimitKey), así que debe ir dentro de APIKeyAuth.Require.
+type EndpointRateLimiter struct {
+	limiters map[string]limiter.RateLimiterInterface
+	clientIP func(r *http.Request) string
+	log      logger.Logger
+}
+
+// NewEndpointRateLimiter crea un limitador con un RateLimiter por ruta de
+// limits (ruta → solicitudes por minuto). clientIP obtiene la IP que
+// identifica al cliente y observer recibe las decisiones de todas las rutas;
+// puede ser nil.
+func NewEndpointRateLimiter(limits map[string]int, observer limiter.RateLimitObserver, clientIP func(r *http.Request) string, log logger.Logger) *EndpointRateLimiter {
+	limiters := make(map[string]limiter.RateLimiterInterface, len(limits))
+	for path, perMinute := range limits {
+		limiters[path] = limiter.NewRateLimiter(perMinute, perMinute, observer)
+	}
+	return &EndpointRateLimiter{
+		limiters: limiters,
+		clientIP: clientIP,
+		log:      log,
+	}
+}
+
+// Limit envuelve next con el límite configurado para path, que debe ser el
+// patrón con el que se registra la ruta. Si path no tiene límite propio
+// devuelve next sin cambios.
+func (el *EndpointRateLimiter) Limit(path string, next http.Handler) http.Handler {
+	rl, ok := el.limiters[path]
+	if !ok {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientIP := el.clientIP(r)
+		if rl.IsAllowed(requestctx.RateLimitKey(r.Context(), clientIP)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		el.log.Warn("Rate limit del endpoint excedido",
+			zap.String("client_ip", clientIP),
+			zap.String("api_key_id", requestctx.APIKeyID(r.Context())),
+			zap.String("path", path))
+		err := errors.TooManyRequests(
+			fmt.Errorf("rate limit de %s excedido", path),
+			"Demasiadas peticiones. Por favor, espere un minuto.",
+			map[string]interface{}{"client_ip": clientIP, "path": path},
+		)
+		errors.HTTPError(w, r, el.log, err)
+	})
+}
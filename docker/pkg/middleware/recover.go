@@ -0,0 +1,99 @@
+// Package middleware contiene los middlewares HTTP comunes a todas las rutas.
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/errors"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/logger"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/requestctx"
+	"go.uber.org/zap"
+)
+
+// Recover envuelve next para que un panic en un manejador no cierre la conexión
+// sin dejar rastro. El panic se registra con su traza y el ID de la solicitud,
+// y el cliente recibe un ErrorResponse 500. El panic también se envía al
+// errors.ErrorObserver configurado (por ejemplo Sentry).
+//
+// Si el manejador ya había empezado a escribir la respuesta no es posible
+// cambiar el código de estado; en ese caso solo se registra y se aborta la
+// respuesta con http.ErrAbortHandler.
+func Recover(next http.Handler, log logger.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &recoverWriter{ResponseWriter: w}
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// Panic usado por net/http para abortar la respuesta a propósito
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			// Reutilizar el ID asignado por el manejador, si llegó a asignarlo
+			requestID := w.Header().Get("X-Request-ID")
+			if requestID == "" {
+				requestID = requestctx.NewRequestID()
+			}
+
+			log.Error("Panic recuperado en el manejador HTTP",
+				zap.String("request_id", requestID),
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.Any("panic", rec),
+				zap.ByteString("stack", debug.Stack()),
+			)
+
+			panicErr := fmt.Errorf("panic: %v", rec)
+			if rw.wroteHeader {
+				// HTTPError no llega a llamarse: enviar el panic aquí
+				errors.ReportError(r, requestID, panicErr)
+				panic(http.ErrAbortHandler)
+			}
+
+			w.Header().Set("X-Request-ID", requestID)
+			err := errors.InternalServerError(
+				panicErr,
+				"Error interno del servidor",
+				map[string]interface{}{"request_id": requestID},
+			)
+			errors.HTTPError(w, r, log, err)
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// recoverWriter registra si ya se enviaron las cabeceras de la respuesta
+type recoverWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+// WriteHeader implementa http.ResponseWriter
+func (rw *recoverWriter) WriteHeader(statusCode int) {
+	rw.wroteHeader = true
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write implementa http.ResponseWriter
+func (rw *recoverWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	return rw.ResponseWriter.Write(b)
+}
+
+// Flush implementa http.Flusher para no romper las respuestas en streaming
+func (rw *recoverWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		rw.wroteHeader = true
+		flusher.Flush()
+	}
+}
+
+// Unwrap permite a http.ResponseController acceder al writer original
+func (rw *recoverWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// HTTPSRedirect responde a todas las solicitudes con una redirección
+// permanente a la misma URL por HTTPS en httpsPort. Usa 308 para que los
+// clientes repitan los POST con el mismo método y cuerpo.
+func HTTPSRedirect(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// r.Host puede traer el puerto HTTP, que se sustituye por el de HTTPS
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = net.JoinHostPort(h, httpsPort)
+		} else if httpsPort != "443" {
+			host = net.JoinHostPort(strings.Trim(host, "[]"), httpsPort)
+		}
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/errors"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// HandlerTimeout limita la duración total de los manejadores, como
+// http.TimeoutHandler, para que un disco lento o un subproceso colgado no
+// retengan una conexión indefinidamente. Al vencer el plazo cancela el
+// contexto de la solicitud y responde 503 con un ErrorResponse.
+//
+// La respuesta del manejador se acumula en memoria hasta que termina, así que
+// no admite streaming: las rutas en streaming deben quedar exentas (timeout 0)
+// y acotarse con su propio timeout de ejecución.
+type HandlerTimeout struct {
+	defaultTimeout time.Duration
+	timeouts       map[string]time.Duration
+	log            logger.Logger
+}
+
+// NewHandlerTimeout crea el limitador con defaultTimeout para todas las rutas
+// salvo las de timeouts (ruta → timeout). Un timeout de 0 deja la ruta sin
+// límite.
+func NewHandlerTimeout(defaultTimeout time.Duration, timeouts map[string]time.Duration, log logger.Logger) *HandlerTimeout {
+	return &HandlerTimeout{
+		defaultTimeout: defaultTimeout,
+		timeouts:       timeouts,
+		log:            log,
+	}
+}
+
+// Timeout envuelve next con el timeout de path, que debe ser el patrón con el
+// que se registra la ruta. Si la ruta no tiene límite devuelve next sin cambios.
+func (ht *HandlerTimeout) Timeout(path string, next http.Handler) http.Handler {
+	timeout, ok := ht.timeouts[path]
+	if !ok {
+		timeout = ht.defaultTimeout
+	}
+	if timeout <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+
+		tw := &timeoutWriter{header: make(http.Header)}
+		done := make(chan struct{})
+		panicked := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				if rec := recover(); rec != nil {
+					panicked <- rec
+				}
+			}()
+			next.ServeHTTP(tw, r)
+			close(done)
+		}()
+
+		select {
+		case rec := <-panicked:
+			// Se relanza en la goroutine de la solicitud para que lo recoja Recover
+			panic(rec)
+		case <-done:
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			for name, values := range tw.header {
+				w.Header()[name] = values
+			}
+			if !tw.wroteHeader {
+				tw.code = http.StatusOK
+			}
+			w.WriteHeader(tw.code)
+			w.Write(tw.buf.Bytes())
+		case <-ctx.Done():
+			tw.mu.Lock()
+			tw.timedOut = true
+			tw.mu.Unlock()
+			if ctx.Err() != context.DeadlineExceeded {
+				// El cliente se desconectó: no hay nadie a quien responder
+				return
+			}
+			ht.log.Warn("Manejador cortado por timeout",
+				zap.String("path", path),
+				zap.Duration("timeout", timeout))
+			err := errors.ServiceUnavailable(
+				fmt.Errorf("%s superó el timeout de %v", path, timeout),
+				"El servidor tardó demasiado en responder, inténtelo de nuevo",
+				map[string]interface{}{"path": path},
+			)
+			errors.HTTPError(w, r, ht.log, err)
+		}
+	})
+}
+
+// timeoutWriter acumula la respuesta del manejador hasta que termina. Tras el
+// timeout descarta lo que se escriba y devuelve http.ErrHandlerTimeout.
+type timeoutWriter struct {
+	mu          sync.Mutex
+	header      http.Header
+	buf         bytes.Buffer
+	code        int
+	wroteHeader bool
+	timedOut    bool
+}
+
+// Header implementa http.ResponseWriter
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+// Write implementa http.ResponseWriter
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+	return tw.buf.Write(p)
+}
+
+// WriteHeader implementa http.ResponseWriter
+func (tw *timeoutWriter) WriteHeader(statusCode int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.writeHeaderLocked(statusCode)
+}
+
+// writeHeaderLocked guarda el código de estado. Debe llamarse con mu bloqueado.
+func (tw *timeoutWriter) writeHeaderLocked(statusCode int) {
+	tw.wroteHeader = true
+	tw.code = statusCode
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// RequestDurationHeader es la cabecera con el tiempo que tardó el servidor en
+// empezar a responder, para que los clientes y la monitorización puedan
+// compararlo con lo que observan
+const RequestDurationHeader = "X-Request-Duration"
+
+// RequestTiming añade a todas las respuestas la cabecera X-Request-Duration
+// ("42.3ms") con el tiempo transcurrido desde que la solicitud llegó al
+// middleware hasta la primera escritura del cuerpo.
+//
+// Las cabeceras no se pueden cambiar después de WriteHeader, así que el código
+// de estado se retiene hasta la primera escritura (o hasta que el manejador
+// termina, si no escribe cuerpo) y la cabecera se fija justo antes de enviarlo.
+// En las respuestas en streaming mide el tiempo hasta el primer byte, no la
+// duración completa.
+func RequestTiming(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tw := &timingWriter{ResponseWriter: w, start: time.Now()}
+		next.ServeHTTP(tw, r)
+		tw.commit()
+	})
+}
+
+// timingWriter retiene el código de estado hasta la primera escritura del
+// cuerpo para fijar antes X-Request-Duration
+type timingWriter struct {
+	http.ResponseWriter
+	start     time.Time
+	status    int
+	committed bool
+}
+
+// WriteHeader implementa http.ResponseWriter. Las respuestas informativas
+// (1xx) se envían de inmediato: no son la respuesta final.
+func (tw *timingWriter) WriteHeader(statusCode int) {
+	if tw.committed {
+		tw.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	if statusCode >= 100 && statusCode < 200 && statusCode != http.StatusSwitchingProtocols {
+		tw.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	if tw.status == 0 {
+		tw.status = statusCode
+	}
+}
+
+// Write implementa http.ResponseWriter
+func (tw *timingWriter) Write(b []byte) (int, error) {
+	tw.commit()
+	return tw.ResponseWriter.Write(b)
+}
+
+// Flush implementa http.Flusher para no romper las respuestas en streaming
+func (tw *timingWriter) Flush() {
+	tw.commit()
+	if flusher, ok := tw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap permite a http.ResponseController acceder al writer original
+func (tw *timingWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+// commit fija X-Request-Duration y envía el código de estado retenido. Solo
+// tiene efecto la primera vez.
+func (tw *timingWriter) commit() {
+	if tw.committed {
+		return
+	}
+	tw.committed = true
+	elapsed := time.Since(tw.start)
+	tw.Header().Set(RequestDurationHeader, fmt.Sprintf("%.1fms", float64(elapsed.Microseconds())/1000))
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	tw.ResponseWriter.WriteHeader(tw.status)
+}
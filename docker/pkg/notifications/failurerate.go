@@ -0,0 +1,164 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// minAlertExecutions es el mínimo de ejecuciones en la ventana para alertar,
+	// para que unos pocos fallos con poco tráfico no disparen la alerta
+	minAlertExecutions = 10
+	// maxSampleErrors es el número de errores recientes incluidos en la alerta
+	maxSampleErrors = 5
+	// alertTimeout es el tiempo máximo para enviar una alerta
+	alertTimeout = 10 * time.Second
+)
+
+// ExecutionRecorder define el comportamiento para registrar el resultado de
+// cada ejecución
+type ExecutionRecorder interface {
+	RecordExecution(err error)
+}
+
+// ExecutionStats son los contadores acumulados de FailureRateMonitor para /metrics
+type ExecutionStats struct {
+	// Total es el número de ejecuciones registradas
+	Total uint64
+	// Failed es el número de ejecuciones que terminaron con error
+	Failed uint64
+	// Alerts es el número de alertas enviadas
+	Alerts uint64
+}
+
+// outcomeBucket cuenta las ejecuciones de un minuto
+type outcomeBucket struct {
+	minute int64
+	total  int
+	failed int
+}
+
+// FailureRateMonitor calcula la tasa de ejecuciones fallidas en una ventana
+// deslizante, con un contador por minuto, y envía una alerta cuando supera el
+// umbral. Entre dos alertas pasa al menos debounce.
+//
+// Una ejecución falla si el ejecutor devuelve error (timeout, límite de
+// capacidad, error interno...). Los programas que no compilan o terminan con
+// un código distinto de 0 no cuentan como fallos, y las ejecuciones canceladas
+// por el cliente no se registran.
+type FailureRateMonitor struct {
+	alerter   Alerter
+	threshold float64
+	debounce  time.Duration
+	log       logger.Logger
+
+	mu        sync.Mutex
+	buckets   []outcomeBucket
+	samples   []string
+	lastAlert time.Time
+
+	total  atomic.Uint64
+	failed atomic.Uint64
+	alerts atomic.Uint64
+}
+
+// NewFailureRateMonitor crea un monitor que alerta con alerter cuando la
+// fracción de ejecuciones fallidas (0-1) en los últimos window supera threshold.
+// window se redondea a minutos, con un mínimo de uno.
+func NewFailureRateMonitor(alerter Alerter, threshold float64, window, debounce time.Duration, log logger.Logger) *FailureRateMonitor {
+	minutes := max(int(window/time.Minute), 1)
+	return &FailureRateMonitor{
+		alerter:   alerter,
+		threshold: threshold,
+		debounce:  debounce,
+		log:       log,
+		buckets:   make([]outcomeBucket, minutes),
+	}
+}
+
+// RecordExecution registra el resultado de una ejecución y envía la alerta en
+// segundo plano si la tasa de fallos supera el umbral.
+func (fm *FailureRateMonitor) RecordExecution(err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	fm.total.Add(1)
+	if err != nil {
+		fm.failed.Add(1)
+	}
+
+	now := time.Now()
+	minute := now.Unix() / 60
+
+	fm.mu.Lock()
+	bucket := &fm.buckets[minute%int64(len(fm.buckets))]
+	if bucket.minute != minute {
+		*bucket = outcomeBucket{minute: minute}
+	}
+	bucket.total++
+	if err != nil {
+		bucket.failed++
+		fm.samples = append(fm.samples, err.Error())
+		if len(fm.samples) > maxSampleErrors {
+			fm.samples = fm.samples[len(fm.samples)-maxSampleErrors:]
+		}
+	}
+
+	total, failed := 0, 0
+	for _, b := range fm.buckets {
+		if b.minute > minute-int64(len(fm.buckets)) {
+			total += b.total
+			failed += b.failed
+		}
+	}
+	rate := float64(failed) / float64(total)
+	if total < minAlertExecutions || rate <= fm.threshold || now.Sub(fm.lastAlert) < fm.debounce {
+		fm.mu.Unlock()
+		return
+	}
+	fm.lastAlert = now
+	event := AlertEvent{
+		Type: AlertTypeFailureRate,
+		Message: fmt.Sprintf("%d de %d ejecuciones fallaron en los últimos %d minutos (umbral %.0f%%)",
+			failed, total, len(fm.buckets), fm.threshold*100),
+		Timestamp:    now.UTC(),
+		ErrorRate:    rate,
+		SampleErrors: append([]string(nil), fm.samples...),
+	}
+	fm.mu.Unlock()
+
+	go fm.send(event)
+}
+
+// send envía event con el alerter y registra el resultado
+func (fm *FailureRateMonitor) send(event AlertEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), alertTimeout)
+	defer cancel()
+
+	if err := fm.alerter.Alert(ctx, event); err != nil {
+		fm.log.Error("Error al enviar la alerta de tasa de fallos",
+			zap.Float64("error_rate", event.ErrorRate),
+			zap.Error(err))
+		return
+	}
+	fm.alerts.Add(1)
+	fm.log.Warn("Alerta enviada: tasa de ejecuciones fallidas por encima del umbral",
+		zap.Float64("error_rate", event.ErrorRate),
+		zap.Float64("threshold", fm.threshold))
+}
+
+// Stats devuelve los contadores acumulados, por ejemplo para metrics.RegisterExecutionOutcomes
+func (fm *FailureRateMonitor) Stats() ExecutionStats {
+	return ExecutionStats{
+		Total:  fm.total.Load(),
+		Failed: fm.failed.Load(),
+		Alerts: fm.alerts.Load(),
+	}
+}
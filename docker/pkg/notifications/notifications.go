@@ -0,0 +1,75 @@
+// Package notifications avisa a sistemas externos de incidencias del servidor,
+// como una tasa de ejecuciones fallidas anormalmente alta.
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// AlertTypeFailureRate es el tipo de las alertas de FailureRateMonitor
+const AlertTypeFailureRate = "execution_failure_rate"
+
+// AlertEvent es el contenido de una alerta
+type AlertEvent struct {
+	Type      string    `json:"type"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+	// ErrorRate es la fracción de ejecuciones fallidas en la ventana (0-1)
+	ErrorRate float64 `json:"error_rate"`
+	// SampleErrors son los mensajes de los últimos errores, del más antiguo al más reciente
+	SampleErrors []string `json:"sample_errors"`
+}
+
+// Alerter define el comportamiento para enviar alertas
+type Alerter interface {
+	Alert(ctx context.Context, event AlertEvent) error
+}
+
+// WebhookNotifier envía las alertas como JSON en un POST a una URL
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier crea un notificador que envía las alertas a url, con un
+// timeout por envío
+func NewWebhookNotifier(url string, timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Alert envía event al webhook. Retorna error si el envío falla o el webhook
+// no responde con un estado 2xx.
+func (wn *WebhookNotifier) Alert(ctx context.Context, event AlertEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error al codificar la alerta: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wn.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error al crear la solicitud del webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := wn.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error al enviar el webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	// Vaciar el cuerpo para reutilizar la conexión
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("el webhook respondió %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	logtest "github.com/luis198755/go_playGround_plus/docker/pkg/logger/test"
+)
+
+// webhookServer levanta un webhook que envía a la cola devuelta el cuerpo de
+// cada POST y responde status
+func webhookServer(t *testing.T, status int) (*httptest.Server, chan []byte) {
+	t.Helper()
+	received := make(chan []byte, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("solicitud %s con Content-Type %q, se esperaba un POST JSON", r.Method, r.Header.Get("Content-Type"))
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("leyendo el cuerpo del webhook: %v", err)
+		}
+		received <- body
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, received
+}
+
+func TestWebhookNotifierPayload(t *testing.T) {
+	srv, received := webhookServer(t, http.StatusNoContent)
+	event := AlertEvent{
+		Type:         AlertTypeFailureRate,
+		Message:      "6 de 10 ejecuciones fallaron",
+		Timestamp:    time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC),
+		ErrorRate:    0.6,
+		SampleErrors: []string{"timeout", "capacidad agotada"},
+	}
+	if err := NewWebhookNotifier(srv.URL, time.Second).Alert(context.Background(), event); err != nil {
+		t.Fatalf("Alert(): %v", err)
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(<-received, &payload); err != nil {
+		t.Fatalf("el webhook no recibió JSON: %v", err)
+	}
+	want := map[string]interface{}{
+		"type":          AlertTypeFailureRate,
+		"message":       "6 de 10 ejecuciones fallaron",
+		"timestamp":     "2026-10-16T12:00:00Z",
+		"error_rate":    0.6,
+		"sample_errors": []interface{}{"timeout", "capacidad agotada"},
+	}
+	if len(payload) != len(want) {
+		t.Errorf("payload = %v, se esperaban los campos %v", payload, want)
+	}
+	for key, value := range want {
+		got, _ := json.Marshal(payload[key])
+		expected, _ := json.Marshal(value)
+		if string(got) != string(expected) {
+			t.Errorf("%s = %s, se esperaba %s", key, got, expected)
+		}
+	}
+}
+
+func TestWebhookNotifierRejectedStatus(t *testing.T) {
+	srv, _ := webhookServer(t, http.StatusInternalServerError)
+	err := NewWebhookNotifier(srv.URL, time.Second).Alert(context.Background(), AlertEvent{Type: AlertTypeFailureRate})
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Alert() = %v, se esperaba un error con el estado 500", err)
+	}
+}
+
+func TestFailureRateMonitorAlertsOncePerDebounce(t *testing.T) {
+	srv, received := webhookServer(t, http.StatusOK)
+	log, _ := logtest.NewTestLogger(t)
+	monitor := NewFailureRateMonitor(NewWebhookNotifier(srv.URL, time.Second), 0.5, 5*time.Minute, 5*time.Minute, log)
+
+	for i := 0; i < 4; i++ {
+		monitor.RecordExecution(nil)
+	}
+	// Las ejecuciones canceladas por el cliente no cuentan
+	monitor.RecordExecution(context.Canceled)
+	for i := 0; i < 6; i++ {
+		monitor.RecordExecution(errors.New("timeout de ejecución"))
+	}
+
+	var event AlertEvent
+	select {
+	case body := <-received:
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Fatalf("el webhook no recibió JSON: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no se envió la alerta")
+	}
+	if event.Type != AlertTypeFailureRate || event.ErrorRate != 0.6 {
+		t.Errorf("alerta = %+v, se esperaba %s con una tasa de 0.6", event, AlertTypeFailureRate)
+	}
+	if len(event.SampleErrors) != maxSampleErrors || event.SampleErrors[0] != "timeout de ejecución" {
+		t.Errorf("SampleErrors = %q, se esperaban los %d últimos errores", event.SampleErrors, maxSampleErrors)
+	}
+
+	// Dentro del debounce no se envía otra alerta
+	for i := 0; i < 10; i++ {
+		monitor.RecordExecution(errors.New("timeout de ejecución"))
+	}
+	select {
+	case body := <-received:
+		t.Errorf("se envió una segunda alerta dentro del debounce: %s", body)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for monitor.Stats().Alerts != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if stats := monitor.Stats(); stats != (ExecutionStats{Total: 20, Failed: 16, Alerts: 1}) {
+		t.Errorf("Stats() = %+v, se esperaba {Total:20 Failed:16 Alerts:1}", stats)
+	}
+}
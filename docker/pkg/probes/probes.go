@@ -0,0 +1,60 @@
+// Package probes implementa los endpoints de sondeo para orquestadores como Kubernetes.
+//
+// La sonda de vida (/healthz) solo indica que el servidor HTTP responde; la de
+// disponibilidad (/readyz) indica además que todas las dependencias necesarias
+// para ejecutar código están listas. Ninguna de las dos aplica rate limiting ni
+// autenticación, ya que el orquestador las consulta con frecuencia.
+package probes
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ReadinessCheck comprueba una dependencia del servidor. Devuelve nil si está lista.
+type ReadinessCheck func(ctx context.Context) error
+
+// checkTimeout es el tiempo máximo que se espera al conjunto de comprobaciones
+const checkTimeout = 2 * time.Second
+
+// NewLivenessHandler crea el manejador de /healthz, que responde siempre 200
+func NewLivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeProbe(w, http.StatusOK, "ok")
+	})
+}
+
+// NewReadinessHandler crea el manejador de /readyz. Responde 200 si todas las
+// comprobaciones pasan y 503 con los errores, uno por línea, si alguna falla.
+//
+// Las comprobaciones se ejecutan en cada solicitud, en orden, con un límite
+// de tiempo común de checkTimeout.
+func NewReadinessHandler(checks ...ReadinessCheck) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+		defer cancel()
+
+		var failures []string
+		for _, check := range checks {
+			if err := check(ctx); err != nil {
+				failures = append(failures, err.Error())
+			}
+		}
+
+		if len(failures) > 0 {
+			writeProbe(w, http.StatusServiceUnavailable, strings.Join(failures, "\n"))
+			return
+		}
+		writeProbe(w, http.StatusOK, "ok")
+	})
+}
+
+// writeProbe escribe la respuesta en texto plano y sin caché
+func writeProbe(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write([]byte(body + "\n"))
+}
@@ -0,0 +1,164 @@
+// Package queue limita las ejecuciones simultáneas con una cola FIFO acotada.
+//
+// En lugar de rechazar una ejecución cuando todas las plazas están ocupadas, la
+// solicitud espera su turno en la cola y recibe su posición cada vez que cambia.
+// Solo se rechaza (ErrQueueFull) cuando también la cola está llena.
+package queue
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrQueueFull indica que todas las plazas y la cola de espera están ocupadas
+var ErrQueueFull = errors.New("la cola de ejecución está llena, inténtelo de nuevo en unos segundos")
+
+// ExecutionQueue define el comportamiento para esperar una plaza de ejecución
+type ExecutionQueue interface {
+	Acquire(ctx context.Context, onPosition func(position int)) (release func(), err error)
+}
+
+// Stats resume el estado de la cola para /metrics
+type Stats struct {
+	// Running es el número de ejecuciones con plaza
+	Running int
+	// Waiting es el número de solicitudes en la cola
+	Waiting int
+	// Queued es el número de solicitudes que han tenido que esperar
+	Queued uint64
+	// Rejected es el número de solicitudes rechazadas con la cola llena
+	Rejected uint64
+}
+
+// Queue reparte maxRunning plazas de ejecución por orden de llegada, con
+// hasta maxWaiting solicitudes esperando.
+type Queue struct {
+	maxRunning int
+	maxWaiting int
+
+	mu      sync.Mutex
+	running int
+	waiting *list.List // de *waiter, en orden de llegada
+
+	queued   atomic.Uint64
+	rejected atomic.Uint64
+}
+
+// waiter es una solicitud en la cola
+type waiter struct {
+	// ready se cierra cuando la solicitud recibe una plaza
+	ready chan struct{}
+	// position recibe la posición actual; solo se conserva la última
+	position chan int
+}
+
+// NewQueue crea una cola con maxRunning plazas simultáneas y hasta maxWaiting
+// solicitudes en espera.
+func NewQueue(maxRunning, maxWaiting int) *Queue {
+	return &Queue{
+		maxRunning: maxRunning,
+		maxWaiting: maxWaiting,
+		waiting:    list.New(),
+	}
+}
+
+// Acquire espera una plaza de ejecución. Si hay una libre y nadie espera vuelve
+// de inmediato; si no, la solicitud entra en la cola y onPosition (si no es nil)
+// se llama con su posición, empezando en 1, al entrar y cada vez que avanza.
+//
+// Retorna la función que libera la plaza, que debe llamarse al terminar la
+// ejecución. Retorna ErrQueueFull si la cola está llena, o el error de ctx si
+// se cancela mientras espera; en ambos casos la solicitud no ocupa la cola.
+func (q *Queue) Acquire(ctx context.Context, onPosition func(position int)) (func(), error) {
+	q.mu.Lock()
+	if q.running < q.maxRunning && q.waiting.Len() == 0 {
+		q.running++
+		q.mu.Unlock()
+		return q.releaseFunc(), nil
+	}
+	if q.waiting.Len() >= q.maxWaiting {
+		q.mu.Unlock()
+		q.rejected.Add(1)
+		return nil, ErrQueueFull
+	}
+	w := &waiter{ready: make(chan struct{}), position: make(chan int, 1)}
+	elem := q.waiting.PushBack(w)
+	w.position <- q.waiting.Len()
+	q.mu.Unlock()
+	q.queued.Add(1)
+
+	for {
+		select {
+		case <-w.ready:
+			return q.releaseFunc(), nil
+		case position := <-w.position:
+			if onPosition != nil {
+				onPosition(position)
+			}
+		case <-ctx.Done():
+			q.mu.Lock()
+			select {
+			case <-w.ready:
+				// Recibió la plaza a la vez que se canceló: se devuelve
+				q.mu.Unlock()
+				q.releaseFunc()()
+			default:
+				q.waiting.Remove(elem)
+				q.notifyPositions()
+				q.mu.Unlock()
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// releaseFunc devuelve la función que libera una plaza una sola vez. La plaza
+// pasa directamente a la primera solicitud de la cola, si la hay.
+func (q *Queue) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			front := q.waiting.Front()
+			if front == nil {
+				q.running--
+				return
+			}
+			q.waiting.Remove(front)
+			close(front.Value.(*waiter).ready)
+			q.notifyPositions()
+		})
+	}
+}
+
+// notifyPositions envía a cada solicitud en espera su posición actual,
+// sustituyendo la que aún no hubiera leído. Debe llamarse con q.mu bloqueado.
+func (q *Queue) notifyPositions() {
+	position := 1
+	for e := q.waiting.Front(); e != nil; e = e.Next() {
+		w := e.Value.(*waiter)
+		select {
+		case <-w.position:
+		default:
+		}
+		w.position <- position
+		position++
+	}
+}
+
+// Stats devuelve el estado actual, por ejemplo para metrics.RegisterExecutionQueue
+func (q *Queue) Stats() Stats {
+	q.mu.Lock()
+	running, waiting := q.running, q.waiting.Len()
+	q.mu.Unlock()
+	return Stats{
+		Running:  running,
+		Waiting:  waiting,
+		Queued:   q.queued.Load(),
+		Rejected: q.rejected.Load(),
+	}
+}
@@ -0,0 +1,171 @@
+// Package quota lleva la cuenta del uso de cada clave de API y aplica una
+// cuota diaria de ejecuciones.
+//
+// El uso se acumula en una ventana móvil de 24 horas dividida en tramos de una
+// hora: cada tramo caduca al salir de la ventana, de modo que la cuota se va
+// recuperando poco a poco en lugar de reiniciarse de golpe a medianoche.
+package quota
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// Window es la ventana sobre la que se cuenta el uso y se aplica la cuota
+	Window = 24 * time.Hour
+	// bucketSize es la duración de cada tramo de la ventana
+	bucketSize = time.Hour
+)
+
+// Tracker define el comportamiento para contar y limitar el uso por clave
+type Tracker interface {
+	// Acquire cuenta n ejecuciones para key si caben en su cuota y devuelve el
+	// uso resultante. Si no caben no cuenta nada y devuelve false.
+	Acquire(key string, n int) (Usage, bool)
+	// Record suma a key el tiempo de CPU y el tiempo real de una ejecución
+	Record(key string, cpuTime, wallTime time.Duration)
+	// Snapshot devuelve el uso de todas las claves con actividad en la ventana
+	Snapshot() []Usage
+}
+
+// Usage es el uso de una clave en la ventana
+type Usage struct {
+	KeyID      string `json:"key_id"`
+	Executions int    `json:"executions"`
+	// Quota es el máximo de ejecuciones en la ventana (0 = sin límite)
+	Quota      int   `json:"quota"`
+	CPUTimeMs  int64 `json:"cpu_time_ms"`
+	WallTimeMs int64 `json:"wall_time_ms"`
+}
+
+// Remaining devuelve las ejecuciones que le quedan a la clave en la ventana,
+// o -1 si no tiene límite
+func (u Usage) Remaining() int {
+	if u.Quota == 0 {
+		return -1
+	}
+	return max(u.Quota-u.Executions, 0)
+}
+
+// bucket es el uso de una clave en un tramo de la ventana
+type bucket struct {
+	start      time.Time
+	executions int
+	cpuTime    time.Duration
+	wallTime   time.Duration
+}
+
+// DailyTracker implementa Tracker en memoria. Cada clave usa la cuota de
+// quotas o, si no aparece, defaultQuota; 0 significa sin límite.
+type DailyTracker struct {
+	defaultQuota int
+	quotas       map[string]int
+	now          func() time.Time
+
+	mu   sync.Mutex
+	keys map[string][]bucket // tramos de cada clave, del más antiguo al más reciente
+}
+
+// NewDailyTracker crea un contador de uso con la cuota defaultQuota para todas
+// las claves salvo las de quotas (identificador de clave → cuota)
+func NewDailyTracker(defaultQuota int, quotas map[string]int) *DailyTracker {
+	return &DailyTracker{
+		defaultQuota: defaultQuota,
+		quotas:       quotas,
+		now:          time.Now,
+		keys:         make(map[string][]bucket),
+	}
+}
+
+// quota devuelve la cuota de key
+func (t *DailyTracker) quota(key string) int {
+	if quota, ok := t.quotas[key]; ok {
+		return quota
+	}
+	return t.defaultQuota
+}
+
+// Acquire implementa Tracker
+func (t *DailyTracker) Acquire(key string, n int) (Usage, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	usage := t.usage(key, now)
+	if usage.Quota > 0 && usage.Executions+n > usage.Quota {
+		return usage, false
+	}
+	t.current(key, now).executions += n
+	usage.Executions += n
+	return usage, true
+}
+
+// Record implementa Tracker
+func (t *DailyTracker) Record(key string, cpuTime, wallTime time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := t.current(key, t.now())
+	b.cpuTime += cpuTime
+	b.wallTime += wallTime
+}
+
+// Snapshot implementa Tracker. Las claves se ordenan por identificador.
+func (t *DailyTracker) Snapshot() []Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	usages := make([]Usage, 0, len(t.keys))
+	for key := range t.keys {
+		// usage elimina las claves sin tramos en la ventana
+		usage := t.usage(key, now)
+		if _, active := t.keys[key]; active {
+			usages = append(usages, usage)
+		}
+	}
+	sort.Slice(usages, func(i, j int) bool { return usages[i].KeyID < usages[j].KeyID })
+	return usages
+}
+
+// usage descarta los tramos caducados de key y suma los restantes. Debe
+// llamarse con mu bloqueado.
+func (t *DailyTracker) usage(key string, now time.Time) Usage {
+	buckets := t.keys[key]
+	expired := 0
+	for expired < len(buckets) && !buckets[expired].start.After(now.Add(-Window)) {
+		expired++
+	}
+	if expired == len(buckets) {
+		delete(t.keys, key)
+		buckets = nil
+	} else if expired > 0 {
+		buckets = append(buckets[:0], buckets[expired:]...)
+		t.keys[key] = buckets
+	}
+
+	usage := Usage{KeyID: key, Quota: t.quota(key)}
+	var cpuTime, wallTime time.Duration
+	for _, b := range buckets {
+		usage.Executions += b.executions
+		cpuTime += b.cpuTime
+		wallTime += b.wallTime
+	}
+	usage.CPUTimeMs = cpuTime.Milliseconds()
+	usage.WallTimeMs = wallTime.Milliseconds()
+	return usage
+}
+
+// current devuelve el tramo de key que contiene now, creándolo si no existe.
+// Debe llamarse con mu bloqueado.
+func (t *DailyTracker) current(key string, now time.Time) *bucket {
+	start := now.Truncate(bucketSize)
+	buckets := t.keys[key]
+	if n := len(buckets); n == 0 || buckets[n-1].start.Before(start) {
+		buckets = append(buckets, bucket{start: start})
+		t.keys[key] = buckets
+	}
+	return &buckets[len(buckets)-1]
+}
@@ -0,0 +1,142 @@
+package reporting
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/errors"
+	logtest "github.com/luis198755/go_playGround_plus/docker/pkg/logger/test"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/middleware"
+)
+
+// recordingTransport es un sentry.Transport que guarda los eventos en memoria
+// en lugar de enviarlos
+type recordingTransport struct {
+	mu     sync.Mutex
+	events []*sentry.Event
+}
+
+func (rt *recordingTransport) Configure(options sentry.ClientOptions)    {}
+func (rt *recordingTransport) Flush(timeout time.Duration) bool          { return true }
+func (rt *recordingTransport) FlushWithContext(ctx context.Context) bool { return true }
+func (rt *recordingTransport) Close()                                    {}
+
+func (rt *recordingTransport) SendEvent(event *sentry.Event) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.events = append(rt.events, event)
+}
+
+func (rt *recordingTransport) Events() []*sentry.Event {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return append([]*sentry.Event(nil), rt.events...)
+}
+
+// newRecordingReporter crea un SentryErrorReporter cuyos eventos se guardan
+// en el recordingTransport devuelto, y lo instala como errors.ErrorObserver
+// durante el test
+func newRecordingReporter(t *testing.T) (*SentryErrorReporter, *recordingTransport) {
+	t.Helper()
+	const dsn = "https://public@sentry.example.com/1"
+	reporter, err := NewSentryErrorReporter(dsn, "1.2.3", "production")
+	if err != nil {
+		t.Fatalf("NewSentryErrorReporter: %v", err)
+	}
+	transport := &recordingTransport{}
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn:         dsn,
+		Release:     "1.2.3",
+		Environment: "production",
+		Transport:   transport,
+	})
+	if err != nil {
+		t.Fatalf("sentry.NewClient: %v", err)
+	}
+	// Las etiquetas de NewSentryErrorReporter quedan en el scope del hub
+	reporter.hub.BindClient(client)
+
+	errors.SetErrorObserver(reporter)
+	t.Cleanup(func() { errors.SetErrorObserver(nil) })
+	return reporter, transport
+}
+
+// checkTags comprueba las etiquetas de un evento
+func checkTags(t *testing.T, event *sentry.Event, requestID string) {
+	t.Helper()
+	want := map[string]string{
+		"version":     "1.2.3",
+		"environment": "production",
+		"request_id":  requestID,
+	}
+	for key, value := range want {
+		if got := event.Tags[key]; got != value {
+			t.Errorf("etiqueta %s = %q, se esperaba %q", key, got, value)
+		}
+	}
+}
+
+func TestNewSentryErrorReporterRejectsInvalidDSN(t *testing.T) {
+	if _, err := NewSentryErrorReporter("no es un DSN", "1.2.3", "production"); err == nil {
+		t.Error("NewSentryErrorReporter() no devolvió error con un DSN inválido")
+	}
+}
+
+func TestSentryErrorReporterCapturesPanics(t *testing.T) {
+	reporter, transport := newRecordingReporter(t)
+	log, _ := logtest.NewTestLogger(t)
+
+	handler := middleware.Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-ID", "abc123")
+		panic("fallo inesperado")
+	}), log)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/execute", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, se esperaba 500", w.Code)
+	}
+	if !reporter.Flush(2 * time.Second) {
+		t.Fatal("Flush() no terminó de enviar los eventos")
+	}
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("se enviaron %d eventos, se esperaba 1", len(events))
+	}
+	checkTags(t, events[0], "abc123")
+	captured := false
+	for _, exception := range events[0].Exception {
+		captured = captured || strings.Contains(exception.Value, "panic: fallo inesperado")
+	}
+	if !captured {
+		t.Errorf("excepción = %+v, se esperaba el panic", events[0].Exception)
+	}
+	if events[0].Request == nil || events[0].Request.Method != http.MethodPost {
+		t.Errorf("solicitud del evento = %+v, se esperaba el POST", events[0].Request)
+	}
+}
+
+func TestSentryErrorReporterCapturesOnlyInternalErrors(t *testing.T) {
+	_, transport := newRecordingReporter(t)
+	log, _ := logtest.NewTestLogger(t)
+
+	respond := func(err error) {
+		w := httptest.NewRecorder()
+		w.Header().Set("X-Request-ID", "def456")
+		errors.HTTPError(w, httptest.NewRequest(http.MethodPost, "/api/execute", nil), log, err)
+	}
+	respond(errors.BadRequest(errors.New("JSON inválido"), "JSON inválido", nil))
+	respond(errors.InternalServerError(errors.New("sin espacio en disco"), "Error interno del servidor", nil))
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("se enviaron %d eventos, se esperaba solo el del error 500", len(events))
+	}
+	checkTags(t, events[0], "def456")
+}
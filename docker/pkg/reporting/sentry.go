@@ -0,0 +1,59 @@
+// Package reporting envía los errores internos del servidor a un servicio de
+// seguimiento de errores, para no depender solo de los logs.
+package reporting
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// SentryErrorReporter envía a Sentry los errores que recibe como
+// errors.ErrorObserver: los panics recuperados, las respuestas 500 y los
+// errores del ejecutor que no se deben al código del usuario.
+type SentryErrorReporter struct {
+	hub *sentry.Hub
+}
+
+// NewSentryErrorReporter inicializa el cliente de Sentry con dsn. release es la
+// versión del servidor y environment el entorno ("development" o "production");
+// ambos se añaden como etiquetas a todos los eventos.
+func NewSentryErrorReporter(dsn, release, environment string) (*SentryErrorReporter, error) {
+	err := sentry.Init(sentry.ClientOptions{
+		Dsn:              dsn,
+		Release:          release,
+		Environment:      environment,
+		AttachStacktrace: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error al inicializar Sentry: %w", err)
+	}
+
+	hub := sentry.CurrentHub()
+	hub.ConfigureScope(func(scope *sentry.Scope) {
+		scope.SetTag("version", release)
+		scope.SetTag("environment", environment)
+	})
+	return &SentryErrorReporter{hub: hub}, nil
+}
+
+// ObserveError implementa errors.ErrorObserver. Cada evento lleva la solicitud
+// y su ID en un scope propio, para no mezclar datos de solicitudes concurrentes.
+func (sr *SentryErrorReporter) ObserveError(r *http.Request, requestID string, err error) {
+	hub := sr.hub.Clone()
+	hub.ConfigureScope(func(scope *sentry.Scope) {
+		scope.SetRequest(r)
+		if requestID != "" {
+			scope.SetTag("request_id", requestID)
+		}
+	})
+	hub.CaptureException(err)
+}
+
+// Flush espera a que se envíen los eventos pendientes, como máximo timeout.
+// Retorna false si quedaron eventos sin enviar.
+func (sr *SentryErrorReporter) Flush(timeout time.Duration) bool {
+	return sr.hub.Flush(timeout)
+}
@@ -0,0 +1,96 @@
+// Package requestctx proporciona utilidades para propagar datos de la solicitud
+// HTTP (ID de solicitud e IP del cliente) a través de context.Context.
+//
+// GoExecutor los transmite al proceso hijo como PLAYGROUND_REQUEST_ID y
+// PLAYGROUND_CLIENT_ID (ver ClientID) para poder correlacionar lo que registre.
+//
+// Ejemplo de uso básico:
+//
+//     ctx := requestctx.WithRequestID(r.Context(), requestctx.NewRequestID())
+//     ctx = requestctx.WithClientIP(ctx, clientIP)
+//
+//     // En otro paquete
+//     id := requestctx.RequestID(ctx)
+package requestctx
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// contextKey es el tipo de las claves de contexto de este paquete,
+// no exportado para evitar colisiones con otros paquetes
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	clientIPKey
+	apiKeyIDKey
+)
+
+// NewRequestID genera un identificador de solicitud aleatorio de 16 caracteres hexadecimales
+func NewRequestID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// WithRequestID devuelve un contexto derivado que contiene el ID de solicitud
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID devuelve el ID de solicitud del contexto, o una cadena vacía si no existe
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithClientIP devuelve un contexto derivado que contiene la IP del cliente
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey, ip)
+}
+
+// ClientIP devuelve la IP del cliente del contexto, o una cadena vacía si no existe
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey).(string)
+	return ip
+}
+
+// ClientID devuelve un identificador seudónimo del cliente: los primeros 8
+// caracteres del SHA-256 de su IP. Permite correlacionar solicitudes de un mismo
+// cliente sin exponer la IP. Devuelve una cadena vacía si el contexto no tiene IP.
+func ClientID(ctx context.Context) string {
+	ip := ClientIP(ctx)
+	if ip == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(ip))
+	return hex.EncodeToString(sum[:])[:8]
+}
+
+// WithAPIKeyID devuelve un contexto derivado que contiene el identificador de
+// la clave de API con la que se autenticó la solicitud
+func WithAPIKeyID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, apiKeyIDKey, id)
+}
+
+// APIKeyID devuelve el identificador de la clave de API del contexto, o una
+// cadena vacía si la solicitud no se autenticó con una clave
+func APIKeyID(ctx context.Context) string {
+	id, _ := ctx.Value(apiKeyIDKey).(string)
+	return id
+}
+
+// RateLimitKey devuelve la clave con la que se aplica el rate limit a una
+// solicitud: la clave de API si se autenticó con una y, si no, clientIP
+func RateLimitKey(ctx context.Context, clientIP string) string {
+	if id := APIKeyID(ctx); id != "" {
+		return "apikey:" + id
+	}
+	return clientIP
+}
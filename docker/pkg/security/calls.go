@@ -0,0 +1,143 @@
+package security
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DeniedCall es una función de un paquete que el código no puede usar, aunque
+// el paquete esté permitido (por ejemplo os.Exit o runtime.GC)
+type DeniedCall struct {
+	// Package es la ruta de import del paquete ("os", "math/rand/v2")
+	Package string
+	// Name es el nombre de la función ("Exit")
+	Name string
+}
+
+// String devuelve la función con el formato de la configuración ("os.Exit")
+func (dc DeniedCall) String() string {
+	return dc.Package + "." + dc.Name
+}
+
+// ParseDeniedCall interpreta una función con el formato "paquete.Función",
+// donde paquete es la ruta de import completa ("os.Exit", "os/signal.Notify")
+func ParseDeniedCall(spec string) (DeniedCall, error) {
+	spec = strings.TrimSpace(spec)
+	dot := strings.LastIndex(spec, ".")
+	if dot <= 0 || dot == len(spec)-1 || strings.HasSuffix(spec[:dot], "/") {
+		return DeniedCall{}, fmt.Errorf("función %q no válida: el formato es paquete.Función", spec)
+	}
+	name := spec[dot+1:]
+	if !token.IsIdentifier(name) {
+		return DeniedCall{}, fmt.Errorf("función %q no válida: %q no es un identificador", spec, name)
+	}
+	return DeniedCall{Package: spec[:dot], Name: name}, nil
+}
+
+// ParseDeniedCalls interpreta las funciones de specs con ParseDeniedCall
+func ParseDeniedCalls(specs []string) ([]DeniedCall, error) {
+	calls := make([]DeniedCall, 0, len(specs))
+	for _, spec := range specs {
+		call, err := ParseDeniedCall(spec)
+		if err != nil {
+			return nil, err
+		}
+		calls = append(calls, call)
+	}
+	return calls, nil
+}
+
+// majorVersionSuffix reconoce el sufijo de versión de una ruta de import
+// ("math/rand/v2"), que no forma parte del nombre del paquete
+var majorVersionSuffix = regexp.MustCompile(`^v[0-9]+$`)
+
+// FindDeniedCall busca en el código usos de las funciones prohibidas y
+// devuelve la primera que encuentra ("os.Exit"). Cuenta tanto las llamadas
+// como las referencias a la función sin llamarla (f := os.Exit), que de otro
+// modo permitirían esquivar la comprobación. Si el código no compila no hace
+// nada: el compilador notifica el error.
+//
+// A diferencia de MatchDenyRule, la comprobación entiende el código: no se
+// deja engañar por alias de import (import sys "os"), imports con punto,
+// comentarios o cadenas, y una variable local llamada os no cuenta como el
+// paquete. A cambio, analiza el código completo y recorre todos sus nodos, un
+// coste proporcional al tamaño del código del mismo orden que el de
+// ASTNodeCount; MAX_CODE_LENGTH y MAX_AST_NODES lo acotan. Sin funciones
+// configuradas no se analiza nada.
+func (cv *CodeValidator) FindDeniedCall(code string) (string, bool) {
+	if len(cv.deniedCalls) == 0 {
+		return "", false
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), "main.go", code, 0)
+	if err != nil {
+		return "", false
+	}
+
+	// Funciones prohibidas por nombre local del paquete; las de los paquetes
+	// importados con punto se usan sin prefijo y van bajo "."
+	denied := make(map[string]map[string]string)
+	for _, imp := range file.Imports {
+		importPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		localName := packageName(importPath)
+		if imp.Name != nil {
+			localName = imp.Name.Name
+		}
+		if localName == "_" {
+			continue
+		}
+		for _, call := range cv.deniedCalls {
+			if call.Package != importPath {
+				continue
+			}
+			if denied[localName] == nil {
+				denied[localName] = make(map[string]string)
+			}
+			denied[localName][call.Name] = call.String()
+		}
+	}
+	if len(denied) == 0 {
+		return "", false
+	}
+
+	var found string
+	ast.Inspect(file, func(n ast.Node) bool {
+		if found != "" {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			// Obj es nil en los identificadores que no resuelven a una
+			// declaración del archivo, como los nombres de paquete
+			if pkg, ok := n.X.(*ast.Ident); ok && pkg.Obj == nil {
+				found = denied[pkg.Name][n.Sel.Name]
+			}
+			// El selector no se recorre: n.Sel no es un uso con punto
+			return false
+		case *ast.Ident:
+			if n.Obj == nil {
+				found = denied["."][n.Name]
+			}
+		}
+		return true
+	})
+	return found, found != ""
+}
+
+// packageName devuelve el nombre con el que se usa por defecto el paquete de
+// importPath: el último elemento de la ruta, sin el sufijo de versión
+func packageName(importPath string) string {
+	name := path.Base(importPath)
+	if majorVersionSuffix.MatchString(name) {
+		name = path.Base(path.Dir(importPath))
+	}
+	return name
+}
@@ -0,0 +1,65 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DenyRule es una expresión regular que rechaza el código en el que aparece,
+// para bloquear construcciones que la lista de imports no cubre (por ejemplo
+// directivas //go:linkname). Name identifica la regla en el mensaje de error.
+type DenyRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// ParseDenyRule interpreta una regla con el formato "nombre=expresión". La
+// expresión usa la sintaxis RE2 de regexp y se aplica al código completo.
+func ParseDenyRule(spec string) (DenyRule, error) {
+	name, expr, ok := strings.Cut(spec, "=")
+	name, expr = strings.TrimSpace(name), strings.TrimSpace(expr)
+	if !ok || name == "" || expr == "" {
+		return DenyRule{}, fmt.Errorf("regla %q no válida: el formato es nombre=expresión", spec)
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return DenyRule{}, fmt.Errorf("regla %q no válida: %w", name, err)
+	}
+	return DenyRule{Name: name, Pattern: pattern}, nil
+}
+
+// ParseDenyRules interpreta las reglas de specs con ParseDenyRule. Retorna
+// error si alguna no es válida o si hay dos con el mismo nombre.
+func ParseDenyRules(specs []string) ([]DenyRule, error) {
+	rules := make([]DenyRule, 0, len(specs))
+	seen := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		rule, err := ParseDenyRule(spec)
+		if err != nil {
+			return nil, err
+		}
+		if seen[rule.Name] {
+			return nil, errors.New("regla duplicada: " + rule.Name)
+		}
+		seen[rule.Name] = true
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// MatchDenyRule devuelve el nombre de la primera regla, en el orden de
+// configuración, que coincide con el código.
+//
+// Cada regla recorre el código completo: RE2 garantiza un tiempo lineal en su
+// longitud, pero el coste total crece con el número de reglas, de modo que
+// MAX_CODE_LENGTH acota también esta comprobación.
+func (cv *CodeValidator) MatchDenyRule(code string) (string, bool) {
+	for _, rule := range cv.denyRules {
+		if rule.Pattern.MatchString(code) {
+			return rule.Name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,79 @@
+package security
+
+import (
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// allowedDirectives son las directivas //go: admitidas en el código enviado.
+// Las demás (//go:linkname, //go:cgo_*, //go:noescape...) permiten acceder a
+// símbolos internos del runtime, enlazar código externo o declarar funciones
+// sin cuerpo implementadas en ensamblador.
+var allowedDirectives = map[string]bool{
+	"build":    true,
+	"generate": true,
+	"noinline": true,
+	"embed":    true,
+}
+
+// FindForbiddenConstruct busca en el código construcciones que salen del Go
+// puro: el import "C" de cgo y las directivas //go: que no están en
+// allowedDirectives. Devuelve una descripción de la primera que encuentra.
+//
+// El import se comprueba con go/parser, que no se deja engañar por el formato
+// como la expresión regular de ContainsBlacklistedImports, y las directivas con
+// go/scanner, que solo mira los comentarios y no el contenido de las cadenas.
+// Si el rechazo está desactivado (despliegues de confianza) no hace nada.
+func (cv *CodeValidator) FindForbiddenConstruct(code string) (string, bool) {
+	if !cv.rejectDirectives {
+		return "", false
+	}
+	if usesCgo(code) {
+		return `import "C" (cgo)`, true
+	}
+	if directive, found := forbiddenDirective(code); found {
+		return directive, true
+	}
+	return "", false
+}
+
+// usesCgo indica si el código importa el pseudo-paquete "C". Solo se analizan
+// los imports, así que los errores de sintaxis del resto del código no afectan.
+func usesCgo(code string) bool {
+	file, err := parser.ParseFile(token.NewFileSet(), "main.go", code, parser.ImportsOnly)
+	if err != nil && file == nil {
+		return false
+	}
+	for _, imp := range file.Imports {
+		if path, err := strconv.Unquote(imp.Path.Value); err == nil && path == "C" {
+			return true
+		}
+	}
+	return false
+}
+
+// forbiddenDirective devuelve la primera directiva //go: del código que no
+// está en allowedDirectives
+func forbiddenDirective(code string) (string, bool) {
+	src := []byte(code)
+	fset := token.NewFileSet()
+	var s scanner.Scanner
+	// Los errores de sintaxis los notifica el compilador; aquí se ignoran
+	s.Init(fset.AddFile("main.go", -1, len(src)), src, nil, scanner.ScanComments)
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			return "", false
+		}
+		if tok != token.COMMENT || !strings.HasPrefix(lit, "//go:") {
+			continue
+		}
+		directive := strings.Fields(lit)[0]
+		if !allowedDirectives[strings.TrimPrefix(directive, "//go:")] {
+			return directive, true
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// OriginMatcher decide si un origen (cabecera Origin) está permitido para CORS.
+//
+// Admite tres tipos de patrón:
+//   - "*": cualquier origen.
+//   - Un origen exacto, por ejemplo "https://app.example.com".
+//   - Un subdominio comodín, "*.example.com" o "https://*.example.com", que
+//     permite cualquier subdominio de example.com (pero no example.com). Sin
+//     esquema, se acepta cualquiera.
+type OriginMatcher struct {
+	allowAll  bool
+	exact     map[string]bool
+	wildcards []originWildcard
+}
+
+// originWildcard es un patrón "*.dominio" compilado
+type originWildcard struct {
+	scheme string // Vacío si el patrón no indica esquema
+	suffix string // ".dominio", con el punto inicial
+}
+
+// NewOriginMatcher compila los patrones de origen. Retorna error si alguno no es válido.
+func NewOriginMatcher(patterns []string) (*OriginMatcher, error) {
+	m := &OriginMatcher{exact: make(map[string]bool)}
+	for _, pattern := range patterns {
+		if err := ValidateOriginPattern(pattern); err != nil {
+			return nil, err
+		}
+		if pattern == "*" {
+			m.allowAll = true
+			continue
+		}
+
+		scheme, host := splitOriginPattern(pattern)
+		if strings.HasPrefix(host, "*.") {
+			m.wildcards = append(m.wildcards, originWildcard{
+				scheme: strings.ToLower(scheme),
+				suffix: strings.ToLower(host[1:]),
+			})
+			continue
+		}
+		m.exact[strings.ToLower(pattern)] = true
+	}
+	return m, nil
+}
+
+// AllowAll indica si se permite cualquier origen
+func (m *OriginMatcher) AllowAll() bool {
+	return m.allowAll
+}
+
+// Allowed indica si origin coincide con alguno de los patrones
+func (m *OriginMatcher) Allowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if m.allowAll || m.exact[strings.ToLower(origin)] {
+		return true
+	}
+	if len(m.wildcards) == 0 {
+		return false
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	hostname := strings.ToLower(u.Hostname())
+	for _, w := range m.wildcards {
+		if w.scheme != "" && w.scheme != strings.ToLower(u.Scheme) {
+			continue
+		}
+		if strings.HasSuffix(hostname, w.suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateOriginPattern comprueba que pattern sea un patrón de origen válido
+// para OriginMatcher: el comodín solo puede aparecer como "*" o al principio
+// del host ("*.dominio"), y no se admiten rutas ni rangos CIDR.
+func ValidateOriginPattern(pattern string) error {
+	if pattern == "" {
+		return errors.New("origen vacío")
+	}
+	if pattern == "*" {
+		return nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("origen %q no válido: %w", pattern, err)
+	}
+
+	scheme, host := splitOriginPattern(pattern)
+	if strings.Contains(pattern, "://") && scheme == "" {
+		return fmt.Errorf("origen %q no válido: falta el esquema", pattern)
+	}
+	if host == "" {
+		return fmt.Errorf("origen %q no válido: falta el host", pattern)
+	}
+	if _, _, err := net.ParseCIDR(host); err == nil {
+		return fmt.Errorf("origen %q no válido: los rangos CIDR no son orígenes", pattern)
+	}
+	if strings.Contains(host, "/") {
+		return fmt.Errorf("origen %q no válido: un origen no puede incluir ruta", pattern)
+	}
+
+	wildcard := strings.HasPrefix(host, "*.")
+	domain := strings.TrimPrefix(host, "*.")
+	if domain == "" {
+		return fmt.Errorf("origen %q no válido: falta el dominio tras \"*.\"", pattern)
+	}
+	if strings.ContainsAny(domain, "*?") {
+		return fmt.Errorf("origen %q no válido: el comodín solo se admite como \"*.dominio\"", pattern)
+	}
+	if !wildcard && scheme == "" {
+		// La cabecera Origin siempre incluye el esquema, así que nunca coincidiría
+		return fmt.Errorf("origen %q no válido: un origen exacto debe incluir el esquema (https://...)", pattern)
+	}
+	return nil
+}
+
+// splitOriginPattern separa el esquema (si lo hay) del host de un patrón
+func splitOriginPattern(pattern string) (scheme, host string) {
+	if i := strings.Index(pattern, "://"); i >= 0 {
+		return pattern[:i], pattern[i+3:]
+	}
+	return "", pattern
+}
@@ -1,41 +1,101 @@
 package security
 
 import (
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"net/http"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 )
 
 // SecurityValidator define el comportamiento para validaciones de seguridad
 type SecurityValidator interface {
-	ContainsBlacklistedImports(code string) (bool, string)
+	ContainsBlacklistedImports(language, code string) (bool, string)
+	MatchDenyRule(code string) (string, bool)
+	FindForbiddenConstruct(code string) (string, bool)
+	FindDeniedCall(code string) (string, bool)
+	ImportPolicy(language string) ImportPolicy
+	ASTNodeCount(code string) (int, error)
+	ContainsOversizedLiterals(code string, maxLiteralBytes int) (bool, int)
 	GetClientIP(r *http.Request) string
 	SetSecurityHeaders(w http.ResponseWriter)
 }
 
+// DefaultContentSecurityPolicy es la CSP por defecto: el frontend carga el
+// editor (Monaco) y sus fuentes desde cdn.jsdelivr.net y usa workers blob:
+const DefaultContentSecurityPolicy = "default-src 'self'; script-src 'self' 'unsafe-inline' 'unsafe-eval' https://cdn.jsdelivr.net blob:; worker-src 'self' blob:; connect-src 'self' https://cdn.jsdelivr.net; img-src 'self' https://go.dev data:; style-src 'self' 'unsafe-inline' https://cdn.jsdelivr.net; font-src 'self' https://cdn.jsdelivr.net"
+
+// SecurityHeaders son los valores de los encabezados de seguridad de las
+// respuestas. Los campos vacíos no se envían.
+type SecurityHeaders struct {
+	ContentSecurityPolicy   string
+	FrameOptions            string
+	ContentTypeOptions      string
+	StrictTransportSecurity string
+	ReferrerPolicy          string
+}
+
+// DefaultSecurityHeaders devuelve los encabezados por defecto. No incluye
+// Strict-Transport-Security, que solo tiene sentido si el servidor se sirve por HTTPS.
+func DefaultSecurityHeaders() SecurityHeaders {
+	return SecurityHeaders{
+		ContentSecurityPolicy: DefaultContentSecurityPolicy,
+		FrameOptions:          "DENY",
+		ContentTypeOptions:    "nosniff",
+	}
+}
+
+// goLanguage es el nombre del lenguaje Go en las solicitudes (ver executor.DefaultLanguage)
+const goLanguage = "go"
+
 // CodeValidator implementa validaciones de seguridad para código Go
 type CodeValidator struct {
-	blacklistedImports []string
+	// blacklistedImports son los imports prohibidos de cada lenguaje
+	blacklistedImports map[string][]string
 	importPattern      *regexp.Regexp
+	denyRules          []DenyRule
+	deniedCalls        []DeniedCall
+	rejectDirectives   bool
+	headers            SecurityHeaders
 }
 
-// NewCodeValidator crea un nuevo validador de código
-func NewCodeValidator() *CodeValidator {
+// NewCodeValidator crea un nuevo validador de código que envía los
+// encabezados de seguridad headers y rechaza el código que coincide con
+// alguna de denyRules (ver MatchDenyRule) o usa alguna de deniedCalls (ver
+// FindDeniedCall). Con rejectDirectives rechaza además cgo y las directivas
+// //go: peligrosas (ver FindForbiddenConstruct).
+func NewCodeValidator(headers SecurityHeaders, denyRules []DenyRule, deniedCalls []DeniedCall, rejectDirectives bool) *CodeValidator {
 	return &CodeValidator{
-		blacklistedImports: []string{
-			"os/exec",
-			"syscall",
-			"unsafe",
-			"net",
-			"net/http",
-			"plugin",
+		headers:          headers,
+		denyRules:        denyRules,
+		deniedCalls:      deniedCalls,
+		rejectDirectives: rejectDirectives,
+		blacklistedImports: map[string][]string{
+			goLanguage: {
+				"os/exec",
+				"syscall",
+				"unsafe",
+				"net",
+				"net/http",
+				"plugin",
+			},
 		},
 		importPattern: regexp.MustCompile(`(?m)^\s*import\s*(\((?:[^)]+)\)|"[^"]+")`),
 	}
 }
 
-// ContainsBlacklistedImports verifica si el código contiene imports prohibidos
-func (cv *CodeValidator) ContainsBlacklistedImports(code string) (bool, string) {
+// ContainsBlacklistedImports verifica si el código de language contiene
+// imports prohibidos. Por ahora solo hay lista para Go: el código de otros
+// lenguajes no se comprueba.
+func (cv *CodeValidator) ContainsBlacklistedImports(language, code string) (bool, string) {
+	blacklistedImports := cv.blacklistedImports[language]
+	if len(blacklistedImports) == 0 {
+		return false, ""
+	}
+
 	// Buscar todos los matches de imports en el código
 	matches := cv.importPattern.FindAllStringSubmatch(code, -1)
 	
@@ -53,7 +113,7 @@ func (cv *CodeValidator) ContainsBlacklistedImports(code string) (bool, string)
 			imp = strings.Trim(imp, `"`)                         // Eliminar comillas si existen
 
 			// Comparar con la lista de imports prohibidos
-			for _, blacklisted := range cv.blacklistedImports {
+			for _, blacklisted := range blacklistedImports {
 				if imp == blacklisted {
 					return true, blacklisted
 				}
@@ -63,6 +123,83 @@ func (cv *CodeValidator) ContainsBlacklistedImports(code string) (bool, string)
 	return false, ""
 }
 
+// ImportModeBlocklist es el modo de la política de imports en el que se admite
+// cualquier paquete salvo los de la lista
+const ImportModeBlocklist = "blocklist"
+
+// ImportPolicy describe qué paquetes puede usar el código de un lenguaje
+type ImportPolicy struct {
+	// Mode es el modo de la política; por ahora siempre ImportModeBlocklist
+	Mode string `json:"mode"`
+	// Blocked son las rutas de import prohibidas, que deben coincidir exactamente
+	Blocked []string `json:"blocked"`
+	// DeniedFunctions son las funciones prohibidas aunque su paquete se admita
+	DeniedFunctions []string `json:"denied_functions"`
+}
+
+// ImportPolicy devuelve la política de imports de language, ordenada. Los
+// lenguajes sin lista de imports tienen una política vacía: no se comprueban.
+func (cv *CodeValidator) ImportPolicy(language string) ImportPolicy {
+	policy := ImportPolicy{
+		Mode:            ImportModeBlocklist,
+		Blocked:         append([]string{}, cv.blacklistedImports[language]...),
+		DeniedFunctions: []string{},
+	}
+	sort.Strings(policy.Blocked)
+	// FindDeniedCall solo analiza código Go
+	if language == goLanguage {
+		for _, call := range cv.deniedCalls {
+			policy.DeniedFunctions = append(policy.DeniedFunctions, call.String())
+		}
+		sort.Strings(policy.DeniedFunctions)
+	}
+	return policy
+}
+
+// ASTNodeCount analiza el código y cuenta los nodos de su AST con ast.Inspect.
+// Mide la complejidad mejor que la longitud en bytes: unas pocas líneas con
+// muchas llamadas o bucles anidados suman muchos nodos.
+// Retorna error si el código no es un archivo Go sintácticamente válido.
+func (cv *CodeValidator) ASTNodeCount(code string) (int, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "main.go", code, parser.SkipObjectResolution)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n != nil {
+			count++
+		}
+		return true
+	})
+	return count, nil
+}
+
+// ContainsOversizedLiterals indica si alguna cadena literal del código supera
+// maxLiteralBytes una vez interpretada con strconv.Unquote, y devuelve el
+// tamaño de la mayor de ellas. Las cadenas enormes (por ejemplo una carga en
+// base64) apenas suman nodos al AST pero ocupan memoria al compilar y en el
+// binario. El código que no se puede analizar no se rechaza: lo notifica el
+// compilador.
+func (cv *CodeValidator) ContainsOversizedLiterals(code string, maxLiteralBytes int) (bool, int) {
+	file, err := parser.ParseFile(token.NewFileSet(), "main.go", code, parser.SkipObjectResolution)
+	if err != nil {
+		return false, 0
+	}
+	largest := 0
+	ast.Inspect(file, func(n ast.Node) bool {
+		lit, ok := n.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		if value, err := strconv.Unquote(lit.Value); err == nil {
+			largest = max(largest, len(value))
+		}
+		return true
+	})
+	return largest > maxLiteralBytes, largest
+}
+
 // GetClientIP obtiene la dirección IP del cliente desde la solicitud HTTP
 func (cv *CodeValidator) GetClientIP(r *http.Request) string {
 	forwarded := r.Header.Get("X-Forwarded-For")
@@ -76,10 +213,19 @@ func (cv *CodeValidator) GetClientIP(r *http.Request) string {
 	return r.RemoteAddr
 }
 
-// SetSecurityHeaders establece los encabezados de seguridad en la respuesta HTTP
+// SetSecurityHeaders establece los encabezados de seguridad configurados en la respuesta HTTP
 func (cv *CodeValidator) SetSecurityHeaders(w http.ResponseWriter) {
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.Header().Set("X-Frame-Options", "DENY")
-	w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'self' 'unsafe-inline' 'unsafe-eval' https://cdn.jsdelivr.net blob:; worker-src 'self' blob:; connect-src 'self' https://cdn.jsdelivr.net; img-src 'self' https://go.dev data:; style-src 'self' 'unsafe-inline' https://cdn.jsdelivr.net; font-src 'self' https://cdn.jsdelivr.net")
-	// No establecemos Content-Type aquí para permitir que cada handler lo establezca según el tipo de archivo
+	set := func(name, value string) {
+		if value != "" {
+			w.Header().Set(name, value)
+		}
+	}
+	set("X-Content-Type-Options", cv.headers.ContentTypeOptions)
+	set("X-Frame-Options", cv.headers.FrameOptions)
+	set("Content-Security-Policy", cv.headers.ContentSecurityPolicy)
+	set("Strict-Transport-Security", cv.headers.StrictTransportSecurity)
+	set("Referrer-Policy", cv.headers.ReferrerPolicy)
+	// No establecemos Content-Type aquí para permitir que cada handler lo
+	// establezca según el tipo de archivo o la representación (JSON, texto en
+	// streaming); HTTPError fija application/json
 }
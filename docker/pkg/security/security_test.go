@@ -0,0 +1,105 @@
+package security
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindForbiddenConstruct(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      string
+		construct string
+	}{
+		{"import C", "package main\n\nimport \"C\"\n\nfunc main() {}\n", `import "C" (cgo)`},
+		{"import C agrupado", "package main\n\nimport (\n\t\"fmt\"\n\t\"C\"\n)\n\nfunc main() { fmt.Println() }\n", `import "C" (cgo)`},
+		{"import C con alias", "package main\n\nimport c \"C\"\n\nfunc main() {}\n", `import "C" (cgo)`},
+		{"go:linkname", "package main\n\n//go:linkname now time.now\nfunc now() int64\n\nfunc main() {}\n", "//go:linkname"},
+		{"go:cgo_import_dynamic", "package main\n\n//go:cgo_import_dynamic x x \"libc.so\"\n\nfunc main() {}\n", "//go:cgo_import_dynamic"},
+		{"go:noinline permitida", "package main\n\n//go:noinline\nfunc f() {}\n\nfunc main() { f() }\n", ""},
+		{"directiva dentro de una cadena", "package main\n\nvar s = \"//go:linkname\"\n\nfunc main() {}\n", ""},
+		{"import C dentro de una cadena", "package main\n\nvar s = `import \"C\"`\n\nfunc main() {}\n", ""},
+	}
+
+	cv := NewCodeValidator(SecurityHeaders{}, nil, nil, true)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			construct, found := cv.FindForbiddenConstruct(tt.code)
+			if found != (tt.construct != "") || construct != tt.construct {
+				t.Errorf("FindForbiddenConstruct() = (%q, %v), se esperaba %q", construct, found, tt.construct)
+			}
+		})
+	}
+}
+
+func TestFindForbiddenConstructDisabled(t *testing.T) {
+	cv := NewCodeValidator(SecurityHeaders{}, nil, nil, false)
+	for _, code := range []string{
+		"package main\n\nimport \"C\"\n\nfunc main() {}\n",
+		"package main\n\n//go:linkname now time.now\nfunc now() int64\n\nfunc main() {}\n",
+	} {
+		if construct, found := cv.FindForbiddenConstruct(code); found {
+			t.Errorf("con el rechazo desactivado se rechazó %q", construct)
+		}
+	}
+}
+
+// nestedLoops devuelve un programa con depth bucles for anidados
+func nestedLoops(depth int) string {
+	return "package main\n\nfunc main() {\n" +
+		strings.Repeat("for i := 0; i < 1; i++ {\n", depth) +
+		strings.Repeat("}\n", depth) + "}\n"
+}
+
+func TestASTNodeCount(t *testing.T) {
+	cv := NewCodeValidator(SecurityHeaders{}, nil, nil, true)
+
+	small, err := cv.ASTNodeCount("package main\n\nfunc main() {}\n")
+	if err != nil {
+		t.Fatalf("ASTNodeCount(): %v", err)
+	}
+	nested, err := cv.ASTNodeCount(nestedLoops(100))
+	if err != nil {
+		t.Fatalf("ASTNodeCount(): %v", err)
+	}
+	if nested <= 1000 {
+		t.Errorf("100 bucles anidados suman %d nodos, se esperaban más de 1000", nested)
+	}
+	if small >= nested {
+		t.Errorf("el programa vacío suma %d nodos y el anidado %d", small, nested)
+	}
+
+	if _, err := cv.ASTNodeCount("package main\n\nfunc main() {"); err == nil {
+		t.Error("ASTNodeCount() no devolvió error con código mal formado")
+	}
+}
+
+func TestContainsOversizedLiterals(t *testing.T) {
+	const maxLiteralBytes = 10 * 1024
+	program := func(literal string) string {
+		return "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(len(" + literal + "))\n}\n"
+	}
+	tests := []struct {
+		name      string
+		code      string
+		oversized bool
+		size      int
+	}{
+		{"cadena de 100 KB", program(`"` + strings.Repeat("A", 100*1024) + `"`), true, 100 * 1024},
+		{"cadena raw de 100 KB", program("`" + strings.Repeat("A", 100*1024) + "`"), true, 100 * 1024},
+		{"cadena justo en el límite", program(`"` + strings.Repeat("A", maxLiteralBytes) + `"`), false, maxLiteralBytes},
+		// Las secuencias de escape cuentan por los bytes que producen
+		{"escapes dentro del límite", program(`"` + strings.Repeat(`\x41`, 4*1024) + `"`), false, 4 * 1024},
+		{"código mal formado", "package main\n\nfunc main() { \"" + strings.Repeat("A", 100*1024), false, 0},
+	}
+
+	cv := NewCodeValidator(SecurityHeaders{}, nil, nil, true)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oversized, size := cv.ContainsOversizedLiterals(tt.code, maxLiteralBytes)
+			if oversized != tt.oversized || size != tt.size {
+				t.Errorf("ContainsOversizedLiterals() = (%v, %d), se esperaba (%v, %d)", oversized, size, tt.oversized, tt.size)
+			}
+		})
+	}
+}
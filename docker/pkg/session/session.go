@@ -0,0 +1,175 @@
+// Package session proporciona un almacén en memoria del historial de ejecuciones
+// por sesión de navegador.
+//
+// Cada sesión se identifica mediante un ID aleatorio (UUID v4) que el cliente
+// conserva en una cookie. Por privacidad, el historial nunca guarda el código
+// completo: solo su hash SHA-256, la hora de ejecución, un extracto de la salida
+// y si el resultado provino del caché.
+//
+// Ejemplo de uso básico:
+//
+//     store := session.NewSessionStore(20, time.Hour)
+//     id, _ := session.NewSessionID()
+//     store.Append(id, session.ExecutionSummary{CodeHash: hash, Timestamp: time.Now()})
+//     history := store.History(id, 20)
+package session
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MaxSummaryOutputLength es el tamaño máximo en bytes del extracto de salida
+// que se guarda en cada resumen de ejecución.
+const MaxSummaryOutputLength = 512
+
+// ExecutionSummary describe una ejecución realizada dentro de una sesión
+type ExecutionSummary struct {
+	CodeHash        string    `json:"code_hash"`
+	Timestamp       time.Time `json:"timestamp"`
+	TruncatedOutput string    `json:"truncated_output"`
+	CacheHit        bool      `json:"cache_hit"`
+}
+
+// HistoryStore define el comportamiento de un almacén de historial por sesión
+type HistoryStore interface {
+	Exists(id string) bool
+	Create(id string)
+	Append(id string, summary ExecutionSummary)
+	History(id string, limit int) []ExecutionSummary
+}
+
+// sessionData contiene el historial y la última actividad de una sesión
+type sessionData struct {
+	history    []ExecutionSummary
+	lastAccess time.Time
+}
+
+// SessionStore implementa HistoryStore con un mapa en memoria protegido por un RWMutex.
+// Las sesiones sin actividad durante más tiempo que ttl se eliminan periódicamente.
+type SessionStore struct {
+	sessions   map[string]*sessionData
+	mu         sync.RWMutex
+	maxHistory int
+	ttl        time.Duration
+}
+
+// NewSessionStore crea un nuevo almacén de sesiones.
+//
+// Parámetros:
+//   - maxHistory: Número máximo de ejecuciones guardadas por sesión.
+//   - ttl: Tiempo de inactividad tras el cual una sesión expira.
+func NewSessionStore(maxHistory int, ttl time.Duration) *SessionStore {
+	if maxHistory < 1 {
+		maxHistory = 1
+	}
+	s := &SessionStore{
+		sessions:   make(map[string]*sessionData),
+		maxHistory: maxHistory,
+		ttl:        ttl,
+	}
+
+	// Iniciar rutina de limpieza periódica
+	go s.cleanupRoutine()
+
+	return s
+}
+
+// Exists indica si la sesión existe y no ha expirado
+func (s *SessionStore) Exists(id string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, ok := s.sessions[id]
+	return ok && time.Since(data.lastAccess) <= s.ttl
+}
+
+// Create registra una sesión vacía con el ID indicado
+func (s *SessionStore) Create(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[id] = &sessionData{lastAccess: time.Now()}
+}
+
+// Append añade un resumen de ejecución al historial de la sesión,
+// descartando las entradas más antiguas si se supera maxHistory.
+func (s *SessionStore) Append(id string, summary ExecutionSummary) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.sessions[id]
+	if !ok {
+		data = &sessionData{}
+		s.sessions[id] = data
+	}
+	data.history = append(data.history, summary)
+	if len(data.history) > s.maxHistory {
+		data.history = data.history[len(data.history)-s.maxHistory:]
+	}
+	data.lastAccess = time.Now()
+}
+
+// History devuelve como máximo las últimas limit ejecuciones de la sesión,
+// de la más reciente a la más antigua.
+func (s *SessionStore) History(id string, limit int) []ExecutionSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := []ExecutionSummary{}
+	data, ok := s.sessions[id]
+	if !ok || time.Since(data.lastAccess) > s.ttl {
+		return result
+	}
+	data.lastAccess = time.Now()
+
+	for i := len(data.history) - 1; i >= 0 && len(result) < limit; i-- {
+		result = append(result, data.history[i])
+	}
+	return result
+}
+
+// cleanupRoutine elimina periódicamente las sesiones expiradas.
+// Se ejecuta en una goroutine separada y se activa cada ttl/2 tiempo.
+func (s *SessionStore) cleanupRoutine() {
+	ticker := time.NewTicker(s.ttl / 2)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.cleanup()
+	}
+}
+
+// cleanup elimina las sesiones cuya última actividad supera el TTL
+func (s *SessionStore) cleanup() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, data := range s.sessions {
+		if now.Sub(data.lastAccess) > s.ttl {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// NewSessionID genera un identificador de sesión aleatorio con formato UUID v4
+func NewSessionID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("error generando ID de sesión: %w", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // versión 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variante RFC 4122
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// TruncateOutput recorta la salida a MaxSummaryOutputLength bytes para el historial
+func TruncateOutput(output []byte) string {
+	if len(output) > MaxSummaryOutputLength {
+		return string(output[:MaxSummaryOutputLength]) + "..."
+	}
+	return string(output)
+}
@@ -0,0 +1,98 @@
+// Package share importa código desde los enlaces compartidos del Go Playground
+// oficial (https://go.dev/play/p/<id>) y genera enlaces que contienen el propio
+// código comprimido (ver EncodeURL), sin necesidad de guardarlo en el servidor.
+//
+// El código se descarga de "<baseURL>/<id>.go", que en el playground oficial
+// devuelve el fuente sin formato. baseURL puede apuntar a un proxy o espejo que
+// siga el mismo esquema de URLs.
+package share
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL es la URL de los enlaces compartidos del playground oficial
+const DefaultBaseURL = "https://go.dev/play/p"
+
+// Errores devueltos por Fetch
+var (
+	// ErrInvalidID indica que el ID no tiene el formato de un enlace compartido
+	ErrInvalidID = errors.New("ID de enlace compartido no válido")
+	// ErrNotFound indica que el playground no tiene ningún código con ese ID
+	ErrNotFound = errors.New("enlace compartido no encontrado")
+	// ErrTooLarge indica que el código supera el tamaño máximo admitido
+	ErrTooLarge = errors.New("el código compartido supera el tamaño máximo")
+	// ErrUpstream indica que el playground no respondió o respondió con un error
+	ErrUpstream = errors.New("error al consultar el playground")
+)
+
+// shareID reconoce los IDs de los enlaces compartidos, por ejemplo "HmnNoBf0p1r"
+var shareID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// SnippetImporter define el comportamiento para obtener el código de un enlace compartido
+type SnippetImporter interface {
+	Fetch(ctx context.Context, id string) (string, error)
+}
+
+// Importer descarga el código de los enlaces compartidos por HTTP
+type Importer struct {
+	baseURL  string
+	client   *http.Client
+	maxBytes int
+}
+
+// NewImporter crea un importador que consulta baseURL con un timeout por
+// solicitud y acepta códigos de hasta maxBytes bytes.
+func NewImporter(baseURL string, timeout time.Duration, maxBytes int) *Importer {
+	return &Importer{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		client:   &http.Client{Timeout: timeout},
+		maxBytes: maxBytes,
+	}
+}
+
+// Fetch descarga el código del enlace compartido id.
+//
+// Retorna ErrInvalidID, ErrNotFound o ErrTooLarge según el caso. Los timeouts,
+// los errores de red y las respuestas inesperadas envuelven ErrUpstream.
+func (im *Importer) Fetch(ctx context.Context, id string) (string, error) {
+	if !shareID.MatchString(id) {
+		return "", ErrInvalidID
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, im.baseURL+"/"+id+".go", nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUpstream, err)
+	}
+	req.Header.Set("Accept", "text/plain")
+
+	resp, err := im.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUpstream, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return "", ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("%w: respuesta %d", ErrUpstream, resp.StatusCode)
+	}
+
+	// Leer un byte más del máximo para distinguir el código demasiado grande
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(im.maxBytes)+1))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUpstream, err)
+	}
+	if len(body) > im.maxBytes {
+		return "", ErrTooLarge
+	}
+	return string(body), nil
+}
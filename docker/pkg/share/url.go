@@ -0,0 +1,72 @@
+package share
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// URLPath es la ruta de los enlaces con el código incluido ("/s?c=...")
+const URLPath = "/s"
+
+// MaxURLLength es la longitud máxima de un enlace generado por EncodeURL. El
+// código comprimido no puede ocupar más de unos 3 KB.
+const MaxURLLength = 4096
+
+// Errores devueltos por EncodeURL y DecodeURLCode
+var (
+	// ErrURLTooLong indica que el código comprimido no cabe en MaxURLLength
+	ErrURLTooLong = errors.New("el código comprimido no cabe en un enlace")
+	// ErrInvalidEncoding indica que el parámetro no es base64url de un zstd válido
+	ErrInvalidEncoding = errors.New("el enlace no contiene un código válido")
+)
+
+// zstdEncoder comprime el código de los enlaces. EncodeAll admite uso concurrente.
+var zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+
+// EncodeURL devuelve el enlace relativo "/s?c=<base64url(zstd(code))>" que
+// contiene el código completo, sin guardarlo en el servidor. Retorna
+// ErrURLTooLong si el enlace supera MaxURLLength.
+func EncodeURL(code string) (string, error) {
+	compressed := zstdEncoder.EncodeAll([]byte(code), nil)
+	link := URLPath + "?c=" + base64.RawURLEncoding.EncodeToString(compressed)
+	if len(link) > MaxURLLength {
+		return "", fmt.Errorf("%w: %d bytes (máximo %d)", ErrURLTooLong, len(link), MaxURLLength)
+	}
+	return link, nil
+}
+
+// DecodeURLCode obtiene el código del parámetro c de un enlace de EncodeURL.
+// La descompresión se detiene en maxBytes, para que un parámetro pequeño no
+// pueda expandirse a un código enorme. Retorna ErrURLTooLong si el parámetro
+// no cabría en MaxURLLength, ErrTooLarge si el código supera maxBytes y
+// ErrInvalidEncoding si no se puede decodificar.
+func DecodeURLCode(param string, maxBytes int) (string, error) {
+	if len(URLPath+"?c=")+len(url.QueryEscape(param)) > MaxURLLength {
+		return "", ErrURLTooLong
+	}
+	compressed, err := base64.RawURLEncoding.DecodeString(param)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidEncoding, err)
+	}
+
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(uint64(maxBytes)))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidEncoding, err)
+	}
+	defer decoder.Close()
+
+	code, err := decoder.DecodeAll(compressed, nil)
+	switch {
+	case errors.Is(err, zstd.ErrDecoderSizeExceeded):
+		return "", ErrTooLarge
+	case err != nil:
+		return "", fmt.Errorf("%w: %w", ErrInvalidEncoding, err)
+	case len(code) > maxBytes:
+		return "", ErrTooLarge
+	}
+	return string(code), nil
+}
@@ -0,0 +1,103 @@
+// Package telemetry configura el trazado distribuido con OpenTelemetry.
+//
+// InitTracer instala un TracerProvider global que exporta los spans por OTLP/HTTP.
+// Si no se configura un endpoint, el TracerProvider global sigue siendo el no-op
+// de OpenTelemetry y crear spans no tiene coste apreciable.
+//
+// Ejemplo de uso básico:
+//
+//     shutdown, err := telemetry.InitTracer("go-playground-plus", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"))
+//     if err != nil {
+//         log.Fatalf("Error al configurar el trazado: %v", err)
+//     }
+//     defer shutdown()
+//
+//     ctx, span := telemetry.Tracer("handlers").Start(ctx, "HandleExecuteCode")
+//     defer span.End()
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// shutdownTimeout es el tiempo máximo para enviar los spans pendientes al apagar
+const shutdownTimeout = 5 * time.Second
+
+// InitTracer configura el trazado global con un exportador OTLP/HTTP.
+//
+// exporterEndpoint sigue la semántica de OTEL_EXPORTER_OTLP_ENDPOINT: es la URL
+// base del colector (ej. "http://otel-collector:4318") y los spans se envían a
+// su ruta /v1/traces. Si está vacío no se configura nada.
+//
+// Retorna una función que envía los spans pendientes y cierra el exportador,
+// pensada para llamarse al apagar el servidor.
+func InitTracer(serviceName, exporterEndpoint string) (func(), error) {
+	if exporterEndpoint == "" {
+		return func() {}, nil
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpointURL(strings.TrimSuffix(exporterEndpoint, "/")+"/v1/traces"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(serviceName),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		provider.Shutdown(ctx)
+	}, nil
+}
+
+// Tracer devuelve un tracer del TracerProvider global con el nombre indicado
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(name)
+}
+
+// StartRequestSpan inicia el span de servidor de una solicitud HTTP. Si el
+// cliente envía la cabecera traceparent, el span continúa su traza.
+//
+// El contexto devuelto no deriva de r.Context(): solo transporta el span, para
+// que quien lo use decida su propia cancelación.
+func StartRequestSpan(r *http.Request, tracerName, spanName string) (context.Context, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(r.Header))
+	return Tracer(tracerName).Start(ctx, spanName,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.URLPath(r.URL.Path),
+		),
+	)
+}
+
+// RecordError registra err en el span y lo marca como fallido
+func RecordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,60 @@
+package telemetry
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestInitTracerWithoutEndpoint(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	shutdown, err := InitTracer("go-playground-plus", "")
+	if err != nil {
+		t.Fatalf("InitTracer: %v", err)
+	}
+	shutdown()
+	if otel.GetTracerProvider() != previous {
+		t.Error("InitTracer sin endpoint cambió el TracerProvider global")
+	}
+}
+
+func TestStartRequestSpanContinuesTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	}()
+
+	r := httptest.NewRequest("POST", "/api/execute", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, span := StartRequestSpan(r, "test", "HandleExecuteCode")
+	_, child := Tracer("test").Start(ctx, "CachedExecutor.Execute")
+	child.End()
+	span.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("se registraron %d spans, se esperaban 2", len(spans))
+	}
+	root := spans[1]
+	if got := root.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("TraceID = %s, se esperaba el de traceparent", got)
+	}
+	if got := root.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("el span padre es %s, se esperaba el de traceparent", got)
+	}
+	if root.SpanKind() != trace.SpanKindServer {
+		t.Errorf("SpanKind = %v, se esperaba server", root.SpanKind())
+	}
+	if spans[0].Parent().SpanID() != root.SpanContext().SpanID() {
+		t.Error("el span hijo no cuelga del span de la solicitud")
+	}
+}
@@ -0,0 +1,129 @@
+// Package templates proporciona la biblioteca de plantillas de código de ejemplo.
+//
+// Las plantillas se definen en archivos JSON (un arreglo de plantillas por archivo)
+// que se embeben en el binario con embed.FS, por lo que no se necesitan archivos
+// externos en tiempo de ejecución. Al cargarlas se valida que el código de cada
+// plantilla sea Go sintácticamente correcto usando go/parser.
+//
+// Ejemplo de uso básico:
+//
+//     //go:embed templates/*.json
+//     var templateFS embed.FS
+//
+//     library, err := templates.Load(templateFS, "templates/*.json")
+//     if err != nil {
+//         log.Fatalf("Plantillas inválidas: %v", err)
+//     }
+//     tpl, ok := library.Get("hello-world")
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"sort"
+)
+
+// Categories contiene las categorías de plantillas admitidas
+var Categories = []string{
+	"basics",
+	"concurrency",
+	"data-structures",
+	"algorithms",
+	"stdlib",
+}
+
+// Template representa una plantilla de código de ejemplo
+type Template struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Category string `json:"category"`
+	Code     string `json:"code"`
+}
+
+// Library contiene las plantillas cargadas, indexadas por ID
+type Library struct {
+	templates []Template
+	byID      map[string]Template
+}
+
+// Load carga y valida todas las plantillas de los archivos de fsys que coinciden con pattern.
+//
+// Retorna error si algún archivo no es JSON válido, si hay IDs duplicados o vacíos,
+// si una categoría no está en Categories o si el código de una plantilla no compila
+// sintácticamente.
+func Load(fsys fs.FS, pattern string) (*Library, error) {
+	files, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("error buscando plantillas: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no se encontraron plantillas con el patrón %s", pattern)
+	}
+
+	validCategories := make(map[string]bool, len(Categories))
+	for _, c := range Categories {
+		validCategories[c] = true
+	}
+
+	library := &Library{byID: make(map[string]Template)}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("error leyendo %s: %w", file, err)
+		}
+
+		var items []Template
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, fmt.Errorf("error decodificando %s: %w", file, err)
+		}
+
+		for _, t := range items {
+			if t.ID == "" {
+				return nil, fmt.Errorf("plantilla sin id en %s", file)
+			}
+			if _, exists := library.byID[t.ID]; exists {
+				return nil, fmt.Errorf("plantilla duplicada %q en %s", t.ID, file)
+			}
+			if !validCategories[t.Category] {
+				return nil, fmt.Errorf("categoría desconocida %q en la plantilla %q", t.Category, t.ID)
+			}
+			if _, err := parser.ParseFile(token.NewFileSet(), t.ID+".go", t.Code, parser.AllErrors); err != nil {
+				return nil, fmt.Errorf("código inválido en la plantilla %q: %w", t.ID, err)
+			}
+
+			library.byID[t.ID] = t
+			library.templates = append(library.templates, t)
+		}
+	}
+
+	// Orden estable: por categoría según Categories y luego por ID
+	order := make(map[string]int, len(Categories))
+	for i, c := range Categories {
+		order[c] = i
+	}
+	sort.SliceStable(library.templates, func(i, j int) bool {
+		a, b := library.templates[i], library.templates[j]
+		if order[a.Category] != order[b.Category] {
+			return order[a.Category] < order[b.Category]
+		}
+		return a.ID < b.ID
+	})
+
+	return library, nil
+}
+
+// All devuelve todas las plantillas ordenadas por categoría e ID
+func (l *Library) All() []Template {
+	result := make([]Template, len(l.templates))
+	copy(result, l.templates)
+	return result
+}
+
+// Get devuelve la plantilla con el ID indicado
+func (l *Library) Get(id string) (Template, bool) {
+	t, ok := l.byID[id]
+	return t, ok
+}
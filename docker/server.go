@@ -1,34 +1,61 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"embed"
 	"fmt"
+	"io/fs"
 	"log"
+	"maps"
+	"net"
 	"net/http"
 	"os"
-	"path/filepath"
-	"strconv"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/luis198755/go_playGround_plus/docker/pkg/audit"
 	"github.com/luis198755/go_playGround_plus/docker/pkg/config"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/debug"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/errors"
 	"github.com/luis198755/go_playGround_plus/docker/pkg/executor"
 	"github.com/luis198755/go_playGround_plus/docker/pkg/handlers"
 	"github.com/luis198755/go_playGround_plus/docker/pkg/limiter"
 	"github.com/luis198755/go_playGround_plus/docker/pkg/logger"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/metrics"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/middleware"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/notifications"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/probes"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/queue"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/quota"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/reporting"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/requestctx"
 	"github.com/luis198755/go_playGround_plus/docker/pkg/security"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/session"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/share"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/telemetry"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/templates"
 	"go.uber.org/zap"
 )
 
 // Variables globales y constantes se han movido a los paquetes correspondientes
 
-// getEnvInt obtiene una variable de entorno int o devuelve el valor por defecto
-func getEnvInt(key string, defaultValue int) int {
-	if value, exists := os.LookupEnv(key); exists && value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
-		}
-	}
-	return defaultValue
-}
+// serverVersion es la versión del servidor que se registra al arrancar y se
+// envía con los errores a Sentry
+const serverVersion = "1.0.0"
+
+// templateFS contiene las plantillas de código embebidas en tiempo de compilación
+//
+//go:embed templates/*.json
+var templateFS embed.FS
+
+// placeholderFS contiene la página que se sirve si STATIC_FILES_DIR no existe,
+// para que el servidor arranque sin el frontend compilado
+//
+//go:embed build/*
+var placeholderFS embed.FS
 
 func main() {
 	log.SetFlags(log.Ldate | log.Ltime | log.LUTC)
@@ -39,26 +66,62 @@ func main() {
 	// Inicializar logger estructurado con nivel basado en configuración
 	debugMode := cfg.DebugMode
 	appLogger := logger.NewLogger(debugMode)
+	defer appLogger.Sync()
+	cfg.LogBootstrapMessages(appLogger)
 	appLogger.Info("Iniciando servidor Go Playground Plus", 
-		zap.String("version", "1.0.0"),
+		zap.String("version", serverVersion),
 		zap.String("config", cfg.String()))
 	
-	// Configurar variables de entorno para la ejecución del código Go
-	essentialEnvVars := config.GetEssentialEnvVars()
-	appLogger.Info("Configurando variables de entorno para ejecución de código")
-	
-	// En lugar de limpiar todas las variables de entorno (os.Clearenv),
-	// establecemos solo las variables esenciales que necesitamos
-	for key, value := range essentialEnvVars {
-		if value != "" {
-			os.Setenv(key, value)
-			appLogger.Debug("Variable de entorno configurada", 
-				zap.String("key", key))
+	// Contexto que se cancela al recibir SIGINT o SIGTERM para el apagado ordenado
+	shutdownCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Configurar el trazado distribuido (sin efecto si no hay endpoint OTLP)
+	shutdownTracer, err := telemetry.InitTracer(cfg.OTELServiceName, cfg.OTELExporterEndpoint)
+	if err != nil {
+		appLogger.Fatal("Error al configurar OpenTelemetry", zap.Error(err))
+	}
+	defer shutdownTracer()
+	if cfg.OTELExporterEndpoint != "" {
+		appLogger.Info("Trazado OpenTelemetry configurado", 
+			zap.String("service_name", cfg.OTELServiceName),
+			zap.String("endpoint", cfg.OTELExporterEndpoint))
+	}
+
+	// Enlazar la documentación de cada código de error en las respuestas de error
+	errors.SetDocsBaseURL(cfg.ErrorDocsBaseURL)
+
+	// Enviar los panics y los errores internos a Sentry, si está configurado
+	var errorReporter *reporting.SentryErrorReporter
+	if cfg.SentryDSN != "" {
+		errorReporter, err = reporting.NewSentryErrorReporter(cfg.SentryDSN, serverVersion, cfg.Environment())
+		if err != nil {
+			appLogger.Fatal("Error al configurar Sentry", zap.Error(err))
 		}
+		errors.SetErrorObserver(errorReporter)
+		appLogger.Info("Seguimiento de errores con Sentry configurado", 
+			zap.String("environment", cfg.Environment()))
 	}
 
 	// Inicializar componentes
-	securityValidator := security.NewCodeValidator()
+	denyRules, err := security.ParseDenyRules(cfg.CodeDenyPatterns)
+	if err != nil {
+		appLogger.Fatal("Reglas de CODE_DENY_PATTERNS no válidas", zap.Error(err))
+	}
+	if len(denyRules) > 0 {
+		appLogger.Info("Reglas de contenido prohibido configuradas", zap.Int("rules", len(denyRules)))
+	}
+	deniedCalls, err := security.ParseDeniedCalls(cfg.CodeDenyCalls)
+	if err != nil {
+		appLogger.Fatal("Funciones de CODE_DENY_CALLS no válidas", zap.Error(err))
+	}
+	if len(deniedCalls) > 0 {
+		appLogger.Info("Funciones prohibidas configuradas", zap.Strings("functions", cfg.CodeDenyCalls))
+	}
+	if !cfg.RejectCgoDirectives {
+		appLogger.Warn("Se admiten cgo y todas las directivas //go: en el código enviado")
+	}
+	securityValidator := security.NewCodeValidator(cfg.SecurityHeaders(), denyRules, deniedCalls, cfg.RejectCgoDirectives)
 	
 	// Verificar que el directorio temporal existe
 	if _, err := os.Stat(cfg.TempDir); os.IsNotExist(err) {
@@ -69,95 +132,583 @@ func main() {
 	}
 	
 	// Inicializar rate limiter con configuración
-	rateLimiter := limiter.NewRateLimiter(cfg.MaxRequestsPerMinute)
+	rateLimitObserver := metrics.NewPrometheusRateLimitObserver()
+	rateLimiter := limiter.NewRateLimiter(cfg.MaxRequestsPerMinute, cfg.MaxBurstSize, rateLimitObserver)
 	appLogger.Info("Rate limiter configurado", 
-		zap.Int("max_requests_per_minute", cfg.MaxRequestsPerMinute))
+		zap.Int("max_requests_per_minute", cfg.MaxRequestsPerMinute),
+		zap.Int("burst_size", cfg.MaxBurstSize))
+	
+	// Credenciales sin privilegios para el proceso hijo, si están configuradas
+	var childCredential *syscall.Credential
+	if cfg.ChildUID >= 0 && cfg.ChildGID >= 0 {
+		childCredential = &syscall.Credential{
+			Uid: uint32(cfg.ChildUID),
+			Gid: uint32(cfg.ChildGID),
+		}
+		appLogger.Info("El código se ejecutará con un usuario sin privilegios", 
+			zap.Int("uid", cfg.ChildUID),
+			zap.Int("gid", cfg.ChildGID))
+	}
+	
+	// Entorno del proceso hijo: solo las variables esenciales y las configuradas
+	childEnv := cfg.ChildEnv()
+	childEnvNames := make([]string, len(childEnv))
+	for i, kv := range childEnv {
+		childEnvNames[i], _, _ = strings.Cut(kv, "=")
+	}
+	appLogger.Info("Entorno del proceso hijo configurado", 
+		zap.Strings("variables", childEnvNames))
 	
 	// Inicializar ejecutor de código Go
-	baseExecutor := executor.NewGoExecutor(
-		cfg.GoExecutablePath,
-		cfg.MaxOutputLength,
-		cfg.TempDir,
-	)
+	baseExecutor, err := executor.NewGoExecutor(executor.GoExecutorOptions{
+		GoExecutablePath:       cfg.GoExecutablePath,
+		MaxStdoutLength:        cfg.MaxStdoutLength,
+		MaxStderrLength:        cfg.MaxStderrLength,
+		MaxOutputLines:         cfg.MaxOutputLines,
+		RaceMaxStderrLength:    cfg.RaceMaxStderrLength,
+		TempDir:                cfg.TempDir,
+		MaxConcurrentTempFiles: cfg.MaxConcurrentTempFiles,
+		MaxTempBytes:           cfg.TempDirQuotaBytes,
+		KeepTempFiles:          cfg.KeepTempFiles,
+		Limits: executor.ProcessLimits{
+			GOMAXPROCS:   cfg.ChildGOMAXPROCS,
+			MaxProcesses: cfg.ChildMaxProcesses,
+			Credential:   childCredential,
+		},
+		Cleanup: executor.TempCleanup{
+			Interval: cfg.CleanupInterval,
+			MaxAge:   2 * cfg.MaxRequestTimeout(),
+		},
+		AutoWrapCode:   cfg.AutoWrapCode,
+		ReadBufferSize: cfg.ReadBufferSize,
+		Env:            childEnv,
+		FakeTime:       cfg.FakeTimeEnabled,
+		ScratchHome:    cfg.ChildScratchHome,
+	}, appLogger)
+	if err != nil {
+		appLogger.Fatal("Error al inicializar el ejecutor de código Go", zap.Error(err))
+	}
 	
-	// Configurar el ejecutor con caché
-	maxCacheSize := getEnvInt("MAX_CACHE_SIZE", 100) // Número máximo de entradas en caché
-	cacheTTL := time.Duration(getEnvInt("CACHE_TTL_MINUTES", 30)) * time.Minute
+	// Publicar el uso de los directorios temporales y los recortes de salida en /metrics
+	metrics.RegisterTempUsage(baseExecutor.TempUsage)
+	metrics.RegisterOutputTruncation(baseExecutor.OutputStats)
+	
+	// Eliminar periódicamente archivos temporales huérfanos hasta el apagado
+	baseExecutor.StartCleanup(shutdownCtx)
+	if cfg.KeepTempFiles {
+		appLogger.Warn("Se conservan los archivos temporales para depuración y la limpieza está desactivada", 
+			zap.String("temp_dir", cfg.TempDir))
+	} else {
+		appLogger.Info("Limpieza de archivos temporales configurada", 
+			zap.Duration("interval", cfg.CleanupInterval),
+			zap.Duration("max_age", 2*cfg.MaxRequestTimeout()))
+	}
 	
+	// Configurar el ejecutor con caché
 	appLogger.Info("Configurando caché de ejecución", 
-		zap.Int("max_size", maxCacheSize),
-		zap.Duration("ttl", cacheTTL))
+		zap.Int("max_size", cfg.MaxCacheSize),
+		zap.Int("max_entry_bytes", cfg.MaxCachedOutputLength),
+		zap.Duration("ttl", cfg.CacheTTL),
+		zap.Duration("cleanup_interval", cfg.CacheCleanupInterval),
+		zap.Bool("normalize_code", cfg.CacheNormalizeCode))
 		
-	codeExecutor := executor.NewCachedExecutor(baseExecutor, maxCacheSize, cacheTTL)
+	// El caché también comparte las ejecuciones concurrentes del mismo código
+	cacheOptions := []executor.CachedExecutorOption{
+		executor.WithCleanupInterval(cfg.CacheCleanupInterval),
+		executor.WithEvictionCallback(metrics.NewCacheEvictionCounter()),
+		executor.WithLogger(appLogger),
+		executor.WithExecutionTimeout(cfg.ExecutionTimeout),
+	}
+	if cfg.CacheNormalizeCode {
+		cacheOptions = append(cacheOptions, executor.WithCodeNormalization())
+	}
+	codeExecutor := executor.NewCachedExecutor(baseExecutor, cfg.MaxCacheSize, cfg.MaxCachedOutputLength, cfg.CacheTTL, cacheOptions...)
+	defer codeExecutor.Stop()
+	metrics.RegisterCacheLookupLatency(codeExecutor.Stats)
 	appLogger.Info("Ejecutor de código configurado", 
 		zap.String("go_path", cfg.GoExecutablePath),
-		zap.String("temp_dir", cfg.TempDir))
+		zap.String("temp_dir", cfg.TempDir),
+		zap.Int("max_concurrent_temp_files", cfg.MaxConcurrentTempFiles),
+		zap.Int("child_gomaxprocs", cfg.ChildGOMAXPROCS),
+		zap.Int("child_max_processes", cfg.ChildMaxProcesses))
+	
+	// Ejecutar un programa trivial por toda la cadena del ejecutor para detectar
+	// al arrancar un toolchain roto o un directorio temporal mal configurado
+	smokeLatency, err := runStartupSmokeTest(shutdownCtx, codeExecutor, cfg.ExecutionTimeout)
+	if err != nil {
+		appLogger.Fatal("La ejecución de prueba al arrancar falló, revise GO_EXECUTABLE_PATH y TEMP_DIR", 
+			zap.Duration("latency", smokeLatency),
+			zap.Error(err))
+	}
+	appLogger.Info("Ejecución de prueba al arrancar completada", 
+		zap.Duration("latency", smokeLatency))
+	
+	// Ejecutores por lenguaje para "language" en /api/execute. El de Go se crea
+	// antes porque las métricas, la limpieza y la ejecución de prueba usan sus
+	// tipos concretos; su factoría solo lo entrega al registro.
+	executors := executor.NewExecutorRegistry()
+	if err := executors.Register(executor.DefaultLanguage, func(*config.Config) (executor.CodeExecutor, error) {
+		return codeExecutor, nil
+	}); err != nil {
+		appLogger.Fatal("Error al registrar el ejecutor de Go", zap.Error(err))
+	}
+	if err := executors.Init(cfg); err != nil {
+		appLogger.Fatal("Error al inicializar los ejecutores", zap.Error(err))
+	}
+	appLogger.Info("Lenguajes disponibles", zap.Strings("languages", executors.Languages()))
+	
+	// Inicializar almacén de sesiones para el historial de ejecuciones
+	sessionStore := session.NewSessionStore(cfg.MaxSessionHistory, cfg.SessionTTL)
+	appLogger.Info("Almacén de sesiones configurado", 
+		zap.Int("max_history", cfg.MaxSessionHistory),
+		zap.Duration("ttl", cfg.SessionTTL))
+	
+	// Las ejecuciones con -race solo se aceptan si el detector está habilitado
+	var raceTimeout time.Duration
+	if cfg.RaceDetectorEnabled {
+		raceTimeout = cfg.RaceExecutionTimeout
+		appLogger.Info("Detector de carreras habilitado",
+			zap.Duration("timeout", cfg.RaceExecutionTimeout),
+			zap.Int("max_stderr_length", cfg.RaceMaxStderrLength))
+	}
+
+	// Importación de enlaces compartidos del playground oficial, si está configurada
+	var snippetImporter share.SnippetImporter
+	if cfg.ShareImportURL != "" {
+		snippetImporter = share.NewImporter(cfg.ShareImportURL, cfg.ShareImportTimeout, cfg.MaxCodeLength)
+		appLogger.Info("Importación de enlaces compartidos configurada",
+			zap.String("url", cfg.ShareImportURL),
+			zap.Duration("timeout", cfg.ShareImportTimeout))
+	}
+
+	// Cola FIFO de ejecuciones, si se limitan las ejecuciones simultáneas
+	var executionQueue queue.ExecutionQueue
+	if cfg.MaxConcurrentExecutions > 0 {
+		fifo := queue.NewQueue(cfg.MaxConcurrentExecutions, cfg.ExecutionQueueSize)
+		metrics.RegisterExecutionQueue(fifo.Stats)
+		executionQueue = fifo
+		appLogger.Info("Cola de ejecución configurada",
+			zap.Int("max_concurrent_executions", cfg.MaxConcurrentExecutions),
+			zap.Int("queue_size", cfg.ExecutionQueueSize))
+	}
+
+	// Alertas por webhook cuando la tasa de ejecuciones fallidas supera el umbral
+	var executionOutcomes notifications.ExecutionRecorder
+	if cfg.WebhookURL != "" {
+		monitor := notifications.NewFailureRateMonitor(
+			notifications.NewWebhookNotifier(cfg.WebhookURL, 10*time.Second),
+			float64(cfg.WebhookFailureRateThreshold)/100,
+			cfg.WebhookWindow,
+			cfg.WebhookDebounce,
+			appLogger,
+		)
+		metrics.RegisterExecutionOutcomes(monitor.Stats)
+		executionOutcomes = monitor
+		appLogger.Info("Alertas por webhook configuradas",
+			zap.Int("failure_rate_threshold_percent", cfg.WebhookFailureRateThreshold),
+			zap.Duration("window", cfg.WebhookWindow),
+			zap.Duration("debounce", cfg.WebhookDebounce))
+	}
+
+	// Uso y cuota diaria de cada clave de API, solo con claves configuradas
+	var apiKeyQuotas quota.Tracker
+	if len(cfg.APIKeyHashes) > 0 {
+		apiKeyQuotas = quota.NewDailyTracker(cfg.APIKeyDailyQuota, cfg.APIKeyQuotas)
+		appLogger.Info("Cuotas de las claves de API configuradas",
+			zap.Int("daily_quota", cfg.APIKeyDailyQuota),
+			zap.Any("key_quotas", cfg.APIKeyQuotas))
+	}
+
+	// Límite de ejecuciones de todos los clientes juntos, frente a avalanchas
+	// repartidas entre muchas IPs
+	var globalLimiter limiter.GlobalLimiter
+	if cfg.MaxExecutionsPerSecond > 0 {
+		globalBucket := limiter.NewGlobalTokenBucket(cfg.MaxExecutionsPerSecond, cfg.MaxExecutionsPerSecond)
+		metrics.RegisterGlobalRateLimit(globalBucket.Stats)
+		globalLimiter = globalBucket
+		appLogger.Info("Límite global de ejecuciones configurado",
+			zap.Int("executions_per_second", cfg.MaxExecutionsPerSecond))
+	}
+
+	// Registro de auditoría de las ejecuciones (AUDIT_LOG_ENABLED)
+	var auditRecorder audit.Recorder
+	if cfg.AuditLogEnabled {
+		codeMode, err := audit.ParseCodeMode(cfg.AuditLogCode)
+		if err != nil {
+			appLogger.Fatal("AUDIT_LOG_CODE no válido", zap.Error(err))
+		}
+		// Con el código completo, este va a su propio archivo y no al log general
+		var codeSink audit.CodeSink
+		if codeMode == audit.CodeModeFull {
+			fileSink, err := audit.NewFileSink(cfg.AuditCodeFile)
+			if err != nil {
+				appLogger.Fatal("Error al abrir AUDIT_CODE_FILE", zap.Error(err))
+			}
+			defer fileSink.Close()
+			codeSink = fileSink
+		}
+		auditRecorder = audit.NewLogRecorder(appLogger, codeMode, codeSink)
+		appLogger.Info("Registro de auditoría de ejecuciones habilitado",
+			zap.String("code", string(codeMode)),
+			zap.String("code_file", cfg.AuditCodeFile))
+	}
 	
 	// Inicializar handlers
 	apiHandler := handlers.NewAPIHandler(
 		rateLimiter,
+		globalLimiter,
 		securityValidator,
-		codeExecutor,
+		executors,
+		sessionStore,
 		appLogger,
 		cfg.MaxCodeLength,
+		cfg.MaxASTNodes,
+		cfg.MaxStringLiteralBytes,
+		cfg.MaxOutputLength,
 		cfg.ExecutionTimeout,
+		cfg.BenchmarkTimeout,
+		raceTimeout,
+		snippetImporter,
+		executionQueue,
+		executionOutcomes,
+		apiKeyQuotas,
+		auditRecorder,
+		cfg.NormalizeOutput,
 	)
 	
-	// Configurar rutas
-	http.HandleFunc("/api/execute", apiHandler.HandleExecuteCode)
+	// Cargar y validar la biblioteca de plantillas embebidas
+	templateLibrary, err := templates.Load(templateFS, "templates/*.json")
+	if err != nil {
+		appLogger.Fatal("Error al cargar las plantillas de código", zap.Error(err))
+	}
+	appLogger.Info("Plantillas de código cargadas", 
+		zap.Int("count", len(templateLibrary.All())))
+	templateHandler := handlers.NewTemplateHandler(templateLibrary, securityValidator, appLogger)
+	configHandler := handlers.NewConfigHandler(cfg, securityValidator, appLogger)
+	
+	// Rechazar ejecuciones con 503 si las goroutines se acumulan por encima del umbral
+	loadShedder := middleware.NewLoadShedder(cfg.MaxGoroutines, appLogger)
+	if loadShedder.Enabled() {
+		loadShedder.Start(shutdownCtx)
+		metrics.RegisterLoadShedding(loadShedder.Stats)
+		appLogger.Info("Rechazo de carga por goroutines configurado", 
+			zap.Int("max_goroutines", cfg.MaxGoroutines))
+	}
+	
+	// Claves de API (API_KEY_HASHES); sin claves el servicio queda abierto
+	apiAuth := middleware.NewAPIKeyAuth(cfg.APIKeyHashes, securityValidator.GetClientIP, appLogger)
+	if apiAuth.Enabled() {
+		appLogger.Info("Autenticación con claves de API habilitada", 
+			zap.Int("keys", len(cfg.APIKeyHashes)))
+	}
+	
+	// Límites por endpoint (ENDPOINT_RATE_LIMITS), además del límite global.
+	// Con clave de API se cuentan por clave en lugar de por IP.
+	endpointLimiter := middleware.NewEndpointRateLimiter(cfg.EndpointRateLimits, rateLimitObserver,
+		func(r *http.Request) string {
+			return requestctx.RateLimitKey(r.Context(), securityValidator.GetClientIP(r))
+		}, appLogger)
+	if len(cfg.EndpointRateLimits) > 0 {
+		appLogger.Info("Límites por endpoint configurados", 
+			zap.Any("requests_per_minute", cfg.EndpointRateLimits))
+	}
+	
+	// Timeout total de los manejadores (HANDLER_TIMEOUT_SECONDS). /api/execute
+	// transmite la salida y ya está acotado por el timeout de ejecución, así que
+	// queda exento; las demás rutas que ejecutan código reciben al menos el
+	// timeout de ejecución más un margen, contando con que PATCH /api/config
+	// puede subirlo. HANDLER_TIMEOUTS prevalece sobre ambos.
+	handlerTimeouts := map[string]time.Duration{"/api/execute": 0}
+	if cfg.HandlerTimeout > 0 {
+		maxExecution := max(cfg.MaxRequestTimeout(), cfg.MaxPatchableExecutionTimeout())
+		executionBound := max(cfg.HandlerTimeout, maxExecution+5*time.Second)
+		for _, path := range []string{"/api/compile", "/api/asm", "/api/benchmark", "/api/test", "/api/diff"} {
+			handlerTimeouts[path] = executionBound
+		}
+	}
+	maps.Copy(handlerTimeouts, cfg.HandlerTimeouts)
+	handlerTimeout := middleware.NewHandlerTimeout(cfg.HandlerTimeout, handlerTimeouts, appLogger)
+	if cfg.HandlerTimeout > 0 || len(cfg.HandlerTimeouts) > 0 {
+		appLogger.Info("Timeout de manejadores configurado",
+			zap.Duration("default", cfg.HandlerTimeout),
+			zap.String("routes", fmt.Sprint(handlerTimeouts)))
+	}
+	
+	// Configurar rutas en un mux propio: net/http/pprof registra sus manejadores
+	// en http.DefaultServeMux, que por eso nunca se sirve públicamente. route
+	// exige la clave de API, si hay claves, y aplica a cada ruta su límite por
+	// endpoint y su timeout, si los tiene.
+	mux := http.NewServeMux()
+	route := func(pattern string, handler http.Handler) {
+		mux.Handle(pattern, apiAuth.Require(endpointLimiter.Limit(pattern, handlerTimeout.Timeout(pattern, handler))))
+	}
+	route("/api/execute", loadShedder.Shed(http.HandlerFunc(apiHandler.HandleExecuteCode)))
+	route("/api/execute/cancel", http.HandlerFunc(apiHandler.HandleCancelExecution))
+	route("/api/compile", loadShedder.Shed(http.HandlerFunc(apiHandler.HandleCompile)))
+	route("/api/asm", loadShedder.Shed(http.HandlerFunc(apiHandler.HandleAssembly)))
+	route("/api/benchmark", loadShedder.Shed(http.HandlerFunc(apiHandler.HandleBenchmark)))
+	route("/api/test", loadShedder.Shed(http.HandlerFunc(apiHandler.HandleTest)))
+	route("/api/diff", loadShedder.Shed(http.HandlerFunc(apiHandler.HandleDiff)))
+	route("/api/history", http.HandlerFunc(apiHandler.HandleHistory))
+	route("/api/import/{id}", http.HandlerFunc(apiHandler.HandleImportShare))
+	route("/api/share/encode", http.HandlerFunc(apiHandler.HandleEncodeShareURL))
+	route(share.URLPath, http.HandlerFunc(apiHandler.HandleSharedURL))
+	route("/api/templates", http.HandlerFunc(templateHandler.HandleListTemplates))
+	route("/api/templates/{id}", http.HandlerFunc(templateHandler.HandleGetTemplate))
+	route("/api/config", http.HandlerFunc(configHandler.HandleConfig))
+	route("/api/imports", http.HandlerFunc(configHandler.HandleImports))
+	mux.Handle("/metrics", metrics.Handler())
+	
+	// Endpoints de administración, solo con ADMIN_TOKEN configurado
+	if cfg.AdminToken != "" {
+		adminHandler := handlers.NewAdminHandler(cfg, securityValidator, apiKeyQuotas, appLogger,
+			apiHandler, configHandler,
+			handlers.LimitsFunc(func(limits config.RuntimeLimits) {
+				rateLimiter.SetRate(limits.MaxRequestsPerMinute)
+			}))
+		mux.HandleFunc("/admin/config", adminHandler.HandleConfig)
+		// Más específica que "/api/config", que sigue sirviendo los GET
+		mux.HandleFunc("PATCH /api/config", adminHandler.HandlePatchConfig)
+		mux.HandleFunc("/admin/env-vars", adminHandler.HandleEnvVars)
+		mux.HandleFunc("/admin/api-keys", adminHandler.HandleAPIKeys)
+		appLogger.Info("Endpoints de administración habilitados")
+	}
+	
+	// Sondas para el orquestador, sin rate limiting
+	mux.Handle("/healthz", probes.NewLivenessHandler())
+	mux.Handle("/readyz", probes.NewReadinessHandler(
+		baseExecutor.CheckGoExecutable,
+		baseExecutor.CheckTempDir,
+		codeExecutor.Ready,
+		rateLimiter.Ready,
+	))
 	
 	// Servir archivos estáticos desde la ruta configurada
 	staticDir := cfg.StaticFilesDir
 	appLogger.Info("Configurando servidor de archivos estáticos", 
 		zap.String("static_dir", staticDir))
 	
-	// Verificar que el directorio de archivos estáticos exista
+	// Si el directorio no existe (por ejemplo, solo se ejecuta el backend en
+	// desarrollo) se sirve la página embebida en lugar del frontend
+	var staticFS fs.FS = os.DirFS(staticDir)
 	if _, err := os.Stat(staticDir); os.IsNotExist(err) {
-		appLogger.Error("El directorio de archivos estáticos no existe", 
-			zap.String("static_dir", staticDir),
-			zap.Error(err))
-		// Intentar crear el directorio
-		if err := os.MkdirAll(staticDir, 0755); err != nil {
-			appLogger.Fatal("No se pudo crear el directorio de archivos estáticos", 
-				zap.String("static_dir", staticDir),
-				zap.Error(err))
-		}
-		appLogger.Info("Directorio de archivos estáticos creado", 
+		appLogger.Warn("El directorio de archivos estáticos no existe, se sirve la página embebida", 
 			zap.String("static_dir", staticDir))
+		staticFS, err = fs.Sub(placeholderFS, "build")
+		if err != nil {
+			appLogger.Fatal("Error al cargar la página embebida", zap.Error(err))
+		}
 	}
 	
-	fileServer := handlers.NewFileServer(staticDir, securityValidator)
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		clientIP := securityValidator.GetClientIP(r)
+	fileServer := handlers.NewFileServer(
+		staticFS,
+		securityValidator,
+		cfg.SPAFallbackFile,
+		cfg.SPANoFallbackPrefixes,
+	)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		appLogger.Info("Petición recibida", 
-			zap.String("ip", clientIP),
+			zap.String("ip", securityValidator.GetClientIP(r)),
 			zap.String("method", r.Method),
 			zap.String("path", r.URL.Path))
-
-		path := filepath.Join(staticDir, r.URL.Path)
-		_, err := os.Stat(path)
-		if os.IsNotExist(err) {
-			appLogger.Info("Archivo no encontrado, sirviendo index.html", 
-				zap.String("ip", clientIP),
-				zap.String("path", r.URL.Path))
-			http.ServeFile(w, r, filepath.Join(staticDir, "index.html"))
-			return
-		}
-		appLogger.Info("Sirviendo archivo", 
-			zap.String("ip", clientIP),
-			zap.String("path", r.URL.Path))
 		fileServer.ServeHTTP(w, r)
 	})
 
+	// Compilar los patrones de ALLOWED_ORIGINS (ya validados por la configuración)
+	originMatcher, err := security.NewOriginMatcher(cfg.AllowedOrigins)
+	if err != nil {
+		appLogger.Fatal("Error en ALLOWED_ORIGINS", zap.Error(err))
+	}
+	appLogger.Info("CORS configurado", 
+		zap.Strings("allowed_origins", cfg.AllowedOrigins))
+	
 	// Iniciar servidor
 	serverAddr := fmt.Sprintf("%s:%s", cfg.Host, cfg.Port)
+	if cfg.SocketPath != "" {
+		serverAddr = "unix:" + cfg.SocketPath
+	}
+	listener, err := listen(cfg)
+	if err != nil {
+		appLogger.Fatal("Error al iniciar el servidor", 
+			zap.String("address", serverAddr),
+			zap.Error(err))
+	}
+	// Un panic en cualquier ruta, incluidos los archivos estáticos, responde 500 y queda registrado
+	// Los cuerpos gzip se descomprimen antes de llegar a los manejadores
+	// X-Request-Duration se mide fuera de Recover para cubrir también sus respuestas 500
+	server := newServer(cfg, middleware.RequestTiming(middleware.Recover(middleware.CORS(middleware.RequestDecompression(mux, cfg.MaxDecompressedBodyBytes, appLogger), originMatcher), appLogger)))
 	appLogger.Info("Servidor iniciado", 
 		zap.String("address", serverAddr),
+		zap.Bool("tls", cfg.TLSEnabled()),
+		zap.Bool("http2", cfg.HTTP2Enabled && cfg.TLSEnabled()),
+		zap.Bool("h2c", cfg.H2CEnabled),
+		zap.Duration("read_timeout", cfg.ServerReadTimeout),
+		zap.Duration("write_timeout", cfg.ServerWriteTimeout),
+		zap.Duration("idle_timeout", cfg.ServerIdleTimeout),
 		zap.String("static_dir", staticDir))
 	
-	if err := http.ListenAndServe(serverAddr, nil); err != nil {
-		appLogger.Fatal("Error al iniciar el servidor", 
-			zap.String("address", serverAddr),
-			zap.Error(err))
+	// pprof solo en modo debug y en su propio listener (por defecto solo local)
+	var pprofServer *http.Server
+	if cfg.DebugMode && cfg.PprofAddr != "" {
+		pprofServer = &http.Server{
+			Addr:              cfg.PprofAddr,
+			Handler:           debug.NewPprofHandler(),
+			ReadHeaderTimeout: cfg.ServerReadTimeout,
+		}
+		appLogger.Warn("Perfilado pprof habilitado, no usar en producción", 
+			zap.String("address", cfg.PprofAddr))
+		go func() {
+			if err := pprofServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				appLogger.Error("Error en el servidor de pprof", 
+					zap.String("address", cfg.PprofAddr),
+					zap.Error(err))
+			}
+		}()
+	}
+	
+	// Redirección de HTTP a HTTPS en un segundo puerto, solo con TLS
+	var redirectServer *http.Server
+	if cfg.HTTPRedirectPort != "" {
+		redirectServer = &http.Server{
+			Addr:              net.JoinHostPort(cfg.Host, cfg.HTTPRedirectPort),
+			Handler:           middleware.HTTPSRedirect(cfg.Port),
+			ReadHeaderTimeout: cfg.ServerReadTimeout,
+			IdleTimeout:       cfg.ServerIdleTimeout,
+		}
+		appLogger.Info("Redirección de HTTP a HTTPS habilitada", 
+			zap.String("address", redirectServer.Addr))
+		go func() {
+			if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				appLogger.Error("Error en el servidor de redirección a HTTPS", 
+					zap.String("address", redirectServer.Addr),
+					zap.Error(err))
+			}
+		}()
+	}
+	
+	go func() {
+		var err error
+		if cfg.TLSEnabled() {
+			err = server.ServeTLS(listener, cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			err = server.Serve(listener)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			appLogger.Fatal("Error al iniciar el servidor", 
+				zap.String("address", serverAddr),
+				zap.Error(err))
+		}
+	}()
+
+	// Esperar la señal de apagado y cerrar las conexiones de forma ordenada
+	<-shutdownCtx.Done()
+	appLogger.Info("Apagando servidor")
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.MaxRequestTimeout()+5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		appLogger.Error("Error durante el apagado del servidor", zap.Error(err))
+	}
+	if pprofServer != nil {
+		pprofServer.Close()
+	}
+	if redirectServer != nil {
+		redirectServer.Close()
+	}
+	// Enviar los errores pendientes antes de salir
+	if errorReporter != nil && !errorReporter.Flush(2*time.Second) {
+		appLogger.Warn("No se pudieron enviar todos los errores a Sentry antes del apagado")
+	}
+	// Shutdown cierra el listener, que ya elimina el socket; esto cubre el resto de casos
+	if cfg.SocketPath != "" {
+		if err := os.Remove(cfg.SocketPath); err != nil && !os.IsNotExist(err) {
+			appLogger.Error("Error al eliminar el socket Unix", 
+				zap.String("socket_path", cfg.SocketPath),
+				zap.Error(err))
+		}
+	}
+}
+
+// smokeTestCode es el programa que ejecuta runStartupSmokeTest
+const smokeTestCode = `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("ok")
+}
+`
+
+// runStartupSmokeTest ejecuta smokeTestCode con ex y comprueba que imprime
+// "ok". Devuelve lo que tardó, como referencia de la latencia de una ejecución.
+func runStartupSmokeTest(ctx context.Context, ex executor.CodeExecutor, timeout time.Duration) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	start := time.Now()
+	result, err := executor.RunWithResult(ctx, ex, smokeTestCode, &output)
+	latency := time.Since(start)
+	if err != nil {
+		return latency, err
+	}
+	if result.ExitCode != 0 || output.String() != "ok\n" {
+		return latency, fmt.Errorf("salida inesperada (código de salida %d): %q", result.ExitCode, output.String())
+	}
+	return latency, nil
+}
+
+// newServer crea el servidor HTTP principal con handler y los timeouts de
+// cfg, que evitan que clientes lentos (slow loris) acaparen conexiones
+func newServer(cfg *config.Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ServerReadTimeout,
+		ReadTimeout:       cfg.ServerReadTimeout,
+		WriteTimeout:      cfg.ServerWriteTimeout,
+		IdleTimeout:       cfg.ServerIdleTimeout,
+		Protocols:         serverProtocols(cfg),
+	}
+}
+
+// serverProtocols devuelve los protocolos que acepta el servidor principal:
+// siempre HTTP/1.1, HTTP/2 con TLS si HTTP2_ENABLED y HTTP/2 sin cifrar (h2c)
+// si H2C_ENABLED. h2c solo se admite con conocimiento previo (el cliente
+// empieza directamente en HTTP/2), no con "Upgrade: h2c" desde HTTP/1.1.
+func serverProtocols(cfg *config.Config) *http.Protocols {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(cfg.HTTP2Enabled)
+	protocols.SetUnencryptedHTTP2(cfg.H2CEnabled)
+	return protocols
+}
+
+// listen abre el listener del servidor: un socket Unix si SERVER_SOCKET_PATH
+// está definido (ignorando SERVER_PORT) o TCP en SERVER_HOST:SERVER_PORT.
+//
+// Un socket de una ejecución anterior que no se cerró bien impediría escuchar,
+// por lo que se elimina antes. Solo se elimina si es realmente un socket.
+func listen(cfg *config.Config) (net.Listener, error) {
+	if cfg.SocketPath == "" {
+		return net.Listen("tcp", net.JoinHostPort(cfg.Host, cfg.Port))
+	}
+
+	if info, err := os.Lstat(cfg.SocketPath); err == nil {
+		if info.Mode().Type() != os.ModeSocket {
+			return nil, fmt.Errorf("%s existe y no es un socket", cfg.SocketPath)
+		}
+		if err := os.Remove(cfg.SocketPath); err != nil {
+			return nil, err
+		}
+	}
+
+	listener, err := net.Listen("unix", cfg.SocketPath)
+	if err != nil {
+		return nil, err
+	}
+	// Solo el usuario y el grupo del servidor (por ejemplo, Nginx en el mismo pod)
+	if err := os.Chmod(cfg.SocketPath, 0660); err != nil {
+		listener.Close()
+		return nil, err
 	}
+	return listener, nil
 }
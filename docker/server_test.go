@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luis198755/go_playGround_plus/docker/pkg/config"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/executor"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/handlers"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/limiter"
+	logtest "github.com/luis198755/go_playGround_plus/docker/pkg/logger/test"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/security"
+	"github.com/luis198755/go_playGround_plus/docker/pkg/session"
+)
+
+// serve sirve server en listener hasta el final del test
+func serve(t *testing.T, server *http.Server, listener net.Listener) {
+	t.Helper()
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+}
+
+func TestServerDisconnectsSlowHeaders(t *testing.T) {
+	cfg := &config.Config{
+		ServerReadTimeout:  200 * time.Millisecond,
+		ServerWriteTimeout: time.Minute,
+		ServerIdleTimeout:  time.Minute,
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve(t, newServer(cfg, http.NotFoundHandler()), listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Cabeceras que nunca terminan, como en un ataque slow loris
+	start := time.Now()
+	if _, err := io.WriteString(conn, "GET / HTTP/1.1\r\nHost: localhost\r\nX-Lento: "); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = io.ReadAll(conn)
+	elapsed := time.Since(start)
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		t.Fatalf("el servidor no cerró la conexión en %v", elapsed)
+	}
+	if elapsed < cfg.ServerReadTimeout || elapsed > cfg.ServerReadTimeout+time.Second {
+		t.Errorf("la conexión se cerró tras %v, se esperaba tras ReadHeaderTimeout (%v)", elapsed, cfg.ServerReadTimeout)
+	}
+}
+
+// newTestExecuteHandler crea el manejador de /api/execute con un GoExecutor
+// real, o salta el test si no hay toolchain de Go
+func newTestExecuteHandler(t *testing.T) http.Handler {
+	t.Helper()
+	goPath, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("no se encontró el ejecutable de Go")
+	}
+	log, _ := logtest.NewTestLogger(t)
+	executors := executor.NewExecutorRegistry()
+	err = executors.Register(executor.DefaultLanguage, func(*config.Config) (executor.CodeExecutor, error) {
+		return executor.NewGoExecutor(executor.GoExecutorOptions{
+			GoExecutablePath: goPath,
+			MaxStdoutLength:  10000,
+			MaxStderrLength:  10000,
+			TempDir:          t.TempDir(),
+		}, log)
+	})
+	if err == nil {
+		err = executors.Init(&config.Config{})
+	}
+	if err != nil {
+		t.Fatalf("registro de ejecutores: %v", err)
+	}
+
+	apiHandler := handlers.NewAPIHandler(
+		limiter.NewRateLimiter(60, 10, nil), nil,
+		security.NewCodeValidator(security.SecurityHeaders{}, nil, nil, true),
+		executors, session.NewSessionStore(10, time.Hour), log,
+		64*1024, 0, 0, 0, time.Minute, time.Minute, 0,
+		nil, nil, nil, nil, nil, false,
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/execute", apiHandler.HandleExecuteCode)
+	return mux
+}
+
+func TestServerOnUnixSocket(t *testing.T) {
+	handler := newTestExecuteHandler(t)
+	socketPath := filepath.Join(t.TempDir(), "playground.sock")
+	cfg := &config.Config{
+		SocketPath:         socketPath,
+		Port:               "1",
+		ServerReadTimeout:  5 * time.Second,
+		ServerWriteTimeout: time.Minute,
+		ServerIdleTimeout:  time.Minute,
+	}
+
+	listener, err := listen(cfg)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := newServer(cfg, handler)
+	go server.Serve(listener)
+
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0660 {
+		t.Errorf("permisos del socket = %o, se esperaba 660", perm)
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socketPath)
+		},
+	}}
+	body := `{"code":"package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println(\"hola desde el socket\") }\n"}`
+	resp, err := client.Post("http://playground/api/execute", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST por el socket: %v", err)
+	}
+	output, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(output), "hola desde el socket") {
+		t.Errorf("respuesta %d %q, se esperaba la salida del programa", resp.StatusCode, output)
+	}
+
+	// Shutdown cierra el listener, que elimina el archivo del socket
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("el socket sigue existiendo tras el apagado: %v", err)
+	}
+}
+
+func TestListenReplacesStaleSocketOnly(t *testing.T) {
+	dir := t.TempDir()
+
+	// Un socket de una ejecución anterior que no se cerró se sustituye
+	stalePath := filepath.Join(dir, "stale.sock")
+	stale, err := net.Listen("unix", stalePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+	listener, err := listen(&config.Config{SocketPath: stalePath})
+	if err != nil {
+		t.Fatalf("listen sobre un socket antiguo: %v", err)
+	}
+	listener.Close()
+
+	// Cualquier otro archivo se conserva
+	filePath := filepath.Join(dir, "datos")
+	if err := os.WriteFile(filePath, []byte("no borrar"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listen(&config.Config{SocketPath: filePath}); err == nil {
+		t.Fatal("listen sustituyó un archivo que no es un socket")
+	}
+	if data, err := os.ReadFile(filePath); err != nil || string(data) != "no borrar" {
+		t.Errorf("el archivo cambió: %q, %v", data, err)
+	}
+}